@@ -10,14 +10,21 @@ import (
 	"time"
 
 	"github.com/allaboutapps/integresql/internal/api"
+	"github.com/allaboutapps/integresql/internal/api/grpcapi"
 	"github.com/allaboutapps/integresql/internal/config"
 	"github.com/allaboutapps/integresql/internal/router"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
 )
 
 func main() {
 
+	// "integresql pool ..." talks to the admin API of a running server instead of starting one
+	if len(os.Args) > 1 && os.Args[1] == "pool" {
+		os.Exit(runPoolCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	cfg := api.DefaultServerConfigFromEnv()
 
 	zerolog.TimeFieldFormat = time.RFC3339Nano
@@ -32,10 +39,19 @@ func main() {
 
 	s := api.NewServer(cfg)
 
+	if err := s.InitAuditLog(); err != nil {
+		log.Fatal().Err(err).Msg("Failed to open audit log")
+	}
+
 	if err := s.InitManager(context.Background()); err != nil {
 		log.Fatal().Err(err).Msg("Failed to initialize manager")
 	}
 
+	// declared templates are warm before serving, thus clients never observe them missing
+	if _, err := s.WarmDeclaredTemplates(context.Background()); err != nil {
+		log.Fatal().Err(err).Msg("Failed to load declared templates")
+	}
+
 	router.Init(s)
 
 	go func() {
@@ -48,10 +64,55 @@ func main() {
 		}
 	}()
 
+	if cfg.GRPCPort > 0 {
+		grpcapi.Init(s)
+
+		go func() {
+			if err := s.StartGRPC(); err != nil {
+				if errors.Is(err, grpc.ErrServerStopped) {
+					log.Info().Msg("gRPC server closed")
+				} else {
+					log.Fatal().Err(err).Msg("Failed to start gRPC server")
+				}
+			}
+		}()
+	}
+
+	if len(cfg.SnapshotDumpDir) > 0 {
+		// dumps are taken one after another, a signal received meanwhile triggers another one afterwards
+		dump := make(chan os.Signal, 1)
+		notifySnapshotSignal(dump)
+
+		go func() {
+			for range dump {
+				path, err := s.DumpSnapshots(context.Background())
+				if err != nil {
+					log.Error().Err(err).Msg("Failed to dump pool snapshots")
+					continue
+				}
+
+				log.Info().Str("path", path).Msg("Dumped pool snapshots")
+			}
+		}()
+	}
+
+	exportCtx, stopExport := context.WithCancel(context.Background())
+	defer stopExport()
+
+	if len(cfg.StatsDAddress) > 0 {
+		go func() {
+			if err := s.ExportStatsD(exportCtx); err != nil {
+				log.Error().Err(err).Msg("Failed to export metrics to statsd")
+			}
+		}()
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	<-quit
 
+	stopExport()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
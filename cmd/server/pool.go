@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/client"
+)
+
+const poolUsage = `Usage: integresql pool [flags] <command> [hash]
+
+Commands:
+  stats           print the state of all pools
+  clean [hash]    schedule the dirty test databases of the pool (or of all pools) for immediate recreation
+  drain <hash>    remove all test databases of the pool, keeping the template
+  remove <hash>   discard the template and all its test databases
+  reset <hash>    stop tracking the template and remove all its test databases
+
+Flags:
+`
+
+type poolCommandResult struct {
+	TemplateHash string `json:"templateHash"`
+	Action       string `json:"action"`
+}
+
+type poolCleanResult struct {
+	TemplateHash string `json:"templateHash,omitempty"`
+	Scheduled    int    `json:"scheduled"`
+}
+
+// runPoolCommand executes the "integresql pool" CLI against the admin API of a running server and returns the exit code.
+func runPoolCommand(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("pool", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, poolUsage)
+		fs.PrintDefaults()
+	}
+
+	defaultConfig := client.DefaultClientConfigFromEnv()
+	baseURL := fs.String("url", defaultConfig.BaseURL, "base URL of the integresql API (INTEGRESQL_CLIENT_BASE_URL)")
+	asJSON := fs.Bool("json", false, "print the output as JSON instead of a table")
+	timeout := fs.Duration("timeout", 30*time.Second, "timeout of the request")
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if len(positional) == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	command, positional := positional[0], positional[1:]
+
+	minArgs, maxArgs := 1, 1
+	switch command {
+	case "stats":
+		minArgs, maxArgs = 0, 0
+	case "clean":
+		minArgs = 0
+	}
+
+	if len(positional) < minArgs || len(positional) > maxArgs {
+		fs.Usage()
+		return 2
+	}
+
+	c, err := client.NewClient(client.ClientConfig{
+		BaseURL:    *baseURL,
+		APIVersion: defaultConfig.APIVersion,
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to create client: %v\n", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	switch command {
+	case "stats":
+		snapshots, err := c.GetPoolSnapshots(ctx)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to get pool stats: %v\n", err)
+			return 1
+		}
+
+		if *asJSON {
+			err = writeJSON(stdout, snapshots)
+		} else {
+			err = writePoolSnapshotsTable(stdout, snapshots)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to write output: %v\n", err)
+			return 1
+		}
+
+		return 0
+	case "clean":
+		var hash string
+		if len(positional) > 0 {
+			hash = positional[0]
+		}
+
+		scheduled, err := c.CleanDirty(ctx, hash)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to clean dirty test databases: %v\n", err)
+			return 1
+		}
+
+		result := poolCleanResult{TemplateHash: hash, Scheduled: scheduled}
+		if *asJSON {
+			err = writeJSON(stdout, result)
+		} else {
+			_, err = fmt.Fprintf(stdout, "scheduled %d dirty test databases for cleaning\n", result.Scheduled)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to write output: %v\n", err)
+			return 1
+		}
+
+		return 0
+	case "drain", "remove", "reset":
+		hash := positional[0]
+
+		var action string
+		switch command {
+		case "drain":
+			action = "drained"
+			err = c.DrainPool(ctx, hash)
+		case "remove":
+			action = "removed"
+			err = c.DiscardTemplate(ctx, hash)
+		case "reset":
+			action = "reset"
+			err = c.ResetTracking(ctx, hash)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to %s pool %s: %v\n", command, hash, err)
+			return 1
+		}
+
+		result := poolCommandResult{TemplateHash: hash, Action: action}
+		if *asJSON {
+			err = writeJSON(stdout, result)
+		} else {
+			_, err = fmt.Fprintf(stdout, "%s %s\n", result.Action, result.TemplateHash)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to write output: %v\n", err)
+			return 1
+		}
+
+		return 0
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n", command)
+		fs.Usage()
+		return 2
+	}
+}
+
+// parseInterspersed parses the flags of the given FlagSet, also allowing flags after positional arguments (e.g. "drain <hash> --json").
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func writePoolSnapshotsTable(w io.Writer, snapshots []client.PoolSnapshot) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "HASH\tREADY\tDIRTY\tRECREATING\tTOTAL\tTARGET\tMAX")
+	for _, s := range snapshots {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%d\n", s.TemplateHash, s.Ready, s.Dirty, s.Recreating, len(s.TestDatabases), s.ReadyTarget, s.MaxPoolSize)
+	}
+
+	return tw.Flush()
+}
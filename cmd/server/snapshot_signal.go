@@ -0,0 +1,14 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifySnapshotSignal relays SIGUSR1 onto the given channel, requesting a dump of the pool snapshots.
+func notifySnapshotSignal(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGUSR1)
+}
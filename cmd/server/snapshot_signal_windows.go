@@ -0,0 +1,6 @@
+package main
+
+import "os"
+
+// notifySnapshotSignal is a no-op, SIGUSR1 does not exist on Windows.
+func notifySnapshotSignal(_ chan<- os.Signal) {}
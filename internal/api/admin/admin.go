@@ -1,14 +1,72 @@
 package admin
 
 import (
+	"bytes"
+	"errors"
 	"net/http"
+	"sort"
+	"strconv"
 
 	"github.com/allaboutapps/integresql/internal/api"
+	"github.com/allaboutapps/integresql/internal/api/middleware"
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/allaboutapps/integresql/pkg/manager"
+	"github.com/allaboutapps/integresql/pkg/pool"
+	"github.com/allaboutapps/integresql/pkg/util"
 	"github.com/labstack/echo/v4"
 )
 
+func getServerInfo(s *api.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		info, err := s.Manager.ServerInfo()
+		if err != nil {
+			if errors.Is(err, manager.ErrManagerNotReady) {
+				return echo.ErrServiceUnavailable
+			}
+
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		return c.JSON(http.StatusOK, &info)
+	}
+}
+
+// getConnectionEstimate returns the connections the clients of the test DBs of all pools could demand at most, compared to max_connections.
+func getConnectionEstimate(s *api.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		estimate, err := s.Manager.EstimateConnections(c.Request().Context())
+		if err != nil {
+			if errors.Is(err, manager.ErrManagerNotReady) {
+				return echo.ErrServiceUnavailable
+			}
+
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		return c.JSON(http.StatusOK, &estimate)
+	}
+}
+
+func getConfig(s *api.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// the config spans all hashes, thus only tokens restricted to no prefix at all may read it (if configured)
+		if err := middleware.CheckHashAllowed(c, ""); err != nil {
+			return err
+		}
+
+		config := s.Manager.Config().Redacted()
+
+		return c.JSON(http.StatusOK, &config)
+	}
+}
+
 func deleteResetAllTemplates(s *api.Server) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		// spans all hashes, thus only tokens restricted to no prefix at all may reset them (if configured)
+		if err := middleware.CheckHashAllowed(c, ""); err != nil {
+			return err
+		}
+
 		ctx := c.Request().Context()
 		if err := s.Manager.ResetAllTracking(ctx); err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
@@ -17,3 +75,618 @@ func deleteResetAllTemplates(s *api.Server) echo.HandlerFunc {
 		return c.NoContent(http.StatusNoContent)
 	}
 }
+
+func deleteResetTemplate(s *api.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		hash := c.Param("hash")
+
+		if err := s.Manager.ResetTracking(c.Request().Context(), hash); err != nil {
+			if errors.Is(err, manager.ErrManagerNotReady) {
+				return echo.ErrServiceUnavailable
+			} else if errors.Is(err, manager.ErrTemplateNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "template not found")
+			}
+
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// maxPageLimit caps the page size of the paginated admin list endpoints.
+const maxPageLimit = 500
+
+// headerTotalCount reports the total number of items of a paginated admin list endpoint, the body is the page itself.
+const headerTotalCount = "X-Total-Count"
+
+// getPoolSnapshots lists the snapshots of all pools sorted by template hash, paginated via ?limit=...&offset=... (if supplied).
+// If a hash allowlist is configured, only the pools of hashes allowed for the token of the request are included (and counted).
+func getPoolSnapshots(s *api.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		offset, limit, err := pageParams(c)
+		if err != nil {
+			return err
+		}
+
+		var snapshots []pool.PoolSnapshot
+		var total int
+		if middleware.CheckHashAllowed(c, "") == nil {
+			snapshots, total, err = s.Manager.GetPoolSnapshotsPage(c.Request().Context(), offset, limit)
+		} else {
+			snapshots, total, err = allowedPoolSnapshotsPage(c, s, offset, limit)
+		}
+		if err != nil {
+			if errors.Is(err, manager.ErrManagerNotReady) {
+				return echo.ErrServiceUnavailable
+			}
+
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		c.Response().Header().Set(headerTotalCount, strconv.Itoa(total))
+
+		return c.JSON(http.StatusOK, snapshots)
+	}
+}
+
+// allowedPoolSnapshotsPage is GetPoolSnapshotsPage restricted to the hashes allowed for the token of the request.
+func allowedPoolSnapshotsPage(c echo.Context, s *api.Server, offset int, limit int) ([]pool.PoolSnapshot, int, error) {
+	snapshots, err := s.Manager.GetPoolSnapshots(c.Request().Context())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	allowed := make([]pool.PoolSnapshot, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		if middleware.CheckHashAllowed(c, snapshot.TemplateHash) == nil {
+			allowed = append(allowed, snapshot)
+		}
+	}
+
+	sort.Slice(allowed, func(i, j int) bool {
+		return allowed[i].TemplateHash < allowed[j].TemplateHash
+	})
+
+	total := len(allowed)
+	if offset > total {
+		offset = total
+	}
+	allowed = allowed[offset:]
+
+	if limit > 0 && limit < len(allowed) {
+		allowed = allowed[:limit]
+	}
+
+	return allowed, total, nil
+}
+
+// pageParams returns the offset and limit of the optional ?offset=...&limit=... query params, limit is 0 (all items) if not supplied.
+// Negative values and non-numbers are refused, the limit is clamped to maxPageLimit.
+func pageParams(c echo.Context) (offset int, limit int, err error) {
+	if param := c.QueryParam("offset"); len(param) > 0 {
+		offset, err = strconv.Atoi(param)
+		if err != nil || offset < 0 {
+			return 0, 0, echo.NewHTTPError(http.StatusBadRequest, "invalid offset, expected a number >= 0")
+		}
+	}
+
+	if param := c.QueryParam("limit"); len(param) > 0 {
+		limit, err = strconv.Atoi(param)
+		if err != nil || limit <= 0 {
+			return 0, 0, echo.NewHTTPError(http.StatusBadRequest, "invalid limit, expected a number > 0")
+		}
+
+		if limit > maxPageLimit {
+			limit = maxPageLimit
+		}
+	}
+
+	return offset, limit, nil
+}
+
+// getPendingCleanup lists the dirty test DBs waiting to be cleaned per template hash.
+// If a hash allowlist is configured, only the hashes allowed for the token of the request are included.
+func getPendingCleanup(s *api.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		pending, err := s.Manager.GetPendingCleanup(c.Request().Context())
+		if err != nil {
+			if errors.Is(err, manager.ErrManagerNotReady) {
+				return echo.ErrServiceUnavailable
+			}
+
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		for hash := range pending {
+			if middleware.CheckHashAllowed(c, hash) != nil {
+				delete(pending, hash)
+			}
+		}
+
+		return c.JSON(http.StatusOK, pending)
+	}
+}
+
+// getMetricsSnapshot renders the current pool snapshots and the template lifecycle metrics in the Prometheus text exposition format.
+// If a hash allowlist is configured, only the pools of hashes allowed for the token of the request are included.
+func getMetricsSnapshot(s *api.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		snapshots, err := s.Manager.GetPoolSnapshots(c.Request().Context())
+		if err != nil {
+			if errors.Is(err, manager.ErrManagerNotReady) {
+				return echo.ErrServiceUnavailable
+			}
+
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		allowed := make([]pool.PoolSnapshot, 0, len(snapshots))
+		for _, snapshot := range snapshots {
+			if middleware.CheckHashAllowed(c, snapshot.TemplateHash) == nil {
+				allowed = append(allowed, snapshot)
+			}
+		}
+
+		var buf bytes.Buffer
+		if err := pool.WritePrometheus(&buf, allowed); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		// the template lifecycle metrics span all hashes, thus only tokens restricted to no prefix at all may read them (if configured)
+		if middleware.CheckHashAllowed(c, "") == nil {
+			if err := s.Manager.LifecycleMetrics().WritePrometheus(&buf); err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+			}
+		}
+
+		return c.Blob(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", buf.Bytes())
+	}
+}
+
+// getTemplatesCSV streams the status of all pools as CSV (a header and one record per pool), e.g. for capacity reviews in a spreadsheet.
+// If a hash allowlist is configured, only the pools of hashes allowed for the token of the request are included.
+func getTemplatesCSV(s *api.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !s.Manager.Ready() {
+			return echo.ErrServiceUnavailable
+		}
+
+		res := c.Response()
+		res.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+		res.Header().Set(echo.HeaderContentDisposition, `attachment; filename="templates.csv"`)
+		res.WriteHeader(http.StatusOK)
+
+		// the status is sent already, thus errors while streaming can only abort the response
+		w := pool.NewSnapshotCSVWriter(res)
+		if err := w.WriteHeader(); err != nil {
+			return err
+		}
+
+		return s.Manager.ForEachPoolSnapshot(c.Request().Context(), func(snapshot pool.PoolSnapshot) error {
+			if middleware.CheckHashAllowed(c, snapshot.TemplateHash) != nil {
+				return nil
+			}
+
+			if err := w.Write(snapshot); err != nil {
+				return err
+			}
+
+			res.Flush()
+			return nil
+		})
+	}
+}
+
+func getPoolSnapshot(s *api.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		hash := c.Param("hash")
+
+		snapshot, err := s.Manager.GetPoolSnapshot(c.Request().Context(), hash)
+		if err != nil {
+			if errors.Is(err, manager.ErrManagerNotReady) {
+				return echo.ErrServiceUnavailable
+			} else if errors.Is(err, manager.ErrTemplateNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "template not found")
+			}
+
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		return c.JSON(http.StatusOK, &snapshot)
+	}
+}
+
+func getInUseTestDatabases(s *api.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		hash := c.Param("hash")
+
+		inUse, err := s.Manager.GetInUseTestDatabases(c.Request().Context(), hash)
+		if err != nil {
+			if errors.Is(err, manager.ErrManagerNotReady) {
+				return echo.ErrServiceUnavailable
+			} else if errors.Is(err, manager.ErrTemplateNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "template not found")
+			}
+
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		return c.JSON(http.StatusOK, &inUse)
+	}
+}
+
+func getTestDatabaseDSN(s *api.Server) echo.HandlerFunc {
+	type responsePayload struct {
+		DSN        string  `json:"dsn"`
+		ReplicaDSN *string `json:"replicaDsn,omitempty"`
+		Redacted   bool    `json:"redacted"`
+	}
+
+	return func(c echo.Context) error {
+		hash := c.Param("hash")
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid test database ID")
+		}
+
+		// password is redacted by default, revealing it requires an authenticated token and is audited
+		reveal := c.QueryParam("reveal") == "true"
+		if reveal {
+			middleware.SetAuditReveal(c)
+
+			if err := middleware.CheckAuthenticated(c); err != nil {
+				return err
+			}
+		}
+
+		testDB, err := s.Manager.PeekTestDatabase(c.Request().Context(), hash, id)
+		if err != nil {
+			if errors.Is(err, manager.ErrManagerNotReady) {
+				return echo.ErrServiceUnavailable
+			} else if errors.Is(err, manager.ErrTemplateNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "template not found")
+			} else if errors.Is(err, manager.ErrTestNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "test database not found")
+			}
+
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		dsn := func(config db.DatabaseConfig) string {
+			if reveal {
+				return config.ConnectionString()
+			}
+			return config.RedactedConnectionString()
+		}
+
+		response := responsePayload{DSN: dsn(testDB.Config), Redacted: !reveal}
+		if testDB.Replica != nil {
+			replicaDSN := dsn(*testDB.Replica)
+			response.ReplicaDSN = &replicaDSN
+		}
+
+		return c.JSON(http.StatusOK, &response)
+	}
+}
+
+func deleteForceRemoveTestDatabase(s *api.Server) echo.HandlerFunc {
+	type responsePayload struct {
+		ID int `json:"id"`
+	}
+
+	return func(c echo.Context) error {
+		hash := c.Param("hash")
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid test database ID")
+		}
+
+		ctx := c.Request().Context()
+		log := util.LogFromContext(ctx).With().Str("hash", hash).Int("id", id).Str("remoteIP", c.RealIP()).Logger()
+
+		if err := s.Manager.ForceRemoveTestDatabase(ctx, hash, id); err != nil {
+			log.Warn().Err(err).Msg("force-removing test database failed")
+
+			if errors.Is(err, manager.ErrManagerNotReady) {
+				return echo.ErrServiceUnavailable
+			} else if errors.Is(err, manager.ErrTemplateNotFound) || errors.Is(err, pool.ErrUnknownHash) {
+				return echo.NewHTTPError(http.StatusNotFound, "template not found")
+			} else if errors.Is(err, pool.ErrInvalidIndex) {
+				return echo.NewHTTPError(http.StatusNotFound, "test database not found")
+			} else if errors.Is(err, pool.ErrUnknownID) {
+				return echo.NewHTTPError(http.StatusConflict, pool.ErrUnknownID.Error())
+			}
+
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		log.Warn().Msg("force-removed test database")
+
+		return c.JSON(http.StatusOK, &responsePayload{ID: id})
+	}
+}
+
+func postPromoteTestDatabase(s *api.Server) echo.HandlerFunc {
+	type requestPayload struct {
+		NewHash string `json:"newHash"`
+	}
+
+	return func(c echo.Context) error {
+		hash := c.Param("hash")
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid test database ID")
+		}
+
+		var payload requestPayload
+
+		if err := c.Bind(&payload); err != nil {
+			return err
+		}
+
+		if len(payload.NewHash) == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "newHash is required")
+		}
+
+		ctx := c.Request().Context()
+		log := util.LogFromContext(ctx).With().Str("hash", hash).Int("id", id).Str("newHash", payload.NewHash).Str("remoteIP", c.RealIP()).Logger()
+
+		template, err := s.Manager.PromoteTestDatabaseToTemplate(ctx, hash, id, payload.NewHash)
+		if err != nil {
+			log.Warn().Err(err).Msg("promoting test database to template failed")
+
+			if errors.Is(err, manager.ErrManagerNotReady) {
+				return echo.ErrServiceUnavailable
+			} else if errors.Is(err, manager.ErrTemplateNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "template not found")
+			} else if errors.Is(err, manager.ErrTestNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "test database not found")
+			} else if errors.Is(err, manager.ErrInvalidTemplateState) {
+				return echo.NewHTTPError(http.StatusConflict, "template is not finalized")
+			} else if errors.Is(err, pool.ErrTestDBInUse) {
+				return echo.NewHTTPError(http.StatusLocked, pool.ErrTestDBInUse.Error())
+			} else if errors.Is(err, manager.ErrTemplateAlreadyInitialized) {
+				return echo.NewHTTPError(http.StatusConflict, "new hash is already initialized")
+			}
+
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		log.Info().Msg("promoted test database to template")
+
+		return c.JSON(http.StatusOK, &template)
+	}
+}
+
+func putMaxPoolSize(s *api.Server) echo.HandlerFunc {
+	type requestPayload struct {
+		MaxPoolSize int `json:"maxPoolSize"`
+	}
+
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		hash := c.Param("hash") // optional, all pools if empty
+
+		// all pools: only tokens restricted to no prefix at all may resize them (if configured)
+		if len(hash) == 0 {
+			if err := middleware.CheckHashAllowed(c, ""); err != nil {
+				return err
+			}
+		}
+
+		var payload requestPayload
+
+		if err := c.Bind(&payload); err != nil {
+			return err
+		}
+
+		if err := s.Manager.SetMaxPoolSize(ctx, hash, payload.MaxPoolSize); err != nil {
+			if errors.Is(err, manager.ErrManagerNotReady) {
+				return echo.ErrServiceUnavailable
+			} else if errors.Is(err, manager.ErrTemplateNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "template not found")
+			} else if errors.Is(err, pool.ErrInvalidSize) || errors.Is(err, pool.ErrExceedsCapacity) {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		// respond with the effective sizes
+		if len(hash) == 0 {
+			snapshots, err := s.Manager.GetPoolSnapshots(ctx)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+			}
+
+			return c.JSON(http.StatusOK, snapshots)
+		}
+
+		snapshot, err := s.Manager.GetPoolSnapshot(ctx, hash)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		return c.JSON(http.StatusOK, &snapshot)
+	}
+}
+
+func putPoolSize(s *api.Server) echo.HandlerFunc {
+	type requestPayload struct {
+		Target int `json:"target"`
+	}
+
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+		hash := c.Param("hash")
+
+		var payload requestPayload
+
+		if err := c.Bind(&payload); err != nil {
+			return err
+		}
+
+		if _, err := s.Manager.SetReadyTarget(ctx, hash, payload.Target); err != nil {
+			if errors.Is(err, manager.ErrManagerNotReady) {
+				return echo.ErrServiceUnavailable
+			} else if errors.Is(err, manager.ErrTemplateNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "template not found")
+			} else if errors.Is(err, pool.ErrInvalidSize) {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		// respond with the effective target
+		snapshot, err := s.Manager.GetPoolSnapshot(ctx, hash)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		return c.JSON(http.StatusOK, &snapshot)
+	}
+}
+
+func deleteDrainPool(s *api.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		hash := c.Param("hash")
+
+		if err := s.Manager.ClearTrackedTestDatabases(c.Request().Context(), hash); err != nil {
+			if errors.Is(err, manager.ErrManagerNotReady) {
+				return echo.ErrServiceUnavailable
+			} else if errors.Is(err, manager.ErrTemplateNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "template not found")
+			}
+
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+func postCleanDirty(s *api.Server) echo.HandlerFunc {
+	type responsePayload struct {
+		Scheduled int `json:"scheduled"`
+	}
+
+	return func(c echo.Context) error {
+		hash := c.Param("hash") // optional, all pools if empty
+
+		// all pools: only tokens restricted to no prefix at all may clean them (if configured)
+		if len(hash) == 0 {
+			if err := middleware.CheckHashAllowed(c, ""); err != nil {
+				return err
+			}
+		}
+
+		scheduled, err := s.Manager.CleanDirtyTestDatabases(c.Request().Context(), hash)
+		if err != nil {
+			if errors.Is(err, manager.ErrManagerNotReady) {
+				return echo.ErrServiceUnavailable
+			} else if errors.Is(err, manager.ErrTemplateNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "template not found")
+			}
+
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		return c.JSON(http.StatusAccepted, &responsePayload{Scheduled: scheduled})
+	}
+}
+
+// getAliases lists all aliases mapped to their template hash.
+// If a hash allowlist is configured, only the aliases pointing to hashes allowed for the token of the request are included.
+func getAliases(s *api.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		aliases, err := s.Manager.GetAliases(c.Request().Context())
+		if err != nil {
+			if errors.Is(err, manager.ErrManagerNotReady) {
+				return echo.ErrServiceUnavailable
+			}
+
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		for alias, hash := range aliases {
+			if middleware.CheckHashAllowed(c, hash) != nil {
+				delete(aliases, alias)
+			}
+		}
+
+		return c.JSON(http.StatusOK, aliases)
+	}
+}
+
+func putAlias(s *api.Server) echo.HandlerFunc {
+	type requestPayload struct {
+		Hash string `json:"hash"`
+	}
+
+	type responsePayload struct {
+		Alias        string `json:"alias"`
+		Hash         string `json:"hash"`
+		PreviousHash string `json:"previousHash,omitempty"`
+	}
+
+	return func(c echo.Context) error {
+		alias := c.Param("alias")
+
+		var payload requestPayload
+
+		if err := c.Bind(&payload); err != nil {
+			return err
+		}
+
+		if len(payload.Hash) == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "hash is required")
+		}
+
+		// the alias is used in place of the hash (thus checked by the allowlist of the templates API), it may only point to an allowed hash (if configured)
+		if err := middleware.CheckHashAllowed(c, alias); err != nil {
+			return err
+		}
+		if err := middleware.CheckHashAllowed(c, payload.Hash); err != nil {
+			return err
+		}
+
+		previous, err := s.Manager.SetAlias(c.Request().Context(), alias, payload.Hash)
+		if err != nil {
+			if errors.Is(err, manager.ErrManagerNotReady) {
+				return echo.ErrServiceUnavailable
+			} else if errors.Is(err, manager.ErrTemplateNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "template not found")
+			} else if errors.Is(err, manager.ErrInvalidTemplateState) {
+				return echo.NewHTTPError(http.StatusConflict, "template is not finalized")
+			}
+
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		return c.JSON(http.StatusOK, &responsePayload{Alias: alias, Hash: payload.Hash, PreviousHash: previous})
+	}
+}
+
+func deleteAlias(s *api.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		alias := c.Param("alias")
+
+		if err := middleware.CheckHashAllowed(c, alias); err != nil {
+			return err
+		}
+
+		if err := s.Manager.RemoveAlias(c.Request().Context(), alias); err != nil {
+			if errors.Is(err, manager.ErrManagerNotReady) {
+				return echo.ErrServiceUnavailable
+			} else if errors.Is(err, manager.ErrAliasNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "alias not found")
+			}
+
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
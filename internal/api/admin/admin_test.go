@@ -0,0 +1,78 @@
+package admin_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/allaboutapps/integresql/internal/api"
+	"github.com/allaboutapps/integresql/internal/test"
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// acquireTestDatabase initializes and finalizes a template with a random hash, returning it and the ID of an acquired test DB.
+func acquireTestDatabase(t *testing.T, s *api.Server, headers http.Header) (string, int) {
+	t.Helper()
+
+	hash := uuid.NewString()
+
+	res := test.PerformRequest(t, s, http.MethodPost, "/api/v1/templates", test.GenericPayload{"hash": hash}, headers)
+	require.Equal(t, http.StatusOK, res.Result().StatusCode)
+
+	res = test.PerformRequest(t, s, http.MethodPut, "/api/v1/templates/"+hash, nil, headers)
+	require.Equal(t, http.StatusNoContent, res.Result().StatusCode)
+
+	res = test.PerformRequest(t, s, http.MethodGet, "/api/v1/templates/"+hash+"/tests", nil, headers)
+	require.Equal(t, http.StatusOK, res.Result().StatusCode)
+
+	var testDB map[string]interface{}
+	test.ParseResponseBody(t, res, &testDB)
+	require.IsType(t, float64(0), testDB["id"])
+
+	return hash, int(testDB["id"].(float64))
+}
+
+func TestGetTestDatabaseDSNRevealRefused(t *testing.T) {
+	test.WithTestServer(t, func(s *api.Server) {
+		hash, id := acquireTestDatabase(t, s, nil)
+		path := fmt.Sprintf("/api/v1/admin/tests/%s/%d/dsn", hash, id)
+
+		// no hash allowlist configured, thus no token is authenticated
+		res := test.PerformRequest(t, s, http.MethodGet, path+"?reveal=true", nil, test.HeadersWithAuth(t, "token-a"))
+		assert.Equal(t, http.StatusForbidden, res.Result().StatusCode)
+
+		// the redacted DSN is still available
+		res = test.PerformRequest(t, s, http.MethodGet, path, nil, nil)
+		require.Equal(t, http.StatusOK, res.Result().StatusCode)
+
+		var dsn map[string]interface{}
+		test.ParseResponseBody(t, res, &dsn)
+		assert.Equal(t, true, dsn["redacted"])
+		assert.Contains(t, dsn["dsn"], db.RedactedPassword)
+	})
+}
+
+func TestGetTestDatabaseDSNRevealAllowed(t *testing.T) {
+	config := api.DefaultServerConfigFromEnv()
+	config.HashAllowlist = map[string]string{"token-a": ""}
+
+	test.WithTestServerConfigurable(t, config, func(s *api.Server) {
+		headers := test.HeadersWithAuth(t, "token-a")
+		hash, id := acquireTestDatabase(t, s, headers)
+		path := fmt.Sprintf("/api/v1/admin/tests/%s/%d/dsn?reveal=true", hash, id)
+
+		res := test.PerformRequest(t, s, http.MethodGet, path, nil, nil)
+		assert.Equal(t, http.StatusUnauthorized, res.Result().StatusCode)
+
+		res = test.PerformRequest(t, s, http.MethodGet, path, nil, headers)
+		require.Equal(t, http.StatusOK, res.Result().StatusCode)
+
+		var dsn map[string]interface{}
+		test.ParseResponseBody(t, res, &dsn)
+		assert.Equal(t, false, dsn["redacted"])
+		assert.NotContains(t, dsn["dsn"], db.RedactedPassword)
+	})
+}
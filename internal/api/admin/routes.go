@@ -1,9 +1,55 @@
 package admin
 
-import "github.com/allaboutapps/integresql/internal/api"
+import (
+	"github.com/allaboutapps/integresql/internal/api"
+	"github.com/allaboutapps/integresql/internal/api/middleware"
+	"github.com/labstack/echo/v4"
+)
 
 func InitRoutes(s *api.Server) {
 	g := s.Echo.Group("/api/v1/admin")
 
-	g.DELETE("/templates", deleteResetAllTemplates(s))
+	// routes of a hash are restricted to the tokens allowed to access it, routes spanning all hashes either to the tokens
+	// restricted to no prefix at all or only include the hashes allowed for the token (if configured)
+	var allowlistMiddleware []echo.MiddlewareFunc
+	if len(s.Config.HashAllowlist) > 0 {
+		allowlistMiddleware = append(allowlistMiddleware, middleware.HashAllowlist(s.Config.HashAllowlist))
+	}
+
+	// mutating routes and the ones revealing secrets are audited (if configured)
+	audited := append(s.AuditMiddleware(), allowlistMiddleware...)
+
+	g.GET("/info", getServerInfo(s))
+	g.GET("/connections", getConnectionEstimate(s))
+	g.DELETE("/templates", deleteResetAllTemplates(s), audited...)
+	g.DELETE("/templates/:hash", deleteResetTemplate(s), audited...)
+	g.GET("/pools", getPoolSnapshots(s), allowlistMiddleware...)
+	g.GET("/pools/:hash", getPoolSnapshot(s), allowlistMiddleware...)
+	g.GET("/pools/:hash/inuse", getInUseTestDatabases(s), allowlistMiddleware...)
+	g.GET("/pending-cleanup", getPendingCleanup(s), allowlistMiddleware...)
+
+	// may reveal the password (to tokens of the hash allowlist only)
+	g.GET("/tests/:hash/:id/dsn", getTestDatabaseDSN(s), audited...)
+
+	// terminates the connections of a client
+	g.DELETE("/templates/:hash/tests/:id", deleteForceRemoveTestDatabase(s), audited...)
+
+	// copies the content of a test DB
+	g.POST("/templates/:hash/tests/:id/promote", postPromoteTestDatabase(s), audited...)
+
+	// effective config (passwords redacted)
+	g.GET("/config", getConfig(s), allowlistMiddleware...)
+
+	g.GET("/metrics-snapshot", getMetricsSnapshot(s), allowlistMiddleware...)
+	g.GET("/templates.csv", getTemplatesCSV(s), allowlistMiddleware...)
+
+	g.PUT("/pools", putMaxPoolSize(s), audited...)
+	g.PUT("/pools/:hash", putMaxPoolSize(s), audited...)
+	g.DELETE("/pools/:hash", deleteDrainPool(s), audited...)
+	g.PUT("/templates/:hash/pool-size", putPoolSize(s), audited...)
+	g.POST("/clean", postCleanDirty(s), audited...)
+	g.POST("/clean/:hash", postCleanDirty(s), audited...)
+	g.GET("/aliases", getAliases(s), allowlistMiddleware...)
+	g.PUT("/aliases/:alias", putAlias(s), audited...)
+	g.DELETE("/aliases/:alias", deleteAlias(s), audited...)
 }
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"io"
+	"os"
+
+	"github.com/allaboutapps/integresql/internal/api/middleware"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+)
+
+// InitAuditLog opens the sink of the audit trail of all mutating operations configured by the AuditLog:
+// "stdout", "stderr" or the path of a file the entries are appended to (JSON lines). Nothing is audited if unset.
+func (s *Server) InitAuditLog() error {
+	var w io.Writer
+
+	switch s.Config.AuditLog {
+	case "":
+		return nil
+	case "stdout":
+		w = os.Stdout
+	case "stderr":
+		w = os.Stderr
+	default:
+		f, err := os.OpenFile(s.Config.AuditLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+
+		s.auditFile = f
+		w = f
+	}
+
+	logger := zerolog.New(w).With().Timestamp().Logger()
+	s.Audit = &logger
+
+	return nil
+}
+
+// AuditMiddleware returns the middleware writing a route into the audit trail (see middleware.Audit), none if the audit log is disabled.
+func (s *Server) AuditMiddleware() []echo.MiddlewareFunc {
+	if s.Audit == nil {
+		return nil
+	}
+
+	return []echo.MiddlewareFunc{middleware.Audit(*s.Audit)}
+}
+
+// closeAuditLog closes the file of the audit trail (if any).
+func (s *Server) closeAuditLog() error {
+	if s.auditFile == nil {
+		return nil
+	}
+
+	return s.auditFile.Close()
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/allaboutapps/integresql/pkg/util"
+	"github.com/rs/zerolog/log"
+)
+
+// ConfigVarHeaderPrefix prefixes the HTTP headers (respectively gRPC metadata keys) supplying variables of the ConfigTemplate per request,
+// underscores of the variable name are replaced by hyphens (e.g. X-Integresql-Var-PGBOUNCER-HOST for ${PGBOUNCER_HOST}).
+const ConfigVarHeaderPrefix = "X-Integresql-Var-"
+
+var ErrUndefinedConfigVar = errors.New("undefined variable referenced by the test database config template")
+
+var configVarPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ConfigTemplate overrides fields of the test DB configs returned to clients, empty fields are kept as is (e.g. to route clients through a pgbouncer).
+// All values may reference variables as ${VAR}, resolved per request from the ConfigVarHeaderPrefix headers or else the environment of the server.
+type ConfigTemplate struct {
+	Host             string            `json:"host,omitempty"`
+	Port             string            `json:"port,omitempty"` // string, as it may reference a variable
+	Username         string            `json:"username,omitempty"`
+	Password         string            `json:"password,omitempty"`
+	AdditionalParams map[string]string `json:"additionalParams,omitempty"` // merged into the additional params of the test DB
+}
+
+// ConfigVarLookupFunc looks up the value of a variable referenced by the ConfigTemplate, ok is false if the variable is undefined.
+type ConfigVarLookupFunc func(name string) (value string, ok bool)
+
+// HeaderConfigVars looks up variables from the ConfigVarHeaderPrefix headers of a HTTP request, falling back to the environment of the server.
+func HeaderConfigVars(header http.Header) ConfigVarLookupFunc {
+	return func(name string) (string, bool) {
+		if values := header.Values(ConfigVarHeaderName(name)); len(values) > 0 {
+			return values[0], true
+		}
+
+		return os.LookupEnv(name)
+	}
+}
+
+// ConfigVarHeaderName returns the name of the header supplying the given variable.
+func ConfigVarHeaderName(name string) string {
+	return ConfigVarHeaderPrefix + strings.ReplaceAll(name, "_", "-")
+}
+
+// Resolve substitutes all referenced variables and returns a func applying the template to a test DB config.
+// All undefined variables are reported at once (ErrUndefinedConfigVar), thus no test DB needs to be acquired before.
+func (t *ConfigTemplate) Resolve(lookup ConfigVarLookupFunc) (func(db.DatabaseConfig) db.DatabaseConfig, error) {
+	if t == nil {
+		return func(config db.DatabaseConfig) db.DatabaseConfig { return config }, nil
+	}
+
+	undefined := map[string]struct{}{}
+	substitute := func(value string) string {
+		return configVarPattern.ReplaceAllStringFunc(value, func(match string) string {
+			name := configVarPattern.FindStringSubmatch(match)[1]
+			resolved, ok := lookup(name)
+			if !ok {
+				undefined[name] = struct{}{}
+			}
+			return resolved
+		})
+	}
+
+	resolved := ConfigTemplate{
+		Host:     substitute(t.Host),
+		Port:     substitute(t.Port),
+		Username: substitute(t.Username),
+		Password: substitute(t.Password),
+	}
+	if len(t.AdditionalParams) > 0 {
+		resolved.AdditionalParams = make(map[string]string, len(t.AdditionalParams))
+		for k, v := range t.AdditionalParams {
+			resolved.AdditionalParams[k] = substitute(v)
+		}
+	}
+
+	if len(undefined) > 0 {
+		names := make([]string, 0, len(undefined))
+		for name := range undefined {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return nil, fmt.Errorf("%w: %s (set in the environment of the server or via %s<VAR> headers)", ErrUndefinedConfigVar, strings.Join(names, ", "), ConfigVarHeaderPrefix)
+	}
+
+	var port int
+	if len(resolved.Port) > 0 {
+		var err error
+		if port, err = strconv.Atoi(resolved.Port); err != nil {
+			return nil, fmt.Errorf("invalid port %q of the test database config template: %w", resolved.Port, err)
+		}
+	}
+
+	return func(config db.DatabaseConfig) db.DatabaseConfig {
+		if len(resolved.Host) > 0 {
+			config.Host = resolved.Host
+		}
+		if port > 0 {
+			config.Port = port
+		}
+		if len(resolved.Username) > 0 {
+			config.Username = resolved.Username
+		}
+		if len(resolved.Password) > 0 {
+			config.Password = resolved.Password
+		}
+
+		if len(resolved.AdditionalParams) > 0 {
+			params := make(map[string]string, len(config.AdditionalParams)+len(resolved.AdditionalParams))
+			for k, v := range config.AdditionalParams {
+				params[k] = v
+			}
+			for k, v := range resolved.AdditionalParams {
+				params[k] = v
+			}
+			config.AdditionalParams = params
+		}
+
+		return config
+	}, nil
+}
+
+// configTemplateFromEnv parses INTEGRESQL_TEST_DB_CONFIG_TEMPLATE, a JSON object of the ConfigTemplate (e.g. {"host": "${PGBOUNCER_HOST}", "port": "6432"}).
+// An invalid template is fatal, silently ignoring it would hand out configs clients may not be able to connect to.
+func configTemplateFromEnv() *ConfigTemplate {
+	raw := util.GetEnv("INTEGRESQL_TEST_DB_CONFIG_TEMPLATE", "")
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var template ConfigTemplate
+	if err := json.Unmarshal([]byte(raw), &template); err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse INTEGRESQL_TEST_DB_CONFIG_TEMPLATE, expected a JSON object of host, port, username, password and additionalParams")
+	}
+
+	return &template
+}
@@ -0,0 +1,77 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/allaboutapps/integresql/internal/api"
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigTemplateResolve(t *testing.T) {
+	t.Setenv("INTEGRESQL_TEST_PGBOUNCER_HOST", "pgbouncer-env")
+
+	template := &api.ConfigTemplate{
+		Host:             "${INTEGRESQL_TEST_PGBOUNCER_HOST}",
+		Port:             "${INTEGRESQL_TEST_PGBOUNCER_PORT}",
+		AdditionalParams: map[string]string{"application_name": "tests-${INTEGRESQL_TEST_PGBOUNCER_HOST}"},
+	}
+
+	config := db.DatabaseConfig{
+		Host:             "postgres",
+		Port:             5432,
+		Username:         "dbuser",
+		Password:         "dbpass",
+		Database:         "integresql_test_hash_000",
+		AdditionalParams: map[string]string{"sslmode": "require"},
+	}
+
+	// headers take precedence over the env of the server
+	header := http.Header{}
+	header.Set(api.ConfigVarHeaderName("INTEGRESQL_TEST_PGBOUNCER_HOST"), "pgbouncer-a")
+	header.Set("X-Integresql-Var-INTEGRESQL-TEST-PGBOUNCER-PORT", "6432")
+
+	apply, err := template.Resolve(api.HeaderConfigVars(header))
+	require.NoError(t, err)
+
+	resolved := apply(config)
+	assert.Equal(t, db.DatabaseConfig{
+		Host:             "pgbouncer-a",
+		Port:             6432,
+		Username:         "dbuser",
+		Password:         "dbpass",
+		Database:         "integresql_test_hash_000",
+		AdditionalParams: map[string]string{"sslmode": "require", "application_name": "tests-pgbouncer-a"},
+	}, resolved)
+	assert.Equal(t, map[string]string{"sslmode": "require"}, config.AdditionalParams, "the original config is untouched")
+
+	// falls back to the env of the server, undefined variables are reported at once
+	_, err = template.Resolve(api.HeaderConfigVars(http.Header{}))
+	require.ErrorIs(t, err, api.ErrUndefinedConfigVar)
+	assert.Contains(t, err.Error(), ": INTEGRESQL_TEST_PGBOUNCER_PORT (")
+
+	_, err = (&api.ConfigTemplate{Host: "${INTEGRESQL_TEST_B}", Username: "${INTEGRESQL_TEST_A}"}).Resolve(api.HeaderConfigVars(http.Header{}))
+	require.ErrorIs(t, err, api.ErrUndefinedConfigVar)
+	assert.Contains(t, err.Error(), ": INTEGRESQL_TEST_A, INTEGRESQL_TEST_B (")
+
+	// the port must resolve to a number
+	header.Set("X-Integresql-Var-INTEGRESQL-TEST-PGBOUNCER-PORT", "pgbouncer")
+	_, err = template.Resolve(api.HeaderConfigVars(header))
+	assert.Error(t, err)
+
+	// no template keeps the config as is
+	var none *api.ConfigTemplate
+	apply, err = none.Resolve(api.HeaderConfigVars(http.Header{}))
+	require.NoError(t, err)
+	assert.Equal(t, config, apply(config))
+}
+
+func TestServerConfigTestDatabaseConfigTemplate(t *testing.T) {
+	t.Setenv("INTEGRESQL_TEST_DB_CONFIG_TEMPLATE", `{"host": "${PGBOUNCER_HOST}", "port": "6432"}`)
+	assert.Equal(t, &api.ConfigTemplate{Host: "${PGBOUNCER_HOST}", Port: "6432"}, api.DefaultServerConfigFromEnv().TestDatabaseConfigTemplate)
+
+	t.Setenv("INTEGRESQL_TEST_DB_CONFIG_TEMPLATE", "")
+	assert.Nil(t, api.DefaultServerConfigFromEnv().TestDatabaseConfigTemplate)
+}
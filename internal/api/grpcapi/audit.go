@@ -0,0 +1,54 @@
+package grpcapi
+
+import (
+	"context"
+
+	"github.com/allaboutapps/integresql/internal/api/middleware"
+	"github.com/allaboutapps/integresql/pkg/grpc/integresqlv1"
+	"github.com/allaboutapps/integresql/pkg/util"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// auditInterceptor writes an entry into the given audit logger for each call, equivalent to middleware.Audit of the HTTP API.
+// All operations of the gRPC API change templates or acquire/return test DBs, thus all are audited. Requests and responses are never audited
+// beyond the template hash and test DB ID, neither is the token itself.
+func auditInterceptor(logger zerolog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+
+		event := logger.Log().
+			Str("action", info.FullMethod).
+			Str("status", status.Code(err).String())
+
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			event = event.Str("remoteAddr", p.Addr.String())
+		}
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get("authorization"); len(values) > 0 {
+				if token := middleware.BearerToken(values[0]); len(token) > 0 {
+					event = event.Str("token", middleware.TokenFingerprint(token))
+				}
+			}
+		}
+		if requestID, err := util.RequestIDFromContext(ctx); err == nil {
+			event = event.Str("requestID", requestID)
+		}
+
+		if r, ok := req.(interface{ GetHash() string }); ok {
+			event = event.Str("hash", r.GetHash())
+		}
+		if r, ok := req.(interface{ GetId() int32 }); ok {
+			event = event.Int32("id", r.GetId())
+		} else if r, ok := resp.(*integresqlv1.GetTestDatabaseResponse); ok && r.GetTestDatabase() != nil {
+			event = event.Int32("id", r.GetTestDatabase().GetId())
+		}
+
+		event.Send()
+
+		return resp, err
+	}
+}
@@ -0,0 +1,248 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/allaboutapps/integresql/internal/api"
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/allaboutapps/integresql/pkg/grpc/integresqlv1"
+	"github.com/allaboutapps/integresql/pkg/manager"
+	"github.com/allaboutapps/integresql/pkg/pool"
+	"github.com/allaboutapps/integresql/pkg/templates"
+	"github.com/allaboutapps/integresql/pkg/util"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Init creates the gRPC server, exposing the same operations as the HTTP templates API.
+func Init(s *api.Server) {
+	interceptors := []grpc.UnaryServerInterceptor{requestIDInterceptor}
+	if s.Audit != nil {
+		interceptors = append(interceptors, auditInterceptor(*s.Audit))
+	}
+	if len(s.Config.HashAllowlist) > 0 {
+		interceptors = append(interceptors, hashAllowlistInterceptor(s.Config.HashAllowlist))
+	}
+
+	s.GRPC = grpc.NewServer(grpc.ChainUnaryInterceptor(interceptors...))
+
+	integresqlv1.RegisterIntegreSQLServiceServer(s.GRPC, &service{s: s})
+}
+
+// requestIDHeader is the metadata key of the request ID, equivalent to the X-Request-ID header of the HTTP API.
+const requestIDHeader = "x-request-id"
+
+// requestIDInterceptor attaches the request ID sent by the client (or a generated one) to the ctx and its logger,
+// the ID is sent back within the response header.
+func requestIDInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDHeader); len(values) > 0 {
+			id = values[0]
+		}
+	}
+
+	if len(id) == 0 {
+		id = uuid.NewString()
+	}
+
+	if err := grpc.SetHeader(ctx, metadata.Pairs(requestIDHeader, id)); err != nil {
+		util.LogFromContext(ctx).Warn().Err(err).Msg("Failed to set request ID header")
+	}
+
+	return handler(util.ContextWithRequestID(ctx, id), req)
+}
+
+// metadataConfigVars looks up variables of the api.ConfigTemplate from the metadata of the request (equivalent to the api.ConfigVarHeaderPrefix headers),
+// falling back to the environment of the server.
+func metadataConfigVars(ctx context.Context) api.ConfigVarLookupFunc {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	return func(name string) (string, bool) {
+		if values := md.Get(api.ConfigVarHeaderName(name)); len(values) > 0 {
+			return values[0], true
+		}
+
+		return os.LookupEnv(name)
+	}
+}
+
+type service struct {
+	integresqlv1.UnimplementedIntegreSQLServiceServer
+
+	s *api.Server
+}
+
+func (svc *service) InitializeTemplate(ctx context.Context, req *integresqlv1.InitializeTemplateRequest) (*integresqlv1.InitializeTemplateResponse, error) {
+	if len(req.GetHash()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "hash is required")
+	}
+
+	template, err := svc.s.Manager.InitializeTemplateDatabaseWithOptions(ctx, req.GetHash(), manager.TemplateOptions{
+		InlineRecreateMaxSize: req.GetInlineRecreateMaxSize(),
+		CleanStrategy:         templates.CleanStrategy(req.GetCleanStrategy()),
+		ResetSQL:              req.GetResetSql(),
+		TestDatabaseOwner:     req.GetTestDatabaseOwner(),
+		LeakWarnTimeout:       time.Duration(req.GetLeakWarnTimeoutMs()) * time.Millisecond,
+		LeakReclaimTimeout:    time.Duration(req.GetLeakReclaimTimeoutMs()) * time.Millisecond,
+		Sources:               toSources(req.GetSources()),
+		Fingerprint:           req.GetFingerprint(),
+		ConnectionLimit:       int(req.GetConnectionLimit()),
+		MinReady:              int(req.GetMinReady()),
+		DatabaseLocale: manager.DatabaseLocale{
+			Encoding: req.GetEncoding(),
+			Collate:  req.GetLcCollate(),
+			Ctype:    req.GetLcCtype(),
+		},
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &integresqlv1.InitializeTemplateResponse{
+		Template: &integresqlv1.TemplateDatabase{Database: toDatabase(template.Database)},
+	}, nil
+}
+
+func (svc *service) FinalizeTemplate(ctx context.Context, req *integresqlv1.FinalizeTemplateRequest) (*integresqlv1.FinalizeTemplateResponse, error) {
+	if _, err := svc.s.Manager.FinalizeTemplateDatabaseWithFingerprint(ctx, req.GetHash(), req.GetFingerprint()); err != nil && !errors.Is(err, manager.ErrTemplateAlreadyInitialized) {
+		// template is initialized, we ignore this error
+		return nil, toStatusError(err)
+	}
+
+	return &integresqlv1.FinalizeTemplateResponse{}, nil
+}
+
+func (svc *service) GetTestDatabase(ctx context.Context, req *integresqlv1.GetTestDatabaseRequest) (*integresqlv1.GetTestDatabaseResponse, error) {
+	// resolved before acquiring the test DB, thus it does not leak if variables are undefined
+	applyConfig, err := svc.s.Config.TestDatabaseConfigTemplate.Resolve(metadataConfigVars(ctx))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	test, err := svc.s.Manager.GetTestDatabaseWithOptions(ctx, req.GetHash(), pool.GetOptions{Labels: req.GetLabels()})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	test.Config = applyConfig(test.Config)
+
+	return &integresqlv1.GetTestDatabaseResponse{
+		TestDatabase: &integresqlv1.TestDatabase{
+			Database: toDatabase(test.Database),
+			Id:       int32(test.ID), //nolint:gosec
+			Labels:   test.Labels,
+			Replica:  toDatabaseConfig(test.Replica),
+			Lease:    test.Lease,
+		},
+	}, nil
+}
+
+func (svc *service) ReturnTestDatabase(ctx context.Context, req *integresqlv1.ReturnTestDatabaseRequest) (*integresqlv1.ReturnTestDatabaseResponse, error) {
+	if err := svc.s.CheckLease(req.GetLease()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if req.GetAsync() {
+		if err := svc.s.Manager.ReturnTestDatabaseAsync(ctx, req.GetHash(), int(req.GetId()), req.GetLease()); err != nil {
+			return nil, toStatusError(err)
+		}
+
+		return &integresqlv1.ReturnTestDatabaseResponse{}, nil
+	}
+
+	if err := svc.s.Manager.ReturnTestDatabaseWithLease(ctx, req.GetHash(), int(req.GetId()), req.GetLease()); err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &integresqlv1.ReturnTestDatabaseResponse{}, nil
+}
+
+func toSources(sources map[string]int32) map[string]int {
+	if len(sources) == 0 {
+		return nil
+	}
+
+	res := make(map[string]int, len(sources))
+	for name, weight := range sources {
+		res[name] = int(weight)
+	}
+
+	return res
+}
+
+func toDatabase(d db.Database) *integresqlv1.Database {
+	return &integresqlv1.Database{
+		TemplateHash: d.TemplateHash,
+		Config:       toDatabaseConfig(&d.Config),
+	}
+}
+
+func toDatabaseConfig(c *db.DatabaseConfig) *integresqlv1.DatabaseConfig {
+	if c == nil {
+		return nil
+	}
+
+	return &integresqlv1.DatabaseConfig{
+		Host:             c.Host,
+		Port:             int32(c.Port), //nolint:gosec
+		Username:         c.Username,
+		Password:         c.Password,
+		Database:         c.Database,
+		AdditionalParams: c.AdditionalParams,
+	}
+}
+
+// toStatusError maps the manager and pool errors onto the appropriate gRPC status codes, matching the handlers of the HTTP templates API
+// (their status code is noted per case, errors not handled there are answered with 500 over HTTP).
+func toStatusError(err error) error {
+	switch {
+	case errors.Is(err, manager.ErrManagerNotReady):
+		return status.Error(codes.Unavailable, err.Error()) // 503
+	case errors.Is(err, manager.ErrInvalidCleanStrategy),
+		errors.Is(err, manager.ErrUnknownTestDatabaseOwner),
+		errors.Is(err, manager.ErrIncompatibleDatabaseLocale),
+		errors.Is(err, manager.ErrInvalidLeakTimeouts),
+		errors.Is(err, manager.ErrInvalidExternalTemplate),
+		errors.Is(err, manager.ErrInvalidTemplateSource),
+		errors.Is(err, manager.ErrInvalidConnectionLimit),
+		errors.Is(err, manager.ErrInvalidMinReady):
+		return status.Error(codes.InvalidArgument, err.Error()) // 400
+	case errors.Is(err, manager.ErrTemplateAlreadyInitialized):
+		return status.Error(codes.AlreadyExists, err.Error()) // 423
+	case errors.Is(err, manager.ErrTemplateNotFound),
+		errors.Is(err, manager.ErrTestNotFound),
+		errors.Is(err, pool.ErrUnknownHash):
+		return status.Error(codes.NotFound, err.Error()) // 404
+	case errors.Is(err, manager.ErrTemplateDiscarded),
+		errors.Is(err, pool.ErrTemplateMissing):
+		return status.Error(codes.FailedPrecondition, err.Error()) // 410
+	case errors.Is(err, manager.ErrHashMismatch),
+		errors.Is(err, pool.ErrInvalidLease),
+		errors.Is(err, pool.ErrUnknownID),
+		errors.Is(err, pool.ErrInvalidState),
+		errors.Is(err, pool.ErrNotReserved):
+		return status.Error(codes.FailedPrecondition, err.Error()) // 409
+	case errors.Is(err, pool.ErrWouldReuseDirty):
+		return status.Error(codes.FailedPrecondition, err.Error()) // 412
+	case errors.Is(err, pool.ErrTestDBInUse):
+		return status.Error(codes.FailedPrecondition, err.Error()) // 423
+	case errors.Is(err, pool.ErrPoolFull):
+		return status.Error(codes.ResourceExhausted, err.Error()) // 423
+	case errors.Is(err, pool.ErrTooManyConnections):
+		return status.Error(codes.Unavailable, err.Error()) // 503
+	case errors.Is(err, pool.ErrTimeout),
+		errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error()) // 500
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error()) // 500
+	default:
+		return status.Error(codes.Internal, err.Error()) // 500
+	}
+}
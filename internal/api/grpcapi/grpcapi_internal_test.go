@@ -0,0 +1,138 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/allaboutapps/integresql/pkg/grpc/integresqlv1"
+	"github.com/allaboutapps/integresql/pkg/manager"
+	"github.com/allaboutapps/integresql/pkg/pool"
+	"github.com/allaboutapps/integresql/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestToStatusError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{manager.ErrManagerNotReady, codes.Unavailable},
+		{manager.ErrTemplateAlreadyInitialized, codes.AlreadyExists},
+		{manager.ErrInvalidCleanStrategy, codes.InvalidArgument},
+		{manager.ErrTemplateNotFound, codes.NotFound},
+		{pool.ErrUnknownHash, codes.NotFound},
+		{fmt.Errorf("wrapped: %w", pool.ErrUnknownHash), codes.NotFound},
+		{manager.ErrTemplateDiscarded, codes.FailedPrecondition},
+		{pool.ErrInvalidLease, codes.FailedPrecondition},
+		{pool.ErrInvalidState, codes.FailedPrecondition},
+		{pool.ErrWouldReuseDirty, codes.FailedPrecondition},
+		{manager.ErrInvalidExternalTemplate, codes.InvalidArgument},
+		{pool.ErrTooManyConnections, codes.Unavailable},
+		{pool.ErrPoolFull, codes.ResourceExhausted},
+		{&pool.PoolError{Op: "GetTestDatabase", Hash: "h1", ID: -1, Err: pool.ErrTimeout}, codes.DeadlineExceeded},
+		{pool.ErrTimeout, codes.DeadlineExceeded},
+		{context.Canceled, codes.Canceled},
+		{errors.New("unknown"), codes.Internal},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, status.Code(toStatusError(tt.err)))
+		})
+	}
+}
+
+func TestRequestIDInterceptor(t *testing.T) {
+	t.Parallel()
+
+	handle := func(ctx context.Context) string {
+		var id string
+		_, err := requestIDInterceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			var err error
+			id, err = util.RequestIDFromContext(ctx)
+			return nil, err
+		})
+		require.NoError(t, err)
+		return id
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "req-1"))
+	assert.Equal(t, "req-1", handle(ctx))
+
+	// generated if not sent by the client
+	generated := handle(context.Background())
+	assert.NotEmpty(t, generated)
+	assert.NotEqual(t, generated, handle(context.Background()))
+}
+
+func TestMetadataConfigVars(t *testing.T) {
+	t.Setenv("INTEGRESQL_TEST_PGBOUNCER_HOST", "pgbouncer-env")
+	t.Setenv("INTEGRESQL_TEST_PGBOUNCER_PORT", "6432")
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("X-Integresql-Var-INTEGRESQL-TEST-PGBOUNCER-HOST", "pgbouncer-a"))
+	lookup := metadataConfigVars(ctx)
+
+	// the metadata takes precedence over the env of the server
+	value, ok := lookup("INTEGRESQL_TEST_PGBOUNCER_HOST")
+	assert.True(t, ok)
+	assert.Equal(t, "pgbouncer-a", value)
+
+	value, ok = lookup("INTEGRESQL_TEST_PGBOUNCER_PORT")
+	assert.True(t, ok)
+	assert.Equal(t, "6432", value)
+
+	_, ok = metadataConfigVars(context.Background())("INTEGRESQL_TEST_UNDEFINED")
+	assert.False(t, ok)
+}
+
+func TestHashAllowlistInterceptor(t *testing.T) {
+	t.Parallel()
+
+	interceptor := hashAllowlistInterceptor(map[string]string{
+		"token-a": "team-a-",
+		"token-b": "team-b-",
+	})
+
+	tests := []struct {
+		name          string
+		authorization string
+		hash          string
+		want          codes.Code
+	}{
+		{name: "Allowed", authorization: "Bearer token-a", hash: "team-a-1234", want: codes.OK},
+		{name: "AllowedLowercaseScheme", authorization: "bearer token-b", hash: "team-b-1234", want: codes.OK},
+		{name: "OtherTeam", authorization: "Bearer token-a", hash: "team-b-1234", want: codes.PermissionDenied},
+		{name: "UnknownToken", authorization: "Bearer token-c", hash: "team-a-1234", want: codes.Unauthenticated},
+		{name: "MissingToken", hash: "team-a-1234", want: codes.Unauthenticated},
+		{name: "BasicAuth", authorization: "Basic token-a", hash: "team-a-1234", want: codes.Unauthenticated},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			if len(tt.authorization) > 0 {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tt.authorization))
+			}
+
+			_, err := interceptor(ctx, &integresqlv1.GetTestDatabaseRequest{Hash: tt.hash}, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return &integresqlv1.GetTestDatabaseResponse{}, nil
+			})
+			assert.Equal(t, tt.want, status.Code(err))
+		})
+	}
+}
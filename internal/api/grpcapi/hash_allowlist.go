@@ -0,0 +1,42 @@
+package grpcapi
+
+import (
+	"context"
+	"strings"
+
+	"github.com/allaboutapps/integresql/internal/api/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// hashAllowlistInterceptor restricts the template hashes a client may access to the prefix allowed for its token
+// (passed as "authorization: Bearer <token>" metadata), equivalent to middleware.HashAllowlist of the HTTP API.
+// Unknown or missing tokens are rejected with Unauthenticated, hashes not allowed for the token with PermissionDenied.
+func hashAllowlistInterceptor(allowlist map[string]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var token string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get("authorization"); len(values) > 0 {
+				token = middleware.BearerToken(values[0])
+			}
+		}
+
+		if len(token) == 0 {
+			return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+		}
+
+		prefix, ok := allowlist[token]
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "unknown token")
+		}
+
+		// all operations are scoped to a single hash
+		if r, ok := req.(interface{ GetHash() string }); !ok || !strings.HasPrefix(r.GetHash(), prefix) {
+			return nil, status.Error(codes.PermissionDenied, "hash is not allowed for this token")
+		}
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,37 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/allaboutapps/integresql/internal/api"
+	"github.com/labstack/echo/v4"
+)
+
+// getLiveness only checks the process itself (e.g. as Kubernetes liveness probe): It fails if the watchdog suspects a deadlock,
+// but not if PostgreSQL is unavailable, thus the process is not restarted merely because of a PostgreSQL outage.
+func getLiveness(s *api.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if s.Manager != nil {
+			if err := s.Manager.Live(); err != nil {
+				return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+			}
+		}
+
+		return c.String(http.StatusOK, "ok")
+	}
+}
+
+// getReadiness checks whether requests can be served (e.g. as Kubernetes readiness probe), i.e. the manager is connected and PostgreSQL responds.
+func getReadiness(s *api.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !s.Ready() {
+			return echo.ErrServiceUnavailable
+		}
+
+		if err := s.Manager.CheckReady(c.Request().Context()); err != nil {
+			return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+		}
+
+		return c.String(http.StatusOK, "ok")
+	}
+}
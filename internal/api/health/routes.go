@@ -0,0 +1,10 @@
+package health
+
+import (
+	"github.com/allaboutapps/integresql/internal/api"
+)
+
+func InitRoutes(s *api.Server) {
+	s.Echo.GET("/livez", getLiveness(s))
+	s.Echo.GET("/readyz", getReadiness(s))
+}
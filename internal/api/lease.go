@@ -0,0 +1,16 @@
+package api
+
+import "errors"
+
+// ErrLeaseRequired is returned by CheckLease if a client changes a test DB without passing its lease.
+var ErrLeaseRequired = errors.New("lease is required")
+
+// CheckLease returns ErrLeaseRequired if the lease is empty, unless LenientLeases is configured.
+// The Go APIs of the manager and pool still accept an empty lease, only the clients of the server must pass it.
+func (s *Server) CheckLease(lease string) error {
+	if len(lease) == 0 && !s.Config.LenientLeases {
+		return ErrLeaseRequired
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+)
+
+const (
+	auditHashContextKey   = "audit_hash"
+	auditIDContextKey     = "audit_id"
+	auditRevealContextKey = "audit_reveal"
+)
+
+// Audit writes an entry into the given audit logger for each handled request: Who (the fingerprint of the bearer token and the
+// remote IP), what (the route, its params, the template hash and test DB ID) and the resulting status, timestamped by the logger.
+// Bodies and headers are never audited, neither is the token itself, thus secrets (e.g. passwords of test DBs) never end up in the trail.
+// Handlers may add the hash or ID not known from the route params (e.g. the ID of an acquired test DB) via SetAuditHash and SetAuditID.
+func Audit(logger zerolog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+
+			status := c.Response().Status
+			if err != nil {
+				status = http.StatusInternalServerError
+
+				var httpErr *echo.HTTPError
+				if errors.As(err, &httpErr) {
+					status = httpErr.Code
+				}
+			}
+
+			event := logger.Log().
+				Str("action", c.Request().Method+" "+c.Path()).
+				Int("status", status).
+				Str("remoteIP", c.RealIP())
+
+			if token := BearerToken(c.Request().Header.Get(echo.HeaderAuthorization)); len(token) > 0 {
+				event = event.Str("token", TokenFingerprint(token))
+			}
+			if requestID := c.Response().Header().Get(echo.HeaderXRequestID); len(requestID) > 0 {
+				event = event.Str("requestID", requestID)
+			}
+
+			for i, name := range c.ParamNames() {
+				if i >= len(c.ParamValues()) {
+					break
+				}
+
+				// test DB IDs are audited as numbers, regardless of how they are known
+				if id, err := strconv.Atoi(c.ParamValues()[i]); err == nil && name == "id" {
+					event = event.Int(name, id)
+					continue
+				}
+
+				event = event.Str(name, c.ParamValues()[i])
+			}
+
+			if hash, ok := c.Get(auditHashContextKey).(string); ok {
+				event = event.Str("hash", hash)
+			}
+			if id, ok := c.Get(auditIDContextKey).(int); ok {
+				event = event.Int("id", id)
+			}
+			if reveal, ok := c.Get(auditRevealContextKey).(bool); ok {
+				event = event.Bool("reveal", reveal)
+			}
+
+			event.Send()
+
+			return err
+		}
+	}
+}
+
+// SetAuditHash records the template hash the request operates on if it is not a route param (e.g. supplied within the payload).
+func SetAuditHash(c echo.Context, hash string) {
+	c.Set(auditHashContextKey, hash)
+}
+
+// SetAuditID records the test DB ID the request operates on if it is not a route param (e.g. the ID of an acquired test DB).
+func SetAuditID(c echo.Context, id int) {
+	c.Set(auditIDContextKey, id)
+}
+
+// SetAuditReveal records that the request asked for a secret (e.g. the password of a test DB), which is never audited itself.
+func SetAuditReveal(c echo.Context) {
+	c.Set(auditRevealContextKey, true)
+}
+
+// TokenFingerprint identifies a token within the audit trail without revealing it (truncated hex SHA-256).
+func TokenFingerprint(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
+
+// BearerToken returns the token of the given "Bearer <token>" authorization header, empty if none.
+func BearerToken(authorization string) string {
+	scheme, token, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+
+	return token
+}
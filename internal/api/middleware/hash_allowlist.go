@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const hashPrefixContextKey = "hash_allowlist_prefix"
+
+// HashAllowlist restricts the template hashes a client may access to the prefix allowed for its token
+// (passed as "Authorization: Bearer <token>"). Unknown or missing tokens are rejected with 401.
+// The hash route param is checked right away, handlers receiving the hash otherwise (e.g. within the payload) must call CheckHashAllowed.
+func HashAllowlist(allowlist map[string]string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			scheme, token, found := strings.Cut(c.Request().Header.Get(echo.HeaderAuthorization), " ")
+			if !found || !strings.EqualFold(scheme, "Bearer") {
+				return echo.NewHTTPError(http.StatusUnauthorized, "missing bearer token")
+			}
+
+			prefix, ok := allowlist[token]
+			if !ok {
+				return echo.NewHTTPError(http.StatusUnauthorized, "unknown token")
+			}
+
+			c.Set(hashPrefixContextKey, prefix)
+
+			if hash := c.Param("hash"); len(hash) > 0 {
+				if err := CheckHashAllowed(c, hash); err != nil {
+					return err
+				}
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// CheckHashAllowed returns 403 if the given hash is not allowed for the token of the request.
+// All hashes are allowed if the HashAllowlist middleware is not in use.
+func CheckHashAllowed(c echo.Context, hash string) error {
+	prefix, ok := c.Get(hashPrefixContextKey).(string)
+	if !ok {
+		return nil
+	}
+
+	if !strings.HasPrefix(hash, prefix) {
+		return echo.NewHTTPError(http.StatusForbidden, "hash is not allowed for this token")
+	}
+
+	return nil
+}
+
+// CheckAuthenticated returns 403 unless the token of the request was authenticated by the HashAllowlist middleware.
+// Secrets (e.g. passwords of test DBs) are thus never handed out if no allowlist is configured.
+func CheckAuthenticated(c echo.Context) error {
+	if _, ok := c.Get(hashPrefixContextKey).(string); !ok {
+		return echo.NewHTTPError(http.StatusForbidden, "requires a token of the hash allowlist")
+	}
+
+	return nil
+}
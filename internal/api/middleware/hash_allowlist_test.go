@@ -0,0 +1,110 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/allaboutapps/integresql/internal/api/middleware"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashAllowlist(t *testing.T) {
+	e := echo.New()
+
+	g := e.Group("/templates")
+	g.Use(middleware.HashAllowlist(map[string]string{
+		"token-a": "team-a-",
+		"token-b": "team-b-",
+	}))
+	g.POST("", func(c echo.Context) error {
+		if err := middleware.CheckHashAllowed(c, c.QueryParam("hash")); err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+	g.GET("/:hash/tests", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		authorization string
+		want          int
+	}{
+		{name: "Allowed", method: http.MethodGet, path: "/templates/team-a-1234/tests", authorization: "Bearer token-a", want: http.StatusNoContent},
+		{name: "AllowedLowercaseScheme", method: http.MethodGet, path: "/templates/team-b-1234/tests", authorization: "bearer token-b", want: http.StatusNoContent},
+		{name: "OtherTeam", method: http.MethodGet, path: "/templates/team-b-1234/tests", authorization: "Bearer token-a", want: http.StatusForbidden},
+		{name: "UnknownToken", method: http.MethodGet, path: "/templates/team-a-1234/tests", authorization: "Bearer token-c", want: http.StatusUnauthorized},
+		{name: "MissingToken", method: http.MethodGet, path: "/templates/team-a-1234/tests", want: http.StatusUnauthorized},
+		{name: "BasicAuth", method: http.MethodGet, path: "/templates/team-a-1234/tests", authorization: "Basic token-a", want: http.StatusUnauthorized},
+		{name: "PayloadAllowed", method: http.MethodPost, path: "/templates?hash=team-a-1234", authorization: "Bearer token-a", want: http.StatusNoContent},
+		{name: "PayloadOtherTeam", method: http.MethodPost, path: "/templates?hash=team-b-1234", authorization: "Bearer token-a", want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if len(tt.authorization) > 0 {
+				req.Header.Set(echo.HeaderAuthorization, tt.authorization)
+			}
+
+			res := httptest.NewRecorder()
+			e.ServeHTTP(res, req)
+
+			assert.Equal(t, tt.want, res.Code)
+		})
+	}
+}
+
+func TestCheckHashAllowedWithoutAllowlist(t *testing.T) {
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/templates", nil), httptest.NewRecorder())
+
+	assert.NoError(t, middleware.CheckHashAllowed(c, "any-hash"))
+}
+
+func TestCheckAuthenticated(t *testing.T) {
+	e := echo.New()
+
+	handler := func(c echo.Context) error {
+		if err := middleware.CheckAuthenticated(c); err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	e.GET("/open/dsn", handler)
+	e.GET("/restricted/dsn", handler, middleware.HashAllowlist(map[string]string{"token-a": "team-a-"}))
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		want          int
+	}{
+		{name: "Authenticated", path: "/restricted/dsn", authorization: "Bearer token-a", want: http.StatusNoContent},
+		{name: "UnknownToken", path: "/restricted/dsn", authorization: "Bearer token-c", want: http.StatusUnauthorized},
+		{name: "WithoutAllowlist", path: "/open/dsn", authorization: "Bearer token-a", want: http.StatusForbidden},
+		{name: "WithoutAllowlistMissingToken", path: "/open/dsn", want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if len(tt.authorization) > 0 {
+				req.Header.Set(echo.HeaderAuthorization, tt.authorization)
+			}
+
+			res := httptest.NewRecorder()
+			e.ServeHTTP(res, req)
+
+			assert.Equal(t, tt.want, res.Code)
+		})
+	}
+}
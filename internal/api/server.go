@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"os"
 	"time"
 
 	// #nosec G108 - pprof handlers (conditionally made available via http.DefaultServeMux within router)
@@ -14,19 +15,27 @@ import (
 	"github.com/allaboutapps/integresql/pkg/manager"
 	"github.com/allaboutapps/integresql/pkg/util"
 	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
 )
 
 type Server struct {
 	Config  ServerConfig
 	Echo    *echo.Echo
+	GRPC    *grpc.Server // optional, nil if disabled
 	Manager *manager.Manager
+	Audit   *zerolog.Logger // optional audit trail of all mutating operations (see InitAuditLog), nil if disabled
+
+	auditFile *os.File
 }
 
 func NewServer(config ServerConfig) *Server {
 	s := &Server{
 		Config:  config,
 		Echo:    nil,
+		GRPC:    nil,
 		Manager: nil,
+		Audit:   nil,
 	}
 
 	return s
@@ -48,24 +57,54 @@ func (s *Server) Start() error {
 	return s.Echo.Start(net.JoinHostPort(s.Config.Address, fmt.Sprintf("%d", s.Config.Port)))
 }
 
+// StartGRPC starts serving the gRPC API on its separate port (blocking).
+func (s *Server) StartGRPC() error {
+	if !s.Ready() || s.GRPC == nil {
+		return errors.New("grpc server is not ready")
+	}
+
+	lis, err := net.Listen("tcp", net.JoinHostPort(s.Config.Address, fmt.Sprintf("%d", s.Config.GRPCPort)))
+	if err != nil {
+		return err
+	}
+
+	return s.GRPC.Serve(lis)
+}
+
 func (s *Server) Shutdown(ctx context.Context) error {
+	if s.GRPC != nil {
+		s.GRPC.GracefulStop()
+	}
+
 	if s.Manager != nil {
 		if err := s.Manager.Disconnect(ctx, true); err != nil {
 			log.Printf("Received error while disconnecting manager during shutdown: %v", err)
 		}
 	}
 
+	if err := s.closeAuditLog(); err != nil {
+		log.Printf("Received error while closing audit log during shutdown: %v", err)
+	}
+
 	return s.Echo.Shutdown(ctx)
 }
 
 func (s *Server) InitManager(ctx context.Context) error {
 	m := manager.DefaultFromEnv()
 
-	if err := util.Retry(30, 1*time.Second, func() error {
+	log := util.LogFromContext(ctx)
+
+	// PostgreSQL may still be starting (e.g. within docker compose), thus retry a few times
+	if err := util.RetryWithBackoff(ctx, s.Config.ConnectRetryAttempts, s.Config.ConnectRetryDelay, s.Config.ConnectRetryDelayMax, func(attempt int) error {
 		ctxx, cancel := context.WithTimeout(ctx, 10*time.Second)
 		defer cancel()
 
-		return m.Initialize(ctxx)
+		err := m.Initialize(ctxx)
+		if err != nil {
+			log.Warn().Err(err).Int("attempt", attempt).Int("maxAttempts", s.Config.ConnectRetryAttempts).Msg("Failed to initialize manager")
+		}
+
+		return err
 	}); err != nil {
 		return err
 	}
@@ -1,18 +1,41 @@
 package api
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/allaboutapps/integresql/pkg/util"
 	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 )
 
 type ServerConfig struct {
 	Address        string
 	Port           int
+	GRPCPort       int // 0 disables the gRPC API
 	DebugEndpoints bool
-	Logger         LoggerConfig
-	Echo           EchoConfig
+	HashAllowlist  map[string]string // token (Authorization: Bearer <token>) -> template hash prefix it may access, empty disables
+	LenientLeases  bool              // Accept returning (or recreating, poisoning, extending) test DBs without their lease, for clients predating leases
+
+	TestDatabaseConfigTemplate *ConfigTemplate // Optional overrides of the test DB configs returned to clients, supporting ${VAR} substitution per request (nil disables)
+
+	SnapshotDumpDir string // Directory the snapshots of all pools are dumped into on SIGUSR1 (see Server.DumpSnapshots), empty disables
+
+	TemplatesFile          string        // JSON file of the templates registered and warmed at startup (see Server.WarmDeclaredTemplates), empty disables
+	TemplatesWarmUpTimeout time.Duration // Time the startup waits for the declared templates to be warm (0 waits indefinitely)
+
+	AuditLog string // Sink of the audit trail of all mutating operations: "stdout", "stderr" or a file path (see Server.InitAuditLog), empty disables
+
+	StatsDAddress  string        // host:port of a StatsD / DogStatsD agent the metrics are sent to via UDP (see Server.ExportStatsD), empty disables
+	StatsDInterval time.Duration // Interval of sending the metrics to StatsD
+
+	ConnectRetryAttempts int           // Attempts to connect to PostgreSQL (and initialize the manager) at startup, e.g. while PostgreSQL is still starting
+	ConnectRetryDelay    time.Duration // Sleep after the first failed attempt, doubled after each further failed attempt up to...
+	ConnectRetryDelayMax time.Duration // ... this maximum
+
+	Logger LoggerConfig
+	Echo   EchoConfig
 }
 
 type EchoConfig struct {
@@ -42,7 +65,27 @@ func DefaultServerConfigFromEnv() ServerConfig {
 	return ServerConfig{
 		Address:        util.GetEnv("INTEGRESQL_ADDRESS", ""),
 		Port:           util.GetEnvAsInt("INTEGRESQL_PORT", 5000),
+		GRPCPort:       util.GetEnvAsInt("INTEGRESQL_GRPC_PORT", 0 /*disabled*/),
 		DebugEndpoints: util.GetEnvAsBool("INTEGRESQL_DEBUG_ENDPOINTS", false), // https://golang.org/pkg/net/http/pprof/
+		HashAllowlist:  hashAllowlistFromEnv(),
+		LenientLeases:  util.GetEnvAsBool("INTEGRESQL_LENIENT_LEASES", false),
+
+		TestDatabaseConfigTemplate: configTemplateFromEnv(),
+
+		SnapshotDumpDir: util.GetEnv("INTEGRESQL_SNAPSHOT_DUMP_DIR", ""),
+
+		TemplatesFile:          util.GetEnv("INTEGRESQL_TEMPLATES_FILE", ""),
+		TemplatesWarmUpTimeout: time.Millisecond * time.Duration(util.GetEnvAsInt("INTEGRESQL_TEMPLATES_WARM_UP_TIMEOUT_MS", 5*60*1000 /*5 min*/)),
+
+		AuditLog: util.GetEnv("INTEGRESQL_AUDIT_LOG", ""),
+
+		StatsDAddress:  util.GetEnv("INTEGRESQL_STATSD_ADDR", ""),
+		StatsDInterval: time.Millisecond * time.Duration(util.GetEnvAsInt("INTEGRESQL_STATSD_INTERVAL_MS", 10*1000 /*10 sec*/)),
+
+		ConnectRetryAttempts: util.GetEnvAsInt("INTEGRESQL_CONNECT_RETRY_ATTEMPTS", 30),
+		ConnectRetryDelay:    time.Millisecond * time.Duration(util.GetEnvAsInt("INTEGRESQL_CONNECT_RETRY_DELAY_MS", 1000 /*1 sec*/)),
+		ConnectRetryDelayMax: time.Millisecond * time.Duration(util.GetEnvAsInt("INTEGRESQL_CONNECT_RETRY_DELAY_MAX_MS", 1000 /*1 sec, no backoff*/)),
+
 		Echo: EchoConfig{
 			Debug:                         util.GetEnvAsBool("INTEGRESQL_ECHO_DEBUG", false),
 			EnableCORSMiddleware:          util.GetEnvAsBool("INTEGRESQL_ECHO_ENABLE_CORS_MIDDLEWARE", true),
@@ -57,14 +100,48 @@ func DefaultServerConfigFromEnv() ServerConfig {
 			RequestTimeout: time.Millisecond * time.Duration(util.GetEnvAsInt("INTEGRESQL_ECHO_REQUEST_TIMEOUT_MS", 60*1000 /*1 min*/)), // affects INTEGRESQL_TEMPLATE_FINALIZE_TIMEOUT_MS and INTEGRESQL_TEST_DB_GET_TIMEOUT_MS
 		},
 		Logger: LoggerConfig{
-			Level:              util.LogLevelFromString(util.GetEnv("INTEGRESQL_LOGGER_LEVEL", zerolog.InfoLevel.String())),
+			Level:              util.LogLevelFromString(util.GetEnv("INTEGRESQL_LOG_LEVEL", util.GetEnv("INTEGRESQL_LOGGER_LEVEL", zerolog.InfoLevel.String()))),
 			RequestLevel:       util.LogLevelFromString(util.GetEnv("INTEGRESQL_LOGGER_REQUEST_LEVEL", zerolog.InfoLevel.String())),
 			LogRequestBody:     util.GetEnvAsBool("INTEGRESQL_LOGGER_LOG_REQUEST_BODY", false),
 			LogRequestHeader:   util.GetEnvAsBool("INTEGRESQL_LOGGER_LOG_REQUEST_HEADER", false),
 			LogRequestQuery:    util.GetEnvAsBool("INTEGRESQL_LOGGER_LOG_REQUEST_QUERY", false),
 			LogResponseBody:    util.GetEnvAsBool("INTEGRESQL_LOGGER_LOG_RESPONSE_BODY", false),
 			LogResponseHeader:  util.GetEnvAsBool("INTEGRESQL_LOGGER_LOG_RESPONSE_HEADER", false),
-			PrettyPrintConsole: util.GetEnvAsBool("INTEGRESQL_LOGGER_PRETTY_PRINT_CONSOLE", false),
+			PrettyPrintConsole: prettyPrintConsoleFromEnv(),
 		},
 	}
 }
+
+// hashAllowlistFromEnv parses INTEGRESQL_HASH_ALLOWLIST, a JSON object mapping tokens onto allowed hash prefixes (e.g. {"token-a": "team-a-"}).
+// An invalid allowlist is fatal, silently disabling it would grant all tokens access to all hashes.
+func hashAllowlistFromEnv() map[string]string {
+	raw := util.GetEnv("INTEGRESQL_HASH_ALLOWLIST", "")
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var allowlist map[string]string
+	if err := json.Unmarshal([]byte(raw), &allowlist); err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse INTEGRESQL_HASH_ALLOWLIST, expected a JSON object of token to hash prefix")
+	}
+
+	return allowlist
+}
+
+// prettyPrintConsoleFromEnv selects the log format via INTEGRESQL_LOG_FORMAT ("json" or "text"),
+// falling back to INTEGRESQL_LOGGER_PRETTY_PRINT_CONSOLE (json by default) if unset or invalid.
+func prettyPrintConsoleFromEnv() bool {
+	fallback := util.GetEnvAsBool("INTEGRESQL_LOGGER_PRETTY_PRINT_CONSOLE", false)
+
+	switch format := util.GetEnv("INTEGRESQL_LOG_FORMAT", ""); strings.ToLower(format) {
+	case "":
+		return fallback
+	case "json":
+		return false
+	case "text":
+		return true
+	default:
+		log.Warn().Str("format", format).Msg("Invalid INTEGRESQL_LOG_FORMAT, expected json or text, ignoring")
+		return fallback
+	}
+}
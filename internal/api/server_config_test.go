@@ -0,0 +1,59 @@
+package api_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/allaboutapps/integresql/internal/api"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerConfigLogFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		prettyPrint string
+		want        bool
+	}{
+		{name: "Default", want: false},
+		{name: "JSON", format: "json", want: false},
+		{name: "Text", format: "text", want: true},
+		{name: "TextUppercase", format: "TEXT", want: true},
+		{name: "JSONOverridesPrettyPrint", format: "json", prettyPrint: "true", want: false},
+		{name: "PrettyPrintFallback", prettyPrint: "true", want: true},
+		{name: "InvalidFallback", format: "yaml", prettyPrint: "true", want: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("INTEGRESQL_LOG_FORMAT", tt.format)
+			t.Setenv("INTEGRESQL_LOGGER_PRETTY_PRINT_CONSOLE", tt.prettyPrint)
+
+			assert.Equal(t, tt.want, api.DefaultServerConfigFromEnv().Logger.PrettyPrintConsole)
+		})
+	}
+}
+
+func TestServerConfigLogLevel(t *testing.T) {
+	t.Setenv("INTEGRESQL_LOGGER_LEVEL", "warn")
+	t.Setenv("INTEGRESQL_LOG_LEVEL", "")
+	os.Unsetenv("INTEGRESQL_LOG_LEVEL") // restored by t.Setenv
+	assert.Equal(t, zerolog.WarnLevel, api.DefaultServerConfigFromEnv().Logger.Level)
+
+	t.Setenv("INTEGRESQL_LOG_LEVEL", "debug")
+	assert.Equal(t, zerolog.DebugLevel, api.DefaultServerConfigFromEnv().Logger.Level)
+}
+
+func TestServerConfigConnectRetry(t *testing.T) {
+	t.Setenv("INTEGRESQL_CONNECT_RETRY_ATTEMPTS", "5")
+	t.Setenv("INTEGRESQL_CONNECT_RETRY_DELAY_MS", "250")
+	t.Setenv("INTEGRESQL_CONNECT_RETRY_DELAY_MAX_MS", "4000")
+
+	cfg := api.DefaultServerConfigFromEnv()
+	assert.Equal(t, 5, cfg.ConnectRetryAttempts)
+	assert.Equal(t, 250*time.Millisecond, cfg.ConnectRetryDelay)
+	assert.Equal(t, 4*time.Second, cfg.ConnectRetryDelayMax)
+}
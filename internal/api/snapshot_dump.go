@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/manager"
+	"github.com/allaboutapps/integresql/pkg/pool"
+)
+
+// snapshotDump is the content of a file written by DumpSnapshots.
+type snapshotDump struct {
+	TakenAt   time.Time                `json:"takenAt"`
+	Pools     []pool.PoolSnapshot      `json:"pools"`
+	Lifecycle manager.LifecycleMetrics `json:"lifecycle"`
+}
+
+// DumpSnapshots writes the current snapshots of all pools as JSON into a new timestamped file within the SnapshotDumpDir, returning its path.
+// Each pool is only locked while its own snapshot is taken, the file is written afterwards without holding any lock.
+func (s *Server) DumpSnapshots(ctx context.Context) (string, error) {
+	if len(s.Config.SnapshotDumpDir) == 0 {
+		return "", errors.New("snapshot dump dir is not configured")
+	}
+
+	if s.Manager == nil {
+		return "", manager.ErrManagerNotReady
+	}
+
+	snapshots, err := s.Manager.GetPoolSnapshots(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return writeSnapshotDump(s.Config.SnapshotDumpDir, snapshotDump{
+		TakenAt:   time.Now().UTC(),
+		Pools:     snapshots,
+		Lifecycle: s.Manager.LifecycleMetrics(),
+	})
+}
+
+// writeSnapshotDump writes the dump into a file named by the time it was taken. The file is renamed into place once completely written,
+// thus readers never observe a partial dump.
+func writeSnapshotDump(dir string, dump snapshotDump) (string, error) {
+	b, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".integresql-snapshot-*.json")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		return "", err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("integresql-snapshot-%s.json", dump.TakenAt.Format("20060102T150405.000000000Z")))
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
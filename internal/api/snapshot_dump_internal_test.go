@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSnapshotDump(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	takenAt := time.Date(2024, 3, 1, 12, 30, 45, 123456789, time.UTC)
+
+	path, err := writeSnapshotDump(dir, snapshotDump{
+		TakenAt: takenAt,
+		Pools:   []pool.PoolSnapshot{{TemplateHash: "h1", Ready: 2, Dirty: 1}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "integresql-snapshot-20240301T123045.123456789Z.json"), path)
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var dump snapshotDump
+	require.NoError(t, json.Unmarshal(b, &dump))
+	assert.True(t, takenAt.Equal(dump.TakenAt))
+	require.Len(t, dump.Pools, 1)
+	assert.Equal(t, "h1", dump.Pools[0].TemplateHash)
+	assert.Equal(t, 2, dump.Pools[0].Ready)
+
+	// no temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// the dump dir must exist
+	_, err = writeSnapshotDump(filepath.Join(dir, "missing"), snapshotDump{TakenAt: takenAt})
+	assert.Error(t, err)
+}
+
+func TestDumpSnapshotsNotConfigured(t *testing.T) {
+	t.Parallel()
+
+	s := NewServer(ServerConfig{})
+	_, err := s.DumpSnapshots(context.Background())
+	assert.Error(t, err)
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/manager"
+	"github.com/allaboutapps/integresql/pkg/pool"
+	"github.com/allaboutapps/integresql/pkg/util"
+)
+
+// statsDMaxPacketSize keeps the datagrams below the typical MTU of 1500 bytes (minus IP and UDP headers).
+const statsDMaxPacketSize = 1432
+
+// ExportStatsD sends the gauges and counters of all pools and the template lifecycle metrics (the same ones as GET /api/v1/admin/metrics-snapshot)
+// to the StatsDAddress via UDP every StatsDInterval, until the given ctx is done. Failed flushes are only logged, StatsD is best-effort anyways.
+func (s *Server) ExportStatsD(ctx context.Context) error {
+	if len(s.Config.StatsDAddress) == 0 {
+		return errors.New("statsd address is not configured")
+	}
+
+	if s.Config.StatsDInterval <= 0 {
+		return errors.New("statsd interval must be greater than 0")
+	}
+
+	if s.Manager == nil {
+		return manager.ErrManagerNotReady
+	}
+
+	log := util.LogFromContext(ctx).With().Str("statsdAddress", s.Config.StatsDAddress).Logger()
+
+	conn, err := net.Dial("udp", s.Config.StatsDAddress)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	counters := pool.NewStatsDCounters()
+
+	ticker := time.NewTicker(s.Config.StatsDInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := s.flushStatsD(ctx, conn, counters); err != nil {
+				log.Warn().Err(err).Msg("Failed to flush metrics to statsd")
+			}
+		}
+	}
+}
+
+func (s *Server) flushStatsD(ctx context.Context, conn net.Conn, counters *pool.StatsDCounters) error {
+	snapshots, err := s.Manager.GetPoolSnapshots(ctx)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := pool.WriteStatsD(&buf, snapshots, counters); err != nil {
+		return err
+	}
+	if err := s.Manager.LifecycleMetrics().WriteStatsD(&buf, counters); err != nil {
+		return err
+	}
+	counters.Sweep()
+
+	for _, packet := range splitStatsDPackets(buf.Bytes(), statsDMaxPacketSize) {
+		if _, err := conn.Write(packet); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splitStatsDPackets packs the given newline terminated lines into datagrams of up to maxSize bytes, never splitting a line.
+// A single line exceeding maxSize is sent as datagram on its own.
+func splitStatsDPackets(lines []byte, maxSize int) [][]byte {
+	var packets [][]byte
+
+	for len(lines) > 0 {
+		end := 0
+		for end < len(lines) {
+			next := bytes.IndexByte(lines[end:], '\n') + 1
+			if next == 0 {
+				next = len(lines) - end
+			}
+
+			if end > 0 && end+next > maxSize {
+				break
+			}
+			end += next
+		}
+
+		packets = append(packets, lines[:end])
+		lines = lines[end:]
+	}
+
+	return packets
+}
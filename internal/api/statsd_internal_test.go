@@ -0,0 +1,24 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitStatsDPackets(t *testing.T) {
+	t.Parallel()
+
+	lines := []byte("a:1|g\nbb:2|g\nccc:3|g\n")
+
+	// all lines fit into a single packet
+	assert.Equal(t, [][]byte{lines}, splitStatsDPackets(lines, 1432))
+
+	// lines are never split
+	assert.Equal(t, [][]byte{[]byte("a:1|g\nbb:2|g\n"), []byte("ccc:3|g\n")}, splitStatsDPackets(lines, 14))
+
+	// oversized lines are sent on their own
+	assert.Equal(t, [][]byte{[]byte("a:1|g\n"), []byte("bb:2|g\n"), []byte("ccc:3|g\n")}, splitStatsDPackets(lines, 4))
+
+	assert.Empty(t, splitStatsDPackets(nil, 1432))
+}
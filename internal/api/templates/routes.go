@@ -1,17 +1,32 @@
 package templates
 
-import "github.com/allaboutapps/integresql/internal/api"
+import (
+	"github.com/allaboutapps/integresql/internal/api"
+	"github.com/allaboutapps/integresql/internal/api/middleware"
+)
 
 func InitRoutes(s *api.Server) {
 	g := s.Echo.Group("/api/v1/templates")
 
-	g.POST("", postInitializeTemplate(s))
-	g.PUT("/:hash", putFinalizeTemplate(s))
-	g.DELETE("/:hash", deleteDiscardTemplate(s))
-	g.GET("/:hash/tests", getTestDatabase(s))
-	g.DELETE("/:hash/tests/:id", deleteReturnTestDatabase(s)) // deprecated, use POST /unlock instead
+	if len(s.Config.HashAllowlist) > 0 {
+		g.Use(middleware.HashAllowlist(s.Config.HashAllowlist))
+	}
 
-	g.POST("/:hash/tests/:id/recreate", postRecreateTestDatabase(s))
-	g.POST("/:hash/tests/:id/unlock", postUnlockTestDatabase(s))
+	// all routes but the state change templates or acquire/return test DBs, thus are audited (if configured)
+	audit := s.AuditMiddleware()
+
+	g.POST("", postInitializeTemplate(s), audit...)
+	g.POST("/external", postRegisterExternalTemplate(s), audit...)
+	g.PUT("/:hash", putFinalizeTemplate(s), audit...)
+	g.DELETE("/:hash", deleteDiscardTemplate(s), audit...)
+	g.GET("/:hash/state", getTemplateState(s))
+	g.GET("/:hash/tests", getTestDatabase(s), audit...)
+	g.GET("/:hash/tests/:id", getTestDatabaseByID(s), audit...)
+	g.DELETE("/:hash/tests/:id", deleteReturnTestDatabase(s), audit...) // deprecated, use POST /unlock instead
+
+	g.POST("/:hash/tests/:id/recreate", postRecreateTestDatabase(s), audit...)
+	g.POST("/:hash/tests/:id/unlock", postUnlockTestDatabase(s), audit...)
+	g.POST("/:hash/tests/:id/poisoned", postReturnPoisonedTestDatabase(s), audit...)
+	g.POST("/:hash/tests/:id/heartbeat", postHeartbeatTestDatabase(s), audit...)
 
 }
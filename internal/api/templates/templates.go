@@ -4,16 +4,35 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/allaboutapps/integresql/internal/api"
+	"github.com/allaboutapps/integresql/internal/api/middleware"
+	"github.com/allaboutapps/integresql/pkg/db"
 	"github.com/allaboutapps/integresql/pkg/manager"
 	"github.com/allaboutapps/integresql/pkg/pool"
+	"github.com/allaboutapps/integresql/pkg/templates"
 	"github.com/labstack/echo/v4"
 )
 
 func postInitializeTemplate(s *api.Server) echo.HandlerFunc {
 	type requestPayload struct {
-		Hash string `json:"hash"`
+		Hash                  string         `json:"hash"`
+		InlineRecreateMaxSize int64          `json:"inlineRecreateMaxSize,omitempty"` // optional per hash override (bytes)
+		CleanStrategy         string         `json:"cleanStrategy,omitempty"`         // optional "recopy" (default), "truncate" or "schema"
+		ResetSQL              string         `json:"resetSql,omitempty"`              // required for the "truncate" and "schema" clean strategies
+		TestDatabaseOwner     string         `json:"testDatabaseOwner,omitempty"`     // optional per hash override of the test DB owner role
+		LeakWarnTimeoutMs     int64          `json:"leakWarnTimeoutMs,omitempty"`     // optional per hash override of the leak warn timeout
+		LeakReclaimTimeoutMs  int64          `json:"leakReclaimTimeoutMs,omitempty"`  // optional per hash override of the leak reclaim timeout
+		Sources               map[string]int `json:"sources,omitempty"`               // optional additional template DBs (name -> weight) the test DBs are copied from
+		Fingerprint           string         `json:"fingerprint,omitempty"`           // optional fingerprint of the template content, detecting a reused hash
+		ConnectionLimit       int            `json:"connectionLimit,omitempty"`       // optional per hash override of the connection limit of the test DBs (-1 unlimited)
+		MinReady              int            `json:"minReady,omitempty"`              // optional per hash override of the warm standby of ready test DBs
+		Encoding              string         `json:"encoding,omitempty"`              // optional per hash override of the DB encoding
+		LCCollate             string         `json:"lcCollate,omitempty"`             // optional per hash override of the DB LC_COLLATE
+		LCCtype               string         `json:"lcCtype,omitempty"`               // optional per hash override of the DB LC_CTYPE
+		Variant               string         `json:"variant,omitempty"`               // optional variant of the template (e.g. "schema-only"), initialized as a template on its own
 	}
 
 	return func(c echo.Context) error {
@@ -27,12 +46,112 @@ func postInitializeTemplate(s *api.Server) echo.HandlerFunc {
 			return echo.NewHTTPError(http.StatusBadRequest, "hash is required")
 		}
 
-		template, err := s.Manager.InitializeTemplateDatabase(c.Request().Context(), payload.Hash)
+		middleware.SetAuditHash(c, pool.VariantHash(payload.Hash, payload.Variant))
+
+		if err := middleware.CheckHashAllowed(c, payload.Hash); err != nil {
+			return err
+		}
+
+		if err := pool.ValidateVariant(payload.Variant); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+
+		template, err := s.Manager.InitializeTemplateDatabaseWithOptions(c.Request().Context(), pool.VariantHash(payload.Hash, payload.Variant), manager.TemplateOptions{
+			InlineRecreateMaxSize: payload.InlineRecreateMaxSize,
+			CleanStrategy:         templates.CleanStrategy(payload.CleanStrategy),
+			ResetSQL:              payload.ResetSQL,
+			TestDatabaseOwner:     payload.TestDatabaseOwner,
+			LeakWarnTimeout:       time.Duration(payload.LeakWarnTimeoutMs) * time.Millisecond,
+			LeakReclaimTimeout:    time.Duration(payload.LeakReclaimTimeoutMs) * time.Millisecond,
+			Sources:               payload.Sources,
+			Fingerprint:           payload.Fingerprint,
+			ConnectionLimit:       payload.ConnectionLimit,
+			MinReady:              payload.MinReady,
+			DatabaseLocale: manager.DatabaseLocale{
+				Encoding: payload.Encoding,
+				Collate:  payload.LCCollate,
+				Ctype:    payload.LCCtype,
+			},
+		})
 		if err != nil {
 			if errors.Is(err, manager.ErrManagerNotReady) {
 				return echo.ErrServiceUnavailable
 			} else if errors.Is(err, manager.ErrTemplateAlreadyInitialized) {
 				return echo.NewHTTPError(http.StatusLocked, "template is already initialized")
+			} else if errors.Is(err, manager.ErrHashMismatch) {
+				return echo.NewHTTPError(http.StatusConflict, err.Error())
+			} else if errors.Is(err, manager.ErrInvalidCleanStrategy) || errors.Is(err, manager.ErrUnknownTestDatabaseOwner) || errors.Is(err, manager.ErrIncompatibleDatabaseLocale) || errors.Is(err, manager.ErrInvalidLeakTimeouts) || errors.Is(err, manager.ErrInvalidTemplateSource) || errors.Is(err, manager.ErrInvalidConnectionLimit) || errors.Is(err, manager.ErrInvalidMinReady) {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			} else if errors.Is(err, pool.ErrTooManyConnections) {
+				return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+			}
+
+			// default 500
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		return c.JSON(http.StatusOK, &template)
+	}
+}
+
+func postRegisterExternalTemplate(s *api.Server) echo.HandlerFunc {
+	type requestPayload struct {
+		Hash                  string         `json:"hash"`
+		Database              string         `json:"database"`                        // name of the existing template database
+		InlineRecreateMaxSize int64          `json:"inlineRecreateMaxSize,omitempty"` // optional per hash override (bytes)
+		CleanStrategy         string         `json:"cleanStrategy,omitempty"`         // optional "recopy" (default), "truncate" or "schema"
+		ResetSQL              string         `json:"resetSql,omitempty"`              // required for the "truncate" and "schema" clean strategies
+		TestDatabaseOwner     string         `json:"testDatabaseOwner,omitempty"`     // optional per hash override of the test DB owner role
+		LeakWarnTimeoutMs     int64          `json:"leakWarnTimeoutMs,omitempty"`     // optional per hash override of the leak warn timeout
+		LeakReclaimTimeoutMs  int64          `json:"leakReclaimTimeoutMs,omitempty"`  // optional per hash override of the leak reclaim timeout
+		Sources               map[string]int `json:"sources,omitempty"`               // optional additional template DBs (name -> weight) the test DBs are copied from
+		Fingerprint           string         `json:"fingerprint,omitempty"`           // optional fingerprint of the template content, detecting a reused hash
+		ConnectionLimit       int            `json:"connectionLimit,omitempty"`       // optional per hash override of the connection limit of the test DBs (-1 unlimited)
+		MinReady              int            `json:"minReady,omitempty"`              // optional per hash override of the warm standby of ready test DBs
+	}
+
+	return func(c echo.Context) error {
+		var payload requestPayload
+
+		if err := c.Bind(&payload); err != nil {
+			return err
+		}
+
+		if len(payload.Hash) == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "hash is required")
+		}
+
+		if len(payload.Database) == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "database is required")
+		}
+
+		middleware.SetAuditHash(c, payload.Hash)
+
+		if err := middleware.CheckHashAllowed(c, payload.Hash); err != nil {
+			return err
+		}
+
+		template, err := s.Manager.RegisterExternalTemplateDatabase(c.Request().Context(), payload.Hash, payload.Database, manager.TemplateOptions{
+			InlineRecreateMaxSize: payload.InlineRecreateMaxSize,
+			CleanStrategy:         templates.CleanStrategy(payload.CleanStrategy),
+			ResetSQL:              payload.ResetSQL,
+			TestDatabaseOwner:     payload.TestDatabaseOwner,
+			LeakWarnTimeout:       time.Duration(payload.LeakWarnTimeoutMs) * time.Millisecond,
+			LeakReclaimTimeout:    time.Duration(payload.LeakReclaimTimeoutMs) * time.Millisecond,
+			Sources:               payload.Sources,
+			Fingerprint:           payload.Fingerprint,
+			ConnectionLimit:       payload.ConnectionLimit,
+			MinReady:              payload.MinReady,
+		})
+		if err != nil {
+			if errors.Is(err, manager.ErrManagerNotReady) {
+				return echo.ErrServiceUnavailable
+			} else if errors.Is(err, manager.ErrTemplateAlreadyInitialized) {
+				return echo.NewHTTPError(http.StatusLocked, "template is already initialized")
+			} else if errors.Is(err, manager.ErrHashMismatch) {
+				return echo.NewHTTPError(http.StatusConflict, err.Error())
+			} else if errors.Is(err, manager.ErrInvalidCleanStrategy) || errors.Is(err, manager.ErrUnknownTestDatabaseOwner) || errors.Is(err, manager.ErrInvalidLeakTimeouts) || errors.Is(err, manager.ErrInvalidExternalTemplate) || errors.Is(err, manager.ErrInvalidTemplateSource) || errors.Is(err, manager.ErrInvalidConnectionLimit) || errors.Is(err, manager.ErrInvalidMinReady) {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 			}
 
 			// default 500
@@ -45,12 +164,20 @@ func postInitializeTemplate(s *api.Server) echo.HandlerFunc {
 
 func putFinalizeTemplate(s *api.Server) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		hash := c.Param("hash")
+		hash, err := variantHashParam(c)
+		if err != nil {
+			return err
+		}
 
-		if _, err := s.Manager.FinalizeTemplateDatabase(c.Request().Context(), hash); err != nil {
+		// optional fingerprint of the template content, supplied as ?fingerprint=...
+		fingerprint := c.QueryParam("fingerprint")
+
+		if _, err := s.Manager.FinalizeTemplateDatabaseWithFingerprint(c.Request().Context(), hash, fingerprint); err != nil {
 			if errors.Is(err, manager.ErrTemplateAlreadyInitialized) {
 				// template is initialized, we ignore this error
 				return c.NoContent(http.StatusNoContent)
+			} else if errors.Is(err, manager.ErrHashMismatch) {
+				return echo.NewHTTPError(http.StatusConflict, err.Error())
 			} else if errors.Is(err, manager.ErrManagerNotReady) {
 				return echo.ErrServiceUnavailable
 			} else if errors.Is(err, manager.ErrTemplateNotFound) {
@@ -67,7 +194,10 @@ func putFinalizeTemplate(s *api.Server) echo.HandlerFunc {
 
 func deleteDiscardTemplate(s *api.Server) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		hash := c.Param("hash")
+		hash, err := variantHashParam(c)
+		if err != nil {
+			return err
+		}
 
 		if err := s.Manager.DiscardTemplateDatabase(c.Request().Context(), hash); err != nil {
 			if errors.Is(err, manager.ErrManagerNotReady) {
@@ -84,12 +214,77 @@ func deleteDiscardTemplate(s *api.Server) echo.HandlerFunc {
 	}
 }
 
+func getTemplateState(s *api.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		hash, err := variantHashParam(c)
+		if err != nil {
+			return err
+		}
+
+		status, err := s.Manager.GetTemplateStatus(c.Request().Context(), hash)
+		if err != nil {
+			if errors.Is(err, manager.ErrManagerNotReady) {
+				return echo.ErrServiceUnavailable
+			} else if errors.Is(err, manager.ErrTemplateNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "template not found")
+			}
+
+			// default 500
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		return c.JSON(http.StatusOK, &status)
+	}
+}
+
 func getTestDatabase(s *api.Server) echo.HandlerFunc {
+	// returned instead of the plain test DB with ?verbose=true, the default response stays compatible
+	type verboseResponsePayload struct {
+		db.TestDatabase
+		Dirty       bool  `json:"dirty"`       // handed out as is (again for a sticky key) instead of freshly cleaned
+		WaitedMs    int64 `json:"waitedMs"`    // time spent within the pool, mostly waiting for a ready test DB
+		Ready       int   `json:"ready"`       // test DBs left ready right after the acquisition
+		ReadyTarget int   `json:"readyTarget"` // test DBs the pool tries to keep ready
+	}
 
 	return func(c echo.Context) error {
-		hash := c.Param("hash")
+		hash, err := variantHashParam(c)
+		if err != nil {
+			return err
+		}
 
-		test, err := s.Manager.GetTestDatabase(c.Request().Context(), hash)
+		// optional labels, supplied as ?label=key:value&label=key2:value2
+		var labels map[string]string
+		for _, label := range c.QueryParams()["label"] {
+			key, value, found := strings.Cut(label, ":")
+			if !found || len(key) == 0 {
+				return echo.NewHTTPError(http.StatusBadRequest, "invalid label, expected key:value")
+			}
+
+			if labels == nil {
+				labels = make(map[string]string)
+			}
+			labels[key] = value
+		}
+
+		reservationTTL, err := reservationTTLParam(c)
+		if err != nil {
+			return err
+		}
+
+		// resolved before acquiring the test DB, thus it does not leak if variables are undefined
+		applyConfig, err := s.Config.TestDatabaseConfigTemplate.Resolve(api.HeaderConfigVars(c.Request().Header))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+
+		// optional sticky key, e.g. the name of a retried test, supplied as ?stickyKey=...
+		stickyKey := c.QueryParam("stickyKey")
+
+		// optional metadata of the acquisition, e.g. to reduce the parallelism of the client while the pool is thin
+		verbose := c.QueryParam("verbose") == "true"
+
+		test, info, err := s.Manager.GetTestDatabaseWithInfo(c.Request().Context(), hash, pool.GetOptions{Labels: labels, ReservationTTL: reservationTTL, StickyKey: stickyKey})
 		if err != nil {
 
 			if errors.Is(err, manager.ErrManagerNotReady) {
@@ -98,16 +293,90 @@ func getTestDatabase(s *api.Server) echo.HandlerFunc {
 				return echo.NewHTTPError(http.StatusNotFound, "template not found")
 			} else if errors.Is(err, manager.ErrTemplateDiscarded) {
 				return echo.NewHTTPError(http.StatusGone, "template was just discarded")
+			} else if errors.Is(err, pool.ErrTemplateMissing) {
+				return echo.NewHTTPError(http.StatusGone, pool.ErrTemplateMissing.Error())
+			} else if errors.Is(err, pool.ErrPoolFull) {
+				return echo.NewHTTPError(http.StatusLocked, pool.ErrPoolFull.Error())
+			} else if errors.Is(err, pool.ErrTooManyConnections) {
+				return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
 			}
 
 			// default 500
 			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 		}
 
+		middleware.SetAuditID(c, test.ID)
+
+		test.Config = applyConfig(test.Config)
+
+		if verbose {
+			return c.JSON(http.StatusOK, &verboseResponsePayload{
+				TestDatabase: test,
+				Dirty:        info.Dirty,
+				WaitedMs:     info.Waited.Milliseconds(),
+				Ready:        info.Ready,
+				ReadyTarget:  info.ReadyTarget,
+			})
+		}
+
 		return c.JSON(http.StatusOK, &test)
 	}
 }
 
+func getTestDatabaseByID(s *api.Server) echo.HandlerFunc {
+	type responsePayload struct {
+		db.TestDatabase
+		Dirty bool `json:"dirty"` // handed out as is, without being recreated
+	}
+
+	return func(c echo.Context) error {
+		hash, err := variantHashParam(c)
+		if err != nil {
+			return err
+		}
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid test database ID")
+		}
+
+		rejectDirty := c.QueryParam("rejectDirty") == "true" // optional, fail instead of handing out a dirty test DB as is
+
+		reservationTTL, err := reservationTTLParam(c)
+		if err != nil {
+			return err
+		}
+
+		applyConfig, err := s.Config.TestDatabaseConfigTemplate.Resolve(api.HeaderConfigVars(c.Request().Header))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+
+		test, dirty, err := s.Manager.GetTestDatabaseByIDWithOptions(c.Request().Context(), hash, id, pool.GetOptions{RejectDirty: rejectDirty, ReservationTTL: reservationTTL})
+		if err != nil {
+			if errors.Is(err, manager.ErrManagerNotReady) {
+				return echo.ErrServiceUnavailable
+			} else if errors.Is(err, manager.ErrTemplateNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "template not found")
+			} else if errors.Is(err, manager.ErrTestNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "test database not found")
+			} else if errors.Is(err, pool.ErrTestDBInUse) {
+				return echo.NewHTTPError(http.StatusLocked, pool.ErrTestDBInUse.Error())
+			} else if errors.Is(err, pool.ErrInvalidState) {
+				return echo.NewHTTPError(http.StatusConflict, "test database is currently being recreated")
+			} else if errors.Is(err, pool.ErrWouldReuseDirty) {
+				return echo.NewHTTPError(http.StatusPreconditionFailed, pool.ErrWouldReuseDirty.Error())
+			}
+
+			// default 500
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		test.Config = applyConfig(test.Config)
+
+		return c.JSON(http.StatusOK, &responsePayload{TestDatabase: test, Dirty: dirty})
+	}
+}
+
 // deprecated
 func deleteReturnTestDatabase(s *api.Server) echo.HandlerFunc {
 	return postUnlockTestDatabase(s)
@@ -115,13 +384,34 @@ func deleteReturnTestDatabase(s *api.Server) echo.HandlerFunc {
 
 func postUnlockTestDatabase(s *api.Server) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		hash := c.Param("hash")
+		hash, err := variantHashParam(c)
+		if err != nil {
+			return err
+		}
 		id, err := strconv.Atoi(c.Param("id"))
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, "invalid test database ID")
 		}
 
-		if err := s.Manager.ReturnTestDatabase(c.Request().Context(), hash, id); err != nil {
+		lease := c.QueryParam("lease") // must match the lease of the current holder (optional only if LenientLeases is configured)
+		if err := s.CheckLease(lease); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+
+		if c.QueryParam("async") == "true" {
+			// best-effort, errors are only logged by the manager
+			if err := s.Manager.ReturnTestDatabaseAsync(c.Request().Context(), hash, id, lease); err != nil {
+				if errors.Is(err, manager.ErrManagerNotReady) {
+					return echo.ErrServiceUnavailable
+				}
+
+				return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+			}
+
+			return c.NoContent(http.StatusAccepted)
+		}
+
+		if err := s.Manager.ReturnTestDatabaseWithLease(c.Request().Context(), hash, id, lease); err != nil {
 			if errors.Is(err, manager.ErrManagerNotReady) {
 				return echo.ErrServiceUnavailable
 			} else if errors.Is(err, manager.ErrTemplateNotFound) {
@@ -130,6 +420,10 @@ func postUnlockTestDatabase(s *api.Server) echo.HandlerFunc {
 				return echo.NewHTTPError(http.StatusNotFound, "test database not found")
 			} else if errors.Is(err, pool.ErrTestDBInUse) {
 				return echo.NewHTTPError(http.StatusLocked, pool.ErrTestDBInUse.Error())
+			} else if errors.Is(err, pool.ErrInvalidLease) {
+				return echo.NewHTTPError(http.StatusConflict, pool.ErrInvalidLease.Error())
+			} else if errors.Is(err, pool.ErrUnknownID) {
+				return echo.NewHTTPError(http.StatusConflict, pool.ErrUnknownID.Error())
 			}
 
 			// default 500
@@ -142,13 +436,21 @@ func postUnlockTestDatabase(s *api.Server) echo.HandlerFunc {
 
 func postRecreateTestDatabase(s *api.Server) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		hash := c.Param("hash")
+		hash, err := variantHashParam(c)
+		if err != nil {
+			return err
+		}
 		id, err := strconv.Atoi(c.Param("id"))
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, "invalid test database ID")
 		}
 
-		if err := s.Manager.RecreateTestDatabase(c.Request().Context(), hash, id); err != nil {
+		lease := c.QueryParam("lease") // must match the lease of the current holder (optional only if LenientLeases is configured)
+		if err := s.CheckLease(lease); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+
+		if err := s.Manager.RecreateTestDatabaseWithLease(c.Request().Context(), hash, id, lease); err != nil {
 
 			if errors.Is(err, manager.ErrManagerNotReady) {
 				return echo.ErrServiceUnavailable
@@ -158,6 +460,8 @@ func postRecreateTestDatabase(s *api.Server) echo.HandlerFunc {
 				return echo.NewHTTPError(http.StatusNotFound, "test database not found")
 			} else if errors.Is(err, pool.ErrTestDBInUse) {
 				return echo.NewHTTPError(http.StatusLocked, pool.ErrTestDBInUse.Error())
+			} else if errors.Is(err, pool.ErrInvalidLease) {
+				return echo.NewHTTPError(http.StatusConflict, pool.ErrInvalidLease.Error())
 			}
 
 			// default 500
@@ -167,3 +471,112 @@ func postRecreateTestDatabase(s *api.Server) echo.HandlerFunc {
 		return c.NoContent(http.StatusNoContent)
 	}
 }
+
+func postReturnPoisonedTestDatabase(s *api.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		hash, err := variantHashParam(c)
+		if err != nil {
+			return err
+		}
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid test database ID")
+		}
+
+		lease := c.QueryParam("lease") // must match the lease of the current holder (optional only if LenientLeases is configured)
+		if err := s.CheckLease(lease); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+
+		if err := s.Manager.ReturnTestDatabasePoisonedWithLease(c.Request().Context(), hash, id, lease); err != nil {
+
+			if errors.Is(err, manager.ErrManagerNotReady) {
+				return echo.ErrServiceUnavailable
+			} else if errors.Is(err, manager.ErrTemplateNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "template not found")
+			} else if errors.Is(err, manager.ErrTestNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "test database not found")
+			} else if errors.Is(err, pool.ErrTestDBInUse) {
+				return echo.NewHTTPError(http.StatusLocked, pool.ErrTestDBInUse.Error())
+			} else if errors.Is(err, pool.ErrInvalidLease) {
+				return echo.NewHTTPError(http.StatusConflict, pool.ErrInvalidLease.Error())
+			}
+
+			// default 500
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+func postHeartbeatTestDatabase(s *api.Server) echo.HandlerFunc {
+	type responsePayload struct {
+		ReservedUntil time.Time `json:"reservedUntil"`
+	}
+
+	return func(c echo.Context) error {
+		hash, err := variantHashParam(c)
+		if err != nil {
+			return err
+		}
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid test database ID")
+		}
+
+		lease := c.QueryParam("lease") // must match the lease of the current holder (optional only if LenientLeases is configured)
+		if err := s.CheckLease(lease); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+
+		reservedUntil, err := s.Manager.ExtendTestDatabaseReservation(c.Request().Context(), hash, id, lease)
+		if err != nil {
+			if errors.Is(err, manager.ErrManagerNotReady) {
+				return echo.ErrServiceUnavailable
+			} else if errors.Is(err, manager.ErrTemplateNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "template not found")
+			} else if errors.Is(err, manager.ErrTestNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "test database not found")
+			} else if errors.Is(err, pool.ErrInvalidLease) {
+				return echo.NewHTTPError(http.StatusConflict, pool.ErrInvalidLease.Error())
+			} else if errors.Is(err, pool.ErrUnknownID) {
+				// already returned or reclaimed after its reservation expired
+				return echo.NewHTTPError(http.StatusConflict, pool.ErrUnknownID.Error())
+			} else if errors.Is(err, pool.ErrNotReserved) {
+				return echo.NewHTTPError(http.StatusConflict, pool.ErrNotReserved.Error())
+			}
+
+			// default 500
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+
+		return c.JSON(http.StatusOK, &responsePayload{ReservedUntil: reservedUntil})
+	}
+}
+
+// reservationTTLParam returns the optional soft reservation TTL of an acquired test DB, supplied as ?ttlMs=... (see pool.GetOptions.ReservationTTL).
+func reservationTTLParam(c echo.Context) (time.Duration, error) {
+	param := c.QueryParam("ttlMs")
+	if len(param) == 0 {
+		return 0, nil
+	}
+
+	ttlMs, err := strconv.Atoi(param)
+	if err != nil || ttlMs <= 0 {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "invalid ttlMs, expected a positive number of milliseconds")
+	}
+
+	return time.Duration(ttlMs) * time.Millisecond, nil
+}
+
+// variantHashParam returns the key of the pool addressed by the hash route param and the optional variant of the template,
+// supplied as ?variant=... (see pool.VariantHash).
+func variantHashParam(c echo.Context) (string, error) {
+	variant := c.QueryParam("variant")
+	if err := pool.ValidateVariant(variant); err != nil {
+		return "", echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return pool.VariantHash(c.Param("hash"), variant), nil
+}
@@ -0,0 +1,143 @@
+package templates_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/allaboutapps/integresql/internal/api"
+	"github.com/allaboutapps/integresql/internal/test"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The following tests assert the REST contract of the original allaboutapps IntegreSQL,
+// existing clients must keep working unmodified against this server.
+
+func assertDatabaseContract(t *testing.T, database interface{}, hash string) {
+	t.Helper()
+
+	d, ok := database.(map[string]interface{})
+	require.True(t, ok, "database must be an object")
+	assert.Equal(t, hash, d["templateHash"])
+
+	config, ok := d["config"].(map[string]interface{})
+	require.True(t, ok, "database.config must be an object")
+
+	for _, key := range []string{"host", "port", "username", "password", "database"} {
+		assert.Contains(t, config, key)
+	}
+	assert.IsType(t, "", config["host"])
+	assert.IsType(t, float64(0), config["port"])
+	assert.IsType(t, "", config["database"])
+}
+
+func TestTemplatesContract(t *testing.T) {
+	test.WithTestServer(t, func(s *api.Server) {
+		hash := uuid.NewString()
+		basePath := fmt.Sprintf("/api/v1/templates/%s", hash)
+
+		res := test.PerformRequest(t, s, http.MethodPost, "/api/v1/templates", test.GenericPayload{"hash": hash}, nil)
+		require.Equal(t, http.StatusOK, res.Result().StatusCode)
+
+		var template map[string]interface{}
+		test.ParseResponseBody(t, res, &template)
+		assertDatabaseContract(t, template["database"], hash)
+
+		// initializing again is locked
+		res = test.PerformRequest(t, s, http.MethodPost, "/api/v1/templates", test.GenericPayload{"hash": hash}, nil)
+		assert.Equal(t, http.StatusLocked, res.Result().StatusCode)
+
+		var state map[string]interface{}
+		res = test.PerformRequest(t, s, http.MethodGet, basePath+"/state", nil, nil)
+		require.Equal(t, http.StatusOK, res.Result().StatusCode)
+		test.ParseResponseBody(t, res, &state)
+		assert.Equal(t, map[string]interface{}{"state": "init", "ready": float64(0), "dirty": float64(0)}, state)
+
+		res = test.PerformRequest(t, s, http.MethodPut, basePath, nil, nil)
+		assert.Equal(t, http.StatusNoContent, res.Result().StatusCode)
+
+		res = test.PerformRequest(t, s, http.MethodGet, basePath+"/state", nil, nil)
+		require.Equal(t, http.StatusOK, res.Result().StatusCode)
+		test.ParseResponseBody(t, res, &state)
+		assert.Equal(t, "finalized", state["state"])
+
+		// finalizing again is fine
+		res = test.PerformRequest(t, s, http.MethodPut, basePath, nil, nil)
+		assert.Equal(t, http.StatusNoContent, res.Result().StatusCode)
+
+		res = test.PerformRequest(t, s, http.MethodGet, basePath+"/tests", nil, nil)
+		require.Equal(t, http.StatusOK, res.Result().StatusCode)
+
+		var testDB map[string]interface{}
+		test.ParseResponseBody(t, res, &testDB)
+		assertDatabaseContract(t, testDB["database"], hash)
+		require.IsType(t, float64(0), testDB["id"])
+
+		id := int(testDB["id"].(float64))
+		require.IsType(t, "", testDB["lease"])
+
+		// the lease is required
+		res = test.PerformRequest(t, s, http.MethodPost, fmt.Sprintf("%s/tests/%d/unlock", basePath, id), nil, nil)
+		assert.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+
+		res = test.PerformRequest(t, s, http.MethodPost, fmt.Sprintf("%s/tests/%d/unlock?lease=%s", basePath, id, testDB["lease"]), nil, nil)
+		assert.Equal(t, http.StatusNoContent, res.Result().StatusCode)
+
+		// deprecated return is still served
+		res = test.PerformRequest(t, s, http.MethodGet, basePath+"/tests", nil, nil)
+		require.Equal(t, http.StatusOK, res.Result().StatusCode)
+		test.ParseResponseBody(t, res, &testDB)
+		id = int(testDB["id"].(float64))
+
+		res = test.PerformRequest(t, s, http.MethodDelete, fmt.Sprintf("%s/tests/%d?lease=%s", basePath, id, testDB["lease"]), nil, nil)
+		assert.Equal(t, http.StatusNoContent, res.Result().StatusCode)
+
+		res = test.PerformRequest(t, s, http.MethodDelete, basePath, nil, nil)
+		assert.Equal(t, http.StatusNoContent, res.Result().StatusCode)
+
+		res = test.PerformRequest(t, s, http.MethodGet, basePath+"/tests", nil, nil)
+		assert.Equal(t, http.StatusNotFound, res.Result().StatusCode)
+	})
+}
+
+func TestTemplatesContractErrors(t *testing.T) {
+	test.WithTestServer(t, func(s *api.Server) {
+		unknownPath := fmt.Sprintf("/api/v1/templates/%s", uuid.NewString())
+
+		tests := []struct {
+			name   string
+			method string
+			path   string
+			body   test.GenericPayload
+			want   int
+		}{
+			{name: "InitializeWithoutHash", method: http.MethodPost, path: "/api/v1/templates", body: test.GenericPayload{}, want: http.StatusBadRequest},
+			{name: "FinalizeUnknown", method: http.MethodPut, path: unknownPath, want: http.StatusNotFound},
+			{name: "DiscardUnknown", method: http.MethodDelete, path: unknownPath, want: http.StatusNotFound},
+			{name: "GetTestUnknown", method: http.MethodGet, path: unknownPath + "/tests", want: http.StatusNotFound},
+			{name: "StateUnknown", method: http.MethodGet, path: unknownPath + "/state", want: http.StatusNotFound},
+			{name: "UnlockUnknown", method: http.MethodPost, path: unknownPath + "/tests/0/unlock?lease=x", want: http.StatusNotFound},
+			{name: "UnlockInvalidID", method: http.MethodPost, path: unknownPath + "/tests/abc/unlock", want: http.StatusBadRequest},
+			{name: "RecreateUnknown", method: http.MethodPost, path: unknownPath + "/tests/0/recreate?lease=x", want: http.StatusNotFound},
+			{name: "RecreateInvalidID", method: http.MethodPost, path: unknownPath + "/tests/abc/recreate", want: http.StatusBadRequest},
+			{name: "UnlockWithoutLease", method: http.MethodPost, path: unknownPath + "/tests/0/unlock", want: http.StatusBadRequest},
+			{name: "RecreateWithoutLease", method: http.MethodPost, path: unknownPath + "/tests/0/recreate", want: http.StatusBadRequest},
+			{name: "ReturnUnknown", method: http.MethodDelete, path: unknownPath + "/tests/0?lease=x", want: http.StatusNotFound},
+		}
+
+		for _, tt := range tests {
+			tt := tt
+			t.Run(tt.name, func(t *testing.T) {
+				res := test.PerformRequest(t, s, tt.method, tt.path, tt.body, nil)
+				assert.Equal(t, tt.want, res.Result().StatusCode)
+
+				// errors are returned as {"message": "..."} by echo
+				var body map[string]interface{}
+				test.ParseResponseBody(t, res, &body)
+				assert.Contains(t, body, "message")
+			})
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/manager"
+	"github.com/allaboutapps/integresql/pkg/templates"
+)
+
+// declaredTemplate is an entry of the TemplatesFile, its fields mirror the payload of POST /api/v1/templates/external.
+type declaredTemplate struct {
+	Hash                  string         `json:"hash"`
+	Database              string         `json:"database"` // name of the existing template database
+	InlineRecreateMaxSize int64          `json:"inlineRecreateMaxSize,omitempty"`
+	CleanStrategy         string         `json:"cleanStrategy,omitempty"`
+	ResetSQL              string         `json:"resetSql,omitempty"`
+	TestDatabaseOwner     string         `json:"testDatabaseOwner,omitempty"`
+	LeakWarnTimeoutMs     int64          `json:"leakWarnTimeoutMs,omitempty"`
+	LeakReclaimTimeoutMs  int64          `json:"leakReclaimTimeoutMs,omitempty"`
+	Sources               map[string]int `json:"sources,omitempty"`
+	Fingerprint           string         `json:"fingerprint,omitempty"`
+	ConnectionLimit       int            `json:"connectionLimit,omitempty"`
+	MinReady              int            `json:"minReady,omitempty"`
+}
+
+// WarmDeclaredTemplates registers all templates declared within the TemplatesFile concurrently and waits until their pools are warm,
+// bounded by the TemplatesWarmUpTimeout (see manager.Manager.WarmTemplates). Only an unreadable or invalid file is reported as error,
+// failed templates are logged and may still be initialized by clients afterwards.
+func (s *Server) WarmDeclaredTemplates(ctx context.Context) ([]manager.WarmResult, error) {
+	if len(s.Config.TemplatesFile) == 0 {
+		return nil, nil
+	}
+
+	if s.Manager == nil {
+		return nil, manager.ErrManagerNotReady
+	}
+
+	declared, err := loadDeclaredTemplates(s.Config.TemplatesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Config.TemplatesWarmUpTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Config.TemplatesWarmUpTimeout)
+		defer cancel()
+	}
+
+	return s.Manager.WarmTemplates(ctx, declared), nil
+}
+
+// loadDeclaredTemplates reads the JSON array of declared templates from the given file.
+func loadDeclaredTemplates(path string) ([]manager.DeclaredTemplate, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []declaredTemplate
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, fmt.Errorf("parse templates file %s: %w", path, err)
+	}
+
+	declared := make([]manager.DeclaredTemplate, 0, len(entries))
+	seen := make(map[string]struct{}, len(entries))
+	for i, entry := range entries {
+		if len(entry.Hash) == 0 || len(entry.Database) == 0 {
+			return nil, fmt.Errorf("templates file %s: entry %d: hash and database are required", path, i)
+		}
+
+		if _, ok := seen[entry.Hash]; ok {
+			return nil, fmt.Errorf("templates file %s: entry %d: duplicate hash %q", path, i, entry.Hash)
+		}
+		seen[entry.Hash] = struct{}{}
+
+		declared = append(declared, manager.DeclaredTemplate{
+			Hash:     entry.Hash,
+			Database: entry.Database,
+			Options: manager.TemplateOptions{
+				InlineRecreateMaxSize: entry.InlineRecreateMaxSize,
+				CleanStrategy:         templates.CleanStrategy(entry.CleanStrategy),
+				ResetSQL:              entry.ResetSQL,
+				TestDatabaseOwner:     entry.TestDatabaseOwner,
+				LeakWarnTimeout:       time.Duration(entry.LeakWarnTimeoutMs) * time.Millisecond,
+				LeakReclaimTimeout:    time.Duration(entry.LeakReclaimTimeoutMs) * time.Millisecond,
+				Sources:               entry.Sources,
+				Fingerprint:           entry.Fingerprint,
+				ConnectionLimit:       entry.ConnectionLimit,
+				MinReady:              entry.MinReady,
+			},
+		})
+	}
+
+	return declared, nil
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/templates"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDeclaredTemplates(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	declared, err := loadDeclaredTemplates(write("templates.json", `[
+		{"hash": "h1", "database": "fixtures_a", "minReady": 4, "leakWarnTimeoutMs": 1500},
+		{"hash": "h2", "database": "fixtures_b", "cleanStrategy": "truncate", "resetSql": "TRUNCATE users"}
+	]`))
+	require.NoError(t, err)
+	require.Len(t, declared, 2)
+	assert.Equal(t, "h1", declared[0].Hash)
+	assert.Equal(t, "fixtures_a", declared[0].Database)
+	assert.Equal(t, 4, declared[0].Options.MinReady)
+	assert.Equal(t, 1500*time.Millisecond, declared[0].Options.LeakWarnTimeout)
+	assert.Equal(t, templates.CleanStrategyTruncate, declared[1].Options.CleanStrategy)
+	assert.Equal(t, "TRUNCATE users", declared[1].Options.ResetSQL)
+
+	_, err = loadDeclaredTemplates(filepath.Join(dir, "unknown.json"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = loadDeclaredTemplates(write("invalid.json", `{"hash": "h1"}`))
+	assert.Error(t, err)
+
+	_, err = loadDeclaredTemplates(write("incomplete.json", `[{"hash": "h1"}]`))
+	assert.ErrorContains(t, err, "hash and database are required")
+
+	_, err = loadDeclaredTemplates(write("duplicate.json", `[{"hash": "h1", "database": "a"}, {"hash": "h1", "database": "b"}]`))
+	assert.ErrorContains(t, err, "duplicate hash")
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/allaboutapps/integresql/internal/api"
 	"github.com/allaboutapps/integresql/internal/api/admin"
+	"github.com/allaboutapps/integresql/internal/api/health"
 	"github.com/allaboutapps/integresql/internal/api/middleware"
 	"github.com/allaboutapps/integresql/internal/api/templates"
 	"github.com/labstack/echo/v4"
@@ -72,6 +73,7 @@ func Init(s *api.Server) {
 		s.Echo.GET("/debug/*", echo.WrapHandler(http.DefaultServeMux))
 	}
 
+	health.InitRoutes(s)
 	admin.InitRoutes(s)
 	templates.InitRoutes(s)
 }
@@ -33,3 +33,13 @@ func TestPprofDisabled(t *testing.T) {
 		require.Equal(t, 404, res.Result().StatusCode)
 	})
 }
+
+func TestHealthEndpoints(t *testing.T) {
+	test.WithTestServer(t, func(s *api.Server) {
+		res := test.PerformRequest(t, s, "GET", "/livez", nil, nil)
+		require.Equal(t, 200, res.Result().StatusCode)
+
+		res = test.PerformRequest(t, s, "GET", "/readyz", nil, nil)
+		require.Equal(t, 200, res.Result().StatusCode)
+	})
+}
@@ -0,0 +1,830 @@
+// Package client provides a simple integresql client implementation, used by the tests and the pool subcommand of the server.
+// Please refer to https://github.com/allaboutapps/integresql-client-go
+// for a full client implementation to be used in your application.
+package client
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/manager"
+	"github.com/allaboutapps/integresql/pkg/pool"
+	"github.com/allaboutapps/integresql/pkg/util"
+
+	// Import postgres driver for database/sql package
+	_ "github.com/lib/pq"
+)
+
+type ClientConfig struct {
+	BaseURL    string
+	APIVersion string
+}
+
+func DefaultClientConfigFromEnv() ClientConfig {
+	return ClientConfig{
+		BaseURL:    util.GetEnv("INTEGRESQL_CLIENT_BASE_URL", "http://integresql:5000/api"),
+		APIVersion: util.GetEnv("INTEGRESQL_CLIENT_API_VERSION", "v1"),
+	}
+}
+
+type Client struct {
+	baseURL *url.URL
+	client  *http.Client
+	config  ClientConfig
+
+	shared      map[string]*sharedTestDatabase // test DBs shared by the transactions of a hash (see GetTestTransaction)
+	sharedMutex sync.Mutex
+}
+
+func NewClient(config ClientConfig) (*Client, error) {
+	c := &Client{
+		baseURL: nil,
+		client:  nil,
+		config:  config,
+		shared:  make(map[string]*sharedTestDatabase),
+	}
+
+	defaultConfig := DefaultClientConfigFromEnv()
+
+	if len(c.config.BaseURL) == 0 {
+		c.config.BaseURL = defaultConfig.BaseURL
+	}
+
+	if len(c.config.APIVersion) == 0 {
+		c.config.APIVersion = defaultConfig.APIVersion
+	}
+
+	u, err := url.Parse(c.config.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	c.baseURL = u.ResolveReference(&url.URL{Path: path.Join(u.Path, c.config.APIVersion)})
+
+	c.client = &http.Client{}
+
+	return c, nil
+}
+
+func DefaultClientFromEnv() (*Client, error) {
+	return NewClient(DefaultClientConfigFromEnv())
+}
+
+func (c *Client) ResetAllTracking(ctx context.Context) error {
+	req, err := c.newRequest(ctx, "DELETE", "/admin/templates", nil)
+	if err != nil {
+		return err
+	}
+
+	var msg string
+	resp, err := c.do(req, &msg)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to reset all tracking: %v", msg)
+	}
+
+	return nil
+}
+
+func (c *Client) ResetTracking(ctx context.Context, hash string) error {
+	req, err := c.newRequest(ctx, "DELETE", fmt.Sprintf("/admin/templates/%s", hash), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req, nil)
+	if err != nil {
+		return err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return manager.ErrTemplateNotFound
+	case http.StatusServiceUnavailable:
+		return manager.ErrManagerNotReady
+	default:
+		return fmt.Errorf("received unexpected HTTP status %d (%s)", resp.StatusCode, resp.Status)
+	}
+}
+
+func (c *Client) SetAlias(ctx context.Context, alias string, hash string) error {
+	payload := map[string]string{"hash": hash}
+
+	req, err := c.newRequest(ctx, "PUT", fmt.Sprintf("/admin/aliases/%s", alias), payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req, nil)
+	if err != nil {
+		return err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return manager.ErrTemplateNotFound
+	case http.StatusConflict:
+		return manager.ErrInvalidTemplateState
+	case http.StatusServiceUnavailable:
+		return manager.ErrManagerNotReady
+	default:
+		return fmt.Errorf("received unexpected HTTP status %d (%s)", resp.StatusCode, resp.Status)
+	}
+}
+
+func (c *Client) GetPoolSnapshots(ctx context.Context) ([]PoolSnapshot, error) {
+	var snapshots []PoolSnapshot
+
+	req, err := c.newRequest(ctx, "GET", "/admin/pools", nil)
+	if err != nil {
+		return snapshots, err
+	}
+
+	resp, err := c.do(req, &snapshots)
+	if err != nil {
+		return snapshots, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return snapshots, nil
+	case http.StatusServiceUnavailable:
+		return snapshots, manager.ErrManagerNotReady
+	default:
+		return snapshots, fmt.Errorf("received unexpected HTTP status %d (%s)", resp.StatusCode, resp.Status)
+	}
+}
+
+// GetServerInfo returns the version and capabilities of the PostgreSQL server used by IntegreSQL.
+func (c *Client) GetServerInfo(ctx context.Context) (ServerInfo, error) {
+	var info ServerInfo
+
+	req, err := c.newRequest(ctx, "GET", "/admin/info", nil)
+	if err != nil {
+		return info, err
+	}
+
+	resp, err := c.do(req, &info)
+	if err != nil {
+		return info, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return info, nil
+	case http.StatusServiceUnavailable:
+		return info, manager.ErrManagerNotReady
+	default:
+		return info, fmt.Errorf("received unexpected HTTP status %d (%s)", resp.StatusCode, resp.Status)
+	}
+}
+
+func (c *Client) GetPoolSnapshot(ctx context.Context, hash string) (PoolSnapshot, error) {
+	var snapshot PoolSnapshot
+
+	req, err := c.newRequest(ctx, "GET", fmt.Sprintf("/admin/pools/%s", hash), nil)
+	if err != nil {
+		return snapshot, err
+	}
+
+	resp, err := c.do(req, &snapshot)
+	if err != nil {
+		return snapshot, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return snapshot, nil
+	case http.StatusNotFound:
+		return snapshot, manager.ErrTemplateNotFound
+	case http.StatusServiceUnavailable:
+		return snapshot, manager.ErrManagerNotReady
+	default:
+		return snapshot, fmt.Errorf("received unexpected HTTP status %d (%s)", resp.StatusCode, resp.Status)
+	}
+}
+
+// GetInUseTestDatabases returns the test DBs of the given template hash currently held by clients, oldest acquisition first.
+func (c *Client) GetInUseTestDatabases(ctx context.Context, hash string) ([]InUseInfo, error) {
+	var inUse []InUseInfo
+
+	req, err := c.newRequest(ctx, "GET", fmt.Sprintf("/admin/pools/%s/inuse", hash), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req, &inUse)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return inUse, nil
+	case http.StatusNotFound:
+		return nil, manager.ErrTemplateNotFound
+	case http.StatusServiceUnavailable:
+		return nil, manager.ErrManagerNotReady
+	default:
+		return nil, fmt.Errorf("received unexpected HTTP status %d (%s)", resp.StatusCode, resp.Status)
+	}
+}
+
+// GetTestDatabaseDSN returns the connection string of the given test database (without handing it out), the password is only included if reveal is set.
+// Revealing the password requires a token of the hash allowlist of the server, the request fails with 403 Forbidden otherwise.
+func (c *Client) GetTestDatabaseDSN(ctx context.Context, hash string, id int, reveal bool) (TestDatabaseDSN, error) {
+	var dsn TestDatabaseDSN
+
+	req, err := c.newRequest(ctx, "GET", fmt.Sprintf("/admin/tests/%s/%d/dsn", hash, id), nil)
+	if err != nil {
+		return dsn, err
+	}
+
+	if reveal {
+		req.URL.RawQuery = url.Values{"reveal": []string{"true"}}.Encode()
+	}
+
+	resp, err := c.do(req, &dsn)
+	if err != nil {
+		return dsn, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return dsn, nil
+	case http.StatusNotFound:
+		return dsn, manager.ErrTestNotFound
+	case http.StatusServiceUnavailable:
+		return dsn, manager.ErrManagerNotReady
+	default:
+		return dsn, fmt.Errorf("received unexpected HTTP status %d (%s)", resp.StatusCode, resp.Status)
+	}
+}
+
+// SetMaxPoolSize changes the maximal pool size of the given template hash (or of all pools if hash is empty) at runtime.
+func (c *Client) SetMaxPoolSize(ctx context.Context, hash string, size int) error {
+	payload := map[string]int{"maxPoolSize": size}
+
+	path := "/admin/pools"
+	if len(hash) > 0 {
+		path = fmt.Sprintf("/admin/pools/%s", hash)
+	}
+
+	req, err := c.newRequest(ctx, "PUT", path, payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req, nil)
+	if err != nil {
+		return err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusBadRequest:
+		return pool.ErrInvalidSize
+	case http.StatusNotFound:
+		return manager.ErrTemplateNotFound
+	case http.StatusServiceUnavailable:
+		return manager.ErrManagerNotReady
+	default:
+		return fmt.Errorf("received unexpected HTTP status %d (%s)", resp.StatusCode, resp.Status)
+	}
+}
+
+// CleanDirty schedules the dirty test databases of the given template hash (or of all templates if hash is empty)
+// for immediate recreation and returns their number.
+func (c *Client) CleanDirty(ctx context.Context, hash string) (int, error) {
+	var response struct {
+		Scheduled int `json:"scheduled"`
+	}
+
+	path := "/admin/clean"
+	if len(hash) > 0 {
+		path = fmt.Sprintf("/admin/clean/%s", hash)
+	}
+
+	req, err := c.newRequest(ctx, "POST", path, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := c.do(req, &response)
+	if err != nil {
+		return 0, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusAccepted:
+		return response.Scheduled, nil
+	case http.StatusNotFound:
+		return 0, manager.ErrTemplateNotFound
+	case http.StatusServiceUnavailable:
+		return 0, manager.ErrManagerNotReady
+	default:
+		return 0, fmt.Errorf("received unexpected HTTP status %d (%s)", resp.StatusCode, resp.Status)
+	}
+}
+
+func (c *Client) DrainPool(ctx context.Context, hash string) error {
+	req, err := c.newRequest(ctx, "DELETE", fmt.Sprintf("/admin/pools/%s", hash), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req, nil)
+	if err != nil {
+		return err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return manager.ErrTemplateNotFound
+	case http.StatusServiceUnavailable:
+		return manager.ErrManagerNotReady
+	default:
+		return fmt.Errorf("received unexpected HTTP status %d (%s)", resp.StatusCode, resp.Status)
+	}
+}
+
+func (c *Client) InitializeTemplate(ctx context.Context, hash string) (TemplateDatabase, error) {
+	var template TemplateDatabase
+
+	payload := map[string]string{"hash": hash}
+
+	req, err := c.newRequest(ctx, "POST", "/templates", payload)
+	if err != nil {
+		return template, err
+	}
+
+	resp, err := c.do(req, &template)
+	if err != nil {
+		return template, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return template, nil
+	case http.StatusLocked:
+		return template, manager.ErrTemplateAlreadyInitialized
+	case http.StatusServiceUnavailable:
+		return template, manager.ErrManagerNotReady
+	default:
+		return template, fmt.Errorf("received unexpected HTTP status %d (%s)", resp.StatusCode, resp.Status)
+	}
+}
+
+func (c *Client) SetupTemplate(ctx context.Context, hash string, init func(conn string) error) error {
+	template, err := c.InitializeTemplate(ctx, hash)
+	if err == nil {
+		if err := init(template.Config.ConnectionString()); err != nil {
+			return err
+		}
+
+		return c.FinalizeTemplate(ctx, hash)
+	} else if errors.Is(err, manager.ErrTemplateAlreadyInitialized) {
+		return nil
+	}
+
+	return err
+
+}
+
+func (c *Client) SetupTemplateWithDBClient(ctx context.Context, hash string, init func(db *sql.DB) error) error {
+	template, err := c.InitializeTemplate(ctx, hash)
+	if err != nil {
+		return err
+	}
+
+	db, err := sql.Open("postgres", template.Config.ConnectionString())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return err
+	}
+
+	if err := init(db); err != nil {
+		return err
+	}
+
+	return c.FinalizeTemplate(ctx, hash)
+}
+
+func (c *Client) DiscardTemplate(ctx context.Context, hash string) error {
+	req, err := c.newRequest(ctx, "DELETE", fmt.Sprintf("/templates/%s", hash), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req, nil)
+	if err != nil {
+		return err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return manager.ErrTemplateNotFound
+	case http.StatusServiceUnavailable:
+		return manager.ErrManagerNotReady
+	default:
+		return fmt.Errorf("received unexpected HTTP status %d (%s)", resp.StatusCode, resp.Status)
+	}
+}
+
+func (c *Client) FinalizeTemplate(ctx context.Context, hash string) error {
+	req, err := c.newRequest(ctx, "PUT", fmt.Sprintf("/templates/%s", hash), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req, nil)
+	if err != nil {
+		return err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return manager.ErrTemplateNotFound
+	case http.StatusServiceUnavailable:
+		return manager.ErrManagerNotReady
+	default:
+		return fmt.Errorf("received unexpected HTTP status %d (%s)", resp.StatusCode, resp.Status)
+	}
+}
+
+func (c *Client) GetTestDatabase(ctx context.Context, hash string) (TestDatabase, error) {
+	var test TestDatabase
+
+	req, err := c.newRequest(ctx, "GET", fmt.Sprintf("/templates/%s/tests", hash), nil)
+	if err != nil {
+		return test, err
+	}
+
+	resp, err := c.do(req, &test)
+	if err != nil {
+		return test, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return test, nil
+	case http.StatusNotFound:
+		return test, manager.ErrTemplateNotFound
+	case http.StatusGone:
+		return test, manager.ErrTestNotFound
+	case http.StatusServiceUnavailable:
+		return test, manager.ErrManagerNotReady
+	default:
+		return test, fmt.Errorf("received unexpected HTTP status %d (%s)", resp.StatusCode, resp.Status)
+	}
+}
+
+// GetTestDatabaseWithReservation gets a test DB soft-reserved for the given TTL: Unless returned or heartbeated
+// (see HeartbeatTestDatabase) within the TTL, the server reclaims it and the lease of the test DB becomes invalid.
+func (c *Client) GetTestDatabaseWithReservation(ctx context.Context, hash string, ttl time.Duration) (TestDatabase, error) {
+	var test TestDatabase
+
+	req, err := c.newRequest(ctx, "GET", fmt.Sprintf("/templates/%s/tests", hash), nil)
+	if err != nil {
+		return test, err
+	}
+
+	req.URL.RawQuery = url.Values{"ttlMs": []string{strconv.FormatInt(ttl.Milliseconds(), 10)}}.Encode()
+
+	resp, err := c.do(req, &test)
+	if err != nil {
+		return test, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return test, nil
+	case http.StatusNotFound:
+		return test, manager.ErrTemplateNotFound
+	case http.StatusGone:
+		return test, manager.ErrTestNotFound
+	case http.StatusServiceUnavailable:
+		return test, manager.ErrManagerNotReady
+	default:
+		return test, fmt.Errorf("received unexpected HTTP status %d (%s)", resp.StatusCode, resp.Status)
+	}
+}
+
+// GetTestDatabaseWithStickyKey gets a test DB like GetTestDatabase, but hands out the test DB last handed out for the given key
+// (e.g. the name of a retried test) again as is, as long as the server did not recreate it meanwhile.
+func (c *Client) GetTestDatabaseWithStickyKey(ctx context.Context, hash string, stickyKey string) (TestDatabase, error) {
+	var test TestDatabase
+
+	req, err := c.newRequest(ctx, "GET", fmt.Sprintf("/templates/%s/tests", hash), nil)
+	if err != nil {
+		return test, err
+	}
+
+	req.URL.RawQuery = url.Values{"stickyKey": []string{stickyKey}}.Encode()
+
+	resp, err := c.do(req, &test)
+	if err != nil {
+		return test, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return test, nil
+	case http.StatusNotFound:
+		return test, manager.ErrTemplateNotFound
+	case http.StatusGone:
+		return test, manager.ErrTestNotFound
+	case http.StatusServiceUnavailable:
+		return test, manager.ErrManagerNotReady
+	default:
+		return test, fmt.Errorf("received unexpected HTTP status %d (%s)", resp.StatusCode, resp.Status)
+	}
+}
+
+// GetTestDatabaseWithInfo gets a test DB like GetTestDatabase, additionally describing the acquisition (?verbose=true),
+// e.g. to reduce the parallelism of the test runner while the pool is thin.
+func (c *Client) GetTestDatabaseWithInfo(ctx context.Context, hash string) (TestDatabase, AcquireInfo, error) {
+	var test struct {
+		TestDatabase
+		AcquireInfo
+	}
+
+	req, err := c.newRequest(ctx, "GET", fmt.Sprintf("/templates/%s/tests", hash), nil)
+	if err != nil {
+		return test.TestDatabase, test.AcquireInfo, err
+	}
+
+	req.URL.RawQuery = url.Values{"verbose": []string{"true"}}.Encode()
+
+	resp, err := c.do(req, &test)
+	if err != nil {
+		return test.TestDatabase, test.AcquireInfo, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return test.TestDatabase, test.AcquireInfo, nil
+	case http.StatusNotFound:
+		return test.TestDatabase, test.AcquireInfo, manager.ErrTemplateNotFound
+	case http.StatusGone:
+		return test.TestDatabase, test.AcquireInfo, manager.ErrTestNotFound
+	case http.StatusServiceUnavailable:
+		return test.TestDatabase, test.AcquireInfo, manager.ErrManagerNotReady
+	default:
+		return test.TestDatabase, test.AcquireInfo, fmt.Errorf("received unexpected HTTP status %d (%s)", resp.StatusCode, resp.Status)
+	}
+}
+
+// HeartbeatTestDatabase extends the reservation of a test DB acquired by GetTestDatabaseWithReservation by its TTL.
+// Returns the new deadline of the reservation.
+func (c *Client) HeartbeatTestDatabase(ctx context.Context, hash string, id int, lease string) (time.Time, error) {
+	var heartbeat struct {
+		ReservedUntil time.Time `json:"reservedUntil"`
+	}
+
+	req, err := c.newRequest(ctx, "POST", fmt.Sprintf("/templates/%s/tests/%d/heartbeat", hash, id), nil)
+	if err != nil {
+		return heartbeat.ReservedUntil, err
+	}
+
+	if len(lease) > 0 {
+		req.URL.RawQuery = url.Values{"lease": []string{lease}}.Encode()
+	}
+
+	resp, err := c.do(req, &heartbeat)
+	if err != nil {
+		return heartbeat.ReservedUntil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return heartbeat.ReservedUntil, nil
+	case http.StatusNotFound:
+		return heartbeat.ReservedUntil, manager.ErrTestNotFound
+	case http.StatusConflict:
+		// lease invalidated or test DB no longer held, e.g. the reservation expired
+		return heartbeat.ReservedUntil, pool.ErrInvalidLease
+	case http.StatusServiceUnavailable:
+		return heartbeat.ReservedUntil, manager.ErrManagerNotReady
+	default:
+		return heartbeat.ReservedUntil, fmt.Errorf("received unexpected HTTP status %d (%s)", resp.StatusCode, resp.Status)
+	}
+}
+
+// GetTestDatabaseVariant gets a test DB of the given variant of a template (e.g. schema-only without seed data).
+// The variant is initialized, finalized and returned like any other template, addressed by pool.VariantHash(hash, variant).
+func (c *Client) GetTestDatabaseVariant(ctx context.Context, hash string, variant string) (TestDatabase, error) {
+	return c.GetTestDatabase(ctx, pool.VariantHash(hash, variant))
+}
+
+func (c *Client) GetTestDatabaseByID(ctx context.Context, hash string, id int) (TestDatabase, bool, error) {
+	var test struct {
+		TestDatabase
+		Dirty bool `json:"dirty"`
+	}
+
+	req, err := c.newRequest(ctx, "GET", fmt.Sprintf("/templates/%s/tests/%d", hash, id), nil)
+	if err != nil {
+		return test.TestDatabase, false, err
+	}
+
+	resp, err := c.do(req, &test)
+	if err != nil {
+		return test.TestDatabase, false, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return test.TestDatabase, test.Dirty, nil
+	case http.StatusNotFound:
+		return test.TestDatabase, false, manager.ErrTestNotFound
+	case http.StatusLocked:
+		return test.TestDatabase, false, pool.ErrTestDBInUse
+	case http.StatusConflict:
+		return test.TestDatabase, false, pool.ErrInvalidState
+	case http.StatusPreconditionFailed:
+		return test.TestDatabase, false, pool.ErrWouldReuseDirty
+	case http.StatusServiceUnavailable:
+		return test.TestDatabase, false, manager.ErrManagerNotReady
+	default:
+		return test.TestDatabase, false, fmt.Errorf("received unexpected HTTP status %d (%s)", resp.StatusCode, resp.Status)
+	}
+}
+
+// ReturnTestDatabase returns the test database without its lease, only accepted by servers configured with INTEGRESQL_LENIENT_LEASES.
+//
+// Deprecated: use ReturnTestDatabaseWithLease.
+func (c *Client) ReturnTestDatabase(ctx context.Context, hash string, id int) error {
+	return c.ReturnTestDatabaseWithLease(ctx, hash, id, "")
+}
+
+// ReturnTestDatabaseWithLease returns the test database only if the given lease (TestDatabase.Lease) is still the one of its current holder.
+func (c *Client) ReturnTestDatabaseWithLease(ctx context.Context, hash string, id int, lease string) error {
+	req, err := c.newRequest(ctx, "DELETE", fmt.Sprintf("/templates/%s/tests/%d", hash, id), nil)
+	if err != nil {
+		return err
+	}
+
+	if len(lease) > 0 {
+		req.URL.RawQuery = url.Values{"lease": []string{lease}}.Encode()
+	}
+
+	resp, err := c.do(req, nil)
+	if err != nil {
+		return err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return manager.ErrTemplateNotFound
+	case http.StatusConflict:
+		return pool.ErrInvalidLease
+	case http.StatusServiceUnavailable:
+		return manager.ErrManagerNotReady
+	default:
+		return fmt.Errorf("received unexpected HTTP status %d (%s)", resp.StatusCode, resp.Status)
+	}
+}
+
+// ReturnTestDatabaseAsync returns the test database without waiting for the server to process the return (best-effort).
+// Only a not ready server is reported, any other error is logged by the server.
+func (c *Client) ReturnTestDatabaseAsync(ctx context.Context, hash string, id int, lease string) error {
+	req, err := c.newRequest(ctx, "POST", fmt.Sprintf("/templates/%s/tests/%d/unlock", hash, id), nil)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{"async": []string{"true"}}
+	if len(lease) > 0 {
+		query.Set("lease", lease)
+	}
+	req.URL.RawQuery = query.Encode()
+
+	resp, err := c.do(req, nil)
+	if err != nil {
+		return err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusAccepted:
+		return nil
+	case http.StatusServiceUnavailable:
+		return manager.ErrManagerNotReady
+	default:
+		return fmt.Errorf("received unexpected HTTP status %d (%s)", resp.StatusCode, resp.Status)
+	}
+}
+
+// ReturnTestDatabasePoisoned returns a test database corrupted beyond what cleaning can fix, it is fully recreated from the template.
+// The lease (TestDatabase.Lease) is optional, if given it must still be the one of the current holder.
+func (c *Client) ReturnTestDatabasePoisoned(ctx context.Context, hash string, id int, lease string) error {
+	req, err := c.newRequest(ctx, "POST", fmt.Sprintf("/templates/%s/tests/%d/poisoned", hash, id), nil)
+	if err != nil {
+		return err
+	}
+
+	if len(lease) > 0 {
+		req.URL.RawQuery = url.Values{"lease": []string{lease}}.Encode()
+	}
+
+	resp, err := c.do(req, nil)
+	if err != nil {
+		return err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return manager.ErrTemplateNotFound
+	case http.StatusConflict:
+		return pool.ErrInvalidLease
+	case http.StatusLocked:
+		return pool.ErrTestDBInUse
+	case http.StatusServiceUnavailable:
+		return manager.ErrManagerNotReady
+	default:
+		return fmt.Errorf("received unexpected HTTP status %d (%s)", resp.StatusCode, resp.Status)
+	}
+}
+
+func (c *Client) newRequest(ctx context.Context, method string, endpoint string, body interface{}) (*http.Request, error) {
+	u := c.baseURL.ResolveReference(&url.URL{Path: path.Join(c.baseURL.Path, endpoint)})
+
+	var buf io.ReadWriter
+	if body != nil {
+		buf = new(bytes.Buffer)
+		if err := json.NewEncoder(buf).Encode(body); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	return req, nil
+}
+
+func (c *Client) do(req *http.Request, v interface{}) (*http.Response, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// body must always be closed
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusAccepted || resp.StatusCode == http.StatusNoContent {
+		return resp, nil
+	}
+
+	// if the provided v pointer is nil we cannot unmarschal the body to anything
+	if v == nil {
+		return resp, nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return nil, err
+	}
+
+	return resp, err
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+type TestDatabase struct {
+	Database `json:"database"`
+
+	ID            int               `json:"id"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	Lease         string            `json:"lease,omitempty"`
+	ReservedUntil *time.Time        `json:"reservedUntil,omitempty"`
+
+	Replica *DatabaseConfig `json:"replica,omitempty"`
+}
+
+// AcquireInfo describes how a test database was handed out, returned by GetTestDatabaseWithInfo.
+type AcquireInfo struct {
+	Dirty       bool  `json:"dirty"`
+	WaitedMs    int64 `json:"waitedMs"`
+	Ready       int   `json:"ready"`
+	ReadyTarget int   `json:"readyTarget"`
+}
+
+type ServerInfo struct {
+	Version                int    `json:"version"`
+	VersionString          string `json:"versionString"`
+	DropDatabaseForce      bool   `json:"dropDatabaseForce"`
+	CreateDatabaseStrategy bool   `json:"createDatabaseStrategy"`
+	MaxConnections         int    `json:"maxConnections"`
+}
+
+type PoolSnapshot struct {
+	TemplateHash            string                 `json:"templateHash"`
+	Ready                   int                    `json:"ready"`
+	Dirty                   int                    `json:"dirty"`
+	Recreating              int                    `json:"recreating"`
+	Poisoned                int                    `json:"poisoned"`
+	InUse                   int                    `json:"inUse"`
+	MaxPoolSize             int                    `json:"maxPoolSize"`
+	Workers                 int                    `json:"workers"`
+	WorkersBusy             int                    `json:"workersBusy"`
+	ReadyTarget             int                    `json:"readyTarget"`
+	MinReady                int                    `json:"minReady"`
+	SelectionPolicy         string                 `json:"selectionPolicy"`
+	CleanHook               bool                   `json:"cleanHook"`
+	TemplateMissing         bool                   `json:"templateMissing"`
+	GetCleanTotal           uint64                 `json:"getCleanTotal"`
+	GetDirtyTotal           uint64                 `json:"getDirtyTotal"`
+	DirtyRatio              float64                `json:"dirtyRatio"`
+	CreatedAt               time.Time              `json:"createdAt"`
+	LastUsed                time.Time              `json:"lastUsed"`
+	TooManyConnectionsTotal uint64                 `json:"tooManyConnectionsTotal"`
+	VerifyCleanFailedTotal  uint64                 `json:"verifyCleanFailedTotal"`
+	StaleDirtyTotal         uint64                 `json:"staleDirtyTotal"`
+	CopyDurations           DurationHistogram      `json:"copyDurations"`
+	CopyWaitDurations       DurationHistogram      `json:"copyWaitDurations"`
+	LockWaitDurations       DurationHistogram      `json:"lockWaitDurations"`
+	LockWaitP95Ms           float64                `json:"lockWaitP95Ms"`
+	TestDatabases           []TestDatabaseSnapshot `json:"testDatabases"`
+}
+
+type DurationHistogram struct {
+	Count   uint64           `json:"count"`
+	SumMs   float64          `json:"sumMs"`
+	MinMs   float64          `json:"minMs"`
+	MaxMs   float64          `json:"maxMs"`
+	Buckets []DurationBucket `json:"buckets"`
+}
+
+type DurationBucket struct {
+	LeMs  float64 `json:"leMs"`
+	Count uint64  `json:"count"`
+}
+
+type TestDatabaseSnapshot struct {
+	ID       int               `json:"id"`
+	Database string            `json:"database"`
+	State    string            `json:"state"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Poisoned bool              `json:"poisoned,omitempty"`
+}
+
+type InUseInfo struct {
+	ID         int               `json:"id"`
+	Database   string            `json:"database"`
+	AcquiredAt time.Time         `json:"acquiredAt"`
+	Labels     map[string]string `json:"labels,omitempty"`
+}
+
+type TestDatabaseDSN struct {
+	DSN        string  `json:"dsn"`
+	ReplicaDSN *string `json:"replicaDsn,omitempty"`
+	Redacted   bool    `json:"redacted"`
+}
+
+type TemplateDatabase struct {
+	Database `json:"database"`
+}
+
+type Database struct {
+	TemplateHash string         `json:"templateHash"`
+	Config       DatabaseConfig `json:"config"`
+}
+
+type DatabaseConfig struct {
+	Host             string            `json:"host"`
+	Port             int               `json:"port"`
+	Username         string            `json:"username"`
+	Password         string            `json:"password"`
+	Database         string            `json:"database"`
+	AdditionalParams map[string]string `json:"additionalParams,omitempty"` // Optional additional connection parameters mapped into the connection string
+}
+
+// Generates a connection string to be passed to sql.Open or equivalents, assuming Postgres syntax
+func (c DatabaseConfig) ConnectionString() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s", quoteConnectionStringValue(c.Host), c.Port, quoteConnectionStringValue(c.Username), quoteConnectionStringValue(c.Password), quoteConnectionStringValue(c.Database)))
+
+	if _, ok := c.AdditionalParams["sslmode"]; !ok {
+		b.WriteString(" sslmode=disable")
+	}
+
+	if len(c.AdditionalParams) > 0 {
+		params := make([]string, 0, len(c.AdditionalParams))
+		for param := range c.AdditionalParams {
+			params = append(params, param)
+		}
+
+		sort.Strings(params)
+
+		for _, param := range params {
+			fmt.Fprintf(&b, " %s=%s", param, quoteConnectionStringValue(c.AdditionalParams[param]))
+		}
+	}
+
+	return b.String()
+}
+
+// quoteConnectionStringValue single-quotes values which would otherwise break the key=value connection string (empty, spaces, quotes or backslashes).
+func quoteConnectionStringValue(v string) string {
+	if len(v) > 0 && !strings.ContainsAny(v, " \t\n\r'\\") {
+		return v
+	}
+
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+}
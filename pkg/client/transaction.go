@@ -0,0 +1,96 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// sharedTestDatabase is the test DB shared by all transactions of a hash (see GetTestTransaction).
+type sharedTestDatabase struct {
+	TestDatabase
+	db *sql.DB
+}
+
+// GetTestTransaction begins a transaction within a test database shared by all tests of the given hash (using this client),
+// instead of getting a fresh test database per test. The returned rollback func must be called at the end of the test:
+// It rolls back all changes of the test, leaving the shared test database clean for the next one.
+// The shared test database is requested on first use and held until CloseTestTransactions.
+//
+// This is far faster than getting a test database per test, but strictly limited to tests not depending on the commit behavior:
+// The test must never commit, deferred constraints are never checked, sequences are not rolled back and other connections
+// (e.g. the code under test opening its own connection) don't see the changes of the test.
+func (c *Client) GetTestTransaction(ctx context.Context, hash string) (*sql.Tx, func() error, error) {
+	db, err := c.getSharedTestDatabase(ctx, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rollback := func() error {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			return err
+		}
+
+		return nil
+	}
+
+	return tx, rollback, nil
+}
+
+// CloseTestTransactions closes the connections to all shared test databases (see GetTestTransaction) and returns them.
+// All transactions must have been rolled back before.
+func (c *Client) CloseTestTransactions(ctx context.Context) error {
+	c.sharedMutex.Lock()
+	defer c.sharedMutex.Unlock()
+
+	var errs []error
+	for hash, shared := range c.shared {
+		if err := shared.db.Close(); err != nil {
+			errs = append(errs, err)
+		}
+
+		// all changes were rolled back, thus the test database is returned as clean
+		if err := c.ReturnTestDatabaseWithLease(ctx, hash, shared.ID, shared.Lease); err != nil {
+			errs = append(errs, err)
+		}
+
+		delete(c.shared, hash)
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *Client) getSharedTestDatabase(ctx context.Context, hash string) (*sql.DB, error) {
+	c.sharedMutex.Lock()
+	defer c.sharedMutex.Unlock()
+
+	if shared, ok := c.shared[hash]; ok {
+		return shared.db, nil
+	}
+
+	test, err := c.GetTestDatabase(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("postgres", test.Config.ConnectionString())
+	if err == nil {
+		err = db.PingContext(ctx)
+	}
+	if err != nil {
+		if db != nil {
+			db.Close()
+		}
+
+		return nil, errors.Join(err, c.ReturnTestDatabaseWithLease(ctx, hash, test.ID, test.Lease))
+	}
+
+	c.shared[hash] = &sharedTestDatabase{TestDatabase: test, db: db}
+
+	return db, nil
+}
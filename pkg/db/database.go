@@ -1,5 +1,7 @@
 package db
 
+import "time"
+
 type Database struct {
 	TemplateHash string         `json:"templateHash"`
 	Config       DatabaseConfig `json:"config"`
@@ -8,7 +10,13 @@ type Database struct {
 type TestDatabase struct {
 	Database `json:"database"`
 
-	ID int `json:"id"`
+	ID     int               `json:"id"`
+	Labels map[string]string `json:"labels,omitempty"` // Custom labels supplied while acquiring the test database (e.g. the CI job ID), cleared on return
+	Lease  string            `json:"lease,omitempty"`  // Opaque token of the current holder, renewed on each acquire. Must be passed back on return/recreate (the server only accepts it missing with INTEGRESQL_LENIENT_LEASES)
+
+	ReservedUntil *time.Time `json:"reservedUntil,omitempty"` // Deadline of a soft reservation acquired with a TTL, the test database is reclaimed unless returned or heartbeated before
+
+	Replica *DatabaseConfig `json:"replica,omitempty"` // Optional read-only connection config to the same database on a streaming replica (might lag behind for just created databases)
 }
 
 type TemplateDatabase struct {
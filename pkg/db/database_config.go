@@ -6,6 +6,9 @@ import (
 	"strings"
 )
 
+// RedactedPassword replaces passwords within redacted configs and connection strings.
+const RedactedPassword = "********"
+
 type DatabaseConfig struct {
 	Host             string            `json:"host"`
 	Port             int               `json:"port"`
@@ -18,7 +21,7 @@ type DatabaseConfig struct {
 // Generates a connection string to be passed to sql.Open or equivalents, assuming Postgres syntax
 func (c DatabaseConfig) ConnectionString() string {
 	var b strings.Builder
-	b.WriteString(fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s", c.Host, c.Port, c.Username, c.Password, c.Database))
+	b.WriteString(fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s", quoteConnectionStringValue(c.Host), c.Port, quoteConnectionStringValue(c.Username), quoteConnectionStringValue(c.Password), quoteConnectionStringValue(c.Database)))
 
 	if _, ok := c.AdditionalParams["sslmode"]; !ok {
 		b.WriteString(" sslmode=disable")
@@ -33,9 +36,32 @@ func (c DatabaseConfig) ConnectionString() string {
 		sort.Strings(params)
 
 		for _, param := range params {
-			fmt.Fprintf(&b, " %s=%s", param, c.AdditionalParams[param])
+			fmt.Fprintf(&b, " %s=%s", param, quoteConnectionStringValue(c.AdditionalParams[param]))
 		}
 	}
 
 	return b.String()
 }
+
+// RedactedConnectionString generates the connection string like ConnectionString, but with the password masked (e.g. for logging).
+func (c DatabaseConfig) RedactedConnectionString() string {
+	c.Password = RedactedPassword
+	return c.ConnectionString()
+}
+
+// Redacted returns a copy of the config with its password masked (e.g. for introspection), an empty password stays empty.
+func (c DatabaseConfig) Redacted() DatabaseConfig {
+	if len(c.Password) > 0 {
+		c.Password = RedactedPassword
+	}
+	return c
+}
+
+// quoteConnectionStringValue single-quotes values which would otherwise break the key=value connection string (empty, spaces, quotes or backslashes).
+func quoteConnectionStringValue(v string) string {
+	if len(v) > 0 && !strings.ContainsAny(v, " \t\n\r'\\") {
+		return v
+	}
+
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+}
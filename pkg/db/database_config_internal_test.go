@@ -57,6 +57,31 @@ func TestDatabaseConfigConnectionString(t *testing.T) {
 			},
 			want: "host=localhost port=5432 user=simple password=database_config dbname=simple_database_config connect_timeout=10 sslcert=/app/certs/pg.pem sslkey=/app/certs/pg.key sslmode=verify-full sslrootcert=/app/certs/pg_root.pem",
 		},
+		{
+			name: "HyphenUppercase",
+			config: DatabaseConfig{
+				Host:     "localhost",
+				Port:     5432,
+				Username: "simple",
+				Password: "database_config",
+				Database: "Team-A_test_HASH_001",
+			},
+			want: "host=localhost port=5432 user=simple password=database_config dbname=Team-A_test_HASH_001 sslmode=disable",
+		},
+		{
+			name: "Quoted",
+			config: DatabaseConfig{
+				Host:     "localhost",
+				Port:     5432,
+				Username: "simple",
+				Password: "",
+				Database: `team a's \db`,
+				AdditionalParams: map[string]string{
+					"application_name": "integresql test",
+				},
+			},
+			want: `host=localhost port=5432 user=simple password='' dbname='team a\'s \\db' sslmode=disable application_name='integresql test'`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -71,6 +96,59 @@ func TestDatabaseConfigConnectionString(t *testing.T) {
 	}
 }
 
+func TestDatabaseConfigRedactedConnectionString(t *testing.T) {
+	t.Parallel()
+
+	config := DatabaseConfig{
+		Host:     "localhost",
+		Port:     5432,
+		Username: "simple",
+		Password: "database_config",
+		Database: "simple_database_config",
+	}
+
+	want := "host=localhost port=5432 user=simple password=******** dbname=simple_database_config sslmode=disable"
+	if got := config.RedactedConnectionString(); got != want {
+		t.Errorf("invalid redacted connection string, got %q, want %q", got, want)
+	}
+
+	// the config itself is left untouched
+	if config.Password != "database_config" {
+		t.Errorf("password of the config was modified, got %q", config.Password)
+	}
+}
+
+func TestDatabaseConfigRedacted(t *testing.T) {
+	t.Parallel()
+
+	config := DatabaseConfig{
+		Host:     "localhost",
+		Port:     5432,
+		Username: "simple",
+		Password: "database_config",
+		Database: "simple_database_config",
+	}
+
+	redacted := config.Redacted()
+	if redacted.Password != RedactedPassword {
+		t.Errorf("password was not redacted, got %q", redacted.Password)
+	}
+	if redacted.Host != config.Host || redacted.Username != config.Username || redacted.Database != config.Database {
+		t.Errorf("invalid redacted config, got %#v", redacted)
+	}
+
+	// the config itself is left untouched
+	if config.Password != "database_config" {
+		t.Errorf("password of the config was modified, got %q", config.Password)
+	}
+
+	// no password is kept as is
+	config.Password = ""
+	if got := config.Redacted().Password; got != "" {
+		t.Errorf("empty password was redacted, got %q", got)
+	}
+}
+
 func TestDatabaseConfigMarshal(t *testing.T) {
 	t.Parallel() // marks table driven test execution function as capable of running in parallel with other tests
 
@@ -0,0 +1,1208 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.32.0
+// 	protoc        (unknown)
+// source: integresql/v1/integresql.proto
+
+package integresqlv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type DatabaseConfig struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Host             string            `protobuf:"bytes,1,opt,name=host,proto3" json:"host,omitempty"`
+	Port             int32             `protobuf:"varint,2,opt,name=port,proto3" json:"port,omitempty"`
+	Username         string            `protobuf:"bytes,3,opt,name=username,proto3" json:"username,omitempty"`
+	Password         string            `protobuf:"bytes,4,opt,name=password,proto3" json:"password,omitempty"`
+	Database         string            `protobuf:"bytes,5,opt,name=database,proto3" json:"database,omitempty"`
+	AdditionalParams map[string]string `protobuf:"bytes,6,rep,name=additional_params,json=additionalParams,proto3" json:"additional_params,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (x *DatabaseConfig) Reset() {
+	*x = DatabaseConfig{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_integresql_v1_integresql_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DatabaseConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DatabaseConfig) ProtoMessage() {}
+
+func (x *DatabaseConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_integresql_v1_integresql_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DatabaseConfig.ProtoReflect.Descriptor instead.
+func (*DatabaseConfig) Descriptor() ([]byte, []int) {
+	return file_integresql_v1_integresql_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *DatabaseConfig) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *DatabaseConfig) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *DatabaseConfig) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *DatabaseConfig) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+func (x *DatabaseConfig) GetDatabase() string {
+	if x != nil {
+		return x.Database
+	}
+	return ""
+}
+
+func (x *DatabaseConfig) GetAdditionalParams() map[string]string {
+	if x != nil {
+		return x.AdditionalParams
+	}
+	return nil
+}
+
+type Database struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	TemplateHash string          `protobuf:"bytes,1,opt,name=template_hash,json=templateHash,proto3" json:"template_hash,omitempty"`
+	Config       *DatabaseConfig `protobuf:"bytes,2,opt,name=config,proto3" json:"config,omitempty"`
+}
+
+func (x *Database) Reset() {
+	*x = Database{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_integresql_v1_integresql_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Database) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Database) ProtoMessage() {}
+
+func (x *Database) ProtoReflect() protoreflect.Message {
+	mi := &file_integresql_v1_integresql_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Database.ProtoReflect.Descriptor instead.
+func (*Database) Descriptor() ([]byte, []int) {
+	return file_integresql_v1_integresql_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Database) GetTemplateHash() string {
+	if x != nil {
+		return x.TemplateHash
+	}
+	return ""
+}
+
+func (x *Database) GetConfig() *DatabaseConfig {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+type TemplateDatabase struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Database *Database `protobuf:"bytes,1,opt,name=database,proto3" json:"database,omitempty"`
+}
+
+func (x *TemplateDatabase) Reset() {
+	*x = TemplateDatabase{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_integresql_v1_integresql_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TemplateDatabase) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TemplateDatabase) ProtoMessage() {}
+
+func (x *TemplateDatabase) ProtoReflect() protoreflect.Message {
+	mi := &file_integresql_v1_integresql_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TemplateDatabase.ProtoReflect.Descriptor instead.
+func (*TemplateDatabase) Descriptor() ([]byte, []int) {
+	return file_integresql_v1_integresql_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *TemplateDatabase) GetDatabase() *Database {
+	if x != nil {
+		return x.Database
+	}
+	return nil
+}
+
+type TestDatabase struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Database *Database         `protobuf:"bytes,1,opt,name=database,proto3" json:"database,omitempty"`
+	Id       int32             `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`
+	Labels   map[string]string `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// Optional read-only config of the same database on a streaming replica, might lag behind for just created databases.
+	Replica *DatabaseConfig `protobuf:"bytes,4,opt,name=replica,proto3" json:"replica,omitempty"`
+	// Opaque token of the current holder, pass it on return to make sure the test database was not handed out to another client in the meantime.
+	Lease string `protobuf:"bytes,5,opt,name=lease,proto3" json:"lease,omitempty"`
+}
+
+func (x *TestDatabase) Reset() {
+	*x = TestDatabase{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_integresql_v1_integresql_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TestDatabase) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TestDatabase) ProtoMessage() {}
+
+func (x *TestDatabase) ProtoReflect() protoreflect.Message {
+	mi := &file_integresql_v1_integresql_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TestDatabase.ProtoReflect.Descriptor instead.
+func (*TestDatabase) Descriptor() ([]byte, []int) {
+	return file_integresql_v1_integresql_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *TestDatabase) GetDatabase() *Database {
+	if x != nil {
+		return x.Database
+	}
+	return nil
+}
+
+func (x *TestDatabase) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *TestDatabase) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *TestDatabase) GetReplica() *DatabaseConfig {
+	if x != nil {
+		return x.Replica
+	}
+	return nil
+}
+
+func (x *TestDatabase) GetLease() string {
+	if x != nil {
+		return x.Lease
+	}
+	return ""
+}
+
+type InitializeTemplateRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Hash string `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	// Optional per hash override of the template size threshold (bytes) for inline recreation of test databases.
+	InlineRecreateMaxSize int64 `protobuf:"varint,2,opt,name=inline_recreate_max_size,json=inlineRecreateMaxSize,proto3" json:"inline_recreate_max_size,omitempty"`
+	// Optional strategy of cleaning dirty test databases: "recopy" (default), "truncate" or "schema".
+	CleanStrategy string `protobuf:"bytes,3,opt,name=clean_strategy,json=cleanStrategy,proto3" json:"clean_strategy,omitempty"`
+	// SQL executed within a dirty test database to reset it, required for the "truncate" and "schema" clean strategies.
+	ResetSql string `protobuf:"bytes,4,opt,name=reset_sql,json=resetSql,proto3" json:"reset_sql,omitempty"`
+	// Optional role owning the test databases of this hash, defaults to the globally configured test database owner.
+	TestDatabaseOwner string `protobuf:"bytes,5,opt,name=test_database_owner,json=testDatabaseOwner,proto3" json:"test_database_owner,omitempty"`
+	// Optional per hash overrides of the encoding and locale of the template database (inherited by its test databases).
+	// Unless the root template is template0, they must match the ones of the root template.
+	Encoding  string `protobuf:"bytes,6,opt,name=encoding,proto3" json:"encoding,omitempty"`
+	LcCollate string `protobuf:"bytes,7,opt,name=lc_collate,json=lcCollate,proto3" json:"lc_collate,omitempty"`
+	LcCtype   string `protobuf:"bytes,8,opt,name=lc_ctype,json=lcCtype,proto3" json:"lc_ctype,omitempty"`
+	// Optional per hash overrides of the durations (ms) a test database may be held before it is logged as suspected leak,
+	// respectively force-returned for recreation (must exceed the former).
+	LeakWarnTimeoutMs    int64 `protobuf:"varint,9,opt,name=leak_warn_timeout_ms,json=leakWarnTimeoutMs,proto3" json:"leak_warn_timeout_ms,omitempty"`
+	LeakReclaimTimeoutMs int64 `protobuf:"varint,10,opt,name=leak_reclaim_timeout_ms,json=leakReclaimTimeoutMs,proto3" json:"leak_reclaim_timeout_ms,omitempty"`
+	// Optional additional template databases (name -> weight) with identical content, test databases are copied from them
+	// and the template database (weight 1 unless listed) by weighted round-robin.
+	Sources map[string]int32 `protobuf:"bytes,11,rep,name=sources,proto3" json:"sources,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	// Optional fingerprint of the template content (e.g. a checksum of the migrations and fixtures). Initializing an existing hash
+	// with a different fingerprint fails with FAILED_PRECONDITION, as the hash is likely computed incorrectly.
+	Fingerprint string `protobuf:"bytes,12,opt,name=fingerprint,proto3" json:"fingerprint,omitempty"`
+	// Optional per hash override of the maximal number of concurrent connections to each test database (-1 unlimited).
+	ConnectionLimit int32 `protobuf:"varint,13,opt,name=connection_limit,json=connectionLimit,proto3" json:"connection_limit,omitempty"`
+	// Optional per hash override of the minimal number of ready test databases kept as warm standby under churn (0 keeps the server default).
+	MinReady int32 `protobuf:"varint,14,opt,name=min_ready,json=minReady,proto3" json:"min_ready,omitempty"`
+}
+
+func (x *InitializeTemplateRequest) Reset() {
+	*x = InitializeTemplateRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_integresql_v1_integresql_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *InitializeTemplateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InitializeTemplateRequest) ProtoMessage() {}
+
+func (x *InitializeTemplateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_integresql_v1_integresql_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InitializeTemplateRequest.ProtoReflect.Descriptor instead.
+func (*InitializeTemplateRequest) Descriptor() ([]byte, []int) {
+	return file_integresql_v1_integresql_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *InitializeTemplateRequest) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *InitializeTemplateRequest) GetInlineRecreateMaxSize() int64 {
+	if x != nil {
+		return x.InlineRecreateMaxSize
+	}
+	return 0
+}
+
+func (x *InitializeTemplateRequest) GetCleanStrategy() string {
+	if x != nil {
+		return x.CleanStrategy
+	}
+	return ""
+}
+
+func (x *InitializeTemplateRequest) GetResetSql() string {
+	if x != nil {
+		return x.ResetSql
+	}
+	return ""
+}
+
+func (x *InitializeTemplateRequest) GetTestDatabaseOwner() string {
+	if x != nil {
+		return x.TestDatabaseOwner
+	}
+	return ""
+}
+
+func (x *InitializeTemplateRequest) GetEncoding() string {
+	if x != nil {
+		return x.Encoding
+	}
+	return ""
+}
+
+func (x *InitializeTemplateRequest) GetLcCollate() string {
+	if x != nil {
+		return x.LcCollate
+	}
+	return ""
+}
+
+func (x *InitializeTemplateRequest) GetLcCtype() string {
+	if x != nil {
+		return x.LcCtype
+	}
+	return ""
+}
+
+func (x *InitializeTemplateRequest) GetLeakWarnTimeoutMs() int64 {
+	if x != nil {
+		return x.LeakWarnTimeoutMs
+	}
+	return 0
+}
+
+func (x *InitializeTemplateRequest) GetLeakReclaimTimeoutMs() int64 {
+	if x != nil {
+		return x.LeakReclaimTimeoutMs
+	}
+	return 0
+}
+
+func (x *InitializeTemplateRequest) GetSources() map[string]int32 {
+	if x != nil {
+		return x.Sources
+	}
+	return nil
+}
+
+func (x *InitializeTemplateRequest) GetFingerprint() string {
+	if x != nil {
+		return x.Fingerprint
+	}
+	return ""
+}
+
+func (x *InitializeTemplateRequest) GetConnectionLimit() int32 {
+	if x != nil {
+		return x.ConnectionLimit
+	}
+	return 0
+}
+
+func (x *InitializeTemplateRequest) GetMinReady() int32 {
+	if x != nil {
+		return x.MinReady
+	}
+	return 0
+}
+
+type InitializeTemplateResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Template *TemplateDatabase `protobuf:"bytes,1,opt,name=template,proto3" json:"template,omitempty"`
+}
+
+func (x *InitializeTemplateResponse) Reset() {
+	*x = InitializeTemplateResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_integresql_v1_integresql_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *InitializeTemplateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InitializeTemplateResponse) ProtoMessage() {}
+
+func (x *InitializeTemplateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_integresql_v1_integresql_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InitializeTemplateResponse.ProtoReflect.Descriptor instead.
+func (*InitializeTemplateResponse) Descriptor() ([]byte, []int) {
+	return file_integresql_v1_integresql_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *InitializeTemplateResponse) GetTemplate() *TemplateDatabase {
+	if x != nil {
+		return x.Template
+	}
+	return nil
+}
+
+type FinalizeTemplateRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Hash string `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	// Optional fingerprint of the template content, finalizing with a different one than supplied before fails with FAILED_PRECONDITION.
+	Fingerprint string `protobuf:"bytes,2,opt,name=fingerprint,proto3" json:"fingerprint,omitempty"`
+}
+
+func (x *FinalizeTemplateRequest) Reset() {
+	*x = FinalizeTemplateRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_integresql_v1_integresql_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FinalizeTemplateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FinalizeTemplateRequest) ProtoMessage() {}
+
+func (x *FinalizeTemplateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_integresql_v1_integresql_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FinalizeTemplateRequest.ProtoReflect.Descriptor instead.
+func (*FinalizeTemplateRequest) Descriptor() ([]byte, []int) {
+	return file_integresql_v1_integresql_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *FinalizeTemplateRequest) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *FinalizeTemplateRequest) GetFingerprint() string {
+	if x != nil {
+		return x.Fingerprint
+	}
+	return ""
+}
+
+type FinalizeTemplateResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *FinalizeTemplateResponse) Reset() {
+	*x = FinalizeTemplateResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_integresql_v1_integresql_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FinalizeTemplateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FinalizeTemplateResponse) ProtoMessage() {}
+
+func (x *FinalizeTemplateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_integresql_v1_integresql_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FinalizeTemplateResponse.ProtoReflect.Descriptor instead.
+func (*FinalizeTemplateResponse) Descriptor() ([]byte, []int) {
+	return file_integresql_v1_integresql_proto_rawDescGZIP(), []int{7}
+}
+
+type GetTestDatabaseRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Hash string `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	// Optional custom labels stored with the in-use test database (e.g. the CI job ID).
+	Labels map[string]string `protobuf:"bytes,2,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (x *GetTestDatabaseRequest) Reset() {
+	*x = GetTestDatabaseRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_integresql_v1_integresql_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetTestDatabaseRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTestDatabaseRequest) ProtoMessage() {}
+
+func (x *GetTestDatabaseRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_integresql_v1_integresql_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTestDatabaseRequest.ProtoReflect.Descriptor instead.
+func (*GetTestDatabaseRequest) Descriptor() ([]byte, []int) {
+	return file_integresql_v1_integresql_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetTestDatabaseRequest) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *GetTestDatabaseRequest) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+type GetTestDatabaseResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	TestDatabase *TestDatabase `protobuf:"bytes,1,opt,name=test_database,json=testDatabase,proto3" json:"test_database,omitempty"`
+}
+
+func (x *GetTestDatabaseResponse) Reset() {
+	*x = GetTestDatabaseResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_integresql_v1_integresql_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetTestDatabaseResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTestDatabaseResponse) ProtoMessage() {}
+
+func (x *GetTestDatabaseResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_integresql_v1_integresql_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTestDatabaseResponse.ProtoReflect.Descriptor instead.
+func (*GetTestDatabaseResponse) Descriptor() ([]byte, []int) {
+	return file_integresql_v1_integresql_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetTestDatabaseResponse) GetTestDatabase() *TestDatabase {
+	if x != nil {
+		return x.TestDatabase
+	}
+	return nil
+}
+
+type ReturnTestDatabaseRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Hash string `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	Id   int32  `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`
+	// Optional lease of the test database, must match the one of its current holder if given.
+	Lease string `protobuf:"bytes,3,opt,name=lease,proto3" json:"lease,omitempty"`
+	// Process the return in background without waiting for its result, errors are only logged by the server.
+	Async bool `protobuf:"varint,4,opt,name=async,proto3" json:"async,omitempty"`
+}
+
+func (x *ReturnTestDatabaseRequest) Reset() {
+	*x = ReturnTestDatabaseRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_integresql_v1_integresql_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReturnTestDatabaseRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReturnTestDatabaseRequest) ProtoMessage() {}
+
+func (x *ReturnTestDatabaseRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_integresql_v1_integresql_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReturnTestDatabaseRequest.ProtoReflect.Descriptor instead.
+func (*ReturnTestDatabaseRequest) Descriptor() ([]byte, []int) {
+	return file_integresql_v1_integresql_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ReturnTestDatabaseRequest) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *ReturnTestDatabaseRequest) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *ReturnTestDatabaseRequest) GetLease() string {
+	if x != nil {
+		return x.Lease
+	}
+	return ""
+}
+
+func (x *ReturnTestDatabaseRequest) GetAsync() bool {
+	if x != nil {
+		return x.Async
+	}
+	return false
+}
+
+type ReturnTestDatabaseResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ReturnTestDatabaseResponse) Reset() {
+	*x = ReturnTestDatabaseResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_integresql_v1_integresql_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReturnTestDatabaseResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReturnTestDatabaseResponse) ProtoMessage() {}
+
+func (x *ReturnTestDatabaseResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_integresql_v1_integresql_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReturnTestDatabaseResponse.ProtoReflect.Descriptor instead.
+func (*ReturnTestDatabaseResponse) Descriptor() ([]byte, []int) {
+	return file_integresql_v1_integresql_proto_rawDescGZIP(), []int{11}
+}
+
+var File_integresql_v1_integresql_proto protoreflect.FileDescriptor
+
+var file_integresql_v1_integresql_proto_rawDesc = []byte{
+	0x0a, 0x1e, 0x69, 0x6e, 0x74, 0x65, 0x67, 0x72, 0x65, 0x73, 0x71, 0x6c, 0x2f, 0x76, 0x31, 0x2f,
+	0x69, 0x6e, 0x74, 0x65, 0x67, 0x72, 0x65, 0x73, 0x71, 0x6c, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x12, 0x0d, 0x69, 0x6e, 0x74, 0x65, 0x67, 0x72, 0x65, 0x73, 0x71, 0x6c, 0x2e, 0x76, 0x31, 0x22,
+	0xb3, 0x02, 0x0a, 0x0e, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x43, 0x6f, 0x6e, 0x66,
+	0x69, 0x67, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x75, 0x73,
+	0x65, 0x72, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x73,
+	0x65, 0x72, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f,
+	0x72, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f,
+	0x72, 0x64, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x64, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x12, 0x60,
+	0x0a, 0x11, 0x61, 0x64, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x61, 0x6c, 0x5f, 0x70, 0x61, 0x72,
+	0x61, 0x6d, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x33, 0x2e, 0x69, 0x6e, 0x74, 0x65,
+	0x67, 0x72, 0x65, 0x73, 0x71, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61,
+	0x73, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x41, 0x64, 0x64, 0x69, 0x74, 0x69, 0x6f,
+	0x6e, 0x61, 0x6c, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x10,
+	0x61, 0x64, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x61, 0x6c, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73,
+	0x1a, 0x43, 0x0a, 0x15, 0x41, 0x64, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x61, 0x6c, 0x50, 0x61,
+	0x72, 0x61, 0x6d, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x66, 0x0a, 0x08, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73,
+	0x65, 0x12, 0x23, 0x0a, 0x0d, 0x74, 0x65, 0x6d, 0x70, 0x6c, 0x61, 0x74, 0x65, 0x5f, 0x68, 0x61,
+	0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x74, 0x65, 0x6d, 0x70, 0x6c, 0x61,
+	0x74, 0x65, 0x48, 0x61, 0x73, 0x68, 0x12, 0x35, 0x0a, 0x06, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x69, 0x6e, 0x74, 0x65, 0x67, 0x72, 0x65,
+	0x73, 0x71, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x43,
+	0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x06, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x22, 0x47, 0x0a,
+	0x10, 0x54, 0x65, 0x6d, 0x70, 0x6c, 0x61, 0x74, 0x65, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73,
+	0x65, 0x12, 0x33, 0x0a, 0x08, 0x64, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x69, 0x6e, 0x74, 0x65, 0x67, 0x72, 0x65, 0x73, 0x71, 0x6c,
+	0x2e, 0x76, 0x31, 0x2e, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x52, 0x08, 0x64, 0x61,
+	0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x22, 0x9e, 0x02, 0x0a, 0x0c, 0x54, 0x65, 0x73, 0x74, 0x44,
+	0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x12, 0x33, 0x0a, 0x08, 0x64, 0x61, 0x74, 0x61, 0x62,
+	0x61, 0x73, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x69, 0x6e, 0x74, 0x65,
+	0x67, 0x72, 0x65, 0x73, 0x71, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61,
+	0x73, 0x65, 0x52, 0x08, 0x64, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x12, 0x0e, 0x0a, 0x02,
+	0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x02, 0x69, 0x64, 0x12, 0x3f, 0x0a, 0x06,
+	0x6c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x27, 0x2e, 0x69,
+	0x6e, 0x74, 0x65, 0x67, 0x72, 0x65, 0x73, 0x71, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x65, 0x73,
+	0x74, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x2e, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x73,
+	0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x12, 0x37, 0x0a,
+	0x07, 0x72, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d,
+	0x2e, 0x69, 0x6e, 0x74, 0x65, 0x67, 0x72, 0x65, 0x73, 0x71, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x44,
+	0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x07, 0x72,
+	0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x1a, 0x39, 0x0a, 0x0b,
+	0x4c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b,
+	0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a,
+	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x91, 0x05, 0x0a, 0x19, 0x49, 0x6e, 0x69, 0x74,
+	0x69, 0x61, 0x6c, 0x69, 0x7a, 0x65, 0x54, 0x65, 0x6d, 0x70, 0x6c, 0x61, 0x74, 0x65, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x68, 0x61, 0x73, 0x68, 0x12, 0x37, 0x0a, 0x18, 0x69, 0x6e, 0x6c,
+	0x69, 0x6e, 0x65, 0x5f, 0x72, 0x65, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x5f, 0x6d, 0x61, 0x78,
+	0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x15, 0x69, 0x6e, 0x6c,
+	0x69, 0x6e, 0x65, 0x52, 0x65, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x4d, 0x61, 0x78, 0x53, 0x69,
+	0x7a, 0x65, 0x12, 0x25, 0x0a, 0x0e, 0x63, 0x6c, 0x65, 0x61, 0x6e, 0x5f, 0x73, 0x74, 0x72, 0x61,
+	0x74, 0x65, 0x67, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x63, 0x6c, 0x65, 0x61,
+	0x6e, 0x53, 0x74, 0x72, 0x61, 0x74, 0x65, 0x67, 0x79, 0x12, 0x1b, 0x0a, 0x09, 0x72, 0x65, 0x73,
+	0x65, 0x74, 0x5f, 0x73, 0x71, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x72, 0x65,
+	0x73, 0x65, 0x74, 0x53, 0x71, 0x6c, 0x12, 0x2e, 0x0a, 0x13, 0x74, 0x65, 0x73, 0x74, 0x5f, 0x64,
+	0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x5f, 0x6f, 0x77, 0x6e, 0x65, 0x72, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x11, 0x74, 0x65, 0x73, 0x74, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73,
+	0x65, 0x4f, 0x77, 0x6e, 0x65, 0x72, 0x12, 0x1a, 0x0a, 0x08, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69,
+	0x6e, 0x67, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69,
+	0x6e, 0x67, 0x12, 0x1d, 0x0a, 0x0a, 0x6c, 0x63, 0x5f, 0x63, 0x6f, 0x6c, 0x6c, 0x61, 0x74, 0x65,
+	0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x6c, 0x63, 0x43, 0x6f, 0x6c, 0x6c, 0x61, 0x74,
+	0x65, 0x12, 0x19, 0x0a, 0x08, 0x6c, 0x63, 0x5f, 0x63, 0x74, 0x79, 0x70, 0x65, 0x18, 0x08, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x07, 0x6c, 0x63, 0x43, 0x74, 0x79, 0x70, 0x65, 0x12, 0x2f, 0x0a, 0x14,
+	0x6c, 0x65, 0x61, 0x6b, 0x5f, 0x77, 0x61, 0x72, 0x6e, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75,
+	0x74, 0x5f, 0x6d, 0x73, 0x18, 0x09, 0x20, 0x01, 0x28, 0x03, 0x52, 0x11, 0x6c, 0x65, 0x61, 0x6b,
+	0x57, 0x61, 0x72, 0x6e, 0x54, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x4d, 0x73, 0x12, 0x35, 0x0a,
+	0x17, 0x6c, 0x65, 0x61, 0x6b, 0x5f, 0x72, 0x65, 0x63, 0x6c, 0x61, 0x69, 0x6d, 0x5f, 0x74, 0x69,
+	0x6d, 0x65, 0x6f, 0x75, 0x74, 0x5f, 0x6d, 0x73, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x03, 0x52, 0x14,
+	0x6c, 0x65, 0x61, 0x6b, 0x52, 0x65, 0x63, 0x6c, 0x61, 0x69, 0x6d, 0x54, 0x69, 0x6d, 0x65, 0x6f,
+	0x75, 0x74, 0x4d, 0x73, 0x12, 0x4f, 0x0a, 0x07, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x73, 0x18,
+	0x0b, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x35, 0x2e, 0x69, 0x6e, 0x74, 0x65, 0x67, 0x72, 0x65, 0x73,
+	0x71, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x69, 0x7a, 0x65,
+	0x54, 0x65, 0x6d, 0x70, 0x6c, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e,
+	0x53, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x07, 0x73, 0x6f,
+	0x75, 0x72, 0x63, 0x65, 0x73, 0x12, 0x20, 0x0a, 0x0b, 0x66, 0x69, 0x6e, 0x67, 0x65, 0x72, 0x70,
+	0x72, 0x69, 0x6e, 0x74, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x66, 0x69, 0x6e, 0x67,
+	0x65, 0x72, 0x70, 0x72, 0x69, 0x6e, 0x74, 0x12, 0x29, 0x0a, 0x10, 0x63, 0x6f, 0x6e, 0x6e, 0x65,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x0d, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x0f, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x4c, 0x69, 0x6d,
+	0x69, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x6d, 0x69, 0x6e, 0x5f, 0x72, 0x65, 0x61, 0x64, 0x79, 0x18,
+	0x0e, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x6d, 0x69, 0x6e, 0x52, 0x65, 0x61, 0x64, 0x79, 0x1a,
+	0x3a, 0x0a, 0x0c, 0x53, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12,
+	0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65,
+	0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x59, 0x0a, 0x1a, 0x49,
+	0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x69, 0x7a, 0x65, 0x54, 0x65, 0x6d, 0x70, 0x6c, 0x61, 0x74,
+	0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3b, 0x0a, 0x08, 0x74, 0x65, 0x6d,
+	0x70, 0x6c, 0x61, 0x74, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x69, 0x6e,
+	0x74, 0x65, 0x67, 0x72, 0x65, 0x73, 0x71, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x65, 0x6d, 0x70,
+	0x6c, 0x61, 0x74, 0x65, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x52, 0x08, 0x74, 0x65,
+	0x6d, 0x70, 0x6c, 0x61, 0x74, 0x65, 0x22, 0x4f, 0x0a, 0x17, 0x46, 0x69, 0x6e, 0x61, 0x6c, 0x69,
+	0x7a, 0x65, 0x54, 0x65, 0x6d, 0x70, 0x6c, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x68, 0x61, 0x73, 0x68, 0x12, 0x20, 0x0a, 0x0b, 0x66, 0x69, 0x6e, 0x67, 0x65, 0x72, 0x70,
+	0x72, 0x69, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x66, 0x69, 0x6e, 0x67,
+	0x65, 0x72, 0x70, 0x72, 0x69, 0x6e, 0x74, 0x22, 0x1a, 0x0a, 0x18, 0x46, 0x69, 0x6e, 0x61, 0x6c,
+	0x69, 0x7a, 0x65, 0x54, 0x65, 0x6d, 0x70, 0x6c, 0x61, 0x74, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x22, 0xb2, 0x01, 0x0a, 0x16, 0x47, 0x65, 0x74, 0x54, 0x65, 0x73, 0x74, 0x44,
+	0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12,
+	0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x68, 0x61,
+	0x73, 0x68, 0x12, 0x49, 0x0a, 0x06, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x18, 0x02, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x31, 0x2e, 0x69, 0x6e, 0x74, 0x65, 0x67, 0x72, 0x65, 0x73, 0x71, 0x6c, 0x2e,
+	0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x54, 0x65, 0x73, 0x74, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61,
+	0x73, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x73,
+	0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x1a, 0x39, 0x0a,
+	0x0b, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03,
+	0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14,
+	0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x5b, 0x0a, 0x17, 0x47, 0x65, 0x74, 0x54,
+	0x65, 0x73, 0x74, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x40, 0x0a, 0x0d, 0x74, 0x65, 0x73, 0x74, 0x5f, 0x64, 0x61, 0x74, 0x61,
+	0x62, 0x61, 0x73, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x69, 0x6e, 0x74,
+	0x65, 0x67, 0x72, 0x65, 0x73, 0x71, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x65, 0x73, 0x74, 0x44,
+	0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x52, 0x0c, 0x74, 0x65, 0x73, 0x74, 0x44, 0x61, 0x74,
+	0x61, 0x62, 0x61, 0x73, 0x65, 0x22, 0x6b, 0x0a, 0x19, 0x52, 0x65, 0x74, 0x75, 0x72, 0x6e, 0x54,
+	0x65, 0x73, 0x74, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x68, 0x61, 0x73, 0x68, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x02, 0x69, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x12, 0x14, 0x0a, 0x05,
+	0x61, 0x73, 0x79, 0x6e, 0x63, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x61, 0x73, 0x79,
+	0x6e, 0x63, 0x22, 0x1c, 0x0a, 0x1a, 0x52, 0x65, 0x74, 0x75, 0x72, 0x6e, 0x54, 0x65, 0x73, 0x74,
+	0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x32, 0xb0, 0x03, 0x0a, 0x11, 0x49, 0x6e, 0x74, 0x65, 0x67, 0x72, 0x65, 0x53, 0x51, 0x4c, 0x53,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x69, 0x0a, 0x12, 0x49, 0x6e, 0x69, 0x74, 0x69, 0x61,
+	0x6c, 0x69, 0x7a, 0x65, 0x54, 0x65, 0x6d, 0x70, 0x6c, 0x61, 0x74, 0x65, 0x12, 0x28, 0x2e, 0x69,
+	0x6e, 0x74, 0x65, 0x67, 0x72, 0x65, 0x73, 0x71, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x6e, 0x69,
+	0x74, 0x69, 0x61, 0x6c, 0x69, 0x7a, 0x65, 0x54, 0x65, 0x6d, 0x70, 0x6c, 0x61, 0x74, 0x65, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x29, 0x2e, 0x69, 0x6e, 0x74, 0x65, 0x67, 0x72, 0x65,
+	0x73, 0x71, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x69, 0x7a,
+	0x65, 0x54, 0x65, 0x6d, 0x70, 0x6c, 0x61, 0x74, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x63, 0x0a, 0x10, 0x46, 0x69, 0x6e, 0x61, 0x6c, 0x69, 0x7a, 0x65, 0x54, 0x65, 0x6d,
+	0x70, 0x6c, 0x61, 0x74, 0x65, 0x12, 0x26, 0x2e, 0x69, 0x6e, 0x74, 0x65, 0x67, 0x72, 0x65, 0x73,
+	0x71, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x69, 0x6e, 0x61, 0x6c, 0x69, 0x7a, 0x65, 0x54, 0x65,
+	0x6d, 0x70, 0x6c, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x27, 0x2e,
+	0x69, 0x6e, 0x74, 0x65, 0x67, 0x72, 0x65, 0x73, 0x71, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x69,
+	0x6e, 0x61, 0x6c, 0x69, 0x7a, 0x65, 0x54, 0x65, 0x6d, 0x70, 0x6c, 0x61, 0x74, 0x65, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x60, 0x0a, 0x0f, 0x47, 0x65, 0x74, 0x54, 0x65, 0x73,
+	0x74, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x12, 0x25, 0x2e, 0x69, 0x6e, 0x74, 0x65,
+	0x67, 0x72, 0x65, 0x73, 0x71, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x54, 0x65, 0x73,
+	0x74, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x26, 0x2e, 0x69, 0x6e, 0x74, 0x65, 0x67, 0x72, 0x65, 0x73, 0x71, 0x6c, 0x2e, 0x76, 0x31,
+	0x2e, 0x47, 0x65, 0x74, 0x54, 0x65, 0x73, 0x74, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x69, 0x0a, 0x12, 0x52, 0x65, 0x74, 0x75,
+	0x72, 0x6e, 0x54, 0x65, 0x73, 0x74, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x12, 0x28,
+	0x2e, 0x69, 0x6e, 0x74, 0x65, 0x67, 0x72, 0x65, 0x73, 0x71, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x52,
+	0x65, 0x74, 0x75, 0x72, 0x6e, 0x54, 0x65, 0x73, 0x74, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73,
+	0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x29, 0x2e, 0x69, 0x6e, 0x74, 0x65, 0x67,
+	0x72, 0x65, 0x73, 0x71, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x74, 0x75, 0x72, 0x6e, 0x54,
+	0x65, 0x73, 0x74, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x42, 0x47, 0x5a, 0x45, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f,
+	0x6d, 0x2f, 0x61, 0x6c, 0x6c, 0x61, 0x62, 0x6f, 0x75, 0x74, 0x61, 0x70, 0x70, 0x73, 0x2f, 0x69,
+	0x6e, 0x74, 0x65, 0x67, 0x72, 0x65, 0x73, 0x71, 0x6c, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x67, 0x72,
+	0x70, 0x63, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x67, 0x72, 0x65, 0x73, 0x71, 0x6c, 0x76, 0x31, 0x3b,
+	0x69, 0x6e, 0x74, 0x65, 0x67, 0x72, 0x65, 0x73, 0x71, 0x6c, 0x76, 0x31, 0x62, 0x06, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_integresql_v1_integresql_proto_rawDescOnce sync.Once
+	file_integresql_v1_integresql_proto_rawDescData = file_integresql_v1_integresql_proto_rawDesc
+)
+
+func file_integresql_v1_integresql_proto_rawDescGZIP() []byte {
+	file_integresql_v1_integresql_proto_rawDescOnce.Do(func() {
+		file_integresql_v1_integresql_proto_rawDescData = protoimpl.X.CompressGZIP(file_integresql_v1_integresql_proto_rawDescData)
+	})
+	return file_integresql_v1_integresql_proto_rawDescData
+}
+
+var file_integresql_v1_integresql_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_integresql_v1_integresql_proto_goTypes = []interface{}{
+	(*DatabaseConfig)(nil),             // 0: integresql.v1.DatabaseConfig
+	(*Database)(nil),                   // 1: integresql.v1.Database
+	(*TemplateDatabase)(nil),           // 2: integresql.v1.TemplateDatabase
+	(*TestDatabase)(nil),               // 3: integresql.v1.TestDatabase
+	(*InitializeTemplateRequest)(nil),  // 4: integresql.v1.InitializeTemplateRequest
+	(*InitializeTemplateResponse)(nil), // 5: integresql.v1.InitializeTemplateResponse
+	(*FinalizeTemplateRequest)(nil),    // 6: integresql.v1.FinalizeTemplateRequest
+	(*FinalizeTemplateResponse)(nil),   // 7: integresql.v1.FinalizeTemplateResponse
+	(*GetTestDatabaseRequest)(nil),     // 8: integresql.v1.GetTestDatabaseRequest
+	(*GetTestDatabaseResponse)(nil),    // 9: integresql.v1.GetTestDatabaseResponse
+	(*ReturnTestDatabaseRequest)(nil),  // 10: integresql.v1.ReturnTestDatabaseRequest
+	(*ReturnTestDatabaseResponse)(nil), // 11: integresql.v1.ReturnTestDatabaseResponse
+	nil,                                // 12: integresql.v1.DatabaseConfig.AdditionalParamsEntry
+	nil,                                // 13: integresql.v1.TestDatabase.LabelsEntry
+	nil,                                // 14: integresql.v1.InitializeTemplateRequest.SourcesEntry
+	nil,                                // 15: integresql.v1.GetTestDatabaseRequest.LabelsEntry
+}
+var file_integresql_v1_integresql_proto_depIdxs = []int32{
+	12, // 0: integresql.v1.DatabaseConfig.additional_params:type_name -> integresql.v1.DatabaseConfig.AdditionalParamsEntry
+	0,  // 1: integresql.v1.Database.config:type_name -> integresql.v1.DatabaseConfig
+	1,  // 2: integresql.v1.TemplateDatabase.database:type_name -> integresql.v1.Database
+	1,  // 3: integresql.v1.TestDatabase.database:type_name -> integresql.v1.Database
+	13, // 4: integresql.v1.TestDatabase.labels:type_name -> integresql.v1.TestDatabase.LabelsEntry
+	0,  // 5: integresql.v1.TestDatabase.replica:type_name -> integresql.v1.DatabaseConfig
+	14, // 6: integresql.v1.InitializeTemplateRequest.sources:type_name -> integresql.v1.InitializeTemplateRequest.SourcesEntry
+	2,  // 7: integresql.v1.InitializeTemplateResponse.template:type_name -> integresql.v1.TemplateDatabase
+	15, // 8: integresql.v1.GetTestDatabaseRequest.labels:type_name -> integresql.v1.GetTestDatabaseRequest.LabelsEntry
+	3,  // 9: integresql.v1.GetTestDatabaseResponse.test_database:type_name -> integresql.v1.TestDatabase
+	4,  // 10: integresql.v1.IntegreSQLService.InitializeTemplate:input_type -> integresql.v1.InitializeTemplateRequest
+	6,  // 11: integresql.v1.IntegreSQLService.FinalizeTemplate:input_type -> integresql.v1.FinalizeTemplateRequest
+	8,  // 12: integresql.v1.IntegreSQLService.GetTestDatabase:input_type -> integresql.v1.GetTestDatabaseRequest
+	10, // 13: integresql.v1.IntegreSQLService.ReturnTestDatabase:input_type -> integresql.v1.ReturnTestDatabaseRequest
+	5,  // 14: integresql.v1.IntegreSQLService.InitializeTemplate:output_type -> integresql.v1.InitializeTemplateResponse
+	7,  // 15: integresql.v1.IntegreSQLService.FinalizeTemplate:output_type -> integresql.v1.FinalizeTemplateResponse
+	9,  // 16: integresql.v1.IntegreSQLService.GetTestDatabase:output_type -> integresql.v1.GetTestDatabaseResponse
+	11, // 17: integresql.v1.IntegreSQLService.ReturnTestDatabase:output_type -> integresql.v1.ReturnTestDatabaseResponse
+	14, // [14:18] is the sub-list for method output_type
+	10, // [10:14] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
+}
+
+func init() { file_integresql_v1_integresql_proto_init() }
+func file_integresql_v1_integresql_proto_init() {
+	if File_integresql_v1_integresql_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_integresql_v1_integresql_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DatabaseConfig); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_integresql_v1_integresql_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Database); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_integresql_v1_integresql_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TemplateDatabase); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_integresql_v1_integresql_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TestDatabase); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_integresql_v1_integresql_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*InitializeTemplateRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_integresql_v1_integresql_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*InitializeTemplateResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_integresql_v1_integresql_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FinalizeTemplateRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_integresql_v1_integresql_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FinalizeTemplateResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_integresql_v1_integresql_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetTestDatabaseRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_integresql_v1_integresql_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetTestDatabaseResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_integresql_v1_integresql_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReturnTestDatabaseRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_integresql_v1_integresql_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReturnTestDatabaseResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_integresql_v1_integresql_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   16,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_integresql_v1_integresql_proto_goTypes,
+		DependencyIndexes: file_integresql_v1_integresql_proto_depIdxs,
+		MessageInfos:      file_integresql_v1_integresql_proto_msgTypes,
+	}.Build()
+	File_integresql_v1_integresql_proto = out.File
+	file_integresql_v1_integresql_proto_rawDesc = nil
+	file_integresql_v1_integresql_proto_goTypes = nil
+	file_integresql_v1_integresql_proto_depIdxs = nil
+}
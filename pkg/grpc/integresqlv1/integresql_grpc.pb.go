@@ -0,0 +1,228 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: integresql/v1/integresql.proto
+
+package integresqlv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	IntegreSQLService_InitializeTemplate_FullMethodName = "/integresql.v1.IntegreSQLService/InitializeTemplate"
+	IntegreSQLService_FinalizeTemplate_FullMethodName   = "/integresql.v1.IntegreSQLService/FinalizeTemplate"
+	IntegreSQLService_GetTestDatabase_FullMethodName    = "/integresql.v1.IntegreSQLService/GetTestDatabase"
+	IntegreSQLService_ReturnTestDatabase_FullMethodName = "/integresql.v1.IntegreSQLService/ReturnTestDatabase"
+)
+
+// IntegreSQLServiceClient is the client API for IntegreSQLService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type IntegreSQLServiceClient interface {
+	// InitializeTemplate creates a new template database (HTTP: POST /api/v1/templates).
+	InitializeTemplate(ctx context.Context, in *InitializeTemplateRequest, opts ...grpc.CallOption) (*InitializeTemplateResponse, error)
+	// FinalizeTemplate marks the template database as ready to be used (HTTP: PUT /api/v1/templates/:hash).
+	FinalizeTemplate(ctx context.Context, in *FinalizeTemplateRequest, opts ...grpc.CallOption) (*FinalizeTemplateResponse, error)
+	// GetTestDatabase acquires a ready test database (HTTP: GET /api/v1/templates/:hash/tests).
+	GetTestDatabase(ctx context.Context, in *GetTestDatabaseRequest, opts ...grpc.CallOption) (*GetTestDatabaseResponse, error)
+	// ReturnTestDatabase unlocks the test database, it is returned to the pool without recreating it (HTTP: POST /api/v1/templates/:hash/tests/:id/unlock).
+	ReturnTestDatabase(ctx context.Context, in *ReturnTestDatabaseRequest, opts ...grpc.CallOption) (*ReturnTestDatabaseResponse, error)
+}
+
+type integreSQLServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewIntegreSQLServiceClient(cc grpc.ClientConnInterface) IntegreSQLServiceClient {
+	return &integreSQLServiceClient{cc}
+}
+
+func (c *integreSQLServiceClient) InitializeTemplate(ctx context.Context, in *InitializeTemplateRequest, opts ...grpc.CallOption) (*InitializeTemplateResponse, error) {
+	out := new(InitializeTemplateResponse)
+	err := c.cc.Invoke(ctx, IntegreSQLService_InitializeTemplate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *integreSQLServiceClient) FinalizeTemplate(ctx context.Context, in *FinalizeTemplateRequest, opts ...grpc.CallOption) (*FinalizeTemplateResponse, error) {
+	out := new(FinalizeTemplateResponse)
+	err := c.cc.Invoke(ctx, IntegreSQLService_FinalizeTemplate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *integreSQLServiceClient) GetTestDatabase(ctx context.Context, in *GetTestDatabaseRequest, opts ...grpc.CallOption) (*GetTestDatabaseResponse, error) {
+	out := new(GetTestDatabaseResponse)
+	err := c.cc.Invoke(ctx, IntegreSQLService_GetTestDatabase_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *integreSQLServiceClient) ReturnTestDatabase(ctx context.Context, in *ReturnTestDatabaseRequest, opts ...grpc.CallOption) (*ReturnTestDatabaseResponse, error) {
+	out := new(ReturnTestDatabaseResponse)
+	err := c.cc.Invoke(ctx, IntegreSQLService_ReturnTestDatabase_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// IntegreSQLServiceServer is the server API for IntegreSQLService service.
+// All implementations must embed UnimplementedIntegreSQLServiceServer
+// for forward compatibility
+type IntegreSQLServiceServer interface {
+	// InitializeTemplate creates a new template database (HTTP: POST /api/v1/templates).
+	InitializeTemplate(context.Context, *InitializeTemplateRequest) (*InitializeTemplateResponse, error)
+	// FinalizeTemplate marks the template database as ready to be used (HTTP: PUT /api/v1/templates/:hash).
+	FinalizeTemplate(context.Context, *FinalizeTemplateRequest) (*FinalizeTemplateResponse, error)
+	// GetTestDatabase acquires a ready test database (HTTP: GET /api/v1/templates/:hash/tests).
+	GetTestDatabase(context.Context, *GetTestDatabaseRequest) (*GetTestDatabaseResponse, error)
+	// ReturnTestDatabase unlocks the test database, it is returned to the pool without recreating it (HTTP: POST /api/v1/templates/:hash/tests/:id/unlock).
+	ReturnTestDatabase(context.Context, *ReturnTestDatabaseRequest) (*ReturnTestDatabaseResponse, error)
+	mustEmbedUnimplementedIntegreSQLServiceServer()
+}
+
+// UnimplementedIntegreSQLServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedIntegreSQLServiceServer struct {
+}
+
+func (UnimplementedIntegreSQLServiceServer) InitializeTemplate(context.Context, *InitializeTemplateRequest) (*InitializeTemplateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InitializeTemplate not implemented")
+}
+func (UnimplementedIntegreSQLServiceServer) FinalizeTemplate(context.Context, *FinalizeTemplateRequest) (*FinalizeTemplateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FinalizeTemplate not implemented")
+}
+func (UnimplementedIntegreSQLServiceServer) GetTestDatabase(context.Context, *GetTestDatabaseRequest) (*GetTestDatabaseResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTestDatabase not implemented")
+}
+func (UnimplementedIntegreSQLServiceServer) ReturnTestDatabase(context.Context, *ReturnTestDatabaseRequest) (*ReturnTestDatabaseResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReturnTestDatabase not implemented")
+}
+func (UnimplementedIntegreSQLServiceServer) mustEmbedUnimplementedIntegreSQLServiceServer() {}
+
+// UnsafeIntegreSQLServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to IntegreSQLServiceServer will
+// result in compilation errors.
+type UnsafeIntegreSQLServiceServer interface {
+	mustEmbedUnimplementedIntegreSQLServiceServer()
+}
+
+func RegisterIntegreSQLServiceServer(s grpc.ServiceRegistrar, srv IntegreSQLServiceServer) {
+	s.RegisterService(&IntegreSQLService_ServiceDesc, srv)
+}
+
+func _IntegreSQLService_InitializeTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InitializeTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IntegreSQLServiceServer).InitializeTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IntegreSQLService_InitializeTemplate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IntegreSQLServiceServer).InitializeTemplate(ctx, req.(*InitializeTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IntegreSQLService_FinalizeTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FinalizeTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IntegreSQLServiceServer).FinalizeTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IntegreSQLService_FinalizeTemplate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IntegreSQLServiceServer).FinalizeTemplate(ctx, req.(*FinalizeTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IntegreSQLService_GetTestDatabase_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTestDatabaseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IntegreSQLServiceServer).GetTestDatabase(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IntegreSQLService_GetTestDatabase_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IntegreSQLServiceServer).GetTestDatabase(ctx, req.(*GetTestDatabaseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IntegreSQLService_ReturnTestDatabase_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReturnTestDatabaseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IntegreSQLServiceServer).ReturnTestDatabase(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IntegreSQLService_ReturnTestDatabase_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IntegreSQLServiceServer).ReturnTestDatabase(ctx, req.(*ReturnTestDatabaseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// IntegreSQLService_ServiceDesc is the grpc.ServiceDesc for IntegreSQLService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var IntegreSQLService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "integresql.v1.IntegreSQLService",
+	HandlerType: (*IntegreSQLServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "InitializeTemplate",
+			Handler:    _IntegreSQLService_InitializeTemplate_Handler,
+		},
+		{
+			MethodName: "FinalizeTemplate",
+			Handler:    _IntegreSQLService_FinalizeTemplate_Handler,
+		},
+		{
+			MethodName: "GetTestDatabase",
+			Handler:    _IntegreSQLService_GetTestDatabase_Handler,
+		},
+		{
+			MethodName: "ReturnTestDatabase",
+			Handler:    _IntegreSQLService_ReturnTestDatabase_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "integresql/v1/integresql.proto",
+}
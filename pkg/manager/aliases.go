@@ -0,0 +1,107 @@
+package manager
+
+import (
+	"context"
+	"sync"
+
+	"github.com/allaboutapps/integresql/pkg/templates"
+)
+
+// aliasCollection maps logical alias names (e.g. "myapp-fixtures") to template hashes.
+type aliasCollection struct {
+	aliases map[string]string // map[alias]hash
+	mutex   sync.RWMutex
+}
+
+func newAliasCollection() *aliasCollection {
+	return &aliasCollection{
+		aliases: make(map[string]string),
+	}
+}
+
+// SetAlias atomically points the given alias to the given template hash, which must be finalized already.
+// Calls to GetTestDatabase with the alias instead of a hash are resolved to this template from now on.
+// The previously aliased hash is returned (empty if the alias is new).
+func (m Manager) SetAlias(ctx context.Context, alias string, hash string) (string, error) {
+
+	log := m.getManagerLogger(ctx, "SetAlias").With().Str("alias", alias).Str("hash", hash).Logger()
+
+	if !m.Ready() {
+		log.Error().Msg("not ready")
+		return "", ErrManagerNotReady
+	}
+
+	template, found := m.templates.Get(ctx, hash)
+	if !found {
+		return "", ErrTemplateNotFound
+	}
+
+	if template.GetState(ctx) != templates.TemplateStateFinalized {
+		return "", ErrInvalidTemplateState
+	}
+
+	m.aliases.mutex.Lock()
+	previous := m.aliases.aliases[alias]
+	m.aliases.aliases[alias] = hash
+	m.aliases.mutex.Unlock()
+
+	log.Info().Str("previous", previous).Msg("alias set")
+
+	return previous, nil
+}
+
+// RemoveAlias removes the given alias, the aliased template itself is kept.
+func (m Manager) RemoveAlias(ctx context.Context, alias string) error {
+
+	if !m.Ready() {
+		return ErrManagerNotReady
+	}
+
+	m.aliases.mutex.Lock()
+	defer m.aliases.mutex.Unlock()
+
+	if _, ok := m.aliases.aliases[alias]; !ok {
+		return ErrAliasNotFound
+	}
+
+	delete(m.aliases.aliases, alias)
+
+	return nil
+}
+
+// GetAliases returns a copy of all aliases mapped to their template hash.
+func (m Manager) GetAliases(_ context.Context) (map[string]string, error) {
+
+	if !m.Ready() {
+		return nil, ErrManagerNotReady
+	}
+
+	m.aliases.mutex.RLock()
+	defer m.aliases.mutex.RUnlock()
+
+	aliases := make(map[string]string, len(m.aliases.aliases))
+	for alias, hash := range m.aliases.aliases {
+		aliases[alias] = hash
+	}
+
+	return aliases, nil
+}
+
+// resolveHash returns the template hash the given alias points to, or the given hash itself if it is no alias.
+func (m Manager) resolveHash(hashOrAlias string) string {
+	m.aliases.mutex.RLock()
+	defer m.aliases.mutex.RUnlock()
+
+	if hash, ok := m.aliases.aliases[hashOrAlias]; ok {
+		return hash
+	}
+
+	return hashOrAlias
+}
+
+func (m Manager) removeAllAliases() {
+	m.aliases.mutex.Lock()
+	defer m.aliases.mutex.Unlock()
+
+	m.aliases.aliases = make(map[string]string)
+}
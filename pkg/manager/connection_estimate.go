@@ -0,0 +1,60 @@
+package manager
+
+import (
+	"context"
+
+	"github.com/allaboutapps/integresql/pkg/pool"
+)
+
+// ConnectionEstimate compares the connections the clients of the test DBs could demand at most to max_connections of the PostgreSQL server.
+type ConnectionEstimate struct {
+	pool.ConnectionEstimate
+	MaxConnections int  `json:"maxConnections"` // max_connections of the PostgreSQL server (see ServerInfo)
+	Exceeded       bool `json:"exceeded"`       // the estimated connections exceed max_connections, expect connection exhaustion under load
+}
+
+func newConnectionEstimate(estimate pool.ConnectionEstimate, maxConnections int) ConnectionEstimate {
+	return ConnectionEstimate{
+		ConnectionEstimate: estimate,
+		MaxConnections:     maxConnections,
+		Exceeded:           maxConnections > 0 && estimate.Connections > maxConnections,
+	}
+}
+
+// EstimateConnections estimates the connections the clients of the test DBs of all pools could demand at most
+// (see pool.PoolCollection.EstimatedMaxConnections), compared to max_connections of the PostgreSQL server.
+func (m Manager) EstimateConnections(ctx context.Context) (ConnectionEstimate, error) {
+	if !m.Ready() {
+		return ConnectionEstimate{}, ErrManagerNotReady
+	}
+
+	return newConnectionEstimate(m.pool.EstimatedMaxConnections(ctx), m.serverInfo.MaxConnections), nil
+}
+
+// defaultConnectionEstimate estimates the connections the clients of the test DBs could demand at most with the default config, before any pool exists:
+// The MaxTotalDBs (if configured, otherwise the MaxPoolSize of a single pool) times the TestDatabaseConnectionLimit. Per hash overrides are not known yet.
+func (m Manager) defaultConnectionEstimate() ConnectionEstimate {
+	if m.config.TestDatabaseConnectionLimit <= 0 {
+		return newConnectionEstimate(pool.ConnectionEstimate{}, m.serverInfo.MaxConnections)
+	}
+
+	maxDBs := m.config.PoolConfig.MaxPoolSize
+	if m.config.PoolConfig.MaxTotalDBs > 0 {
+		maxDBs = m.config.PoolConfig.MaxTotalDBs
+	}
+
+	return newConnectionEstimate(pool.ConnectionEstimate{Connections: maxDBs * m.config.TestDatabaseConnectionLimit}, m.serverInfo.MaxConnections)
+}
+
+// warnConnectionEstimate logs a warning if the given estimate exceeds max_connections, as the config will inevitably exhaust the connections under load.
+func (m Manager) warnConnectionEstimate(ctx context.Context, estimate ConnectionEstimate) {
+	if !estimate.Exceeded {
+		return
+	}
+
+	log := m.getManagerLogger(ctx, "warnConnectionEstimate")
+	log.Warn().
+		Int("estimatedConnections", estimate.Connections).
+		Int("maxConnections", estimate.MaxConnections).
+		Msg("test databases could demand more connections than max_connections allows, lower the pool size or the connection limit (INTEGRESQL_TEST_DB_CONNECTION_LIMIT) or raise max_connections")
+}
@@ -0,0 +1,87 @@
+package manager
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// DatabaseLocale holds the encoding and locale of the template DBs (CREATE DATABASE ... ENCODING, LC_COLLATE and LC_CTYPE).
+// Empty values default to the ones of the root template (see ManagerConfig.TemplateDatabaseTemplate).
+// Test DBs always inherit the encoding and locale of their template DB.
+type DatabaseLocale struct {
+	Encoding string `json:"encoding,omitempty"` // e.g. "UTF8"
+	Collate  string `json:"collate,omitempty"`  // e.g. "en_US.UTF-8"
+	Ctype    string `json:"ctype,omitempty"`    // e.g. "en_US.UTF-8"
+}
+
+func (l DatabaseLocale) isZero() bool {
+	return len(l.Encoding) == 0 && len(l.Collate) == 0 && len(l.Ctype) == 0
+}
+
+// override returns the locale with all values set within the given one replaced (e.g. a per hash override of the global locale).
+func (l DatabaseLocale) override(o DatabaseLocale) DatabaseLocale {
+	if len(o.Encoding) > 0 {
+		l.Encoding = o.Encoding
+	}
+	if len(o.Collate) > 0 {
+		l.Collate = o.Collate
+	}
+	if len(o.Ctype) > 0 {
+		l.Ctype = o.Ctype
+	}
+
+	return l
+}
+
+// createDatabaseOptions returns the options to append to CREATE DATABASE (empty if no value is set).
+func (l DatabaseLocale) createDatabaseOptions() string {
+	var b strings.Builder
+
+	if len(l.Encoding) > 0 {
+		fmt.Fprintf(&b, " ENCODING %s", pq.QuoteLiteral(l.Encoding))
+	}
+	if len(l.Collate) > 0 {
+		fmt.Fprintf(&b, " LC_COLLATE %s", pq.QuoteLiteral(l.Collate))
+	}
+	if len(l.Ctype) > 0 {
+		fmt.Fprintf(&b, " LC_CTYPE %s", pq.QuoteLiteral(l.Ctype))
+	}
+
+	return b.String()
+}
+
+// checkDatabaseLocale makes sure databases with the given locale can be created from the given template:
+// Except for template0, PostgreSQL requires the encoding and locale to match the ones of the template.
+// An unknown template is left to fail while creating the database.
+func (m Manager) checkDatabaseLocale(ctx context.Context, template string, locale DatabaseLocale) error {
+	if locale.isZero() || template == "template0" {
+		return nil
+	}
+
+	var encoding, collate, ctype string
+	if err := m.db.QueryRowContext(ctx, "SELECT pg_encoding_to_char(encoding), datcollate, datctype FROM pg_database WHERE datname = $1", template).Scan(&encoding, &collate, &ctype); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+
+		return mapPostgresError(ctx, err)
+	}
+
+	if (len(locale.Encoding) > 0 && normalizeEncoding(locale.Encoding) != normalizeEncoding(encoding)) ||
+		(len(locale.Collate) > 0 && locale.Collate != collate) ||
+		(len(locale.Ctype) > 0 && locale.Ctype != ctype) {
+		return fmt.Errorf("%w: template %s has encoding %s, LC_COLLATE %s and LC_CTYPE %s", ErrIncompatibleDatabaseLocale, template, encoding, collate, ctype)
+	}
+
+	return nil
+}
+
+// normalizeEncoding strips the notational variants PostgreSQL accepts for encoding names (e.g. "utf-8" for "UTF8").
+func normalizeEncoding(encoding string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", "_", "").Replace(encoding))
+}
@@ -0,0 +1,53 @@
+package manager
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// finalizeLimiter limits the template finalizations running at the same time (see ManagerConfig.TemplateMaxConcurrentFinalizations),
+// further ones are queued in the order they arrive. Unlike the limit of concurrent copies, it applies to whole finalizations.
+type finalizeLimiter struct {
+	slots   chan struct{} // nil if unlimited
+	queued  int64         // finalizations waiting for a free slot, accessed atomically
+	running int64         // finalizations holding a slot (or running unlimited), accessed atomically
+}
+
+func newFinalizeLimiter(limit int) *finalizeLimiter {
+	l := &finalizeLimiter{}
+	if limit > 0 {
+		l.slots = make(chan struct{}, limit)
+	}
+
+	return l
+}
+
+// acquire waits for a free slot until the ctx is done, the returned func releases it.
+func (l *finalizeLimiter) acquire(ctx context.Context) (release func(), err error) {
+	if l.slots != nil {
+		atomic.AddInt64(&l.queued, 1)
+
+		select {
+		case l.slots <- struct{}{}:
+			atomic.AddInt64(&l.queued, -1)
+		case <-ctx.Done():
+			atomic.AddInt64(&l.queued, -1)
+			return nil, ctx.Err()
+		}
+	}
+
+	atomic.AddInt64(&l.running, 1)
+
+	return func() {
+		atomic.AddInt64(&l.running, -1)
+
+		if l.slots != nil {
+			<-l.slots
+		}
+	}, nil
+}
+
+// stats returns the number of queued and running finalizations.
+func (l *finalizeLimiter) stats() (queued int, running int) {
+	return int(atomic.LoadInt64(&l.queued)), int(atomic.LoadInt64(&l.running))
+}
@@ -0,0 +1,196 @@
+package manager
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/pool"
+)
+
+// outcome label values of the lifecycle metrics
+const (
+	lifecycleOutcomeSuccess = "success"
+	lifecycleOutcomeFailure = "failure"
+)
+
+var lifecycleOutcomes = []string{lifecycleOutcomeSuccess, lifecycleOutcomeFailure}
+
+// finalizeDurationBuckets are the upper bounds of the buckets of the template finalize duration histogram,
+// spanning typical migrations and fixture imports.
+var finalizeDurationBuckets = []time.Duration{
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	1 * time.Minute,
+	2 * time.Minute,
+	5 * time.Minute,
+}
+
+// LifecycleMetrics holds the counters of the template lifecycle events, keyed by their outcome (success or failure).
+type LifecycleMetrics struct {
+	TemplatesCreated  map[string]uint64                 `json:"templatesCreated"`  // InitializeTemplateDatabase and RegisterExternalTemplateDatabase calls
+	TemplatesRemoved  map[string]uint64                 `json:"templatesRemoved"`  // DiscardTemplateDatabase and ResetTracking calls, removing the pool of the template
+	FinalizeDurations map[string]pool.DurationHistogram `json:"finalizeDurations"` // durations from initializing a template until it was finalized (failure if discarded meanwhile)
+	FinalizeQueued    int                               `json:"finalizeQueued"`    // finalizations waiting for a free slot (see TemplateMaxConcurrentFinalizations)
+	FinalizeRunning   int                               `json:"finalizeRunning"`   // finalizations currently running
+}
+
+// lifecycleMetrics records the template lifecycle events, safe for concurrent use.
+type lifecycleMetrics struct {
+	templatesCreated  map[string]uint64
+	templatesRemoved  map[string]uint64
+	finalizeDurations map[string]*pool.DurationRecorder
+	mutex             sync.Mutex
+}
+
+func newLifecycleMetrics() *lifecycleMetrics {
+	l := &lifecycleMetrics{
+		templatesCreated:  make(map[string]uint64, len(lifecycleOutcomes)),
+		templatesRemoved:  make(map[string]uint64, len(lifecycleOutcomes)),
+		finalizeDurations: make(map[string]*pool.DurationRecorder, len(lifecycleOutcomes)),
+	}
+
+	for _, outcome := range lifecycleOutcomes {
+		l.finalizeDurations[outcome] = pool.NewDurationRecorder(finalizeDurationBuckets)
+	}
+
+	return l
+}
+
+// lifecycleOutcome maps the error of a lifecycle operation onto its outcome.
+// Errors of operations which did not change anything (e.g. the manager is not ready, the template is already initialized, its fingerprint mismatches or it is unknown) are not counted.
+func lifecycleOutcome(err error) (outcome string, counted bool) {
+	switch {
+	case err == nil:
+		return lifecycleOutcomeSuccess, true
+	case errors.Is(err, ErrManagerNotReady),
+		errors.Is(err, ErrTemplateAlreadyInitialized),
+		errors.Is(err, ErrHashMismatch),
+		errors.Is(err, ErrTemplateNotFound):
+		return "", false
+	default:
+		return lifecycleOutcomeFailure, true
+	}
+}
+
+func (l *lifecycleMetrics) templateCreated(err error) {
+	outcome, counted := lifecycleOutcome(err)
+	if !counted {
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.templatesCreated[outcome]++
+}
+
+func (l *lifecycleMetrics) templateRemoved(err error) {
+	outcome, counted := lifecycleOutcome(err)
+	if !counted {
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.templatesRemoved[outcome]++
+}
+
+func (l *lifecycleMetrics) templateFinalized(d time.Duration, err error) {
+	outcome, counted := lifecycleOutcome(err)
+	if !counted {
+		return
+	}
+
+	l.finalizeDurations[outcome].Observe(d)
+}
+
+func (l *lifecycleMetrics) snapshot() LifecycleMetrics {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	s := LifecycleMetrics{
+		TemplatesCreated:  make(map[string]uint64, len(lifecycleOutcomes)),
+		TemplatesRemoved:  make(map[string]uint64, len(lifecycleOutcomes)),
+		FinalizeDurations: make(map[string]pool.DurationHistogram, len(lifecycleOutcomes)),
+	}
+
+	for _, outcome := range lifecycleOutcomes {
+		s.TemplatesCreated[outcome] = l.templatesCreated[outcome]
+		s.TemplatesRemoved[outcome] = l.templatesRemoved[outcome]
+		s.FinalizeDurations[outcome] = l.finalizeDurations[outcome].Snapshot()
+	}
+
+	return s
+}
+
+// WritePrometheus renders the lifecycle metrics in the Prometheus text exposition format, labeled by outcome.
+// The finalize durations are rendered as histogram (in seconds), the queued and running finalizations as unlabeled gauges.
+func (s LifecycleMetrics) WritePrometheus(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	counters := []struct {
+		name   string
+		help   string
+		values map[string]uint64
+	}{
+		{"integresql_templates_created_total", "Templates initialized or registered.", s.TemplatesCreated},
+		{"integresql_templates_removed_total", "Templates discarded or reset, including their pools.", s.TemplatesRemoved},
+	}
+
+	for _, counter := range counters {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s counter\n", counter.name, counter.help, counter.name)
+		for _, outcome := range lifecycleOutcomes {
+			fmt.Fprintf(bw, "%s{outcome=\"%s\"} %d\n", counter.name, outcome, counter.values[outcome])
+		}
+	}
+
+	gauges := []struct {
+		name  string
+		help  string
+		value int
+	}{
+		{"integresql_template_finalize_queued", "Template finalizations waiting for a free slot.", s.FinalizeQueued},
+		{"integresql_template_finalize_running", "Template finalizations currently running.", s.FinalizeRunning},
+	}
+
+	for _, gauge := range gauges {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s gauge\n%s %d\n", gauge.name, gauge.help, gauge.name, gauge.name, gauge.value)
+	}
+
+	name := "integresql_template_finalize_duration_seconds"
+	fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s histogram\n", name, "Durations from initializing a template until it was finalized.", name)
+	for _, outcome := range lifecycleOutcomes {
+		pool.WritePrometheusHistogramSeries(bw, name, fmt.Sprintf("outcome=\"%s\"", outcome), s.FinalizeDurations[outcome])
+	}
+
+	return bw.Flush()
+}
+
+// WriteStatsD renders the lifecycle counters in the DogStatsD line format (see pool.StatsDCounters), tagged by outcome.
+// The finalize durations are rendered as their count and sum (in seconds).
+func (s LifecycleMetrics) WriteStatsD(w io.Writer, counters *pool.StatsDCounters) error {
+	bw := bufio.NewWriter(w)
+
+	for _, outcome := range lifecycleOutcomes {
+		tags := "outcome:" + outcome
+		counters.WriteMetric(bw, "integresql_templates_created_total", "counter", tags, float64(s.TemplatesCreated[outcome]))
+		counters.WriteMetric(bw, "integresql_templates_removed_total", "counter", tags, float64(s.TemplatesRemoved[outcome]))
+		counters.WriteMetric(bw, "integresql_template_finalize_duration_seconds_count", "counter", tags, float64(s.FinalizeDurations[outcome].Count))
+		counters.WriteMetric(bw, "integresql_template_finalize_duration_seconds_sum", "counter", tags, s.FinalizeDurations[outcome].SumMs/1000)
+	}
+
+	counters.WriteMetric(bw, "integresql_template_finalize_queued", "gauge", "", float64(s.FinalizeQueued))
+	counters.WriteMetric(bw, "integresql_template_finalize_running", "gauge", "", float64(s.FinalizeRunning))
+
+	return bw.Flush()
+}
@@ -0,0 +1,66 @@
+package manager
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLifecycleMetrics(t *testing.T) {
+	t.Parallel()
+
+	l := newLifecycleMetrics()
+
+	l.templateCreated(nil)
+	l.templateCreated(nil)
+	l.templateCreated(errors.New("database is stuck"))
+	l.templateCreated(ErrTemplateAlreadyInitialized) // nothing changed, not counted
+	l.templateCreated(ErrManagerNotReady)
+
+	l.templateRemoved(nil)
+	l.templateRemoved(ErrTemplateNotFound)
+
+	l.templateFinalized(3*time.Second, nil)
+	l.templateFinalized(200*time.Millisecond, ErrTemplateDiscarded)
+
+	s := l.snapshot()
+	s.FinalizeQueued, s.FinalizeRunning = 3, 1
+	assert.Equal(t, map[string]uint64{"success": 2, "failure": 1}, s.TemplatesCreated)
+	assert.Equal(t, map[string]uint64{"success": 1, "failure": 0}, s.TemplatesRemoved)
+	assert.Equal(t, uint64(1), s.FinalizeDurations["success"].Count)
+	assert.Equal(t, float64(3000), s.FinalizeDurations["success"].SumMs)
+	assert.Equal(t, uint64(1), s.FinalizeDurations["failure"].Count)
+
+	var buf bytes.Buffer
+	require.NoError(t, s.WritePrometheus(&buf))
+
+	out := buf.String()
+	assert.Contains(t, out, "# TYPE integresql_templates_created_total counter\n")
+	assert.Contains(t, out, "integresql_templates_created_total{outcome=\"success\"} 2\n")
+	assert.Contains(t, out, "integresql_templates_created_total{outcome=\"failure\"} 1\n")
+	assert.Contains(t, out, "integresql_templates_removed_total{outcome=\"success\"} 1\n")
+	assert.Contains(t, out, "integresql_templates_removed_total{outcome=\"failure\"} 0\n")
+	assert.Contains(t, out, "# TYPE integresql_template_finalize_duration_seconds histogram\n")
+	assert.Contains(t, out, "integresql_template_finalize_duration_seconds_bucket{outcome=\"success\",le=\"2.5\"} 0\n")
+	assert.Contains(t, out, "integresql_template_finalize_duration_seconds_bucket{outcome=\"success\",le=\"5\"} 1\n")
+	assert.Contains(t, out, "integresql_template_finalize_duration_seconds_bucket{outcome=\"failure\",le=\"+Inf\"} 1\n")
+	assert.Contains(t, out, "integresql_template_finalize_duration_seconds_sum{outcome=\"success\"} 3\n")
+	assert.Contains(t, out, "integresql_template_finalize_duration_seconds_count{outcome=\"failure\"} 1\n")
+	assert.Contains(t, out, "# TYPE integresql_template_finalize_queued gauge\nintegresql_template_finalize_queued 3\n")
+	assert.Contains(t, out, "integresql_template_finalize_running 1\n")
+
+	buf.Reset()
+	require.NoError(t, s.WriteStatsD(&buf, pool.NewStatsDCounters()))
+
+	out = buf.String()
+	assert.Contains(t, out, "integresql_templates_created_total:2|c|#outcome:success\n")
+	assert.Contains(t, out, "integresql_templates_created_total:1|c|#outcome:failure\n")
+	assert.NotContains(t, out, "integresql_templates_removed_total:0", "unchanged counters are skipped")
+	assert.Contains(t, out, "integresql_template_finalize_duration_seconds_sum:3|c|#outcome:success\n")
+	assert.Contains(t, out, "integresql_template_finalize_queued:3|g\n")
+}
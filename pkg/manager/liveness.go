@@ -0,0 +1,91 @@
+package manager
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// livenessState tracks the consecutive failed probes of the liveness watchdog (see Live).
+type livenessState struct {
+	mutex    sync.Mutex
+	failures int
+	lastErr  error
+}
+
+// record records the result of a single probe, any success resets the failures.
+func (l *livenessState) record(err error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if err == nil {
+		l.failures = 0
+		l.lastErr = nil
+		return
+	}
+
+	l.failures++
+	l.lastErr = err
+}
+
+// check returns the error of the last probe once the given number of consecutive probes failed, nil otherwise.
+func (l *livenessState) check(threshold int) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.failures < threshold {
+		return nil
+	}
+
+	return l.lastErr
+}
+
+// Live reports whether the process is alive: It fails only if the liveness watchdog could not acquire the pool locks for
+// LivenessFailureThreshold consecutive probes (a suspected deadlock, see pool.PoolCollection.CheckLocks).
+// Contrary to CheckReady, it does not depend on PostgreSQL, thus an orchestrator does not restart the process merely
+// because PostgreSQL is temporarily unavailable. Always nil if the watchdog is disabled.
+func (m Manager) Live() error {
+	return m.liveness.check(m.config.LivenessFailureThreshold)
+}
+
+// CheckReady reports whether the manager is ready to serve requests, i.e. connected to PostgreSQL and PostgreSQL responds.
+func (m Manager) CheckReady(ctx context.Context) error {
+	if !m.Ready() {
+		return ErrManagerNotReady
+	}
+
+	return m.db.PingContext(ctx)
+}
+
+func (m *Manager) startWatchdog() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	m.stopWatchdog = func() {
+		cancel()
+		<-done
+	}
+
+	log := m.getManagerLogger(ctx, "watchdog")
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(m.config.LivenessCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				err := m.pool.CheckLocks(m.config.LivenessLockTimeout)
+				if err != nil {
+					log.Warn().Err(err).Msg("liveness probe failed")
+				}
+
+				m.liveness.record(err)
+			}
+		}
+	}()
+}
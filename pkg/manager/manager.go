@@ -6,8 +6,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"runtime/trace"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/allaboutapps/integresql/pkg/db"
 	"github.com/allaboutapps/integresql/pkg/pool"
@@ -18,6 +22,11 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// InstanceIDTimestamp as PoolConfig.InstanceID embeds the start time of the server (UTC, e.g. 261016042648) into the test DB names.
+const InstanceIDTimestamp = "timestamp"
+
+const instanceIDTimestampLayout = "060102150405"
+
 var (
 	ErrManagerNotReady            = errors.New("manager not ready")
 	ErrTemplateAlreadyInitialized = errors.New("template is already initialized")
@@ -25,6 +34,16 @@ var (
 	ErrTestNotFound               = errors.New("test database not found")
 	ErrTemplateDiscarded          = errors.New("template is discarded, can't be used")
 	ErrInvalidTemplateState       = errors.New("unexpected template state")
+	ErrAliasNotFound              = errors.New("alias not found")
+	ErrInvalidCleanStrategy       = errors.New("invalid clean strategy, must be recopy, truncate or schema (both requiring a reset SQL)")
+	ErrUnknownTestDatabaseOwner   = errors.New("test database owner role does not exist")
+	ErrIncompatibleDatabaseLocale = errors.New("database encoding or locale is incompatible with the root template, use template0 or a template with matching settings")
+	ErrInvalidConnectionLimit     = errors.New("invalid connection limit, must be -1 (unlimited) or greater")
+	ErrInvalidMinReady            = errors.New("invalid min ready, must be 0 (disabled) or greater")
+	ErrInvalidLeakTimeouts        = errors.New("invalid leak timeouts, the leak reclaim timeout must exceed the leak warn timeout")
+	ErrInvalidExternalTemplate    = errors.New("external template database does not exist or cannot be used as a copy source (must be marked datistemplate or owned by the manager role)")
+	ErrHashMismatch               = errors.New("template fingerprint does not match the one stored for this hash, the template content changed without changing the hash")
+	ErrInvalidTemplateSource      = errors.New("invalid template source, must be a database usable as copy source (marked datistemplate or owned by the manager role) with a weight >= 0")
 )
 
 type Manager struct {
@@ -33,6 +52,19 @@ type Manager struct {
 
 	templates *templates.Collection
 	pool      *pool.PoolCollection
+	aliases   *aliasCollection
+
+	stopIdleSweeper func()          // stops the idle pool sweeper and waits until it has exited (nil if not running)
+	stopLeakSweeper func()          // stops the leaked test DB sweeper and waits until it has exited (nil if not running)
+	stopRebalancer  func()          // stops the rebalancer of test DBs across pools and waits until it has exited (nil if not running)
+	stopWatchdog    func()          // stops the liveness watchdog and waits until it has exited (nil if not running)
+	asyncReturns    *sync.WaitGroup // pending returns of ReturnTestDatabaseAsync, awaited by Disconnect
+
+	serverInfo ServerInfo // version and capabilities of the connected PostgreSQL server, detected while connecting
+
+	lifecycle *lifecycleMetrics // counters of the template lifecycle events (see LifecycleMetrics)
+	liveness  *livenessState    // consecutive failed probes of the liveness watchdog (see Live)
+	finalizes *finalizeLimiter  // limits the concurrent template finalizations (see TemplateMaxConcurrentFinalizations)
 }
 
 func New(config ManagerConfig) (*Manager, ManagerConfig) {
@@ -47,6 +79,10 @@ func New(config ManagerConfig) (*Manager, ManagerConfig) {
 
 	config.PoolConfig.TestDBNamePrefix = testDBPrefix
 
+	if config.PoolConfig.InstanceID == InstanceIDTimestamp {
+		config.PoolConfig.InstanceID = time.Now().UTC().Format(instanceIDTimestampLayout)
+	}
+
 	if len(config.TestDatabaseOwner) == 0 {
 		config.TestDatabaseOwner = config.ManagerDatabaseConfig.Username
 	}
@@ -60,14 +96,42 @@ func New(config ManagerConfig) (*Manager, ManagerConfig) {
 		config.PoolConfig.InitialPoolSize = 1
 	}
 
+	// typically a copy-paste misconfiguration, the pool could never be warmed up beyond its max size anyways
 	if config.PoolConfig.InitialPoolSize > config.PoolConfig.MaxPoolSize && config.PoolConfig.MaxPoolSize > 0 {
+		log.Warn().
+			Int("initialPoolSize", config.PoolConfig.InitialPoolSize).
+			Int("maxPoolSize", config.PoolConfig.MaxPoolSize).
+			Msg("INTEGRESQL_TEST_INITIAL_POOL_SIZE exceeds INTEGRESQL_TEST_MAX_POOL_SIZE, clamping initial pool size to max pool size")
 		config.PoolConfig.InitialPoolSize = config.PoolConfig.MaxPoolSize
 	}
 
+	if config.TestDatabaseConnectionLimit < -1 {
+		log.Warn().
+			Int("connectionLimit", config.TestDatabaseConnectionLimit).
+			Msg("INTEGRESQL_TEST_DB_CONNECTION_LIMIT must be -1 (unlimited) or greater, disabling the connection limit")
+		config.TestDatabaseConnectionLimit = -1
+	}
+
+	if !config.PoolConfig.SelectionPolicy.Valid() {
+		log.Warn().
+			Str("selectionPolicy", string(config.PoolConfig.SelectionPolicy)).
+			Msg("unknown INTEGRESQL_POOL_SELECTION_POLICY, falling back to the default")
+		config.PoolConfig.SelectionPolicy = ""
+	}
+
 	if config.PoolConfig.MaxParallelTasks < 1 {
 		config.PoolConfig.MaxParallelTasks = 1
 	}
 
+	// a single attempt of creating a test DB must not exceed the time clients are willing to wait for it
+	if config.PoolConfig.RecreateAttemptTimeout == 0 {
+		config.PoolConfig.RecreateAttemptTimeout = config.TestDatabaseGetTimeout
+	}
+
+	if config.PoolIdleTTL > 0 && config.PoolIdleSweepInterval <= 0 {
+		config.PoolIdleSweepInterval = config.PoolIdleTTL
+	}
+
 	// debug log final derived config
 	c, err := json.Marshal(config)
 
@@ -81,9 +145,28 @@ func New(config ManagerConfig) (*Manager, ManagerConfig) {
 		config:    config,
 		db:        nil,
 		templates: templates.NewCollection(),
-		pool:      pool.NewPoolCollection(config.PoolConfig),
+		aliases:   newAliasCollection(),
+		lifecycle: newLifecycleMetrics(),
+		liveness:  &livenessState{},
+		finalizes: newFinalizeLimiter(config.TemplateMaxConcurrentFinalizations),
+
+		asyncReturns: &sync.WaitGroup{},
 	}
 
+	if config.TestDatabaseLivenessCheck {
+		m.config.PoolConfig.PingDB = func(ctx context.Context, testDB db.TestDatabase) error {
+			return m.pingTestPoolDB(ctx, testDB)
+		}
+	}
+
+	if config.TestDatabaseStorageLimit > 0 {
+		m.config.PoolConfig.CheckStorage = func(ctx context.Context, templateDB db.Database) error {
+			return m.checkTestPoolStorage(ctx, templateDB)
+		}
+	}
+
+	m.pool = pool.NewPoolCollection(m.config.PoolConfig)
+
 	return m, m.config
 }
 
@@ -102,7 +185,7 @@ func (m *Manager) Connect(ctx context.Context) error {
 		return err
 	}
 
-	db, err := sql.Open("postgres", m.config.ManagerDatabaseConfig.ConnectionString())
+	db, err := sql.Open("postgres", m.connectionConfig().ConnectionString())
 	if err != nil {
 		log.Error().Err(err).Msg("unable to connect")
 		return err
@@ -110,16 +193,73 @@ func (m *Manager) Connect(ctx context.Context) error {
 
 	if err := db.PingContext(ctx); err != nil {
 		log.Error().Err(err).Msg("unable to ping")
+		_ = db.Close()
 		return err
 	}
 
 	m.db = db
 
+	serverInfo, err := m.queryServerInfo(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("unable to detect server version")
+		m.db = nil
+		_ = db.Close()
+		return err
+	}
+
+	m.serverInfo = serverInfo
+	log.Debug().Int("serverVersion", serverInfo.Version).Msg("detected server version")
+
+	m.warnConnectionEstimate(ctx, m.defaultConnectionEstimate())
+
+	if m.config.PoolIdleTTL > 0 {
+		m.startIdleSweeper()
+	}
+
+	if m.config.LeakSweepInterval > 0 {
+		m.startLeakSweeper()
+	}
+
+	if m.config.RebalanceInterval > 0 && m.config.PoolConfig.MaxTotalDBs > 0 {
+		m.startRebalancer()
+	}
+
+	if m.config.LivenessCheckInterval > 0 {
+		m.startWatchdog()
+	}
+
 	log.Debug().Msg("connected.")
 
 	return nil
 }
 
+// connectionConfig returns the config of the manager connections.
+// The configured timeouts are passed as run-time parameters, thus they are applied to each session right after connecting.
+func (m Manager) connectionConfig() db.DatabaseConfig {
+	config := m.config.ManagerDatabaseConfig
+
+	if m.config.StatementTimeout <= 0 && m.config.LockTimeout <= 0 {
+		return config
+	}
+
+	params := make(map[string]string, len(config.AdditionalParams)+2)
+	for k, v := range config.AdditionalParams {
+		params[k] = v
+	}
+
+	if m.config.StatementTimeout > 0 {
+		params["statement_timeout"] = strconv.FormatInt(m.config.StatementTimeout.Milliseconds(), 10)
+	}
+
+	if m.config.LockTimeout > 0 {
+		params["lock_timeout"] = strconv.FormatInt(m.config.LockTimeout.Milliseconds(), 10)
+	}
+
+	config.AdditionalParams = params
+
+	return config
+}
+
 func (m *Manager) Disconnect(ctx context.Context, ignoreCloseError bool) error {
 
 	log := m.getManagerLogger(ctx, "Disconnect").With().Bool("ignoreCloseError", ignoreCloseError).Logger()
@@ -130,6 +270,29 @@ func (m *Manager) Disconnect(ctx context.Context, ignoreCloseError bool) error {
 		return err
 	}
 
+	if m.stopIdleSweeper != nil {
+		m.stopIdleSweeper()
+		m.stopIdleSweeper = nil
+	}
+
+	if m.stopLeakSweeper != nil {
+		m.stopLeakSweeper()
+		m.stopLeakSweeper = nil
+	}
+
+	if m.stopRebalancer != nil {
+		m.stopRebalancer()
+		m.stopRebalancer = nil
+	}
+
+	if m.stopWatchdog != nil {
+		m.stopWatchdog()
+		m.stopWatchdog = nil
+	}
+
+	// don't drop any returns a client has been told to be accepted
+	m.asyncReturns.Wait()
+
 	// stop the pool before closing DB connection
 	m.pool.Stop()
 
@@ -157,6 +320,14 @@ func (m Manager) Ready() bool {
 	return m.db != nil
 }
 
+// LifecycleMetrics returns the counters of the template lifecycle events since the manager was created.
+func (m Manager) LifecycleMetrics() LifecycleMetrics {
+	s := m.lifecycle.snapshot()
+	s.FinalizeQueued, s.FinalizeRunning = m.finalizes.stats()
+
+	return s
+}
+
 func (m Manager) Config() ManagerConfig {
 	return m.config
 }
@@ -200,7 +371,72 @@ func (m *Manager) Initialize(ctx context.Context) error {
 	return nil
 }
 
+// TemplateOptions holds optional per hash settings, supplied while initializing a template database.
+type TemplateOptions struct {
+	InlineRecreateMaxSize int64                   // Overrides ManagerConfig.TestDatabaseInlineRecreateMaxTemplateSize for this hash if > 0
+	CleanStrategy         templates.CleanStrategy // How dirty test DBs are cleaned (empty defaults to recopy)
+	ResetSQL              string                  // SQL resetting a dirty test DB, required for the truncate and schema clean strategies
+	TestDatabaseOwner     string                  // Overrides ManagerConfig.TestDatabaseOwner for the test DBs of this hash if set
+	DatabaseLocale        DatabaseLocale          // Overrides the values of ManagerConfig.DatabaseLocale set for this hash
+	LeakWarnTimeout       time.Duration           // Overrides PoolConfig.LeakWarnTimeout for this hash if > 0 (e.g. longer grace periods for slow integration tests)
+	LeakReclaimTimeout    time.Duration           // Overrides PoolConfig.LeakReclaimTimeout for this hash if > 0
+	ConnectionLimit       int                     // Overrides ManagerConfig.TestDatabaseConnectionLimit for the test DBs of this hash if != 0 (-1 unlimited)
+	MinReady              int                     // Overrides PoolConfig.MinReady (warm standby of ready test DBs) for this hash if > 0
+	Fingerprint           string                  // Optional fingerprint of the template content (e.g. a checksum of the migrations and fixtures), initializing or finalizing the hash with a different one fails with ErrHashMismatch
+	Sources               map[string]int          // Optional additional template DBs (name -> weight) with identical content, test DBs are copied from them and the template DB (weight 1 unless listed) by weighted round-robin
+}
+
+func (opts TemplateOptions) validate() error {
+	switch opts.CleanStrategy {
+	case "", templates.CleanStrategyRecopy:
+		return nil
+	case templates.CleanStrategyTruncate, templates.CleanStrategySchema:
+		if len(strings.TrimSpace(opts.ResetSQL)) == 0 {
+			return ErrInvalidCleanStrategy
+		}
+		return nil
+	default:
+		return ErrInvalidCleanStrategy
+	}
+}
+
+func (opts TemplateOptions) validateConnectionLimit() error {
+	if opts.ConnectionLimit < -1 {
+		return ErrInvalidConnectionLimit
+	}
+
+	return nil
+}
+
+func (opts TemplateOptions) validateMinReady() error {
+	if opts.MinReady < 0 {
+		return ErrInvalidMinReady
+	}
+
+	return nil
+}
+
+func (opts TemplateOptions) validateLeakTimeouts() error {
+	if opts.LeakWarnTimeout > 0 && opts.LeakReclaimTimeout > 0 && opts.LeakReclaimTimeout <= opts.LeakWarnTimeout {
+		return ErrInvalidLeakTimeouts
+	}
+
+	return nil
+}
+
 func (m Manager) InitializeTemplateDatabase(ctx context.Context, hash string) (db.TemplateDatabase, error) {
+	return m.InitializeTemplateDatabaseWithOptions(ctx, hash, TemplateOptions{})
+}
+
+// InitializeTemplateDatabaseWithOptions initializes the template database, applying the given per hash options.
+func (m Manager) InitializeTemplateDatabaseWithOptions(ctx context.Context, hash string, opts TemplateOptions) (db.TemplateDatabase, error) {
+	template, err := m.initializeTemplateDatabase(ctx, hash, opts)
+	m.lifecycle.templateCreated(err)
+
+	return template, err
+}
+
+func (m Manager) initializeTemplateDatabase(ctx context.Context, hash string, opts TemplateOptions) (db.TemplateDatabase, error) {
 	ctx, task := trace.NewTask(ctx, "initialize_template_db")
 
 	log := m.getManagerLogger(ctx, "InitializeTemplateDatabase").With().Str("hash", hash).Logger()
@@ -212,7 +448,28 @@ func (m Manager) InitializeTemplateDatabase(ctx context.Context, hash string) (d
 		return db.TemplateDatabase{}, ErrManagerNotReady
 	}
 
+	if err := m.checkTemplateOptions(ctx, opts); err != nil {
+		log.Error().Err(err).Str("cleanStrategy", string(opts.CleanStrategy)).Str("owner", opts.TestDatabaseOwner).Msg("invalid options")
+		return db.TemplateDatabase{}, err
+	}
+
+	locale := m.config.DatabaseLocale.override(opts.DatabaseLocale)
+	if err := m.checkDatabaseLocale(ctx, m.config.TemplateDatabaseTemplate, locale); err != nil {
+		log.Error().Err(err).Msg("invalid database locale")
+		return db.TemplateDatabase{}, err
+	}
+
+	if err := m.checkFingerprint(ctx, hash, opts.Fingerprint); err != nil {
+		log.Error().Err(err).Msg("fingerprint mismatch")
+		return db.TemplateDatabase{}, err
+	}
+
 	dbName := m.makeTemplateDatabaseName(hash)
+	if err := m.checkTemplateSources(ctx, dbName, opts.Sources); err != nil {
+		log.Error().Err(err).Msg("invalid template sources")
+		return db.TemplateDatabase{}, err
+	}
+
 	templateConfig := templates.TemplateConfig{
 		DatabaseConfig: db.DatabaseConfig{
 			Host:     m.config.ManagerDatabaseConfig.Host,
@@ -221,6 +478,16 @@ func (m Manager) InitializeTemplateDatabase(ctx context.Context, hash string) (d
 			Password: m.config.ManagerDatabaseConfig.Password,
 			Database: dbName,
 		},
+		InlineRecreateMaxSize: opts.InlineRecreateMaxSize,
+		CleanStrategy:         opts.CleanStrategy,
+		ResetSQL:              opts.ResetSQL,
+		TestDatabaseOwner:     opts.TestDatabaseOwner,
+		LeakWarnTimeout:       opts.LeakWarnTimeout,
+		LeakReclaimTimeout:    opts.LeakReclaimTimeout,
+		Sources:               opts.Sources,
+		Fingerprint:           opts.Fingerprint,
+		ConnectionLimit:       opts.ConnectionLimit,
+		MinReady:              opts.MinReady,
 	}
 
 	added, unlock := m.templates.Push(ctx, hash, templateConfig)
@@ -232,7 +499,7 @@ func (m Manager) InitializeTemplateDatabase(ctx context.Context, hash string) (d
 	}
 
 	reg := trace.StartRegion(ctx, "drop_and_create_db")
-	if err := m.dropAndCreateDatabase(ctx, dbName, m.config.ManagerDatabaseConfig.Username, m.config.TemplateDatabaseTemplate); err != nil {
+	if err := m.dropAndCreateDatabase(ctx, dbName, m.config.ManagerDatabaseConfig.Username, m.config.TemplateDatabaseTemplate, locale, -1); err != nil {
 
 		log.Error().Err(err).Msg("triggering unsafe remove after dropAndCreateDatabase failed...")
 		m.templates.RemoveUnsafe(ctx, hash)
@@ -259,7 +526,109 @@ func (m Manager) InitializeTemplateDatabase(ctx context.Context, hash string) (d
 	}, nil
 }
 
+// RegisterExternalTemplateDatabase registers the existing template database with the given name (e.g. imported by another process) for the hash,
+// skipping its creation. The template is finalized right away, its test DBs are copied from it like from the templates initialized by IntegreSQL.
+// The template database is never dropped by IntegreSQL (not even via DiscardTemplateDatabase). The DatabaseLocale of the options is ignored.
+func (m Manager) RegisterExternalTemplateDatabase(ctx context.Context, hash string, dbName string, opts TemplateOptions) (db.TemplateDatabase, error) {
+	template, err := m.registerExternalTemplateDatabase(ctx, hash, dbName, opts)
+	m.lifecycle.templateCreated(err)
+
+	return template, err
+}
+
+func (m Manager) registerExternalTemplateDatabase(ctx context.Context, hash string, dbName string, opts TemplateOptions) (db.TemplateDatabase, error) {
+	ctx, task := trace.NewTask(ctx, "register_external_template_db")
+
+	log := m.getManagerLogger(ctx, "RegisterExternalTemplateDatabase").With().Str("hash", hash).Str("dbName", dbName).Logger()
+
+	defer task.End()
+
+	if !m.Ready() {
+		log.Error().Msg("not ready")
+		return db.TemplateDatabase{}, ErrManagerNotReady
+	}
+
+	if err := m.checkTemplateOptions(ctx, opts); err != nil {
+		log.Error().Err(err).Str("cleanStrategy", string(opts.CleanStrategy)).Str("owner", opts.TestDatabaseOwner).Msg("invalid options")
+		return db.TemplateDatabase{}, err
+	}
+
+	if err := m.checkFingerprint(ctx, hash, opts.Fingerprint); err != nil {
+		log.Error().Err(err).Msg("fingerprint mismatch")
+		return db.TemplateDatabase{}, err
+	}
+
+	if err := m.checkExternalTemplate(ctx, dbName); err != nil {
+		log.Error().Err(err).Msg("invalid external template")
+		return db.TemplateDatabase{}, err
+	}
+
+	if err := m.checkTemplateSources(ctx, dbName, opts.Sources); err != nil {
+		log.Error().Err(err).Msg("invalid template sources")
+		return db.TemplateDatabase{}, err
+	}
+
+	templateConfig := templates.TemplateConfig{
+		DatabaseConfig: db.DatabaseConfig{
+			Host:     m.config.ManagerDatabaseConfig.Host,
+			Port:     m.config.ManagerDatabaseConfig.Port,
+			Username: m.config.ManagerDatabaseConfig.Username,
+			Password: m.config.ManagerDatabaseConfig.Password,
+			Database: dbName,
+		},
+		InlineRecreateMaxSize: opts.InlineRecreateMaxSize,
+		CleanStrategy:         opts.CleanStrategy,
+		ResetSQL:              opts.ResetSQL,
+		TestDatabaseOwner:     opts.TestDatabaseOwner,
+		LeakWarnTimeout:       opts.LeakWarnTimeout,
+		LeakReclaimTimeout:    opts.LeakReclaimTimeout,
+		Sources:               opts.Sources,
+		Fingerprint:           opts.Fingerprint,
+		ConnectionLimit:       opts.ConnectionLimit,
+		MinReady:              opts.MinReady,
+		External:              true,
+	}
+
+	if err := m.pushExternalTemplate(ctx, hash, templateConfig); err != nil {
+		return db.TemplateDatabase{}, err
+	}
+
+	// the template already holds its final content
+	return m.FinalizeTemplateDatabase(ctx, hash)
+}
+
+// pushExternalTemplate adds the config of an external template to the collection, removing the existing pool of the hash (if any).
+func (m Manager) pushExternalTemplate(ctx context.Context, hash string, templateConfig templates.TemplateConfig) error {
+	log := m.getManagerLogger(ctx, "pushExternalTemplate").With().Str("hash", hash).Logger()
+
+	added, unlock := m.templates.Push(ctx, hash, templateConfig)
+	defer unlock()
+
+	if !added {
+		return ErrTemplateAlreadyInitialized
+	}
+
+	// if template config has been overwritten, the existing pool needs to be removed
+	err := m.pool.RemoveAllWithHash(ctx, hash, m.dropTestPoolDB)
+	if err != nil && !errors.Is(err, pool.ErrUnknownHash) {
+
+		log.Error().Err(err).Msg("triggering unsafe remove after RemoveAllWithHash failed...")
+		m.templates.RemoveUnsafe(ctx, hash)
+
+		return err
+	}
+
+	return nil
+}
+
 func (m Manager) DiscardTemplateDatabase(ctx context.Context, hash string) error {
+	err := m.discardTemplateDatabase(ctx, hash)
+	m.lifecycle.templateRemoved(err)
+
+	return err
+}
+
+func (m Manager) discardTemplateDatabase(ctx context.Context, hash string) error {
 
 	ctx, task := trace.NewTask(ctx, "discard_template_db")
 	log := m.getManagerLogger(ctx, "DiscardTemplateDatabase").With().Str("hash", hash).Logger()
@@ -296,6 +665,11 @@ func (m Manager) DiscardTemplateDatabase(ctx context.Context, hash string) error
 		}
 	} else {
 		template.SetState(ctx, templates.TemplateStateDiscarded)
+
+		if template.GetConfig(ctx).External {
+			log.Debug().Str("dbName", dbName).Msg("keeping external template database")
+			return nil
+		}
 	}
 
 	log.Debug().Msg("found template database, dropping...")
@@ -304,6 +678,13 @@ func (m Manager) DiscardTemplateDatabase(ctx context.Context, hash string) error
 }
 
 func (m Manager) FinalizeTemplateDatabase(ctx context.Context, hash string) (db.TemplateDatabase, error) {
+	return m.FinalizeTemplateDatabaseWithFingerprint(ctx, hash, "")
+}
+
+// FinalizeTemplateDatabaseWithFingerprint finalizes the template database like FinalizeTemplateDatabase, additionally checking the given
+// fingerprint of the template content (if any) against the one stored for the hash: Mismatches fail with ErrHashMismatch (even if the template
+// is already finalized), as the client reuses the hash for changed content. The fingerprint is stored if none was supplied while initializing.
+func (m Manager) FinalizeTemplateDatabaseWithFingerprint(ctx context.Context, hash string, fingerprint string) (db.TemplateDatabase, error) {
 	ctx, task := trace.NewTask(ctx, "finalize_template_db")
 
 	log := m.getManagerLogger(ctx, "FinalizeTemplateDatabase").With().Str("hash", hash).Logger()
@@ -321,9 +702,22 @@ func (m Manager) FinalizeTemplateDatabase(ctx context.Context, hash string) (db.
 		return db.TemplateDatabase{}, ErrTemplateNotFound
 	}
 
+	// queued before locking the template, thus its state may still be read meanwhile
+	release, err := m.finalizes.acquire(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("bailout: gave up waiting for a free finalization slot")
+		return db.TemplateDatabase{}, err
+	}
+	defer release()
+
 	state, lockedTemplate := template.GetStateWithLock(ctx)
 	defer lockedTemplate.Unlock()
 
+	if !template.TemplateConfig.MatchesFingerprint(fingerprint) {
+		log.Error().Str("fingerprint", fingerprint).Str("storedFingerprint", template.TemplateConfig.Fingerprint).Msg("bailout: fingerprint mismatch")
+		return db.TemplateDatabase{}, ErrHashMismatch
+	}
+
 	// early bailout if we are already ready (multiple calls)
 	if state == templates.TemplateStateFinalized {
 		log.Warn().Msg("bailout: template already finalized")
@@ -333,21 +727,59 @@ func (m Manager) FinalizeTemplateDatabase(ctx context.Context, hash string) (db.
 	// Disallow transition from discarded to ready
 	if state == templates.TemplateStateDiscarded {
 		log.Error().Msg("bailout: template discarded!")
+		m.lifecycle.templateFinalized(time.Since(template.InitializedAt()), ErrTemplateDiscarded)
 		return db.TemplateDatabase{}, ErrTemplateDiscarded
 	}
 
-	// Init a pool with this hash
+	if len(fingerprint) > 0 {
+		lockedTemplate.SetFingerprint(ctx, fingerprint)
+	}
+
+	// Init a pool with this hash, the template is locked, thus its config is read directly
 	log.Trace().Msg("init hash pool...")
-	m.pool.InitHashPool(ctx, template.Database, m.recreateTestPoolDB)
+	estimateBefore := newConnectionEstimate(m.pool.EstimatedMaxConnections(ctx), m.serverInfo.MaxConnections)
+	m.initHashPool(ctx, template, template.TemplateConfig)
+
+	// only warn once the pools of the finalized template exceed max_connections, not while finalizing each template afterwards
+	if estimate := newConnectionEstimate(m.pool.EstimatedMaxConnections(ctx), m.serverInfo.MaxConnections); !estimateBefore.Exceeded {
+		m.warnConnectionEstimate(ctx, estimate)
+	}
 
 	lockedTemplate.SetState(ctx, templates.TemplateStateFinalized)
 
+	// external templates are finalized right away while registering them
+	if !template.TemplateConfig.External {
+		m.lifecycle.templateFinalized(time.Since(template.InitializedAt()), nil)
+	}
+
 	log.Debug().Msg("Template database finalized successfully.")
 	return db.TemplateDatabase{Database: template.Database}, nil
 }
 
 // GetTestDatabase tries to get a ready test DB from an existing pool.
 func (m Manager) GetTestDatabase(ctx context.Context, hash string) (db.TestDatabase, error) {
+	return m.GetTestDatabaseWithOptions(ctx, hash, pool.GetOptions{})
+}
+
+// GetTestDatabaseVariant tries to get a ready test DB of the given variant of a template (e.g. schema-only without seed data),
+// which was initialized and finalized as a template on its own under pool.VariantHash. The template itself is used without a variant.
+func (m Manager) GetTestDatabaseVariant(ctx context.Context, hash string, variant string) (db.TestDatabase, error) {
+	if err := pool.ValidateVariant(variant); err != nil {
+		return db.TestDatabase{}, err
+	}
+
+	return m.GetTestDatabaseWithOptions(ctx, pool.VariantHash(hash, variant), pool.GetOptions{})
+}
+
+// GetTestDatabaseWithOptions tries to get a ready test DB from an existing pool, applying the given options (e.g. labels).
+func (m Manager) GetTestDatabaseWithOptions(ctx context.Context, hash string, opts pool.GetOptions) (db.TestDatabase, error) {
+	testDB, _, err := m.GetTestDatabaseWithInfo(ctx, hash, opts)
+	return testDB, err
+}
+
+// GetTestDatabaseWithInfo tries to get a ready test DB like GetTestDatabaseWithOptions, additionally describing the acquisition
+// (e.g. how long it waited within the pool and how many test DBs are left ready), thus clients may adapt to the pressure on the pool.
+func (m Manager) GetTestDatabaseWithInfo(ctx context.Context, hash string, opts pool.GetOptions) (db.TestDatabase, pool.AcquireInfo, error) {
 	ctx, task := trace.NewTask(ctx, "get_test_db")
 
 	log := m.getManagerLogger(ctx, "GetTestDatabase").With().Str("hash", hash).Logger()
@@ -356,43 +788,108 @@ func (m Manager) GetTestDatabase(ctx context.Context, hash string) (db.TestDatab
 
 	if !m.Ready() {
 		log.Error().Msg("not ready")
-		return db.TestDatabase{}, ErrManagerNotReady
+		return db.TestDatabase{}, pool.AcquireInfo{}, ErrManagerNotReady
 	}
 
+	// the hash might be an alias of the actual template hash (see SetAlias)
+	hash = m.resolveHash(hash)
+	log = log.With().Str("resolvedHash", hash).Logger()
+
 	template, found := m.templates.Get(ctx, hash)
 	if !found {
-		return db.TestDatabase{}, ErrTemplateNotFound
+		return db.TestDatabase{}, pool.AcquireInfo{}, ErrTemplateNotFound
 	}
 
 	// if the template has been discarded/not initalized yet,
 	// no DB should be returned, even if already in the pool
 	state := template.WaitUntilFinalized(ctx, m.config.TemplateFinalizeTimeout)
 	if state != templates.TemplateStateFinalized {
-		return db.TestDatabase{}, ErrInvalidTemplateState
+		return db.TestDatabase{}, pool.AcquireInfo{}, ErrInvalidTemplateState
 	}
 
 	ctx, task = trace.NewTask(ctx, "get_with_timeout")
-	testDB, err := m.pool.GetTestDatabase(ctx, template.TemplateHash, m.config.TestDatabaseGetTimeout)
+	testDB, info, err := m.pool.GetTestDatabaseWithInfo(ctx, template.TemplateHash, m.config.TestDatabaseGetTimeout, opts)
 	task.End()
 	if errors.Is(err, pool.ErrUnknownHash) {
 		// Template exists, but the pool is not there -
 		// it must have been removed.
 		// It needs to be reinitialized.
 		log.Warn().Err(err).Msg("ErrUnknownHash, going to InitHashPool and recursively calling us again...")
-		m.pool.InitHashPool(ctx, template.Database, m.recreateTestPoolDB)
+		m.initHashPool(ctx, template, template.GetConfig(ctx))
 
-		testDB, err = m.pool.GetTestDatabase(ctx, template.TemplateHash, m.config.TestDatabaseGetTimeout)
+		testDB, info, err = m.pool.GetTestDatabaseWithInfo(ctx, template.TemplateHash, m.config.TestDatabaseGetTimeout, opts)
 	}
 
 	if err != nil {
-		return db.TestDatabase{}, err
+		return db.TestDatabase{}, pool.AcquireInfo{}, err
 	}
 
-	return testDB, nil
+	testDB.Replica = m.replicaConfig(testDB.Config)
+
+	return testDB, info, nil
 }
 
 // ReturnTestDatabase returns the given test DB directly to the pool, without cleaning (recreating it).
+// GetTestDatabaseByID picks up the test DB with the given ID, e.g. to reproduce a failure on a known test DB.
+// Dirty test DBs are handed out as is (dirty is true), unless they are still in use (pool.ErrTestDBInUse).
+func (m Manager) GetTestDatabaseByID(ctx context.Context, hash string, id int) (db.TestDatabase, bool, error) {
+	return m.GetTestDatabaseByIDWithOptions(ctx, hash, id, pool.GetOptions{})
+}
+
+// GetTestDatabaseByIDWithOptions picks up the test DB with the given ID like GetTestDatabaseByID, applying the given options.
+// With RejectDirty (or PoolConfig.RejectDirty), dirty test DBs fail with pool.ErrWouldReuseDirty instead of being handed out as is.
+func (m Manager) GetTestDatabaseByIDWithOptions(ctx context.Context, hash string, id int, opts pool.GetOptions) (db.TestDatabase, bool, error) {
+	ctx, task := trace.NewTask(ctx, "get_test_db_by_id")
+	defer task.End()
+
+	if !m.Ready() {
+		return db.TestDatabase{}, false, ErrManagerNotReady
+	}
+
+	// check if the template exists and is finalized
+	template, found := m.templates.Get(ctx, hash)
+	if !found {
+		return db.TestDatabase{}, false, ErrTemplateNotFound
+	}
+
+	if template.WaitUntilFinalized(ctx, m.config.TemplateFinalizeTimeout) !=
+		templates.TemplateStateFinalized {
+		return db.TestDatabase{}, false, ErrInvalidTemplateState
+	}
+
+	testDB, dirty, err := m.pool.GetTestDatabaseByIDWithOptions(ctx, hash, id, opts)
+	if err != nil {
+		if errors.Is(err, pool.ErrInvalidIndex) || errors.Is(err, pool.ErrUnknownHash) {
+			return db.TestDatabase{}, false, ErrTestNotFound
+		}
+
+		return db.TestDatabase{}, false, err
+	}
+
+	if dirty {
+		connected, err := m.checkDatabaseConnected(ctx, testDB.Config.Database)
+		if err != nil {
+			return db.TestDatabase{}, false, err
+		}
+
+		if connected {
+			return db.TestDatabase{}, false, pool.ErrTestDBInUse
+		}
+	}
+
+	testDB.Replica = m.replicaConfig(testDB.Config)
+
+	return testDB, dirty, nil
+}
+
 func (m Manager) ReturnTestDatabase(ctx context.Context, hash string, id int) error {
+	return m.ReturnTestDatabaseWithLease(ctx, hash, id, "")
+}
+
+// ReturnTestDatabaseWithLease returns the test DB unchanged to the pool like ReturnTestDatabase.
+// If a lease is given, it must be the one handed out with the test DB, otherwise pool.ErrInvalidLease is returned
+// (e.g. the test DB has been returned and handed out to another client in the meantime).
+func (m Manager) ReturnTestDatabaseWithLease(ctx context.Context, hash string, id int, lease string) error {
 	ctx, task := trace.NewTask(ctx, "return_test_db")
 	defer task.End()
 
@@ -413,11 +910,39 @@ func (m Manager) ReturnTestDatabase(ctx context.Context, hash string, id int) er
 	}
 
 	// template is ready, we can return unchanged testDB to the pool
-	return m.pool.ReturnTestDatabase(ctx, hash, id)
+	return m.pool.ReturnTestDatabaseWithLease(ctx, hash, id, lease)
+}
+
+// ReturnTestDatabaseAsync returns the test DB like ReturnTestDatabaseWithLease, but without waiting for the result:
+// The return is processed in background and errors are only logged. Intended for clients not caring about the confirmation at their test teardown.
+// Only ErrManagerNotReady is reported to the caller, as the return would fail anyways. Disconnect waits for all pending returns.
+func (m Manager) ReturnTestDatabaseAsync(ctx context.Context, hash string, id int, lease string) error {
+	if !m.Ready() {
+		return ErrManagerNotReady
+	}
+
+	log := m.getManagerLogger(ctx, "ReturnTestDatabaseAsync").With().Str("hash", hash).Int("id", id).Logger()
+
+	m.asyncReturns.Add(1)
+	go func() {
+		defer m.asyncReturns.Done()
+
+		// the ctx of the caller (e.g. the HTTP request) is typically done right after we return, only keep its logger
+		if err := m.ReturnTestDatabaseWithLease(log.WithContext(context.Background()), hash, id, lease); err != nil {
+			log.Warn().Err(err).Msg("async return failed")
+		}
+	}()
+
+	return nil
 }
 
 // RecreateTestDatabase recreates the test DB according to the template and returns it back to the pool.
 func (m *Manager) RecreateTestDatabase(ctx context.Context, hash string, id int) error {
+	return m.RecreateTestDatabaseWithLease(ctx, hash, id, "")
+}
+
+// RecreateTestDatabaseWithLease recreates the test DB like RecreateTestDatabase, checking the given lease (if any) like ReturnTestDatabaseWithLease.
+func (m *Manager) RecreateTestDatabaseWithLease(ctx context.Context, hash string, id int, lease string) error {
 	ctx, task := trace.NewTask(ctx, "recreate_test_db")
 	defer task.End()
 
@@ -437,45 +962,690 @@ func (m *Manager) RecreateTestDatabase(ctx context.Context, hash string, id int)
 	}
 
 	// template is ready, we can return the testDB to the pool and have it cleaned up
-	return m.pool.RecreateTestDatabase(ctx, hash, id)
+	return m.pool.RecreateTestDatabaseWithLease(ctx, hash, id, lease)
 }
 
-func (m Manager) ClearTrackedTestDatabases(ctx context.Context, hash string) error {
-
-	log := m.getManagerLogger(ctx, "ClearTrackedTestDatabases").With().Str("hash", hash).Logger()
+// ForceRemoveTestDatabase reclaims a test DB held by a client (e.g. a suspected leak, see ReclaimLeakedTestDatabases) right away:
+// Its lease is invalidated, all its connections are terminated, it is dropped and recreated from the template in the background.
+func (m *Manager) ForceRemoveTestDatabase(ctx context.Context, hash string, id int) error {
+	ctx, task := trace.NewTask(ctx, "force_remove_test_db")
+	defer task.End()
 
 	if !m.Ready() {
-		log.Error().Msg("not ready")
 		return ErrManagerNotReady
 	}
 
-	log.Warn().Msg("clearing...")
-
-	err := m.pool.RemoveAllWithHash(ctx, hash, m.dropTestPoolDB)
-	if errors.Is(err, pool.ErrUnknownHash) {
+	if _, found := m.templates.Get(ctx, hash); !found {
 		return ErrTemplateNotFound
 	}
 
-	return err
+	return m.pool.ForceRemove(ctx, hash, id, m.forceDropTestPoolDB)
 }
 
-func (m Manager) ResetAllTracking(ctx context.Context) error {
-
-	log := m.getManagerLogger(ctx, "ResetAllTracking")
-
+// ExtendTestDatabaseReservation heartbeats the soft reservation of a test DB acquired with a pool.GetOptions.ReservationTTL,
+// checking the given lease (if any) like ReturnTestDatabaseWithLease. Returns the new deadline of the reservation.
+func (m Manager) ExtendTestDatabaseReservation(ctx context.Context, hash string, id int, lease string) (time.Time, error) {
 	if !m.Ready() {
-		log.Error().Msg("not ready")
-		return ErrManagerNotReady
+		return time.Time{}, ErrManagerNotReady
 	}
 
-	log.Warn().Msg("resetting...")
+	if _, found := m.templates.Get(ctx, hash); !found {
+		return time.Time{}, ErrTemplateNotFound
+	}
 
-	// remove all templates to disallow any new test DB creation from existing templates
+	reservedUntil, err := m.pool.ExtendReservation(ctx, hash, id, lease)
+	if errors.Is(err, pool.ErrInvalidIndex) || errors.Is(err, pool.ErrUnknownHash) {
+		return time.Time{}, ErrTestNotFound
+	}
+
+	return reservedUntil, err
+}
+
+// ReturnTestDatabasePoisoned returns a test DB corrupted beyond what cleaning can fix (e.g. altered roles, broken extensions).
+// Instead of being cleaned via the template's reset SQL, it is dropped and fully recreated from the template.
+func (m *Manager) ReturnTestDatabasePoisoned(ctx context.Context, hash string, id int) error {
+	return m.ReturnTestDatabasePoisonedWithLease(ctx, hash, id, "")
+}
+
+// ReturnTestDatabasePoisonedWithLease returns the test DB as poisoned like ReturnTestDatabasePoisoned, checking the given lease (if any) like ReturnTestDatabaseWithLease.
+func (m *Manager) ReturnTestDatabasePoisonedWithLease(ctx context.Context, hash string, id int, lease string) error {
+	ctx, task := trace.NewTask(ctx, "return_poisoned_test_db")
+	defer task.End()
+
+	if !m.Ready() {
+		return ErrManagerNotReady
+	}
+
+	// check if the template exists and is finalized
+	template, found := m.templates.Get(ctx, hash)
+	if !found {
+		return ErrTemplateNotFound
+	}
+
+	if template.WaitUntilFinalized(ctx, m.config.TemplateFinalizeTimeout) !=
+		templates.TemplateStateFinalized {
+		return ErrInvalidTemplateState
+	}
+
+	return m.pool.ReturnTestDatabasePoisonedWithLease(ctx, hash, id, lease)
+}
+
+// GetPoolSnapshot returns the current state of the pool of the given template hash.
+func (m Manager) GetPoolSnapshot(ctx context.Context, hash string) (pool.PoolSnapshot, error) {
+	if !m.Ready() {
+		return pool.PoolSnapshot{}, ErrManagerNotReady
+	}
+
+	snapshot, err := m.pool.Snapshot(ctx, hash)
+	if errors.Is(err, pool.ErrUnknownHash) {
+		return pool.PoolSnapshot{}, ErrTemplateNotFound
+	}
+
+	return snapshot, err
+}
+
+// TemplateStatus summarizes the lifecycle state of a template and its test DBs (see GetTemplateStatus).
+type TemplateStatus struct {
+	State string `json:"state"` // "init", "finalized" or "discarded"
+	Ready int    `json:"ready"` // test DBs ready to be handed out
+	Dirty int    `json:"dirty"` // test DBs in use or awaiting their recreation
+}
+
+// GetTemplateStatus returns the state of the template with the given hash (or alias), allowing clients to wait for the warm-up of its pool
+// without acquiring a test DB. The counts are 0 until the template is finalized and its pool is initialized.
+func (m Manager) GetTemplateStatus(ctx context.Context, hash string) (TemplateStatus, error) {
+	if !m.Ready() {
+		return TemplateStatus{}, ErrManagerNotReady
+	}
+
+	hash = m.resolveHash(hash)
+
+	template, found := m.templates.Get(ctx, hash)
+	if !found {
+		return TemplateStatus{}, ErrTemplateNotFound
+	}
+
+	status := TemplateStatus{State: template.GetState(ctx).String()}
+
+	snapshot, err := m.pool.Snapshot(ctx, hash)
+	if err != nil {
+		if errors.Is(err, pool.ErrUnknownHash) {
+			return status, nil
+		}
+
+		return TemplateStatus{}, err
+	}
+
+	status.Ready = snapshot.Ready
+	status.Dirty = snapshot.Dirty
+
+	return status, nil
+}
+
+// GetInUseTestDatabases returns the test DBs of the given template hash currently held by clients, oldest acquisition first.
+func (m Manager) GetInUseTestDatabases(ctx context.Context, hash string) ([]pool.InUseInfo, error) {
+	if !m.Ready() {
+		return nil, ErrManagerNotReady
+	}
+
+	inUse, err := m.pool.InUse(ctx, hash)
+	if errors.Is(err, pool.ErrUnknownHash) {
+		return nil, ErrTemplateNotFound
+	}
+
+	return inUse, err
+}
+
+// PeekTestDatabase returns the test DB with the given ID (e.g. to inspect its config while debugging a test), without handing it out.
+func (m Manager) PeekTestDatabase(ctx context.Context, hash string, id int) (db.TestDatabase, error) {
+	if !m.Ready() {
+		return db.TestDatabase{}, ErrManagerNotReady
+	}
+
+	testDB, err := m.pool.PeekTestDatabase(ctx, hash, id)
+	if err != nil {
+		if errors.Is(err, pool.ErrUnknownHash) {
+			return db.TestDatabase{}, ErrTemplateNotFound
+		} else if errors.Is(err, pool.ErrInvalidIndex) {
+			return db.TestDatabase{}, ErrTestNotFound
+		}
+
+		return db.TestDatabase{}, err
+	}
+
+	testDB.Replica = m.replicaConfig(testDB.Config)
+
+	return testDB, nil
+}
+
+// GetPoolSnapshots returns the current state of all pools.
+func (m Manager) GetPoolSnapshots(ctx context.Context) ([]pool.PoolSnapshot, error) {
+	if !m.Ready() {
+		return nil, ErrManagerNotReady
+	}
+
+	return m.pool.SnapshotAll(ctx), nil
+}
+
+// GetPoolSnapshotsPage returns the current state of the pools at the given offset (sorted by template hash), at most limit of them
+// (all remaining if limit <= 0), and the total number of pools (see pool.PoolCollection.SnapshotPage).
+func (m Manager) GetPoolSnapshotsPage(ctx context.Context, offset int, limit int) ([]pool.PoolSnapshot, int, error) {
+	if !m.Ready() {
+		return nil, 0, ErrManagerNotReady
+	}
+
+	snapshots, total := m.pool.SnapshotPage(ctx, offset, limit)
+
+	return snapshots, total, nil
+}
+
+// ForEachPoolSnapshot calls fn with the current snapshot of every pool, sorted by template hash (see pool.PoolCollection.ForEachPool).
+// Contrary to GetPoolSnapshots, only a single snapshot is held at a time, thus the pools may be streamed to the client.
+func (m Manager) ForEachPoolSnapshot(ctx context.Context, fn func(snapshot pool.PoolSnapshot) error) error {
+	if !m.Ready() {
+		return ErrManagerNotReady
+	}
+
+	return m.pool.ForEachPool(ctx, func(_ string, snapshot pool.PoolSnapshot) error {
+		return fn(snapshot)
+	})
+}
+
+// GetPendingCleanup returns the IDs of the dirty test DBs waiting to be cleaned per template hash, across all pools.
+func (m Manager) GetPendingCleanup(ctx context.Context) (map[string][]int, error) {
+	if !m.Ready() {
+		return nil, ErrManagerNotReady
+	}
+
+	return m.pool.PendingCleanup(ctx), nil
+}
+
+func (m Manager) ClearTrackedTestDatabases(ctx context.Context, hash string) error {
+
+	log := m.getManagerLogger(ctx, "ClearTrackedTestDatabases").With().Str("hash", hash).Logger()
+
+	if !m.Ready() {
+		log.Error().Msg("not ready")
+		return ErrManagerNotReady
+	}
+
+	log.Warn().Msg("clearing...")
+
+	err := m.pool.RemoveAllWithHash(ctx, hash, m.dropTestPoolDB)
+	if errors.Is(err, pool.ErrUnknownHash) {
+		return ErrTemplateNotFound
+	}
+
+	return err
+}
+
+// SetMaxPoolSize changes the maximal number of test DBs of the pool of the given template hash at runtime
+// (or of all pools, including the ones created from now on, if hash is empty), e.g. to react to the load of the PostgreSQL server.
+// Lowering it does not remove any test DBs, raising it is only possible up to the size an existing pool was created with.
+func (m Manager) SetMaxPoolSize(ctx context.Context, hash string, size int) error {
+
+	log := m.getManagerLogger(ctx, "SetMaxPoolSize").With().Str("hash", hash).Int("size", size).Logger()
+
+	if !m.Ready() {
+		log.Error().Msg("not ready")
+		return ErrManagerNotReady
+	}
+
+	if len(hash) == 0 {
+		return m.pool.SetMaxPoolSize(ctx, size)
+	}
+
+	_, err := m.pool.SetMaxPoolSizeWithHash(ctx, hash, size)
+	if errors.Is(err, pool.ErrUnknownHash) {
+		return ErrTemplateNotFound
+	}
+
+	return err
+}
+
+// SetReadyTarget changes the number of test DBs the pool of the given template hash tries to keep ready at runtime (initially InitialPoolSize)
+// and returns the effective target, e.g. to pre-scale the pool ahead of a big nightly run. Raising it provisions the missing test DBs right away,
+// lowering it lets the excess test DBs age out (they are no longer replenished once handed out).
+func (m Manager) SetReadyTarget(ctx context.Context, hash string, target int) (int, error) {
+
+	log := m.getManagerLogger(ctx, "SetReadyTarget").With().Str("hash", hash).Int("target", target).Logger()
+
+	if !m.Ready() {
+		log.Error().Msg("not ready")
+		return 0, ErrManagerNotReady
+	}
+
+	target, err := m.pool.SetReadyTargetWithHash(ctx, hash, target)
+	if errors.Is(err, pool.ErrUnknownHash) {
+		return 0, ErrTemplateNotFound
+	}
+
+	return target, err
+}
+
+// CleanDirtyTestDatabases schedules the dirty test DBs of the given template hash (or of all templates if hash is empty)
+// for immediate recreation, e.g. to maximize the number of ready test DBs right before a latency-sensitive test run.
+// Returns the number of scheduled test DBs.
+func (m Manager) CleanDirtyTestDatabases(ctx context.Context, hash string) (int, error) {
+
+	log := m.getManagerLogger(ctx, "CleanDirtyTestDatabases").With().Str("hash", hash).Logger()
+
+	if !m.Ready() {
+		log.Error().Msg("not ready")
+		return 0, ErrManagerNotReady
+	}
+
+	if len(hash) == 0 {
+		return m.pool.CleanAllDirty(ctx), nil
+	}
+
+	scheduled, err := m.pool.CleanDirtyWithHash(ctx, hash)
+	if errors.Is(err, pool.ErrUnknownHash) {
+		return 0, ErrTemplateNotFound
+	}
+
+	return scheduled, err
+}
+
+func (m Manager) ResetAllTracking(ctx context.Context) error {
+
+	log := m.getManagerLogger(ctx, "ResetAllTracking")
+
+	if !m.Ready() {
+		log.Error().Msg("not ready")
+		return ErrManagerNotReady
+	}
+
+	log.Warn().Msg("resetting...")
+
+	// remove all templates to disallow any new test DB creation from existing templates
 	m.templates.RemoveAll(ctx)
+	m.removeAllAliases()
 
 	return m.pool.RemoveAll(ctx, m.dropTestPoolDB)
 }
 
+// ResetTracking stops tracking the template with the given hash and removes all its test DBs.
+// Contrary to DiscardTemplateDatabase, the template database itself is kept.
+func (m Manager) ResetTracking(ctx context.Context, hash string) error {
+	err := m.resetTracking(ctx, hash)
+	m.lifecycle.templateRemoved(err)
+
+	return err
+}
+
+func (m Manager) resetTracking(ctx context.Context, hash string) error {
+
+	log := m.getManagerLogger(ctx, "ResetTracking").With().Str("hash", hash).Logger()
+
+	if !m.Ready() {
+		log.Error().Msg("not ready")
+		return ErrManagerNotReady
+	}
+
+	log.Warn().Msg("resetting...")
+
+	// remove all DBs with this hash first, the template is kept if that failed (thus the reset may be retried)
+	err := m.pool.RemoveAllWithHash(ctx, hash, m.dropTestPoolDB)
+	if err != nil && !errors.Is(err, pool.ErrUnknownHash) {
+		log.Error().Err(err).Msg("remove all err")
+		return err
+	}
+
+	if _, found := m.templates.Pop(ctx, hash); !found && err != nil {
+		return ErrTemplateNotFound
+	}
+
+	return nil
+}
+
+// replicaConfig derives the read-only config of the given database on the replica, nil if no replica is configured.
+func (m Manager) replicaConfig(config db.DatabaseConfig) *db.DatabaseConfig {
+	if len(m.config.ReplicaHost) == 0 {
+		return nil
+	}
+
+	replica := config
+	replica.Host = m.config.ReplicaHost
+	replica.Port = m.config.ReplicaPort
+
+	if len(config.AdditionalParams) > 0 {
+		replica.AdditionalParams = make(map[string]string, len(config.AdditionalParams))
+		for k, v := range config.AdditionalParams {
+			replica.AdditionalParams[k] = v
+		}
+	}
+
+	return &replica
+}
+
+// RemoveIdlePools removes all pools which have not been used by any client for the configured PoolIdleTTL,
+// as long as none of their test DBs is still connected. The templates are kept, their pools are recreated on the next request.
+// Returns the template hashes of the removed pools.
+func (m Manager) RemoveIdlePools(ctx context.Context) ([]string, error) {
+
+	log := m.getManagerLogger(ctx, "RemoveIdlePools")
+
+	if !m.Ready() {
+		log.Error().Msg("not ready")
+		return nil, ErrManagerNotReady
+	}
+
+	idleSince := time.Now().Add(-m.config.PoolIdleTTL)
+
+	var removed []string
+	var errs []error
+
+	// errors are collected instead of returned to continue with the remaining pools
+	_ = m.pool.ForEachPool(ctx, func(hash string, snapshot pool.PoolSnapshot) error {
+		if snapshot.LastUsed.After(idleSince) {
+			return nil
+		}
+
+		inUse, err := m.isPoolInUse(ctx, snapshot)
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+
+		if inUse {
+			log.Debug().Str("hash", hash).Msg("idle pool is still in use, skipping")
+			return nil
+		}
+
+		ok, err := m.pool.RemoveIdleWithHash(ctx, hash, idleSince, m.dropTestPoolDB)
+		if err != nil && !errors.Is(err, pool.ErrUnknownHash) {
+			errs = append(errs, fmt.Errorf("failed to remove idle pool %s: %w", hash, err))
+			return nil
+		}
+
+		if ok {
+			log.Info().Str("hash", hash).Time("lastUsed", snapshot.LastUsed).Msg("removed idle pool")
+			removed = append(removed, hash)
+		}
+
+		return nil
+	})
+
+	return removed, errors.Join(errs...)
+}
+
+// isPoolInUse checks whether any dirty test DB of the pool is still connected.
+func (m Manager) isPoolInUse(ctx context.Context, snapshot pool.PoolSnapshot) (bool, error) {
+	for _, testDB := range snapshot.TestDatabases {
+		if testDB.State != "dirty" {
+			continue
+		}
+
+		connected, err := m.checkDatabaseConnected(ctx, testDB.Database)
+		if err != nil || connected {
+			return connected, err
+		}
+	}
+
+	return false, nil
+}
+
+func (m *Manager) startIdleSweeper() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	m.stopIdleSweeper = func() {
+		cancel()
+		<-done
+	}
+
+	log := m.getManagerLogger(ctx, "idleSweeper")
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(m.config.PoolIdleSweepInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := m.RemoveIdlePools(ctx); err != nil && !errors.Is(err, context.Canceled) {
+					log.Error().Err(err).Msg("failed to remove idle pools")
+				}
+			}
+		}
+	}()
+}
+
+// ReclaimLeakedTestDatabases logs the test DBs held longer than their LeakWarnTimeout and force-returns
+// the ones held longer than their LeakReclaimTimeout (see pool.HashPool.ReclaimLeaked). Returns the IDs of the reclaimed test DBs by hash.
+func (m Manager) ReclaimLeakedTestDatabases(ctx context.Context) (map[string][]int, error) {
+	if !m.Ready() {
+		return nil, ErrManagerNotReady
+	}
+
+	return m.pool.ReclaimLeaked(ctx), nil
+}
+
+// ReclaimExpiredTestDatabases reclaims the test DBs whose soft reservation expired without being returned or heartbeated
+// (see pool.HashPool.ReclaimExpired). Returns the IDs of the reclaimed test DBs by hash.
+func (m Manager) ReclaimExpiredTestDatabases(ctx context.Context) (map[string][]int, error) {
+	if !m.Ready() {
+		return nil, ErrManagerNotReady
+	}
+
+	return m.pool.ReclaimExpired(ctx), nil
+}
+
+// RecreateStaleDirtyTestDatabases force-recreates the dirty test DBs not cleaned within their DirtyMaxAge
+// (see pool.HashPool.RecreateStaleDirty). Returns the IDs of the force-recreated test DBs by hash.
+func (m Manager) RecreateStaleDirtyTestDatabases(ctx context.Context) (map[string][]int, error) {
+	if !m.Ready() {
+		return nil, ErrManagerNotReady
+	}
+
+	return m.pool.RecreateStaleDirty(ctx), nil
+}
+
+func (m *Manager) startLeakSweeper() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	m.stopLeakSweeper = func() {
+		cancel()
+		<-done
+	}
+
+	log := m.getManagerLogger(ctx, "leakSweeper")
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(m.config.LeakSweepInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				reclaimed, err := m.ReclaimLeakedTestDatabases(ctx)
+				if err != nil {
+					log.Error().Err(err).Msg("failed to reclaim leaked test databases")
+					continue
+				}
+
+				for hash, ids := range reclaimed {
+					log.Warn().Str("hash", hash).Ints("ids", ids).Msg("reclaimed leaked test databases")
+				}
+
+				expired, err := m.ReclaimExpiredTestDatabases(ctx)
+				if err != nil {
+					log.Error().Err(err).Msg("failed to reclaim expired test database reservations")
+					continue
+				}
+
+				for hash, ids := range expired {
+					log.Warn().Str("hash", hash).Ints("ids", ids).Msg("reclaimed test databases with expired reservations")
+				}
+
+				stale, err := m.RecreateStaleDirtyTestDatabases(ctx)
+				if err != nil {
+					log.Error().Err(err).Msg("failed to recreate stale dirty test databases")
+					continue
+				}
+
+				for hash, ids := range stale {
+					log.Warn().Str("hash", hash).Ints("ids", ids).Msg("force-recreating stale dirty test databases")
+				}
+			}
+		}
+	}()
+}
+
+// RebalanceTestDatabases redistributes the test DBs across all pools by their recent demand under the
+// PoolConfig.MaxTotalDBs, dropping idle ready test DBs of over-provisioned pools (see pool.PoolCollection.Rebalance).
+func (m Manager) RebalanceTestDatabases(ctx context.Context) error {
+	if !m.Ready() {
+		return ErrManagerNotReady
+	}
+
+	return m.pool.Rebalance(ctx, m.dropTestPoolDB)
+}
+
+func (m *Manager) startRebalancer() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	m.stopRebalancer = func() {
+		cancel()
+		<-done
+	}
+
+	log := m.getManagerLogger(ctx, "rebalancer")
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(m.config.RebalanceInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := m.RebalanceTestDatabases(ctx); err != nil {
+					log.Error().Err(err).Msg("failed to rebalance test databases")
+				}
+			}
+		}
+	}()
+}
+
+// initHashPool inits the pool of the given template, deriving its per hash pool config from the given config of the template.
+// The config is passed by the caller, as the template may be locked already (e.g. while finalizing it).
+func (m Manager) initHashPool(ctx context.Context, template *templates.Template, templateConfig templates.TemplateConfig) {
+	owner := m.config.TestDatabaseOwner
+	if override := templateConfig.TestDatabaseOwner; len(override) > 0 {
+		owner = override
+	}
+
+	connectionLimit := m.config.TestDatabaseConnectionLimit
+	if override := templateConfig.ConnectionLimit; override != 0 {
+		connectionLimit = override
+	}
+
+	cfg := m.hashPoolConfig(ctx, template, templateConfig)
+	cfg.ConnectionLimit = connectionLimit
+
+	m.pool.InitHashPoolWithConfig(ctx, cfg, template.Database, func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		return m.recreateTestPoolDB(ctx, testDB, templateName, owner, connectionLimit)
+	})
+}
+
+// hashPoolConfig derives the pool config for the given template from the manager defaults.
+// Test DBs of tiny templates (see TestDatabaseInlineRecreateMaxTemplateSize) are recreated inline.
+func (m Manager) hashPoolConfig(ctx context.Context, template *templates.Template, templateConfig templates.TemplateConfig) pool.PoolConfig {
+
+	log := m.getManagerLogger(ctx, "hashPoolConfig").With().Str("hash", template.TemplateHash).Logger()
+
+	// the defaults may have been changed at runtime (e.g. SetMaxPoolSize)
+	cfg := m.pool.DefaultConfig()
+
+	switch templateConfig.CleanStrategy {
+	case templates.CleanStrategyTruncate:
+		resetSQL := templateConfig.ResetSQL
+		cfg.ResetDB = func(ctx context.Context, testDB db.TestDatabase) error {
+			return m.resetTestPoolDB(ctx, testDB, resetSQL)
+		}
+	case templates.CleanStrategySchema:
+		// executed as a single (implicit) transaction, a failing reset SQL leaves the test DB untouched for the recopy fallback
+		resetSQL := dropSchemasQuery + ";\n" + templateConfig.ResetSQL
+		cfg.ResetDB = func(ctx context.Context, testDB db.TestDatabase) error {
+			return m.resetTestPoolDB(ctx, testDB, resetSQL)
+		}
+	}
+
+	if override := templateConfig.LeakWarnTimeout; override > 0 {
+		cfg.LeakWarnTimeout = override
+	}
+	if override := templateConfig.LeakReclaimTimeout; override > 0 {
+		cfg.LeakReclaimTimeout = override
+	}
+	if override := templateConfig.MinReady; override > 0 {
+		cfg.MinReady = override
+	}
+
+	if sources := templateConfig.Sources; len(sources) > 0 {
+		weights := map[string]int{template.Config.Database: 1}
+		for name, weight := range sources {
+			weights[name] = weight
+		}
+		cfg.SelectTemplate = pool.WeightedRoundRobin(weights)
+	}
+
+	if m.config.TestDatabaseVerifyClean {
+		// counted before the pool exists, connections to the template would block copying it
+		if rowCounts, err := m.countTableRows(ctx, template.Config.Database); err != nil {
+			log.Warn().Err(err).Msg("unable to count the rows of the template, not verifying returned test databases")
+		} else {
+			cfg.VerifyClean = func(ctx context.Context, testDB db.TestDatabase) (bool, error) {
+				return m.verifyTestPoolDB(ctx, testDB, rowCounts)
+			}
+		}
+	}
+
+	maxSize := m.config.TestDatabaseInlineRecreateMaxTemplateSize
+	if override := templateConfig.InlineRecreateMaxSize; override > 0 {
+		maxSize = override
+	}
+
+	if maxSize <= 0 {
+		return cfg
+	}
+
+	size, err := m.getDatabaseSize(ctx, template.Config.Database)
+	if err != nil {
+		log.Warn().Err(err).Msg("unable to determine template size, keeping background recreation")
+		return cfg
+	}
+
+	cfg.RecreateInline = size <= maxSize
+	log.Debug().Int64("size", size).Int64("maxSize", maxSize).Bool("recreateInline", cfg.RecreateInline).Msg("derived pool config")
+
+	return cfg
+}
+
+func (m Manager) getDatabaseSize(ctx context.Context, dbName string) (int64, error) {
+	var size int64
+
+	if err := m.db.QueryRowContext(ctx, "SELECT pg_database_size($1)", dbName).Scan(&size); err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
 func (m Manager) checkDatabaseExists(ctx context.Context, dbName string) (bool, error) {
 	var exists bool
 
@@ -502,7 +1672,7 @@ func (m Manager) checkDatabaseConnected(ctx context.Context, dbName string) (boo
 			return false, nil
 		}
 
-		return false, err
+		return false, mapPostgresError(ctx, err)
 	}
 
 	if countConnected > 0 {
@@ -512,21 +1682,51 @@ func (m Manager) checkDatabaseConnected(ctx context.Context, dbName string) (boo
 	return false, nil
 }
 
-func (m Manager) createDatabase(ctx context.Context, dbName string, owner string, template string) error {
+func (m Manager) createDatabase(ctx context.Context, dbName string, owner string, template string, locale DatabaseLocale, connectionLimit int) error {
 
 	defer trace.StartRegion(ctx, "create_db").End()
 
+	options := locale.createDatabaseOptions() + connectionLimitOption(connectionLimit)
+
 	log := m.getManagerLogger(ctx, "createDatabase")
-	log.Trace().Msgf("CREATE DATABASE %s WITH OWNER %s TEMPLATE %s\n", pq.QuoteIdentifier(dbName), pq.QuoteIdentifier(owner), pq.QuoteIdentifier(template))
+	log.Trace().Msgf("CREATE DATABASE %s WITH OWNER %s TEMPLATE %s%s\n", pq.QuoteIdentifier(dbName), pq.QuoteIdentifier(owner), pq.QuoteIdentifier(template), options)
 
-	if _, err := m.db.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE %s WITH OWNER %s TEMPLATE %s", pq.QuoteIdentifier(dbName), pq.QuoteIdentifier(owner), pq.QuoteIdentifier(template))); err != nil {
-		return err
+	if _, err := m.db.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE %s WITH OWNER %s TEMPLATE %s%s", pq.QuoteIdentifier(dbName), pq.QuoteIdentifier(owner), pq.QuoteIdentifier(template), options)); err != nil {
+		// the template has been dropped out-of-band, the pool is marked as broken instead of retrying the copy
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "3D000" { // invalid_catalog_name
+			return fmt.Errorf("%w: %v", pool.ErrTemplateMissing, err)
+		}
+
+		return mapPostgresError(ctx, err)
 	}
 
 	return nil
 }
 
-func (m Manager) recreateTestPoolDB(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+// connectionLimitOption returns the CONNECTION LIMIT option to append to CREATE DATABASE (empty if unlimited, i.e. <= 0).
+// The limit is set while creating the database, thus no connection can slip in before it applies.
+func connectionLimitOption(connectionLimit int) string {
+	if connectionLimit <= 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(" CONNECTION LIMIT %d", connectionLimit)
+}
+
+// recreateTestPoolDB drops the test DB and creates it again from the template, owned by the given role and limited to the given number of connections (<= 0 unlimited).
+// If TestDatabaseAdvisoryLock is configured, this is serialized with other IntegreSQL instances recreating the same test DB (see withAdvisoryLock).
+func (m Manager) recreateTestPoolDB(ctx context.Context, testDB db.TestDatabase, templateName string, owner string, connectionLimit int) error {
+	if !m.config.TestDatabaseAdvisoryLock {
+		return m.dropAndRecreateTestPoolDB(ctx, testDB, templateName, owner, connectionLimit)
+	}
+
+	return m.withAdvisoryLock(ctx, testDB.Database.Config.Database, func() error {
+		return m.dropAndRecreateTestPoolDB(ctx, testDB, templateName, owner, connectionLimit)
+	})
+}
+
+func (m Manager) dropAndRecreateTestPoolDB(ctx context.Context, testDB db.TestDatabase, templateName string, owner string, connectionLimit int) error {
 
 	connected, err := m.checkDatabaseConnected(ctx, testDB.Database.Config.Database)
 
@@ -538,13 +1738,331 @@ func (m Manager) recreateTestPoolDB(ctx context.Context, testDB db.TestDatabase,
 		return pool.ErrTestDBInUse
 	}
 
-	return m.dropAndCreateDatabase(ctx, testDB.Database.Config.Database, m.config.TestDatabaseOwner, templateName)
+	// the encoding and locale are inherited from the template
+	return m.dropAndCreateDatabase(ctx, testDB.Database.Config.Database, owner, templateName, DatabaseLocale{}, connectionLimit)
+}
+
+// advisoryLockKey derives the key of the advisory lock guarding the database with the given name (see withAdvisoryLock).
+// The name already includes the prefixes, the template hash and the ID of a test DB.
+func advisoryLockKey(dbName string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("integresql:" + dbName))
+
+	return int64(h.Sum64())
+}
+
+// withAdvisoryLock runs fn while holding the session-level PostgreSQL advisory lock of the database with the given name,
+// thus IntegreSQL instances sharing the server don't create the same database concurrently. Waiting for the lock respects
+// the configured LockTimeout (ErrTestDBTimeout, thus retried by the pool). The lock is released as soon as fn returns.
+func (m Manager) withAdvisoryLock(ctx context.Context, dbName string, fn func() error) error {
+
+	defer trace.StartRegion(ctx, "advisory_lock").End()
+
+	// session-level locks are bound to the connection, thus pin one until released
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return mapPostgresError(ctx, err)
+	}
+	defer conn.Close()
+
+	key := advisoryLockKey(dbName)
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", key); err != nil {
+		return mapPostgresError(ctx, err)
+	}
+
+	defer func() {
+		// the ctx may already be done, the lock is released anyways once the connection is closed
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key); err != nil {
+			log := m.getManagerLogger(ctx, "withAdvisoryLock")
+			log.Warn().Err(err).Str("dbName", dbName).Msg("failed to release advisory lock")
+		}
+	}()
+
+	return fn()
+}
+
+// checkTemplateOptions validates the given options, including the existence of the test database owner (if any).
+func (m Manager) checkTemplateOptions(ctx context.Context, opts TemplateOptions) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+
+	if err := opts.validateLeakTimeouts(); err != nil {
+		return err
+	}
+
+	if err := opts.validateConnectionLimit(); err != nil {
+		return err
+	}
+
+	if err := opts.validateMinReady(); err != nil {
+		return err
+	}
+
+	if len(opts.TestDatabaseOwner) == 0 {
+		return nil
+	}
+
+	exists, err := m.roleExists(ctx, opts.TestDatabaseOwner)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return ErrUnknownTestDatabaseOwner
+	}
+
+	return nil
+}
+
+// checkExternalTemplate checks that the database with the given name exists and may be copied by the manager role:
+// PostgreSQL allows copying databases marked as datistemplate, otherwise only their owner (or a superuser) may copy them.
+func (m Manager) checkExternalTemplate(ctx context.Context, dbName string) error {
+	var usable bool
+
+	if err := m.db.QueryRowContext(ctx, "SELECT datistemplate OR pg_has_role(datdba, 'MEMBER') FROM pg_database WHERE datname = $1", dbName).Scan(&usable); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: database %q does not exist", ErrInvalidExternalTemplate, dbName)
+		}
+
+		return mapPostgresError(ctx, err)
+	}
+
+	if !usable {
+		return fmt.Errorf("%w: database %q is neither marked datistemplate nor owned by the manager role", ErrInvalidExternalTemplate, dbName)
+	}
+
+	return nil
+}
+
+// checkFingerprint checks the given fingerprint of the template content against the one stored for the already existing template of the hash (if any).
+func (m Manager) checkFingerprint(ctx context.Context, hash string, fingerprint string) error {
+	template, found := m.templates.Get(ctx, hash)
+	if !found || template.GetConfig(ctx).MatchesFingerprint(fingerprint) {
+		return nil
+	}
+
+	return ErrHashMismatch
+}
+
+// checkTemplateSources checks that the additional template sources of the template DB with the given name may be copied by the manager role (see checkExternalTemplate).
+// Whether their content is actually identical to the template DB is the responsibility of the client.
+func (m Manager) checkTemplateSources(ctx context.Context, templateName string, sources map[string]int) error {
+	for name, weight := range sources {
+		if weight < 0 {
+			return fmt.Errorf("%w: negative weight %d of %q", ErrInvalidTemplateSource, weight, name)
+		}
+
+		// the template DB itself may be listed to adjust its weight, it is created by the manager
+		if name == templateName {
+			continue
+		}
+
+		if err := m.checkExternalTemplate(ctx, name); err != nil {
+			if errors.Is(err, ErrInvalidExternalTemplate) {
+				return fmt.Errorf("%w: %w", ErrInvalidTemplateSource, err)
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m Manager) roleExists(ctx context.Context, role string) (bool, error) {
+	var exists bool
+	if err := m.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM pg_roles WHERE rolname = $1)", role).Scan(&exists); err != nil {
+		return false, mapPostgresError(ctx, err)
+	}
+
+	return exists, nil
+}
+
+// dropSchemasQuery drops all user schemas (with all their objects) of the current database and recreates an empty public schema
+// owned by the database owner, thus the database itself is kept (see templates.CleanStrategySchema).
+const dropSchemasQuery = `DO $$
+DECLARE
+	schema_name name;
+BEGIN
+	FOR schema_name IN SELECT nspname FROM pg_namespace WHERE nspname <> 'information_schema' AND nspname NOT LIKE 'pg\_%' LOOP
+		EXECUTE format('DROP SCHEMA %I CASCADE', schema_name);
+	END LOOP;
+	EXECUTE format('CREATE SCHEMA public AUTHORIZATION %I', (SELECT pg_get_userbyid(datdba) FROM pg_database WHERE datname = current_database()));
+END
+$$`
+
+// resetTestPoolDB cleans the dirty test DB in place by executing the given reset SQL within it
+// (see templates.CleanStrategyTruncate and templates.CleanStrategySchema).
+func (m Manager) resetTestPoolDB(ctx context.Context, testDB db.TestDatabase, resetSQL string) error {
+
+	defer trace.StartRegion(ctx, "reset_db").End()
+
+	connected, err := m.checkDatabaseConnected(ctx, testDB.Database.Config.Database)
+	if err != nil {
+		return err
+	}
+
+	if connected {
+		return pool.ErrTestDBInUse
+	}
+
+	config := m.connectionConfig()
+	config.Database = testDB.Database.Config.Database
+
+	testPoolDB, err := sql.Open("postgres", config.ConnectionString())
+	if err != nil {
+		return err
+	}
+	defer testPoolDB.Close()
+
+	if _, err := testPoolDB.ExecContext(ctx, resetSQL); err != nil {
+		return mapPostgresError(ctx, err)
+	}
+
+	return nil
+}
+
+// countTableRowsQuery counts the rows of all user tables (exactly, contrary to the statistics of pg_stat_user_tables).
+const countTableRowsQuery = `SELECT format('%I.%I', table_schema, table_name),
+	(xpath('/row/c/text()', query_to_xml(format('SELECT count(*) AS c FROM %I.%I', table_schema, table_name), false, true, '')))[1]::text::bigint
+FROM information_schema.tables
+WHERE table_type = 'BASE TABLE' AND table_schema NOT IN ('pg_catalog', 'information_schema')`
+
+// countTableRows returns the number of rows of each user table (schema.table) within the given database.
+func (m Manager) countTableRows(ctx context.Context, dbName string) (map[string]int64, error) {
+
+	config := m.connectionConfig()
+	config.Database = dbName
+
+	conn, err := sql.Open("postgres", config.ConnectionString())
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	rows, err := conn.QueryContext(ctx, countTableRowsQuery)
+	if err != nil {
+		return nil, mapPostgresError(ctx, err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var table string
+		var count int64
+		if err := rows.Scan(&table, &count); err != nil {
+			return nil, err
+		}
+		counts[table] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, mapPostgresError(ctx, err)
+	}
+
+	return counts, nil
+}
+
+// verifyTestPoolDB checks that the test DB returned without cleaning still holds the given row counts of its template (see TestDatabaseVerifyClean).
+// Changes keeping the row counts (e.g. updates) are not detected.
+func (m Manager) verifyTestPoolDB(ctx context.Context, testDB db.TestDatabase, templateRowCounts map[string]int64) (bool, error) {
+
+	defer trace.StartRegion(ctx, "verify_db").End()
+
+	counts, err := m.countTableRows(ctx, testDB.Database.Config.Database)
+	if err != nil {
+		return false, err
+	}
+
+	return equalRowCounts(counts, templateRowCounts), nil
+}
+
+func equalRowCounts(a map[string]int64, b map[string]int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for table, count := range a {
+		if other, ok := b[table]; !ok || other != count {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkTestPoolStorage refuses creating another copy of the given template if the size of all databases would exceed the configured storage limit.
+// PostgreSQL does not expose the free disk space via SQL, thus the limit is based on the size of the databases (pg_database_size).
+func (m Manager) checkTestPoolStorage(ctx context.Context, templateDB db.Database) error {
+
+	var used, templateSize int64
+	if err := m.db.QueryRowContext(ctx, "SELECT (SELECT COALESCE(sum(pg_database_size(datname)), 0) FROM pg_database)::bigint, pg_database_size($1)", templateDB.Config.Database).Scan(&used, &templateSize); err != nil {
+		return mapPostgresError(ctx, err)
+	}
+
+	if used+templateSize > m.config.TestDatabaseStorageLimit {
+		return fmt.Errorf("%w: %d bytes used, a new test database requires %d bytes, limit is %d bytes", pool.ErrInsufficientStorage, used, templateSize, m.config.TestDatabaseStorageLimit)
+	}
+
+	return nil
+}
+
+// pingTestPoolDB checks that the test DB still exists, without connecting to it (which would block its recreation).
+func (m Manager) pingTestPoolDB(ctx context.Context, testDB db.TestDatabase) error {
+
+	exists, err := m.checkDatabaseExists(ctx, testDB.Database.Config.Database)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return fmt.Errorf("%w: %w", pool.ErrTestDBDead, ErrTestNotFound)
+	}
+
+	return nil
 }
 
 func (m Manager) dropTestPoolDB(ctx context.Context, testDB db.TestDatabase) error {
+	if m.config.TestDatabaseForceDrop {
+		return m.forceDropTestPoolDB(ctx, testDB)
+	}
+
 	return m.dropDatabase(ctx, testDB.Config.Database)
 }
 
+// forceDropTestPoolDB drops the given test DB, terminating all remaining connections to it regardless of TestDatabaseForceDrop.
+func (m Manager) forceDropTestPoolDB(ctx context.Context, testDB db.TestDatabase) error {
+	if m.serverInfo.DropDatabaseForce {
+		return m.dropDatabaseForce(ctx, testDB.Config.Database)
+	}
+
+	if err := m.terminateDatabaseConnections(ctx, testDB.Config.Database); err != nil {
+		return err
+	}
+
+	return m.dropDatabase(ctx, testDB.Config.Database)
+}
+
+// terminateDatabaseConnections terminates all backends connected to the given database (except our own).
+// Works for all PostgreSQL versions, contrary to DROP DATABASE ... WITH (FORCE) (PG13+, see dropDatabaseForce).
+func (m Manager) terminateDatabaseConnections(ctx context.Context, dbName string) error {
+
+	log := m.getManagerLogger(ctx, "terminateDatabaseConnections")
+
+	var countTerminated int
+	if err := m.db.QueryRowContext(ctx, "SELECT count(pg_terminate_backend(pid)) FROM pg_stat_activity WHERE datname = $1 AND pid <> pg_backend_pid()", dbName).Scan(&countTerminated); err != nil {
+		return mapPostgresError(ctx, err)
+	}
+
+	if countTerminated > 0 {
+		log.Warn().Str("dbName", dbName).Int("terminated", countTerminated).Msg("terminated connections before dropping")
+	}
+
+	return nil
+}
+
 func (m Manager) dropDatabase(ctx context.Context, dbName string) error {
 
 	defer trace.StartRegion(ctx, "drop_db").End()
@@ -557,13 +2075,28 @@ func (m Manager) dropDatabase(ctx context.Context, dbName string) error {
 			return pool.ErrTestDBInUse
 		}
 
-		return err
+		return mapPostgresError(ctx, err)
 	}
 
 	return nil
 }
 
-func (m Manager) dropAndCreateDatabase(ctx context.Context, dbName string, owner string, template string) error {
+// dropDatabaseForce drops the given database, terminating all remaining connections to it within the same statement (PG13+).
+func (m Manager) dropDatabaseForce(ctx context.Context, dbName string) error {
+
+	defer trace.StartRegion(ctx, "drop_db").End()
+
+	log := m.getManagerLogger(ctx, "dropDatabaseForce")
+	log.Trace().Msgf("DROP DATABASE IF EXISTS %s WITH (FORCE)\n", pq.QuoteIdentifier(dbName))
+
+	if _, err := m.db.ExecContext(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS %s WITH (FORCE)", pq.QuoteIdentifier(dbName))); err != nil {
+		return mapPostgresError(ctx, err)
+	}
+
+	return nil
+}
+
+func (m Manager) dropAndCreateDatabase(ctx context.Context, dbName string, owner string, template string, locale DatabaseLocale, connectionLimit int) error {
 	if !m.Ready() {
 		return ErrManagerNotReady
 	}
@@ -572,7 +2105,7 @@ func (m Manager) dropAndCreateDatabase(ctx context.Context, dbName string, owner
 		return err
 	}
 
-	return m.createDatabase(ctx, dbName, owner, template)
+	return m.createDatabase(ctx, dbName, owner, template, locale, connectionLimit)
 }
 
 func (m Manager) makeTemplateDatabaseName(hash string) string {
@@ -582,3 +2115,23 @@ func (m Manager) makeTemplateDatabaseName(hash string) string {
 func (m Manager) getManagerLogger(ctx context.Context, managerFunction string) zerolog.Logger {
 	return util.LogFromContext(ctx).With().Str("managerFn", managerFunction).Logger()
 }
+
+// mapPostgresError maps statements aborted by statement_timeout or lock_timeout onto pool.ErrTestDBTimeout, so they may be retried,
+// and rejected connections due to max_connections being exceeded onto pool.ErrTooManyConnections, so the pool backs off.
+// Statements canceled due to the context itself are left as is.
+func mapPostgresError(ctx context.Context, err error) error {
+	var pqErr *pq.Error
+	if ctx.Err() != nil || !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch pqErr.Code {
+	case "57014", // query_canceled
+		"55P03": // lock_not_available
+		return fmt.Errorf("%w: %v", pool.ErrTestDBTimeout, err)
+	case "53300": // too_many_connections
+		return fmt.Errorf("%w: %v", pool.ErrTooManyConnections, err)
+	}
+
+	return err
+}
@@ -13,13 +13,39 @@ import (
 type ManagerConfig struct { //nolint:revive
 	ManagerDatabaseConfig    db.DatabaseConfig `json:"-"` // sensitive
 	TemplateDatabaseTemplate string
+	DatabaseLocale           DatabaseLocale // Encoding and locale of the template DBs (inherited by their test DBs), empty values default to the ones of the TemplateDatabaseTemplate
 
-	DatabasePrefix            string
-	TemplateDatabasePrefix    string
-	TestDatabaseOwner         string
-	TestDatabaseOwnerPassword string        `json:"-"` // sensitive
-	TemplateFinalizeTimeout   time.Duration // Time to wait for a template to transition into the 'finalized' state
-	TestDatabaseGetTimeout    time.Duration // Time to wait for a ready database
+	ReplicaHost string // Optional host of a streaming replica of the PostgreSQL server, returned as additional read-only config with each test DB (empty disables)
+	ReplicaPort int
+
+	DatabasePrefix                     string
+	TemplateDatabasePrefix             string
+	TestDatabaseOwner                  string
+	TestDatabaseOwnerPassword          string        `json:"-"` // sensitive
+	TemplateFinalizeTimeout            time.Duration // Time to wait for a template to transition into the 'finalized' state
+	TemplateMaxConcurrentFinalizations int           // Maximal number of templates finalized at the same time, further finalizations are queued (0 unlimited)
+	TestDatabaseGetTimeout             time.Duration // Time to wait for a ready database
+	StatementTimeout                   time.Duration // Postgres statement_timeout of the manager connections, aborts stuck CREATE/DROP DATABASE statements (0 disables)
+	LockTimeout                        time.Duration // Postgres lock_timeout of the manager connections, aborts statements waiting for a lock (0 disables)
+
+	TestDatabaseInlineRecreateMaxTemplateSize int64 // Templates up to this size (bytes) recreate their test DBs inline instead of in background workers (0 disables)
+	TestDatabaseLivenessCheck                 bool  // Check that a test DB still exists in PostgreSQL before handing it out (self-healing against external deletions)
+	TestDatabaseVerifyClean                   bool  // Verify test DBs returned without cleaning still hold the row counts of their template, recreating them otherwise (costs a query per return)
+	TestDatabaseForceDrop                     bool  // Terminate all remaining connections to a test DB before dropping it while removing a pool (destructive, for clients not disconnecting cleanly)
+	TestDatabaseAdvisoryLock                  bool  // Serialize (re)creating each test DB across IntegreSQL instances sharing the PostgreSQL server (e.g. during a rolling deploy) via advisory locks
+	TestDatabaseConnectionLimit               int   // Maximal number of concurrent connections to each test DB (CONNECTION LIMIT), capping the blast radius of a runaway test (-1 or 0 unlimited, superusers are exempt)
+	TestDatabaseStorageLimit                  int64 // Refuse to create further test DBs if the size of all databases plus another copy of the template would exceed this limit (bytes, 0 disables)
+
+	PoolIdleTTL           time.Duration // Pools not used by any client for this duration are removed with all their test DBs (0 disables), the template itself is kept
+	PoolIdleSweepInterval time.Duration // Interval to check for idle pools
+
+	LeakSweepInterval time.Duration // Interval to check for leaked test DBs and expired reservations (see PoolConfig.LeakWarnTimeout, LeakReclaimTimeout and GetOptions.ReservationTTL, 0 disables)
+
+	RebalanceInterval time.Duration // Interval to redistribute the test DBs across all pools by demand (see PoolConfig.MaxTotalDBs, 0 disables)
+
+	LivenessCheckInterval    time.Duration // Interval to probe the pool locks for a suspected deadlock (see Live, 0 disables)
+	LivenessLockTimeout      time.Duration // Time to wait for each lock while probing
+	LivenessFailureThreshold int           // Number of consecutive failed probes until the process is reported as not live
 
 	PoolConfig pool.PoolConfig
 }
@@ -45,6 +71,17 @@ func DefaultManagerConfigFromEnv() ManagerConfig {
 
 		TemplateDatabaseTemplate: util.GetEnv("INTEGRESQL_ROOT_TEMPLATE", "template0"),
 
+		// only template0 allows to deviate from its encoding and locale
+		DatabaseLocale: DatabaseLocale{
+			Encoding: util.GetEnv("INTEGRESQL_DB_ENCODING", ""),
+			Collate:  util.GetEnv("INTEGRESQL_DB_LC_COLLATE", ""),
+			Ctype:    util.GetEnv("INTEGRESQL_DB_LC_CTYPE", ""),
+		},
+
+		// the replica is expected to be a streaming replica of the whole server, thus it shares the credentials of the primary
+		ReplicaHost: util.GetEnv("INTEGRESQL_PG_REPLICA_HOST", ""),
+		ReplicaPort: util.GetEnvAsInt("INTEGRESQL_PG_REPLICA_PORT", util.GetEnvAsInt("INTEGRESQL_PGPORT", util.GetEnvAsInt("PGPORT", 5432))),
+
 		DatabasePrefix: util.GetEnv("INTEGRESQL_DB_PREFIX", "integresql"),
 
 		// DatabasePrefix_TemplateDatabasePrefix_HASH
@@ -59,14 +96,82 @@ func DefaultManagerConfigFromEnv() ManagerConfig {
 		TemplateFinalizeTimeout: time.Millisecond * time.Duration(util.GetEnvAsInt("INTEGRESQL_TEMPLATE_FINALIZE_TIMEOUT_MS", util.GetEnvAsInt("INTEGRESQL_ECHO_REQUEST_TIMEOUT_MS", 60*1000 /*1 min*/))),
 		TestDatabaseGetTimeout:  time.Millisecond * time.Duration(util.GetEnvAsInt("INTEGRESQL_TEST_DB_GET_TIMEOUT_MS", util.GetEnvAsInt("INTEGRESQL_ECHO_REQUEST_TIMEOUT_MS", 60*1000 /*1 min*/))),
 
+		TemplateMaxConcurrentFinalizations: util.GetEnvAsInt("INTEGRESQL_TEMPLATE_MAX_CONCURRENT_FINALIZATIONS", 0 /*unlimited*/),
+
+		StatementTimeout: time.Millisecond * time.Duration(util.GetEnvAsInt("INTEGRESQL_PG_STATEMENT_TIMEOUT_MS", 0 /*disabled*/)),
+		LockTimeout:      time.Millisecond * time.Duration(util.GetEnvAsInt("INTEGRESQL_PG_LOCK_TIMEOUT_MS", 0 /*disabled*/)),
+
+		TestDatabaseInlineRecreateMaxTemplateSize: int64(util.GetEnvAsInt("INTEGRESQL_TEST_DB_INLINE_RECREATE_MAX_TEMPLATE_SIZE", 0 /*disabled*/)),
+		TestDatabaseLivenessCheck:                 util.GetEnvAsBool("INTEGRESQL_TEST_DB_LIVENESS_CHECK", false),
+		TestDatabaseVerifyClean:                   util.GetEnvAsBool("INTEGRESQL_TEST_DB_VERIFY_CLEAN", false),
+		TestDatabaseForceDrop:                     util.GetEnvAsBool("INTEGRESQL_TEST_DB_FORCE_DROP", false),
+		TestDatabaseAdvisoryLock:                  util.GetEnvAsBool("INTEGRESQL_TEST_DB_ADVISORY_LOCK", false),
+		TestDatabaseStorageLimit:                  int64(util.GetEnvAsInt("INTEGRESQL_TEST_DB_STORAGE_LIMIT", 0 /*disabled*/)),
+		TestDatabaseConnectionLimit:               util.GetEnvAsInt("INTEGRESQL_TEST_DB_CONNECTION_LIMIT", -1 /*unlimited*/),
+
+		PoolIdleTTL:           time.Millisecond * time.Duration(util.GetEnvAsInt("INTEGRESQL_POOL_IDLE_TTL_MS", 0 /*disabled*/)),
+		PoolIdleSweepInterval: time.Millisecond * time.Duration(util.GetEnvAsInt("INTEGRESQL_POOL_IDLE_SWEEP_INTERVAL_MS", 60*1000 /*1 min*/)),
+
+		LeakSweepInterval: time.Millisecond * time.Duration(util.GetEnvAsInt("INTEGRESQL_TEST_DB_LEAK_SWEEP_INTERVAL_MS", 10*1000 /*10 sec*/)),
+
+		RebalanceInterval: time.Millisecond * time.Duration(util.GetEnvAsInt("INTEGRESQL_POOL_REBALANCE_INTERVAL_MS", 30*1000 /*30 sec*/)),
+
+		LivenessCheckInterval:    time.Millisecond * time.Duration(util.GetEnvAsInt("INTEGRESQL_LIVENESS_CHECK_INTERVAL_MS", 10*1000 /*10 sec*/)),
+		LivenessLockTimeout:      time.Millisecond * time.Duration(util.GetEnvAsInt("INTEGRESQL_LIVENESS_LOCK_TIMEOUT_MS", 1000 /*1 sec*/)),
+		LivenessFailureThreshold: util.GetEnvAsInt("INTEGRESQL_LIVENESS_FAILURE_THRESHOLD", 3),
+
 		PoolConfig: pool.PoolConfig{
 			InitialPoolSize:                   util.GetEnvAsInt("INTEGRESQL_TEST_INITIAL_POOL_SIZE", runtime.NumCPU()), // previously default 10
 			MaxPoolSize:                       util.GetEnvAsInt("INTEGRESQL_TEST_MAX_POOL_SIZE", runtime.NumCPU()*4),   // previously default 500
 			TestDBNamePrefix:                  util.GetEnv("INTEGRESQL_TEST_DB_PREFIX", "test"),                        // DatabasePrefix_TestDBNamePrefix_HASH_ID
+			InstanceID:                        util.GetEnv("INTEGRESQL_TEST_DB_INSTANCE_ID", "" /*disabled, "timestamp" embeds the server start time*/),
 			MaxParallelTasks:                  util.GetEnvAsInt("INTEGRESQL_POOL_MAX_PARALLEL_TASKS", runtime.NumCPU()),
+			MaxParallelRemoves:                util.GetEnvAsInt("INTEGRESQL_POOL_MAX_PARALLEL_REMOVES", runtime.NumCPU()),
+			RemovedHashHistory:                util.GetEnvAsInt("INTEGRESQL_POOL_REMOVED_HASH_HISTORY", 1000),
 			TestDatabaseRetryRecreateSleepMin: time.Millisecond * time.Duration(util.GetEnvAsInt("INTEGRESQL_TEST_DB_RETRY_RECREATE_SLEEP_MIN_MS", 250 /*250 ms*/)),
 			TestDatabaseRetryRecreateSleepMax: time.Millisecond * time.Duration(util.GetEnvAsInt("INTEGRESQL_TEST_DB_RETRY_RECREATE_SLEEP_MAX_MS", 1000*3 /*3 sec*/)),
 			TestDatabaseMinimalLifetime:       time.Millisecond * time.Duration(util.GetEnvAsInt("INTEGRESQL_TEST_DB_MINIMAL_LIFETIME_MS", 250 /*250 ms*/)),
+			DirtyMaxAge:                       time.Millisecond * time.Duration(util.GetEnvAsInt("INTEGRESQL_TEST_DB_DIRTY_MAX_AGE_MS", 0 /*disabled*/)),
+			ReturnCooldown:                    time.Millisecond * time.Duration(util.GetEnvAsInt("INTEGRESQL_TEST_DB_RETURN_COOLDOWN_MS", 0 /*disabled*/)),
+			LenientReturns:                    util.GetEnvAsBool("INTEGRESQL_POOL_LENIENT_RETURNS", false),
+			RejectDirty:                       util.GetEnvAsBool("INTEGRESQL_POOL_REJECT_DIRTY", false),
+			StickyKeyTTL:                      time.Millisecond * time.Duration(util.GetEnvAsInt("INTEGRESQL_POOL_STICKY_KEY_TTL_MS", 5*60*1000 /*5 min*/)),
+			PingDBMaxRetries:                  util.GetEnvAsInt("INTEGRESQL_TEST_DB_LIVENESS_CHECK_MAX_RETRIES", 3),
+			AutoScale:                         util.GetEnvAsBool("INTEGRESQL_POOL_AUTO_SCALE", false),
+			AutoScaleStarvationThreshold:      util.GetEnvAsInt("INTEGRESQL_POOL_AUTO_SCALE_STARVATION_THRESHOLD_PERCENT", 25),
+			AutoScaleWindow:                   util.GetEnvAsInt("INTEGRESQL_POOL_AUTO_SCALE_WINDOW", 20),
+			CleanBatchSize:                    util.GetEnvAsInt("INTEGRESQL_POOL_CLEAN_BATCH_SIZE", 1),
+			SelectionSeed:                     int64(util.GetEnvAsInt("INTEGRESQL_POOL_SELECTION_SEED", 0 /*disabled*/)),
+			SelectionPolicy:                   pool.SelectionPolicy(util.GetEnv("INTEGRESQL_POOL_SELECTION_POLICY", "" /*fifo, random if seeded*/)),
+			RefillWatermark:                   util.GetEnvAsInt("INTEGRESQL_POOL_REFILL_WATERMARK_PERCENT", 0 /*disabled*/),
+			MinReady:                          util.GetEnvAsInt("INTEGRESQL_POOL_MIN_READY", 0 /*disabled*/),
+			MaxConcurrentCopies:               util.GetEnvAsInt("INTEGRESQL_MAX_CONCURRENT_COPIES", 0 /*unlimited*/),
+			MaxTotalDBs:                       util.GetEnvAsInt("INTEGRESQL_MAX_TOTAL_DBS", 0 /*unlimited*/),
+			TooManyConnectionsBackoff:         time.Millisecond * time.Duration(util.GetEnvAsInt("INTEGRESQL_POOL_TOO_MANY_CONNECTIONS_BACKOFF_MS", 1000 /*1 sec*/)),
+			LeakWarnTimeout:                   time.Millisecond * time.Duration(util.GetEnvAsInt("INTEGRESQL_TEST_DB_LEAK_WARN_TIMEOUT_MS", 0 /*disabled*/)),
+			LeakReclaimTimeout:                time.Millisecond * time.Duration(util.GetEnvAsInt("INTEGRESQL_TEST_DB_LEAK_RECLAIM_TIMEOUT_MS", 0 /*disabled*/)),
 		},
 	}
 }
+
+// RedactedManagerConfig is the ManagerConfig including its sensitive fields, with their passwords masked.
+type RedactedManagerConfig struct {
+	ManagerConfig
+
+	ManagerDatabaseConfig     db.DatabaseConfig
+	TestDatabaseOwnerPassword string
+}
+
+// Redacted returns the sanitized view of the config (e.g. for introspection through the admin API).
+func (c ManagerConfig) Redacted() RedactedManagerConfig {
+	c.ManagerDatabaseConfig = c.ManagerDatabaseConfig.Redacted()
+	if len(c.TestDatabaseOwnerPassword) > 0 {
+		c.TestDatabaseOwnerPassword = db.RedactedPassword
+	}
+
+	return RedactedManagerConfig{
+		ManagerConfig:             c,
+		ManagerDatabaseConfig:     c.ManagerDatabaseConfig,
+		TestDatabaseOwnerPassword: c.TestDatabaseOwnerPassword,
+	}
+}
@@ -0,0 +1,208 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionLimitOption(t *testing.T) {
+	assert.Equal(t, "", connectionLimitOption(-1))
+	assert.Equal(t, "", connectionLimitOption(0))
+	assert.Equal(t, " CONNECTION LIMIT 5", connectionLimitOption(5))
+}
+
+func TestTemplateOptionsValidateConnectionLimit(t *testing.T) {
+	assert.NoError(t, TemplateOptions{ConnectionLimit: -1}.validateConnectionLimit())
+	assert.NoError(t, TemplateOptions{}.validateConnectionLimit())
+	assert.NoError(t, TemplateOptions{ConnectionLimit: 3}.validateConnectionLimit())
+	assert.ErrorIs(t, TemplateOptions{ConnectionLimit: -2}.validateConnectionLimit(), ErrInvalidConnectionLimit)
+}
+
+func TestTemplateOptionsValidateMinReady(t *testing.T) {
+	assert.NoError(t, TemplateOptions{}.validateMinReady())
+	assert.NoError(t, TemplateOptions{MinReady: 3}.validateMinReady())
+	assert.ErrorIs(t, TemplateOptions{MinReady: -1}.validateMinReady(), ErrInvalidMinReady)
+}
+
+func TestDefaultConnectionEstimate(t *testing.T) {
+	cfg := DefaultManagerConfigFromEnv()
+	cfg.PoolConfig.MaxPoolSize = 100
+	cfg.PoolConfig.MaxTotalDBs = 0
+	cfg.TestDatabaseConnectionLimit = -1
+
+	m, _ := New(cfg)
+	m.serverInfo.MaxConnections = 200
+
+	// unlimited connections per test DB can't be estimated
+	assert.False(t, m.defaultConnectionEstimate().Exceeded)
+
+	m.config.TestDatabaseConnectionLimit = 3
+	estimate := m.defaultConnectionEstimate()
+	assert.Equal(t, 300, estimate.Connections)
+	assert.True(t, estimate.Exceeded)
+
+	m.config.PoolConfig.MaxTotalDBs = 50
+	estimate = m.defaultConnectionEstimate()
+	assert.Equal(t, 150, estimate.Connections)
+	assert.False(t, estimate.Exceeded)
+}
+
+func TestNewInstanceIDTimestamp(t *testing.T) {
+	cfg := DefaultManagerConfigFromEnv()
+	cfg.PoolConfig.InstanceID = InstanceIDTimestamp
+
+	_, cfg = New(cfg)
+	assert.Regexp(t, `^\d{12}$`, cfg.PoolConfig.InstanceID)
+
+	cfg.PoolConfig.InstanceID = "run-42"
+	_, cfg = New(cfg)
+	assert.Equal(t, "run-42", cfg.PoolConfig.InstanceID)
+}
+
+func TestEqualRowCounts(t *testing.T) {
+	template := map[string]int64{"public.users": 2, "public.posts": 0}
+
+	assert.True(t, equalRowCounts(map[string]int64{"public.users": 2, "public.posts": 0}, template))
+	assert.False(t, equalRowCounts(map[string]int64{"public.users": 2, "public.posts": 1}, template))
+	assert.False(t, equalRowCounts(map[string]int64{"public.users": 2}, template))
+	assert.False(t, equalRowCounts(map[string]int64{"public.users": 2, "public.tags": 0}, template))
+	assert.True(t, equalRowCounts(map[string]int64{}, nil))
+}
+
+func TestAdvisoryLockKey(t *testing.T) {
+	assert.Equal(t, advisoryLockKey("pgtestpool_test_hash_1"), advisoryLockKey("pgtestpool_test_hash_1"))
+	assert.NotEqual(t, advisoryLockKey("pgtestpool_test_hash_1"), advisoryLockKey("pgtestpool_test_hash_2"))
+}
+
+func TestManagerAdvisoryLockSharedServer(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := DefaultManagerConfigFromEnv()
+	cfg.DatabasePrefix = "pgtestpool" // ensure we don't overlap with other pools running concurrently
+	cfg.PoolConfig.InitialPoolSize = 1
+	cfg.TestDatabaseAdvisoryLock = true
+
+	// two instances sharing the same server (and prefixes), e.g. during a rolling deploy
+	m1, cfg := New(cfg)
+	m2, _ := New(cfg)
+
+	for _, m := range []*Manager{m1, m2} {
+		require.NoError(t, m.Initialize(ctx))
+		m := m
+		t.Cleanup(func() { _ = m.Disconnect(ctx, true) })
+	}
+
+	hash := "advisorylockhash"
+
+	template, err := m1.InitializeTemplateDatabase(ctx, hash)
+	require.NoError(t, err)
+	_, err = m1.FinalizeTemplateDatabase(ctx, hash)
+	require.NoError(t, err)
+
+	testDB := db.TestDatabase{
+		ID: 999,
+		Database: db.Database{
+			TemplateHash: hash,
+			Config:       template.Config,
+		},
+	}
+	testDB.Config.Database = m1.pool.MakeDBName(hash, testDB.ID)
+	t.Cleanup(func() { _ = m1.dropDatabase(ctx, testDB.Config.Database) })
+
+	// both instances concurrently (re)create the same test DB, which would collide without the lock
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		m := m1
+		if i%2 == 1 {
+			m = m2
+		}
+
+		wg.Add(1)
+		go func(m *Manager) {
+			defer wg.Done()
+			errs <- m.recreateTestPoolDB(ctx, testDB, template.Config.Database, cfg.TestDatabaseOwner, -1)
+		}(m)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	exists, err := m2.checkDatabaseExists(ctx, testDB.Config.Database)
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestLivenessStateThreshold(t *testing.T) {
+	l := &livenessState{}
+	errStuck := errors.New("stuck")
+
+	assert.NoError(t, l.check(2))
+
+	l.record(errStuck)
+	assert.NoError(t, l.check(2), "a single failed probe may be a long running operation")
+
+	l.record(errStuck)
+	assert.ErrorIs(t, l.check(2), errStuck)
+
+	// any success resets the failures
+	l.record(nil)
+	assert.NoError(t, l.check(2))
+}
+
+func TestFinalizeLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	l := newFinalizeLimiter(1)
+
+	release, err := l.acquire(ctx)
+	require.NoError(t, err)
+
+	// further finalizations are queued until the slot is released...
+	acquired := make(chan func())
+	go func() {
+		release, err := l.acquire(ctx)
+		assert.NoError(t, err)
+		acquired <- release
+	}()
+
+	assert.Eventually(t, func() bool {
+		queued, running := l.stats()
+		return queued == 1 && running == 1
+	}, time.Second, 5*time.Millisecond)
+
+	// ... or they give up
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = l.acquire(timeoutCtx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	release()
+	(<-acquired)()
+
+	queued, running := l.stats()
+	assert.Equal(t, 0, queued)
+	assert.Equal(t, 0, running)
+
+	// unlimited finalizations are only counted
+	l = newFinalizeLimiter(0)
+	release1, err := l.acquire(ctx)
+	require.NoError(t, err)
+	release2, err := l.acquire(ctx)
+	require.NoError(t, err)
+	_, running = l.stats()
+	assert.Equal(t, 2, running)
+	release1()
+	release2()
+}
@@ -3,6 +3,7 @@ package manager_test
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"sync"
@@ -11,6 +12,8 @@ import (
 
 	"github.com/allaboutapps/integresql/pkg/db"
 	"github.com/allaboutapps/integresql/pkg/manager"
+	"github.com/allaboutapps/integresql/pkg/pool"
+	"github.com/allaboutapps/integresql/pkg/templates"
 	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -54,6 +57,75 @@ func TestManagerConnectError(t *testing.T) {
 	}
 }
 
+func TestManagerInitialPoolSizeExceedsMaxPoolSize(t *testing.T) {
+	t.Parallel()
+
+	cfg := manager.DefaultManagerConfigFromEnv()
+	cfg.PoolConfig.MaxPoolSize = 5
+	cfg.PoolConfig.InitialPoolSize = 10
+
+	// clamped (with a logged warning) instead of silently stopping the warm-up at max
+	_, config := testManagerWithConfig(cfg)
+	assert.Equal(t, 5, config.PoolConfig.InitialPoolSize)
+	assert.Equal(t, 5, config.PoolConfig.MaxPoolSize)
+
+	cfg.PoolConfig.InitialPoolSize = 3
+	_, config = testManagerWithConfig(cfg)
+	assert.Equal(t, 3, config.PoolConfig.InitialPoolSize)
+}
+
+func TestManagerConfigRedacted(t *testing.T) {
+	t.Parallel()
+
+	cfg := manager.DefaultManagerConfigFromEnv()
+	cfg.ManagerDatabaseConfig.Password = "manager-secret"
+	cfg.TestDatabaseOwnerPassword = "owner-secret"
+
+	m, _ := testManagerWithConfig(cfg)
+	redacted := m.Config().Redacted()
+
+	assert.Equal(t, db.RedactedPassword, redacted.ManagerDatabaseConfig.Password)
+	assert.Equal(t, db.RedactedPassword, redacted.TestDatabaseOwnerPassword)
+	assert.Equal(t, cfg.ManagerDatabaseConfig.Host, redacted.ManagerDatabaseConfig.Host)
+	assert.Equal(t, cfg.PoolConfig.MaxPoolSize, redacted.PoolConfig.MaxPoolSize)
+
+	b, err := json.Marshal(redacted)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "manager-secret")
+	assert.NotContains(t, string(b), "owner-secret")
+	assert.Contains(t, string(b), `"ManagerDatabaseConfig":{`)
+
+	// the config of the manager itself is left untouched
+	assert.Equal(t, "manager-secret", m.Config().ManagerDatabaseConfig.Password)
+}
+
+func TestManagerServerInfo(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := testManagerFromEnv()
+
+	_, err := m.ServerVersion()
+	assert.ErrorIs(t, err, manager.ErrManagerNotReady)
+
+	if err := m.Connect(ctx); err != nil {
+		t.Fatalf("manager connection failed: %v", err)
+	}
+
+	defer disconnectManager(t, m)
+
+	version, err := m.ServerVersion()
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, version, 90000)
+
+	info, err := m.ServerInfo()
+	require.NoError(t, err)
+	assert.Equal(t, version, info.Version)
+	assert.NotEmpty(t, info.VersionString)
+	assert.Equal(t, version >= 130000, info.DropDatabaseForce)
+	assert.Equal(t, version >= 150000, info.CreateDatabaseStrategy)
+}
+
 func TestManagerReconnect(t *testing.T) {
 	t.Parallel()
 
@@ -305,6 +377,88 @@ func TestManagerGetTestDatabase(t *testing.T) {
 	verifyTestDB(t, test)
 }
 
+func TestManagerGetTestDatabaseWithReplica(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := manager.DefaultManagerConfigFromEnv()
+	// no actual replica available while testing, the primary acts as its own replica
+	cfg.ReplicaHost = cfg.ManagerDatabaseConfig.Host
+	cfg.ReplicaPort = cfg.ManagerDatabaseConfig.Port
+	m, _ := testManagerWithConfig(cfg)
+
+	if err := m.Initialize(ctx); err != nil {
+		t.Fatalf("initializing manager failed: %v", err)
+	}
+
+	defer disconnectManager(t, m)
+
+	hash := "hashinghash"
+
+	template, err := m.InitializeTemplateDatabase(ctx, hash)
+	if err != nil {
+		t.Fatalf("failed to initialize template database: %v", err)
+	}
+
+	populateTemplateDB(t, template)
+
+	if _, err := m.FinalizeTemplateDatabase(ctx, hash); err != nil {
+		t.Fatalf("failed to finalize template database: %v", err)
+	}
+
+	test, err := m.GetTestDatabase(ctx, hash)
+	if err != nil {
+		t.Fatalf("failed to get test database: %v", err)
+	}
+
+	require.NotNil(t, test.Replica)
+	assert.Equal(t, cfg.ReplicaHost, test.Replica.Host)
+	assert.Equal(t, cfg.ReplicaPort, test.Replica.Port)
+	assert.Equal(t, test.Config.Database, test.Replica.Database)
+
+	verifyTestDB(t, db.TestDatabase{Database: db.Database{TemplateHash: test.TemplateHash, Config: *test.Replica}, ID: test.ID})
+}
+
+func TestManagerGetTestDatabaseHyphenUppercasePrefix(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := manager.DefaultManagerConfigFromEnv()
+	cfg.PoolConfig.TestDBNamePrefix = "Team-A"
+	cfg.PoolConfig.InitialPoolSize = 1
+	m, _ := testManagerWithConfig(cfg)
+
+	if err := m.Initialize(ctx); err != nil {
+		t.Fatalf("initializing manager failed: %v", err)
+	}
+
+	defer disconnectManager(t, m)
+
+	hash := "Hashing-HASH"
+
+	template, err := m.InitializeTemplateDatabase(ctx, hash)
+	if err != nil {
+		t.Fatalf("failed to initialize template database: %v", err)
+	}
+
+	populateTemplateDB(t, template)
+
+	if _, err := m.FinalizeTemplateDatabase(ctx, hash); err != nil {
+		t.Fatalf("failed to finalize template database: %v", err)
+	}
+
+	test, err := m.GetTestDatabase(ctx, hash)
+	if err != nil {
+		t.Fatalf("failed to get test database: %v", err)
+	}
+
+	// postgres must not have folded the name
+	assert.Equal(t, "pgtestpool_Team-A_Hashing-HASH_000", test.Config.Database)
+	verifyTestDB(t, test)
+
+	if err := m.RecreateTestDatabase(ctx, hash, test.ID); err != nil {
+		t.Fatalf("failed to recreate test database: %v", err)
+	}
+}
+
 func TestManagerGetTestDatabaseExtendPool(t *testing.T) {
 	ctx := context.Background()
 
@@ -710,6 +864,58 @@ func TestManagerGetAndRecreateTestDatabase(t *testing.T) {
 	assert.NoError(t, m.DiscardTemplateDatabase(ctx, hash))
 }
 
+func TestManagerGetAndRecreateTestDatabaseInline(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := manager.DefaultManagerConfigFromEnv()
+	cfg.PoolConfig.InitialPoolSize = 1
+	cfg.PoolConfig.MaxPoolSize = 1
+	cfg.TestDatabaseGetTimeout = 100 * time.Millisecond
+	m, _ := testManagerWithConfig(cfg)
+
+	if err := m.Initialize(ctx); err != nil {
+		t.Fatalf("initializing manager failed: %v", err)
+	}
+
+	defer disconnectManager(t, m)
+
+	hash := "hashinghash"
+
+	// any template is below this threshold, test DBs are thus recreated inline
+	template, err := m.InitializeTemplateDatabaseWithOptions(ctx, hash, manager.TemplateOptions{InlineRecreateMaxSize: 1 << 40})
+	if err != nil {
+		t.Fatalf("failed to initialize template database: %v", err)
+	}
+
+	populateTemplateDB(t, template)
+
+	if _, err := m.FinalizeTemplateDatabase(ctx, hash); err != nil {
+		t.Fatalf("failed to finalize template database: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		test, err := m.GetTestDatabase(ctx, hash)
+		require.NoError(t, err, i)
+
+		db, err := sql.Open("postgres", test.Config.ConnectionString())
+		require.NoError(t, err)
+
+		var res int
+		assert.NoError(t, db.QueryRowContext(ctx, "SELECT COUNT(*) FROM pilots WHERE name = 'Anna'").Scan(&res))
+		assert.Equal(t, 0, res, i)
+
+		_, err = db.ExecContext(ctx, `INSERT INTO pilots (id, "name", created_at, updated_at) VALUES ('844a1a87-5ef7-4309-8814-0f1054751156', 'Anna', '2023-03-23 09:44:00.548', '2023-03-23 09:44:00.548');`)
+		require.NoError(t, err)
+		db.Close()
+
+		// the single test DB is ready again as soon as recreate returns
+		require.NoError(t, m.RecreateTestDatabase(ctx, hash, test.ID))
+	}
+
+	// discard the template
+	assert.NoError(t, m.DiscardTemplateDatabase(ctx, hash))
+}
+
 func TestManagerGetTestDatabaseDontReturn(t *testing.T) {
 
 	ctx := context.Background()
@@ -1037,3 +1243,966 @@ func TestManagerClearTrackedTestDatabases(t *testing.T) {
 		t.Errorf("received invalid test ID, got %d, want %d", test.ID, originalID)
 	}
 }
+
+func TestManagerResetTracking(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := manager.DefaultManagerConfigFromEnv()
+	cfg.PoolConfig.InitialPoolSize = 0
+	m, _ := testManagerWithConfig(cfg)
+
+	if err := m.Initialize(ctx); err != nil {
+		t.Fatalf("initializing manager failed: %v", err)
+	}
+
+	defer disconnectManager(t, m)
+
+	hash := "hashinghash"
+
+	template, err := m.InitializeTemplateDatabase(ctx, hash)
+	if err != nil {
+		t.Fatalf("failed to initialize template database: %v", err)
+	}
+
+	populateTemplateDB(t, template)
+
+	if _, err := m.FinalizeTemplateDatabase(ctx, hash); err != nil {
+		t.Fatalf("failed to finalize template database: %v", err)
+	}
+
+	if _, err := m.GetTestDatabase(ctx, hash); err != nil {
+		t.Fatalf("failed to get test database: %v", err)
+	}
+
+	if err := m.ResetTracking(ctx, hash); err != nil {
+		t.Fatalf("failed to reset tracking: %v", err)
+	}
+	assert.ErrorIs(t, m.ResetTracking(ctx, hash), manager.ErrTemplateNotFound)
+
+	_, err = m.GetTestDatabase(ctx, hash)
+	assert.ErrorIs(t, err, manager.ErrTemplateNotFound)
+
+	// the template database itself is kept, thus it can be discarded afterwards
+	if err := m.DiscardTemplateDatabase(ctx, hash); err != nil {
+		t.Fatalf("failed to discard template database: %v", err)
+	}
+}
+
+func TestManagerGetTestDatabaseByAlias(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := manager.DefaultManagerConfigFromEnv()
+	cfg.PoolConfig.InitialPoolSize = 1
+	m, _ := testManagerWithConfig(cfg)
+
+	if err := m.Initialize(ctx); err != nil {
+		t.Fatalf("initializing manager failed: %v", err)
+	}
+
+	defer disconnectManager(t, m)
+
+	alias := "myapp-fixtures"
+	hashV1 := "hashinghash1"
+	hashV2 := "hashinghash2"
+
+	for _, hash := range []string{hashV1, hashV2} {
+		template, err := m.InitializeTemplateDatabase(ctx, hash)
+		if err != nil {
+			t.Fatalf("failed to initialize template database: %v", err)
+		}
+
+		populateTemplateDB(t, template)
+
+		if _, err := m.FinalizeTemplateDatabase(ctx, hash); err != nil {
+			t.Fatalf("failed to finalize template database: %v", err)
+		}
+	}
+
+	_, err := m.SetAlias(ctx, alias, "unknownhash")
+	assert.ErrorIs(t, err, manager.ErrTemplateNotFound)
+
+	previous, err := m.SetAlias(ctx, alias, hashV1)
+	require.NoError(t, err)
+	assert.Empty(t, previous)
+
+	test, err := m.GetTestDatabase(ctx, alias)
+	require.NoError(t, err)
+	assert.Equal(t, hashV1, test.TemplateHash)
+	verifyTestDB(t, test)
+
+	// roll over to the new version
+	previous, err = m.SetAlias(ctx, alias, hashV2)
+	require.NoError(t, err)
+	assert.Equal(t, hashV1, previous)
+
+	test, err = m.GetTestDatabase(ctx, alias)
+	require.NoError(t, err)
+	assert.Equal(t, hashV2, test.TemplateHash)
+	verifyTestDB(t, test)
+
+	aliases, err := m.GetAliases(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{alias: hashV2}, aliases)
+
+	require.NoError(t, m.RemoveAlias(ctx, alias))
+	assert.ErrorIs(t, m.RemoveAlias(ctx, alias), manager.ErrAliasNotFound)
+
+	_, err = m.GetTestDatabase(ctx, alias)
+	assert.ErrorIs(t, err, manager.ErrTemplateNotFound)
+}
+
+func TestManagerRemoveIdlePools(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := manager.DefaultManagerConfigFromEnv()
+	cfg.PoolConfig.InitialPoolSize = 1
+	cfg.PoolIdleTTL = 100 * time.Millisecond
+	cfg.PoolIdleSweepInterval = time.Hour // sweep manually
+	m, _ := testManagerWithConfig(cfg)
+
+	if err := m.Initialize(ctx); err != nil {
+		t.Fatalf("initializing manager failed: %v", err)
+	}
+
+	defer disconnectManager(t, m)
+
+	hash := "hashinghash"
+
+	template, err := m.InitializeTemplateDatabase(ctx, hash)
+	if err != nil {
+		t.Fatalf("failed to initialize template database: %v", err)
+	}
+
+	populateTemplateDB(t, template)
+
+	if _, err := m.FinalizeTemplateDatabase(ctx, hash); err != nil {
+		t.Fatalf("failed to finalize template database: %v", err)
+	}
+
+	test, err := m.GetTestDatabase(ctx, hash)
+	if err != nil {
+		t.Fatalf("failed to get test database: %v", err)
+	}
+
+	// still in use
+	removed, err := m.RemoveIdlePools(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+
+	if err := m.ReturnTestDatabase(ctx, hash, test.ID); err != nil {
+		t.Fatalf("failed to return test database: %v", err)
+	}
+
+	time.Sleep(cfg.PoolIdleTTL * 2)
+
+	removed, err = m.RemoveIdlePools(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{hash}, removed)
+
+	_, err = m.GetPoolSnapshot(ctx, hash)
+	assert.ErrorIs(t, err, manager.ErrTemplateNotFound)
+
+	// the template is kept, its pool is recreated on the next request
+	test, err = m.GetTestDatabase(ctx, hash)
+	if err != nil {
+		t.Fatalf("failed to get test database after removing the idle pool: %v", err)
+	}
+
+	verifyTestDB(t, test)
+}
+
+func TestManagerClearTrackedTestDatabasesForceDrop(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := manager.DefaultManagerConfigFromEnv()
+	cfg.TestDatabaseForceDrop = true
+	m, config := testManagerWithConfig(cfg)
+
+	if err := m.Initialize(ctx); err != nil {
+		t.Fatalf("initializing manager failed: %v", err)
+	}
+
+	defer disconnectManager(t, m)
+
+	hash := "hashinghash"
+
+	template, err := m.InitializeTemplateDatabase(ctx, hash)
+	if err != nil {
+		t.Fatalf("failed to initialize template database: %v", err)
+	}
+
+	populateTemplateDB(t, template)
+
+	if _, err := m.FinalizeTemplateDatabase(ctx, hash); err != nil {
+		t.Fatalf("failed to finalize template database: %v", err)
+	}
+
+	test, err := m.GetTestDatabase(ctx, hash)
+	if err != nil {
+		t.Fatalf("failed to get test database: %v", err)
+	}
+
+	// leaked client, never disconnecting
+	db, err := sql.Open("postgres", test.Config.ConnectionString())
+	require.NoError(t, err)
+	defer db.Close()
+
+	db.SetMaxOpenConns(1)
+	require.NoError(t, db.PingContext(ctx))
+
+	if err := m.ClearTrackedTestDatabases(ctx, hash); err != nil {
+		t.Fatalf("failed to clear tracked test databases despite force drop: %v", err)
+	}
+
+	managerDB, err := sql.Open("postgres", config.ManagerDatabaseConfig.ConnectionString())
+	require.NoError(t, err)
+	defer managerDB.Close()
+
+	var exists bool
+	err = managerDB.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM pg_database WHERE datname = $1)", test.Config.Database).Scan(&exists)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestManagerCleanDirtyTestDatabases(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := manager.DefaultManagerConfigFromEnv()
+	cfg.TestDatabaseGetTimeout = time.Second
+	cfg.PoolConfig.InitialPoolSize = 1
+	cfg.PoolConfig.MaxPoolSize = 1
+	cfg.PoolConfig.TestDatabaseMinimalLifetime = 0
+	m, _ := testManagerWithConfig(cfg)
+
+	if err := m.Initialize(ctx); err != nil {
+		t.Fatalf("initializing manager failed: %v", err)
+	}
+
+	defer disconnectManager(t, m)
+
+	hash := "hashinghash"
+
+	_, err := m.CleanDirtyTestDatabases(ctx, hash)
+	assert.ErrorIs(t, err, manager.ErrTemplateNotFound)
+
+	template, err := m.InitializeTemplateDatabase(ctx, hash)
+	if err != nil {
+		t.Fatalf("failed to initialize template database: %v", err)
+	}
+
+	populateTemplateDB(t, template)
+
+	if _, err := m.FinalizeTemplateDatabase(ctx, hash); err != nil {
+		t.Fatalf("failed to finalize template database: %v", err)
+	}
+
+	if _, err := m.GetTestDatabase(ctx, hash); err != nil {
+		t.Fatalf("failed to get test database: %v", err)
+	}
+
+	scheduled, err := m.CleanDirtyTestDatabases(ctx, "")
+	require.NoError(t, err)
+	assert.LessOrEqual(t, scheduled, 1)
+
+	// the dirty test database is recreated in the background and handed out again
+	test, err := m.GetTestDatabase(ctx, hash)
+	if err != nil {
+		t.Fatalf("failed to get recreated test database: %v", err)
+	}
+
+	verifyTestDB(t, test)
+}
+
+func TestManagerCleanStrategyTruncate(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := manager.DefaultManagerConfigFromEnv()
+	cfg.TestDatabaseGetTimeout = 5 * time.Second
+	cfg.PoolConfig.InitialPoolSize = 1
+	cfg.PoolConfig.MaxPoolSize = 1
+	cfg.PoolConfig.TestDatabaseMinimalLifetime = 0
+	m, _ := testManagerWithConfig(cfg)
+
+	if err := m.Initialize(ctx); err != nil {
+		t.Fatalf("initializing manager failed: %v", err)
+	}
+
+	defer disconnectManager(t, m)
+
+	hash := "hashinghash"
+
+	_, err := m.InitializeTemplateDatabaseWithOptions(ctx, hash, manager.TemplateOptions{CleanStrategy: templates.CleanStrategyTruncate})
+	assert.ErrorIs(t, err, manager.ErrInvalidCleanStrategy)
+
+	_, err = m.InitializeTemplateDatabaseWithOptions(ctx, hash, manager.TemplateOptions{CleanStrategy: "unknown"})
+	assert.ErrorIs(t, err, manager.ErrInvalidCleanStrategy)
+
+	template, err := m.InitializeTemplateDatabaseWithOptions(ctx, hash, manager.TemplateOptions{
+		CleanStrategy: templates.CleanStrategyTruncate,
+		ResetSQL:      "TRUNCATE jets, pilots",
+	})
+	if err != nil {
+		t.Fatalf("failed to initialize template database: %v", err)
+	}
+
+	populateTemplateDB(t, template)
+
+	if _, err := m.FinalizeTemplateDatabase(ctx, hash); err != nil {
+		t.Fatalf("failed to finalize template database: %v", err)
+	}
+
+	// new test databases are still copied from the template
+	test, err := m.GetTestDatabase(ctx, hash)
+	if err != nil {
+		t.Fatalf("failed to get test database: %v", err)
+	}
+
+	verifyTestDB(t, test)
+
+	if err := m.RecreateTestDatabase(ctx, hash, test.ID); err != nil {
+		t.Fatalf("failed to recreate test database: %v", err)
+	}
+
+	// ... while dirty ones are cleaned by the reset SQL instead of recopying the template (fixtures are gone)
+	test, err = m.GetTestDatabase(ctx, hash)
+	if err != nil {
+		t.Fatalf("failed to get cleaned test database: %v", err)
+	}
+
+	db, err := sql.Open("postgres", test.Config.ConnectionString())
+	require.NoError(t, err)
+	defer db.Close()
+
+	var pilotCount int
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT COUNT(*) FROM pilots").Scan(&pilotCount))
+	assert.Equal(t, 0, pilotCount)
+}
+
+func TestManagerCleanStrategySchema(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := manager.DefaultManagerConfigFromEnv()
+	cfg.TestDatabaseGetTimeout = 5 * time.Second
+	cfg.PoolConfig.InitialPoolSize = 1
+	cfg.PoolConfig.MaxPoolSize = 1
+	cfg.PoolConfig.TestDatabaseMinimalLifetime = 0
+	m, _ := testManagerWithConfig(cfg)
+
+	if err := m.Initialize(ctx); err != nil {
+		t.Fatalf("initializing manager failed: %v", err)
+	}
+
+	defer disconnectManager(t, m)
+
+	hash := "hashinghash"
+
+	_, err := m.InitializeTemplateDatabaseWithOptions(ctx, hash, manager.TemplateOptions{CleanStrategy: templates.CleanStrategySchema})
+	assert.ErrorIs(t, err, manager.ErrInvalidCleanStrategy)
+
+	template, err := m.InitializeTemplateDatabaseWithOptions(ctx, hash, manager.TemplateOptions{
+		CleanStrategy: templates.CleanStrategySchema,
+		ResetSQL: `CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+			CREATE TABLE pilots (id uuid NOT NULL DEFAULT uuid_generate_v4(), "name" text NOT NULL)`,
+	})
+	if err != nil {
+		t.Fatalf("failed to initialize template database: %v", err)
+	}
+
+	populateTemplateDB(t, template)
+
+	if _, err := m.FinalizeTemplateDatabase(ctx, hash); err != nil {
+		t.Fatalf("failed to finalize template database: %v", err)
+	}
+
+	test, err := m.GetTestDatabase(ctx, hash)
+	if err != nil {
+		t.Fatalf("failed to get test database: %v", err)
+	}
+
+	verifyTestDB(t, test)
+
+	managerDB, err := sql.Open("postgres", cfg.ManagerDatabaseConfig.ConnectionString())
+	require.NoError(t, err)
+	defer managerDB.Close()
+
+	var oid int64
+	require.NoError(t, managerDB.QueryRowContext(ctx, "SELECT oid FROM pg_database WHERE datname = $1", test.Config.Database).Scan(&oid))
+
+	if err := m.RecreateTestDatabase(ctx, hash, test.ID); err != nil {
+		t.Fatalf("failed to recreate test database: %v", err)
+	}
+
+	// the dirty test database is kept, only its schemas are recreated by the reset SQL
+	test, err = m.GetTestDatabase(ctx, hash)
+	if err != nil {
+		t.Fatalf("failed to get cleaned test database: %v", err)
+	}
+
+	var cleanedOID int64
+	require.NoError(t, managerDB.QueryRowContext(ctx, "SELECT oid FROM pg_database WHERE datname = $1", test.Config.Database).Scan(&cleanedOID))
+	assert.Equal(t, oid, cleanedOID)
+
+	db, err := sql.Open("postgres", test.Config.ConnectionString())
+	require.NoError(t, err)
+	defer db.Close()
+
+	var pilotCount int
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT COUNT(*) FROM pilots").Scan(&pilotCount))
+	assert.Equal(t, 0, pilotCount)
+
+	var jetsExist bool
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT to_regclass('jets') IS NOT NULL").Scan(&jetsExist))
+	assert.False(t, jetsExist)
+}
+
+func TestManagerTestDatabaseOwner(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := manager.DefaultManagerConfigFromEnv()
+	cfg.TestDatabaseGetTimeout = 5 * time.Second
+	cfg.PoolConfig.InitialPoolSize = 1
+	cfg.PoolConfig.MaxPoolSize = 1
+	m, _ := testManagerWithConfig(cfg)
+
+	if err := m.Initialize(ctx); err != nil {
+		t.Fatalf("initializing manager failed: %v", err)
+	}
+
+	defer disconnectManager(t, m)
+
+	managerDB, err := sql.Open("postgres", cfg.ManagerDatabaseConfig.ConnectionString())
+	require.NoError(t, err)
+	defer managerDB.Close()
+
+	owner := "integresql_test_owner"
+	_, err = managerDB.ExecContext(ctx, fmt.Sprintf("DO $$ BEGIN IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = '%s') THEN CREATE ROLE %s; END IF; END $$", owner, owner))
+	require.NoError(t, err)
+
+	hash := "hashinghash"
+
+	_, err = m.InitializeTemplateDatabaseWithOptions(ctx, hash, manager.TemplateOptions{TestDatabaseOwner: "integresql_unknown_owner"})
+	assert.ErrorIs(t, err, manager.ErrUnknownTestDatabaseOwner)
+
+	template, err := m.InitializeTemplateDatabaseWithOptions(ctx, hash, manager.TemplateOptions{TestDatabaseOwner: owner})
+	if err != nil {
+		t.Fatalf("failed to initialize template database: %v", err)
+	}
+
+	populateTemplateDB(t, template)
+
+	if _, err := m.FinalizeTemplateDatabase(ctx, hash); err != nil {
+		t.Fatalf("failed to finalize template database: %v", err)
+	}
+
+	test, err := m.GetTestDatabase(ctx, hash)
+	if err != nil {
+		t.Fatalf("failed to get test database: %v", err)
+	}
+
+	var dbOwner string
+	require.NoError(t, managerDB.QueryRowContext(ctx, "SELECT pg_get_userbyid(datdba) FROM pg_database WHERE datname = $1", test.Config.Database).Scan(&dbOwner))
+	assert.Equal(t, owner, dbOwner)
+
+	// other hashes still fall back to the global test database owner
+	otherHash := "otherhash"
+	otherTemplate, err := m.InitializeTemplateDatabase(ctx, otherHash)
+	if err != nil {
+		t.Fatalf("failed to initialize template database: %v", err)
+	}
+
+	populateTemplateDB(t, otherTemplate)
+
+	if _, err := m.FinalizeTemplateDatabase(ctx, otherHash); err != nil {
+		t.Fatalf("failed to finalize template database: %v", err)
+	}
+
+	otherTest, err := m.GetTestDatabase(ctx, otherHash)
+	if err != nil {
+		t.Fatalf("failed to get test database: %v", err)
+	}
+
+	require.NoError(t, managerDB.QueryRowContext(ctx, "SELECT pg_get_userbyid(datdba) FROM pg_database WHERE datname = $1", otherTest.Config.Database).Scan(&dbOwner))
+	assert.Equal(t, cfg.TestDatabaseOwner, dbOwner)
+}
+
+func TestManagerDatabaseLocale(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := manager.DefaultManagerConfigFromEnv()
+	cfg.TestDatabaseGetTimeout = 5 * time.Second
+	cfg.PoolConfig.InitialPoolSize = 1
+	cfg.PoolConfig.MaxPoolSize = 1
+	cfg.TemplateDatabaseTemplate = "template0"
+	cfg.DatabaseLocale = manager.DatabaseLocale{Encoding: "UTF8"}
+	m, _ := testManagerWithConfig(cfg)
+
+	if err := m.Initialize(ctx); err != nil {
+		t.Fatalf("initializing manager failed: %v", err)
+	}
+
+	defer disconnectManager(t, m)
+
+	managerDB, err := sql.Open("postgres", cfg.ManagerDatabaseConfig.ConnectionString())
+	require.NoError(t, err)
+	defer managerDB.Close()
+
+	hash := "hashinghash"
+
+	// the C locale is available on every server, the encoding falls back to the global one
+	template, err := m.InitializeTemplateDatabaseWithOptions(ctx, hash, manager.TemplateOptions{DatabaseLocale: manager.DatabaseLocale{Collate: "C", Ctype: "C"}})
+	if err != nil {
+		t.Fatalf("failed to initialize template database: %v", err)
+	}
+
+	populateTemplateDB(t, template)
+
+	if _, err := m.FinalizeTemplateDatabase(ctx, hash); err != nil {
+		t.Fatalf("failed to finalize template database: %v", err)
+	}
+
+	test, err := m.GetTestDatabase(ctx, hash)
+	if err != nil {
+		t.Fatalf("failed to get test database: %v", err)
+	}
+
+	// inherited from the template
+	var encoding, collate, ctype string
+	require.NoError(t, managerDB.QueryRowContext(ctx, "SELECT pg_encoding_to_char(encoding), datcollate, datctype FROM pg_database WHERE datname = $1", test.Config.Database).Scan(&encoding, &collate, &ctype))
+	assert.Equal(t, "UTF8", encoding)
+	assert.Equal(t, "C", collate)
+	assert.Equal(t, "C", ctype)
+
+	// only template0 allows to deviate from its encoding
+	cfg.TemplateDatabaseTemplate = "template1"
+	m2, _ := testManagerWithConfig(cfg)
+
+	if err := m2.Initialize(ctx); err != nil {
+		t.Fatalf("initializing manager failed: %v", err)
+	}
+
+	defer disconnectManager(t, m2)
+
+	_, err = m2.InitializeTemplateDatabaseWithOptions(ctx, "otherhash", manager.TemplateOptions{DatabaseLocale: manager.DatabaseLocale{Encoding: "SQL_ASCII"}})
+	assert.ErrorIs(t, err, manager.ErrIncompatibleDatabaseLocale)
+}
+
+func TestManagerReturnTestDatabaseAsync(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := manager.DefaultManagerConfigFromEnv()
+	cfg.PoolConfig.InitialPoolSize = 1
+	cfg.PoolConfig.MaxPoolSize = 1
+	cfg.TestDatabaseGetTimeout = 5 * time.Second
+
+	m, _ := testManagerWithConfig(cfg)
+
+	hash := "hashinghash"
+	assert.ErrorIs(t, m.ReturnTestDatabaseAsync(ctx, hash, 0, ""), manager.ErrManagerNotReady)
+
+	if err := m.Initialize(ctx); err != nil {
+		t.Fatalf("initializing manager failed: %v", err)
+	}
+
+	defer disconnectManager(t, m)
+
+	template, err := m.InitializeTemplateDatabase(ctx, hash)
+	if err != nil {
+		t.Fatalf("failed to initialize template database: %v", err)
+	}
+
+	populateTemplateDB(t, template)
+
+	if _, err := m.FinalizeTemplateDatabase(ctx, hash); err != nil {
+		t.Fatalf("failed to finalize template database: %v", err)
+	}
+
+	test, err := m.GetTestDatabase(ctx, hash)
+	require.NoError(t, err)
+
+	// errors are not reported to the caller
+	assert.NoError(t, m.ReturnTestDatabaseAsync(ctx, hash, 999, ""))
+	assert.NoError(t, m.ReturnTestDatabaseAsync(ctx, hash, test.ID, ""))
+
+	// the single test DB of the pool is handed out again once the return was processed
+	test2, err := m.GetTestDatabase(ctx, hash)
+	require.NoError(t, err)
+	assert.Equal(t, test.ID, test2.ID)
+}
+
+func TestManagerReturnTestDatabaseWithLease(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := manager.DefaultManagerConfigFromEnv()
+	cfg.PoolConfig.InitialPoolSize = 1
+	cfg.PoolConfig.MaxPoolSize = 1
+	m, _ := testManagerWithConfig(cfg)
+
+	if err := m.Initialize(ctx); err != nil {
+		t.Fatalf("initializing manager failed: %v", err)
+	}
+
+	defer disconnectManager(t, m)
+
+	hash := "hashinghash"
+
+	template, err := m.InitializeTemplateDatabase(ctx, hash)
+	if err != nil {
+		t.Fatalf("failed to initialize template database: %v", err)
+	}
+
+	populateTemplateDB(t, template)
+
+	if _, err := m.FinalizeTemplateDatabase(ctx, hash); err != nil {
+		t.Fatalf("failed to finalize template database: %v", err)
+	}
+
+	test, err := m.GetTestDatabase(ctx, hash)
+	if err != nil {
+		t.Fatalf("failed to get test database: %v", err)
+	}
+
+	require.NotEmpty(t, test.Lease)
+
+	err = m.ReturnTestDatabaseWithLease(ctx, hash, test.ID, "stale")
+	assert.ErrorIs(t, err, pool.ErrInvalidLease)
+
+	if err := m.ReturnTestDatabaseWithLease(ctx, hash, test.ID, test.Lease); err != nil {
+		t.Fatalf("failed to return test database with lease: %v", err)
+	}
+
+	// the lease is no longer valid after the return
+	err = m.RecreateTestDatabaseWithLease(ctx, hash, test.ID, test.Lease)
+	assert.ErrorIs(t, err, pool.ErrInvalidLease)
+}
+
+func TestManagerTestDatabaseStorageLimit(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := manager.DefaultManagerConfigFromEnv()
+	cfg.TestDatabaseGetTimeout = time.Second
+	cfg.TestDatabaseStorageLimit = 1 // any PostgreSQL server exceeds this
+	m, _ := testManagerWithConfig(cfg)
+
+	if err := m.Initialize(ctx); err != nil {
+		t.Fatalf("initializing manager failed: %v", err)
+	}
+
+	defer disconnectManager(t, m)
+
+	hash := "hashinghash"
+
+	template, err := m.InitializeTemplateDatabase(ctx, hash)
+	if err != nil {
+		t.Fatalf("failed to initialize template database: %v", err)
+	}
+
+	populateTemplateDB(t, template)
+
+	if _, err := m.FinalizeTemplateDatabase(ctx, hash); err != nil {
+		t.Fatalf("failed to finalize template database: %v", err)
+	}
+
+	// the pool is never extended, thus no test DB gets ready
+	_, err = m.GetTestDatabase(ctx, hash)
+	assert.ErrorIs(t, err, pool.ErrTimeout)
+
+	snapshot, err := m.GetPoolSnapshot(ctx, hash)
+	require.NoError(t, err)
+	assert.Empty(t, snapshot.TestDatabases)
+}
+
+func TestManagerRegisterExternalTemplateDatabase(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := manager.DefaultManagerConfigFromEnv()
+	cfg.TestDatabaseGetTimeout = 5 * time.Second
+	cfg.PoolConfig.InitialPoolSize = 1
+	cfg.PoolConfig.MaxPoolSize = 2
+	m, _ := testManagerWithConfig(cfg)
+
+	if err := m.Initialize(ctx); err != nil {
+		t.Fatalf("initializing manager failed: %v", err)
+	}
+
+	defer disconnectManager(t, m)
+
+	managerDB, err := sql.Open("postgres", cfg.ManagerDatabaseConfig.ConnectionString())
+	require.NoError(t, err)
+	defer managerDB.Close()
+
+	// created by another process, thus not prefixed (the manager drops all prefixed databases while initializing)
+	dbName := "external_template_test"
+	_, err = managerDB.ExecContext(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS %s", pq.QuoteIdentifier(dbName)))
+	require.NoError(t, err)
+	_, err = managerDB.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE %s WITH OWNER %s TEMPLATE template0", pq.QuoteIdentifier(dbName), pq.QuoteIdentifier(cfg.ManagerDatabaseConfig.Username)))
+	require.NoError(t, err)
+	defer func() {
+		_, err := managerDB.ExecContext(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS %s", pq.QuoteIdentifier(dbName)))
+		assert.NoError(t, err)
+	}()
+
+	externalConfig := cfg.ManagerDatabaseConfig
+	externalConfig.Database = dbName
+	populateTemplateDB(t, db.TemplateDatabase{Database: db.Database{Config: externalConfig}})
+
+	hash := "hashinghash"
+
+	_, err = m.RegisterExternalTemplateDatabase(ctx, hash, "external_template_unknown", manager.TemplateOptions{})
+	assert.ErrorIs(t, err, manager.ErrInvalidExternalTemplate)
+
+	template, err := m.RegisterExternalTemplateDatabase(ctx, hash, dbName, manager.TemplateOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, dbName, template.Config.Database)
+
+	// finalized right away
+	_, err = m.FinalizeTemplateDatabase(ctx, hash)
+	assert.ErrorIs(t, err, manager.ErrTemplateAlreadyInitialized)
+
+	_, err = m.RegisterExternalTemplateDatabase(ctx, hash, dbName, manager.TemplateOptions{})
+	assert.ErrorIs(t, err, manager.ErrTemplateAlreadyInitialized)
+
+	test, err := m.GetTestDatabase(ctx, hash)
+	require.NoError(t, err)
+	verifyTestDB(t, test)
+	require.NoError(t, m.ReturnTestDatabase(ctx, hash, test.ID))
+
+	// discarding removes the test DBs, but keeps the external template database
+	require.NoError(t, m.DiscardTemplateDatabase(ctx, hash))
+
+	var exists bool
+	require.NoError(t, managerDB.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM pg_database WHERE datname = $1)", dbName).Scan(&exists))
+	assert.True(t, exists)
+
+	require.NoError(t, managerDB.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM pg_database WHERE datname = $1)", test.Config.Database).Scan(&exists))
+	assert.False(t, exists)
+}
+
+func TestManagerWarmTemplates(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := manager.DefaultManagerConfigFromEnv()
+	cfg.TemplateMaxConcurrentFinalizations = 1
+	cfg.PoolConfig.InitialPoolSize = 2
+	cfg.PoolConfig.MaxPoolSize = 4
+	m, _ := testManagerWithConfig(cfg)
+
+	if err := m.Initialize(ctx); err != nil {
+		t.Fatalf("initializing manager failed: %v", err)
+	}
+
+	defer disconnectManager(t, m)
+
+	managerDB, err := sql.Open("postgres", cfg.ManagerDatabaseConfig.ConnectionString())
+	require.NoError(t, err)
+	defer managerDB.Close()
+
+	// created by another process, thus not prefixed (the manager drops all prefixed databases while initializing)
+	dbNames := []string{"warm_template_a", "warm_template_b"}
+	for _, dbName := range dbNames {
+		_, err = managerDB.ExecContext(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS %s", pq.QuoteIdentifier(dbName)))
+		require.NoError(t, err)
+		_, err = managerDB.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE %s WITH OWNER %s TEMPLATE template0", pq.QuoteIdentifier(dbName), pq.QuoteIdentifier(cfg.ManagerDatabaseConfig.Username)))
+		require.NoError(t, err)
+		defer func(dbName string) {
+			_, err := managerDB.ExecContext(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS %s", pq.QuoteIdentifier(dbName)))
+			assert.NoError(t, err)
+		}(dbName)
+	}
+
+	ctxt, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	// the unknown template database fails on its own, the others are still warmed
+	results := m.WarmTemplates(ctxt, []manager.DeclaredTemplate{
+		{Hash: "warmhash1", Database: dbNames[0]},
+		{Hash: "warmhash2", Database: "warm_template_unknown"},
+		{Hash: "warmhash3", Database: dbNames[1], Options: manager.TemplateOptions{MinReady: 3}},
+	})
+	require.Len(t, results, 3)
+
+	assert.Equal(t, "warmhash1", results[0].Hash)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, 2, results[0].Ready)
+
+	assert.Equal(t, "warmhash2", results[1].Hash)
+	assert.ErrorIs(t, results[1].Err, manager.ErrInvalidExternalTemplate)
+
+	assert.NoError(t, results[2].Err)
+	assert.Equal(t, 3, results[2].Ready)
+
+	// warm pools hand out their test DBs right away
+	for _, hash := range []string{"warmhash1", "warmhash3"} {
+		test, err := m.GetTestDatabase(ctx, hash)
+		require.NoError(t, err)
+		require.NoError(t, m.ReturnTestDatabase(ctx, hash, test.ID))
+		require.NoError(t, m.DiscardTemplateDatabase(ctx, hash))
+	}
+}
+
+func TestManagerTemplateFingerprint(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := manager.DefaultManagerConfigFromEnv()
+	cfg.PoolConfig.InitialPoolSize = 1
+	cfg.PoolConfig.MaxPoolSize = 2
+	m, _ := testManagerWithConfig(cfg)
+
+	if err := m.Initialize(ctx); err != nil {
+		t.Fatalf("initializing manager failed: %v", err)
+	}
+
+	defer disconnectManager(t, m)
+
+	hash := "hashinghash"
+
+	_, err := m.InitializeTemplateDatabaseWithOptions(ctx, hash, manager.TemplateOptions{Fingerprint: "v1"})
+	require.NoError(t, err)
+
+	// the hash is reused for changed content
+	_, err = m.InitializeTemplateDatabaseWithOptions(ctx, hash, manager.TemplateOptions{Fingerprint: "v2"})
+	assert.ErrorIs(t, err, manager.ErrHashMismatch)
+
+	_, err = m.InitializeTemplateDatabaseWithOptions(ctx, hash, manager.TemplateOptions{Fingerprint: "v1"})
+	assert.ErrorIs(t, err, manager.ErrTemplateAlreadyInitialized)
+
+	_, err = m.FinalizeTemplateDatabaseWithFingerprint(ctx, hash, "v2")
+	assert.ErrorIs(t, err, manager.ErrHashMismatch)
+
+	_, err = m.FinalizeTemplateDatabaseWithFingerprint(ctx, hash, "v1")
+	require.NoError(t, err)
+
+	// checked even once finalized, clients without fingerprint are unaffected
+	_, err = m.FinalizeTemplateDatabaseWithFingerprint(ctx, hash, "v2")
+	assert.ErrorIs(t, err, manager.ErrHashMismatch)
+
+	_, err = m.FinalizeTemplateDatabase(ctx, hash)
+	assert.ErrorIs(t, err, manager.ErrTemplateAlreadyInitialized)
+}
+
+func TestManagerTestDatabaseConnectionLimit(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := manager.DefaultManagerConfigFromEnv()
+	cfg.TestDatabaseGetTimeout = 5 * time.Second
+	cfg.TestDatabaseConnectionLimit = 5
+	cfg.PoolConfig.InitialPoolSize = 1
+	cfg.PoolConfig.MaxPoolSize = 2
+	m, _ := testManagerWithConfig(cfg)
+
+	if err := m.Initialize(ctx); err != nil {
+		t.Fatalf("initializing manager failed: %v", err)
+	}
+
+	defer disconnectManager(t, m)
+
+	managerDB, err := sql.Open("postgres", cfg.ManagerDatabaseConfig.ConnectionString())
+	require.NoError(t, err)
+	defer managerDB.Close()
+
+	connectionLimit := func(dbName string) int {
+		var limit int
+		require.NoError(t, managerDB.QueryRowContext(ctx, "SELECT datconnlimit FROM pg_database WHERE datname = $1", dbName).Scan(&limit))
+		return limit
+	}
+
+	_, err = m.InitializeTemplateDatabaseWithOptions(ctx, "hashinghash", manager.TemplateOptions{ConnectionLimit: -2})
+	assert.ErrorIs(t, err, manager.ErrInvalidConnectionLimit)
+
+	for hash, want := range map[string]int{
+		"hashinghash":   5,  // global default
+		"hashinghash-2": 2,  // per hash override
+		"hashinghash-3": -1, // per hash unlimited
+	} {
+		opts := manager.TemplateOptions{}
+		if want != 5 {
+			opts.ConnectionLimit = want
+		}
+
+		template, err := m.InitializeTemplateDatabaseWithOptions(ctx, hash, opts)
+		require.NoError(t, err)
+		populateTemplateDB(t, template)
+
+		_, err = m.FinalizeTemplateDatabase(ctx, hash)
+		require.NoError(t, err)
+
+		test, err := m.GetTestDatabase(ctx, hash)
+		require.NoError(t, err)
+
+		assert.Equal(t, want, connectionLimit(test.Config.Database), hash)
+
+		// the template itself is never limited
+		assert.Equal(t, -1, connectionLimit(template.Config.Database), hash)
+	}
+}
+
+func TestManagerPromoteTestDatabaseToTemplate(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := manager.DefaultManagerConfigFromEnv()
+	cfg.TestDatabaseGetTimeout = 5 * time.Second
+	m, _ := testManagerWithConfig(cfg)
+
+	if err := m.Initialize(ctx); err != nil {
+		t.Fatalf("initializing manager failed: %v", err)
+	}
+
+	defer disconnectManager(t, m)
+
+	hash := "hashinghash"
+	newHash := "hashinghash-failure"
+
+	template, err := m.InitializeTemplateDatabase(ctx, hash)
+	if err != nil {
+		t.Fatalf("failed to initialize template database: %v", err)
+	}
+
+	populateTemplateDB(t, template)
+
+	if _, err := m.FinalizeTemplateDatabase(ctx, hash); err != nil {
+		t.Fatalf("failed to finalize template database: %v", err)
+	}
+
+	test, err := m.GetTestDatabase(ctx, hash)
+	if err != nil {
+		t.Fatalf("failed to get test database: %v", err)
+	}
+
+	db, err := sql.Open("postgres", test.Config.ConnectionString())
+	require.NoError(t, err)
+
+	_, err = db.ExecContext(ctx, "INSERT INTO pilots (id, name, created_at) VALUES ('777a1a87-5ef7-4309-8814-0f1054751156', 'Failing Pilot', now())")
+	require.NoError(t, err)
+
+	// still connected
+	_, err = m.PromoteTestDatabaseToTemplate(ctx, hash, test.ID, newHash)
+	assert.ErrorIs(t, err, pool.ErrTestDBInUse)
+
+	require.NoError(t, db.Close())
+
+	_, err = m.PromoteTestDatabaseToTemplate(ctx, hash, 999, newHash)
+	assert.ErrorIs(t, err, manager.ErrTestNotFound)
+
+	promoted, err := m.PromoteTestDatabaseToTemplate(ctx, hash, test.ID, newHash)
+	require.NoError(t, err)
+	assert.Equal(t, newHash, promoted.TemplateHash)
+
+	_, err = m.PromoteTestDatabaseToTemplate(ctx, hash, test.ID, newHash)
+	assert.ErrorIs(t, err, manager.ErrTemplateAlreadyInitialized)
+
+	// the test DB of the promoted template holds the state of the source test DB
+	reproduced, err := m.GetTestDatabase(ctx, newHash)
+	if err != nil {
+		t.Fatalf("failed to get test database of promoted template: %v", err)
+	}
+
+	reproducedDB, err := sql.Open("postgres", reproduced.Config.ConnectionString())
+	require.NoError(t, err)
+	defer reproducedDB.Close()
+
+	var pilotCount int
+	require.NoError(t, reproducedDB.QueryRowContext(ctx, "SELECT COUNT(*) FROM pilots WHERE name = 'Failing Pilot'").Scan(&pilotCount))
+	assert.Equal(t, 1, pilotCount)
+
+	// the source test DB is left untouched
+	require.NoError(t, m.ReturnTestDatabase(ctx, hash, test.ID))
+}
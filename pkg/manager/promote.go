@@ -0,0 +1,130 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"runtime/trace"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/allaboutapps/integresql/pkg/pool"
+	"github.com/allaboutapps/integresql/pkg/templates"
+)
+
+// PromoteTestDatabaseToTemplate snapshots the current content of the given test DB (e.g. its state after a failed test) into a new
+// template database for newHash, which is finalized right away, thus the state may be reproduced with fresh test DBs later on.
+// The test DB must not be in use, all its clients must have disconnected (pool.ErrTestDBInUse otherwise), it is left untouched.
+// The new template inherits the per hash options of the source template, except that its test DBs are always cleaned by recopying
+// (the reset SQL of the source would restore the original state) and that it is not backed by any additional template sources.
+func (m Manager) PromoteTestDatabaseToTemplate(ctx context.Context, hash string, id int, newHash string) (db.TemplateDatabase, error) {
+	template, err := m.promoteTestDatabaseToTemplate(ctx, hash, id, newHash)
+	m.lifecycle.templateCreated(err)
+
+	return template, err
+}
+
+func (m Manager) promoteTestDatabaseToTemplate(ctx context.Context, hash string, id int, newHash string) (db.TemplateDatabase, error) {
+	ctx, task := trace.NewTask(ctx, "promote_test_db_to_template")
+
+	log := m.getManagerLogger(ctx, "PromoteTestDatabaseToTemplate").With().Str("hash", hash).Int("id", id).Str("newHash", newHash).Logger()
+
+	defer task.End()
+
+	if !m.Ready() {
+		log.Error().Msg("not ready")
+		return db.TemplateDatabase{}, ErrManagerNotReady
+	}
+
+	source, found := m.templates.Get(ctx, hash)
+	if !found {
+		return db.TemplateDatabase{}, ErrTemplateNotFound
+	}
+
+	if source.GetState(ctx) != templates.TemplateStateFinalized {
+		return db.TemplateDatabase{}, ErrInvalidTemplateState
+	}
+
+	testDB, err := m.PeekTestDatabase(ctx, hash, id)
+	if err != nil {
+		return db.TemplateDatabase{}, err
+	}
+
+	// PostgreSQL refuses to copy databases with other sessions anyways, fail early with a meaningful error
+	connected, err := m.checkDatabaseConnected(ctx, testDB.Config.Database)
+	if err != nil {
+		return db.TemplateDatabase{}, err
+	}
+
+	if connected {
+		log.Warn().Msg("bailout: test database still in use")
+		return db.TemplateDatabase{}, pool.ErrTestDBInUse
+	}
+
+	sourceConfig := source.GetConfig(ctx)
+	dbName := m.makeTemplateDatabaseName(newHash)
+
+	templateConfig := templates.TemplateConfig{
+		DatabaseConfig: db.DatabaseConfig{
+			Host:     m.config.ManagerDatabaseConfig.Host,
+			Port:     m.config.ManagerDatabaseConfig.Port,
+			Username: m.config.ManagerDatabaseConfig.Username,
+			Password: m.config.ManagerDatabaseConfig.Password,
+			Database: dbName,
+		},
+		InlineRecreateMaxSize: sourceConfig.InlineRecreateMaxSize,
+		CleanStrategy:         templates.CleanStrategyRecopy,
+		TestDatabaseOwner:     sourceConfig.TestDatabaseOwner,
+		LeakWarnTimeout:       sourceConfig.LeakWarnTimeout,
+		LeakReclaimTimeout:    sourceConfig.LeakReclaimTimeout,
+		ConnectionLimit:       sourceConfig.ConnectionLimit,
+	}
+
+	if err := m.pushPromotedTemplate(ctx, newHash, templateConfig, testDB); err != nil {
+		return db.TemplateDatabase{}, err
+	}
+
+	log.Info().Str("dbName", dbName).Msg("promoted test database to template")
+
+	return m.FinalizeTemplateDatabase(ctx, newHash)
+}
+
+// pushPromotedTemplate adds the config of the promoted template to the collection and copies the test DB into its template DB.
+// The template is only unlocked after the copy, thus concurrent calls for newHash wait for it like for initializing templates.
+func (m Manager) pushPromotedTemplate(ctx context.Context, newHash string, templateConfig templates.TemplateConfig, testDB db.TestDatabase) error {
+	log := m.getManagerLogger(ctx, "pushPromotedTemplate").With().Str("newHash", newHash).Logger()
+
+	added, unlock := m.templates.Push(ctx, newHash, templateConfig)
+	defer unlock()
+
+	if !added {
+		return ErrTemplateAlreadyInitialized
+	}
+
+	copyTestDB := func() error {
+		return m.dropAndCreateDatabase(ctx, templateConfig.Database, m.config.ManagerDatabaseConfig.Username, testDB.Config.Database, DatabaseLocale{}, -1)
+	}
+
+	// serialized with the recreation of the test DB (if configured), which would drop it while being copied
+	var err error
+	if m.config.TestDatabaseAdvisoryLock {
+		err = m.withAdvisoryLock(ctx, testDB.Config.Database, copyTestDB)
+	} else {
+		err = copyTestDB()
+	}
+
+	if err != nil {
+		log.Error().Err(err).Msg("triggering unsafe remove after copying the test database failed...")
+		m.templates.RemoveUnsafe(ctx, newHash)
+
+		return err
+	}
+
+	// if template config has been overwritten, the existing pool needs to be removed
+	if err := m.pool.RemoveAllWithHash(ctx, newHash, m.dropTestPoolDB); err != nil && !errors.Is(err, pool.ErrUnknownHash) {
+		log.Error().Err(err).Msg("triggering unsafe remove after RemoveAllWithHash failed...")
+		m.templates.RemoveUnsafe(ctx, newHash)
+
+		return err
+	}
+
+	return nil
+}
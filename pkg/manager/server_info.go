@@ -0,0 +1,62 @@
+package manager
+
+import (
+	"context"
+)
+
+// Minimal PostgreSQL versions (server_version_num) of the version dependent features.
+const (
+	minServerVersionDropDatabaseForce      = 130000 // DROP DATABASE ... WITH (FORCE)
+	minServerVersionCreateDatabaseStrategy = 150000 // CREATE DATABASE ... STRATEGY
+)
+
+// ServerInfo describes the connected PostgreSQL server and which of the version dependent features it supports.
+type ServerInfo struct {
+	Version                int    `json:"version"`                // server_version_num, e.g. 160002
+	VersionString          string `json:"versionString"`          // server_version, e.g. "16.2 (Debian 16.2-1.pgdg120+2)"
+	DropDatabaseForce      bool   `json:"dropDatabaseForce"`      // DROP DATABASE ... WITH (FORCE) is available (PG13+)
+	CreateDatabaseStrategy bool   `json:"createDatabaseStrategy"` // CREATE DATABASE ... STRATEGY is available (PG15+)
+	MaxConnections         int    `json:"maxConnections"`         // max_connections, including the ones reserved for superusers (see EstimateConnections)
+}
+
+func newServerInfo(version int, versionString string) ServerInfo {
+	return ServerInfo{
+		Version:                version,
+		VersionString:          versionString,
+		DropDatabaseForce:      version >= minServerVersionDropDatabaseForce,
+		CreateDatabaseStrategy: version >= minServerVersionCreateDatabaseStrategy,
+	}
+}
+
+// ServerVersion returns the version of the connected PostgreSQL server (server_version_num, e.g. 160002), detected while connecting.
+func (m Manager) ServerVersion() (int, error) {
+	if !m.Ready() {
+		return 0, ErrManagerNotReady
+	}
+
+	return m.serverInfo.Version, nil
+}
+
+// ServerInfo returns the version and capabilities of the connected PostgreSQL server, detected while connecting.
+func (m Manager) ServerInfo() (ServerInfo, error) {
+	if !m.Ready() {
+		return ServerInfo{}, ErrManagerNotReady
+	}
+
+	return m.serverInfo, nil
+}
+
+func (m Manager) queryServerInfo(ctx context.Context) (ServerInfo, error) {
+	var version int
+	var versionString string
+	var maxConnections int
+
+	if err := m.db.QueryRowContext(ctx, "SELECT current_setting('server_version_num')::integer, current_setting('server_version'), current_setting('max_connections')::integer").Scan(&version, &versionString, &maxConnections); err != nil {
+		return ServerInfo{}, err
+	}
+
+	info := newServerInfo(version, versionString)
+	info.MaxConnections = maxConnections
+
+	return info, nil
+}
@@ -0,0 +1,100 @@
+package manager
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// warmPollInterval is the interval of checking whether the pool of a warmed template reached its ready target.
+const warmPollInterval = 50 * time.Millisecond
+
+// DeclaredTemplate is a template declared upfront (e.g. within the templates file of the server) instead of initialized by a client,
+// its existing template database is registered like via RegisterExternalTemplateDatabase.
+type DeclaredTemplate struct {
+	Hash     string
+	Database string // name of the existing template database
+	Options  TemplateOptions
+}
+
+// WarmResult reports the warm-up of a single declared template.
+type WarmResult struct {
+	Hash     string
+	Ready    int           // ready test DBs of its pool at the end of the warm-up
+	Duration time.Duration // time from starting the registration until the pool was warm (or the warm-up failed)
+	Err      error         // nil if the pool reached its ready target
+}
+
+// WarmTemplates registers all declared templates concurrently and waits until each pool reached its ready target, thus a fresh server
+// is warm as fast as PostgreSQL allows. The finalizations are still bounded by TemplateMaxConcurrentFinalizations (queued in arrival order).
+// A failing template does not abort the others, the results are reported per template (in the order declared) and summarized in the log.
+// The ctx bounds the whole warm-up, templates not warm until it is done report its error.
+func (m Manager) WarmTemplates(ctx context.Context, declared []DeclaredTemplate) []WarmResult {
+	log := m.getManagerLogger(ctx, "WarmTemplates")
+
+	start := time.Now()
+	results := make([]WarmResult, len(declared))
+
+	var wg sync.WaitGroup
+	for i, template := range declared {
+		wg.Add(1)
+		go func(i int, template DeclaredTemplate) {
+			defer wg.Done()
+			results[i] = m.warmTemplate(ctx, template)
+		}(i, template)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			log.Error().Err(result.Err).Str("hash", result.Hash).Int("ready", result.Ready).Dur("duration", result.Duration).Msg("failed to warm template")
+			continue
+		}
+
+		log.Debug().Str("hash", result.Hash).Int("ready", result.Ready).Dur("duration", result.Duration).Msg("warmed template")
+	}
+
+	log.Info().Int("templates", len(results)).Int("warm", len(results)-failed).Int("failed", failed).Dur("duration", time.Since(start)).Msg("warmed declared templates")
+
+	return results
+}
+
+// warmTemplate registers the declared template and waits until its pool reached its ready target.
+func (m Manager) warmTemplate(ctx context.Context, template DeclaredTemplate) WarmResult {
+	start := time.Now()
+	result := WarmResult{Hash: template.Hash}
+
+	_, result.Err = m.RegisterExternalTemplateDatabase(ctx, template.Hash, template.Database, template.Options)
+	if result.Err == nil {
+		result.Ready, result.Err = m.waitPoolWarm(ctx, template.Hash)
+	}
+
+	result.Duration = time.Since(start)
+
+	return result
+}
+
+// waitPoolWarm waits until the pool of the given hash reached its ready target, returning its ready test DBs.
+func (m Manager) waitPoolWarm(ctx context.Context, hash string) (int, error) {
+	ticker := time.NewTicker(warmPollInterval)
+	defer ticker.Stop()
+
+	for {
+		snapshot, err := m.GetPoolSnapshot(ctx, hash)
+		if err != nil {
+			return 0, err
+		}
+
+		if snapshot.Ready >= snapshot.ReadyTarget {
+			return snapshot.Ready, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return snapshot.Ready, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
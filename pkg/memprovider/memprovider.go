@@ -0,0 +1,147 @@
+// Package memprovider provides an in-memory backend of the IntegreSQL pool for fast unit tests, no PostgreSQL server is involved.
+//
+// The Provider merely tracks the names of the test DBs the pool creates and removes, all operations succeed right away.
+// Thus the acquire and return logic of a pool.PoolCollection may be tested within milliseconds, e.g.:
+//
+//	provider := memprovider.New()
+//	p := pool.NewPoolCollection(pool.PoolConfig{MaxPoolSize: 4, MaxParallelTasks: 2, ResetDB: provider.Reset})
+//	p.InitHashPool(ctx, provider.TemplateDatabase("hash"), provider.Recreate)
+//	...
+//	p.RemoveAll(ctx, provider.Remove)
+//
+// It complements the real PostgreSQL backend of the manager (see package pooltest for integration tests against it).
+package memprovider
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/allaboutapps/integresql/pkg/pool"
+)
+
+// Provider implements the callbacks of the pool (see pool.RecreateDBFunc, pool.RemoveDBFunc, ...) in memory, safe for concurrent use.
+// The optional callbacks (ResetDB, PingDB, VerifyClean) are only used if wired into the pool.PoolConfig.
+type Provider struct {
+	databases map[string]string // name of the test DB -> name of the template it was last copied from
+	recreates int
+	resets    int
+	removes   int
+	mutex     sync.Mutex
+}
+
+// the methods implement the callbacks of the pool
+var (
+	_ pool.RecreateDBFunc  = (*Provider)(nil).Recreate
+	_ pool.RemoveDBFunc    = (*Provider)(nil).Remove
+	_ pool.ResetDBFunc     = (*Provider)(nil).Reset
+	_ pool.PingDBFunc      = (*Provider)(nil).Ping
+	_ pool.VerifyCleanFunc = (*Provider)(nil).VerifyClean
+)
+
+// New returns a provider without any test DBs.
+func New() *Provider {
+	return &Provider{
+		databases: make(map[string]string),
+	}
+}
+
+// TemplateDatabase returns the template DB of the given hash to init its pool with, it is never created.
+func (p *Provider) TemplateDatabase(hash string) db.Database {
+	return db.Database{
+		TemplateHash: hash,
+		Config: db.DatabaseConfig{
+			Database: "memprovider_template_" + hash,
+		},
+	}
+}
+
+// Recreate (pool.RecreateDBFunc) tracks the test DB as copied from the given template.
+func (p *Provider) Recreate(_ context.Context, testDB db.TestDatabase, templateName string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.databases[testDB.Config.Database] = templateName
+	p.recreates++
+
+	return nil
+}
+
+// Remove (pool.RemoveDBFunc) no longer tracks the test DB.
+func (p *Provider) Remove(_ context.Context, testDB db.TestDatabase) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	delete(p.databases, testDB.Config.Database)
+	p.removes++
+
+	return nil
+}
+
+// Reset (pool.ResetDBFunc) cleans the test DB in place, which is a no-op besides counting.
+func (p *Provider) Reset(_ context.Context, _ db.TestDatabase) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.resets++
+
+	return nil
+}
+
+// Ping (pool.PingDBFunc) always reports the test DB as alive.
+func (p *Provider) Ping(_ context.Context, _ db.TestDatabase) error {
+	return nil
+}
+
+// VerifyClean (pool.VerifyCleanFunc) always reports the test DB as clean.
+func (p *Provider) VerifyClean(_ context.Context, _ db.TestDatabase) (bool, error) {
+	return true, nil
+}
+
+// Databases returns the names of the currently tracked test DBs (sorted).
+func (p *Provider) Databases() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	names := make([]string, 0, len(p.databases))
+	for name := range p.databases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Exists reports whether the test DB of the given name is tracked and the template it was last copied from.
+func (p *Provider) Exists(name string) (templateName string, exists bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	templateName, exists = p.databases[name]
+	return templateName, exists
+}
+
+// Recreates returns the number of test DBs created or recreated from their template so far.
+func (p *Provider) Recreates() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.recreates
+}
+
+// Resets returns the number of test DBs cleaned in place so far.
+func (p *Provider) Resets() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.resets
+}
+
+// Removes returns the number of test DBs removed so far.
+func (p *Provider) Removes() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.removes
+}
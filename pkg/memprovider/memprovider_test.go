@@ -0,0 +1,64 @@
+package memprovider_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/memprovider"
+	"github.com/allaboutapps/integresql/pkg/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderPool(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	provider := memprovider.New()
+
+	p := pool.NewPoolCollection(pool.PoolConfig{
+		InitialPoolSize:  2,
+		MaxPoolSize:      4,
+		TestDBNamePrefix: "test_",
+		MaxParallelTasks: 2,
+		PingDB:           provider.Ping,
+	})
+	t.Cleanup(p.Stop)
+
+	hash := "h1"
+	templateDB := provider.TemplateDatabase(hash)
+	p.InitHashPool(ctx, templateDB, provider.Recreate)
+
+	testDB, err := p.GetTestDatabase(ctx, hash, time.Second)
+	require.NoError(t, err)
+
+	templateName, exists := provider.Exists(testDB.Config.Database)
+	require.True(t, exists)
+	assert.Equal(t, templateDB.Config.Database, templateName)
+
+	// test DBs returned for recreation are recreated in background and ready again
+	require.NoError(t, p.RecreateTestDatabase(ctx, hash, testDB.ID))
+	assert.Eventually(t, func() bool {
+		ready, err := p.PeekReady(ctx, hash)
+		return err == nil && contains(ready, testDB.ID)
+	}, time.Second, 5*time.Millisecond)
+
+	p.Stop()
+	snapshot, err := p.Snapshot(ctx, hash)
+	require.NoError(t, err)
+
+	require.NoError(t, p.RemoveAll(ctx, provider.Remove))
+	assert.Empty(t, provider.Databases())
+	assert.Equal(t, len(snapshot.TestDatabases), provider.Removes())
+}
+
+func contains(ids []int, id int) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+
+	return false
+}
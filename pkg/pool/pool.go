@@ -3,21 +3,38 @@ package pool
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math/rand"
 	"runtime/trace"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/allaboutapps/integresql/pkg/db"
 	"github.com/allaboutapps/integresql/pkg/util"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 )
 
 var (
-	ErrPoolFull     = errors.New("database pool is full")
-	ErrInvalidState = errors.New("database state is not valid for this operation")
-	ErrInvalidIndex = errors.New("invalid database index (id)")
-	ErrTimeout      = errors.New("timeout when waiting for ready db")
-	ErrTestDBInUse  = errors.New("test database is in use, close the connection before dropping")
+	ErrPoolFull            = errors.New("database pool is full")
+	ErrInvalidState        = errors.New("database state is not valid for this operation")
+	ErrInvalidIndex        = errors.New("invalid database index (id)")
+	ErrTimeout             = errors.New("timeout when waiting for ready db")
+	ErrTestDBInUse         = errors.New("test database is in use, close the connection before dropping")
+	ErrNoAliveDB           = errors.New("no alive test database available, all ready test databases failed the liveness check")
+	ErrTestDBDead          = errors.New("test database is dead (e.g. dropped out-of-band)")
+	ErrTestDBTimeout       = errors.New("test database statement timed out (statement_timeout or lock_timeout exceeded)")
+	ErrInvalidSize         = errors.New("invalid pool size, must be greater or equal 1")
+	ErrExceedsCapacity     = errors.New("pool size exceeds the capacity the pool was created with")
+	ErrInvalidLease        = errors.New("invalid lease, the test database is no longer held by this lease")
+	ErrInsufficientStorage = errors.New("insufficient storage to create another test database")
+	ErrTooManyConnections  = errors.New("too many connections to the PostgreSQL server, reduce the connection usage or raise max_connections")
+	ErrPoolDraining        = errors.New("database pool is draining, no more test databases are handed out")
+	ErrStillInUse          = errors.New("test databases are still in use")
+	ErrCallbackPanic       = errors.New("callback panicked")
+	ErrUnknownID           = errors.New("test database was not handed out, it is still ready (e.g. returned twice)")
+	ErrWouldReuseDirty     = errors.New("test database is dirty, refusing to hand it out as is (reject dirty)")
 )
 
 type dbState int // Indicates a current DB state.
@@ -39,6 +56,27 @@ type existingDB struct {
 	// Prefer to tweak InitialPoolSize (the always ready dbs) and MaxPoolSize instead if you have issues here.
 	blockAutoCleanDirtyUntil time.Time
 
+	// time the test DB was handed out to its current holder, zero if not in use (see InUse)
+	acquiredAt time.Time
+
+	// already logged as suspected leak during the current acquisition (see ReclaimLeaked)
+	leakWarned bool
+
+	// TTL of the soft reservation of the current holder, 0 if not reserved (see GetOptions.ReservationTTL)
+	reservationTTL time.Duration
+
+	// time the test DB was last returned for recreation by its holder, zero if never (see ReturnCooldown)
+	returnedAt time.Time
+
+	// time the test DB last got ready, selects among the ready test DBs with SelectionPolicyLRU / SelectionPolicyMRU
+	readyAt time.Time
+
+	// time the test DB last turned from ready to dirty, force-recreated once older than DirtyMaxAge (see RecreateStaleDirty)
+	dirtyAt time.Time
+
+	// returned as poisoned (corrupted beyond what ResetDB can fix), thus it is fully recreated from the template next time
+	poisoned bool
+
 	// increased after each recreation, useful for sleepy recreating workers to check if we still operate on the same gen.
 	generation uint
 }
@@ -51,6 +89,18 @@ const (
 	workerTaskAutoCleanDirty = "CLEAN_DIRTY"
 )
 
+// queuedTask is a workerTask along with the ID of the request that triggered it (empty if none),
+// the logs of the worker executing the task carry it to correlate them with the request.
+type queuedTask struct {
+	task      workerTask
+	requestID string
+}
+
+func newQueuedTask(ctx context.Context, task workerTask) queuedTask {
+	requestID, _ := util.RequestIDFromContext(ctx)
+	return queuedTask{task: task, requestID: requestID}
+}
+
 // HashPool holds a test DB pool for a certain hash. Each HashPool is running cleanup workers in background.
 type HashPool struct {
 	dbs        []existingDB
@@ -65,9 +115,45 @@ type HashPool struct {
 	sync.RWMutex
 	wg sync.WaitGroup
 
-	tasksChan     chan workerTask
-	running       bool
-	workerContext context.Context // the ctx all background workers will receive (nil if not yet started)
+	tasksChan       chan queuedTask
+	running         bool
+	draining        bool               // no test DBs are handed out anymore as the pool is about to be removed (see drain)
+	templateMissing bool               // the template DB no longer exists, thus no test DBs can be created anymore (see ErrTemplateMissing)
+	workerContext   context.Context    // the ctx all background workers will receive (nil if not yet started)
+	cancelWarmUp    context.CancelFunc // aborts a running EnsureReady (nil if none), called by Stop
+
+	readyTarget      int // number of test DBs we try to keep ready, InitialPoolSize unless bumped by AutoScale
+	autoScaleGets    int // gets within the current AutoScaleWindow
+	autoScaleStarved int // gets within the current AutoScaleWindow that had to wait for a ready test DB
+
+	getCleanTotal uint64 // test DBs handed out in a clean state (recreated according to the template or unlocked unchanged)
+	getDirtyTotal uint64 // test DBs handed out as is, without being recreated (GetTestDatabaseByID)
+
+	onReadyCalled      bool      // the OnReady callback has been called (it is called once per pool)
+	poolFullNotifiedAt time.Time // last time the OnPoolFull callback was called (see notifyPoolFull)
+
+	stickyKeys map[string]stickyEntry // test DBs last handed out per sticky key (see GetOptions.StickyKey)
+
+	createdAt time.Time // time the pool was created
+	lastUsed  time.Time // last time a test DB was requested, returned or recreated by a client (see RemoveIdleWithHash)
+
+	workersBusy int32 // currently running worker tasks, accessed atomically as tasks don't hold the pool lock
+
+	tooManyConnectionsTotal uint64    // recreate attempts rejected by the server as max_connections was exceeded
+	verifyCleanFailedTotal  uint64    // test DBs returned as clean, but reported dirty by VerifyClean (thus recreated)
+	staleDirtyTotal         uint64    // dirty test DBs force-recreated as they exceeded the DirtyMaxAge (see RecreateStaleDirty)
+	extendBackoffUntil      time.Time // extending the pool is refused until then, after max_connections was exceeded
+
+	copyDurations     durationHistogram // durations of successfully copying the template into a test DB (RecreateDBFunc)
+	copyWaitDurations durationHistogram // durations of waiting for a free copy slot (only observed if MaxConcurrentCopies is configured)
+	lockWaits         *DurationRecorder // durations of waiting for the pool lock (see Lock), not guarded by the pool lock itself
+	copySlots         chan struct{}     // limits the concurrent copies, nil if unlimited (shared by all pools of a PoolCollection)
+	budget            *dbBudget         // caps the test DBs, nil if unlimited (shared by all pools of a PoolCollection)
+	getRequestsTotal  uint64            // gets requested by clients (including the ones that had to wait or failed), accessed atomically
+	rebalanceRequests uint64            // getRequestsTotal as of the previous Rebalance
+
+	selectionPolicy SelectionPolicy // active policy selecting among the ready test DBs (see PoolConfig.SelectionPolicy)
+	rng             *rand.Rand      // selects among the ready test DBs with SelectionPolicyRandom (nil otherwise)
 }
 
 // NewHashPool creates new hash pool with the given config.
@@ -80,12 +166,26 @@ func NewHashPool(cfg PoolConfig, templateDB db.Database, initDBFunc RecreateDBFu
 		dirty:      make(chan int, cfg.MaxPoolSize),
 		recreating: make(chan struct{}, cfg.MaxPoolSize),
 
-		recreateDB: makeActualRecreateTestDBFunc(templateDB.Config.Database, initDBFunc),
+		recreateDB: makeActualRecreateTestDBFunc(templateDB.Config.Database, cfg.SelectTemplate, initDBFunc),
 		templateDB: templateDB,
 		PoolConfig: cfg,
 
-		tasksChan: make(chan workerTask, cfg.MaxPoolSize+1),
+		tasksChan: make(chan queuedTask, cfg.MaxPoolSize+1),
 		running:   false,
+
+		readyTarget:       cfg.warmReadyTarget(cfg.InitialPoolSize),
+		createdAt:         time.Now(),
+		lastUsed:          time.Now(),
+		copyDurations:     newDurationHistogram(copyDurationBuckets),
+		copyWaitDurations: newDurationHistogram(copyDurationBuckets),
+		lockWaits:         NewDurationRecorder(lockWaitBuckets),
+		copySlots:         newCopySlots(cfg.MaxConcurrentCopies),
+		stickyKeys:        make(map[string]stickyEntry),
+	}
+
+	pool.selectionPolicy = cfg.activeSelectionPolicy()
+	if pool.selectionPolicy == SelectionPolicyRandom {
+		pool.rng = cfg.newSelectionRNG()
 	}
 
 	return pool
@@ -109,8 +209,9 @@ func (pool *HashPool) Start() {
 	ctx, cancel := context.WithCancel(context.Background())
 	pool.workerContext = ctx
 
-	for i := 0; i < pool.InitialPoolSize; i++ {
-		pool.tasksChan <- workerTaskExtend
+	// only extend up to the initial size or MinReady (a restarted pool may still hold test DBs)
+	for i := len(pool.dbs); i < pool.warmReadyTarget(pool.InitialPoolSize); i++ {
+		pool.tasksChan <- queuedTask{task: workerTaskExtend}
 	}
 
 	pool.wg.Add(1)
@@ -128,35 +229,217 @@ func (pool *HashPool) Stop() {
 	log.Debug().Msg("stopping...")
 
 	pool.Lock()
+	if pool.cancelWarmUp != nil {
+		// don't let a synchronous warm-up delay the shutdown
+		pool.cancelWarmUp()
+	}
 	if !pool.running {
 		log.Warn().Msg("bailout already stopped!")
+		pool.Unlock()
 		return
 	}
 	pool.running = false
 	pool.Unlock()
 
-	pool.tasksChan <- workerTaskStop
+	pool.tasksChan <- queuedTask{task: workerTaskStop}
 	pool.wg.Wait()
 	pool.workerContext = nil
 	log.Warn().Msg("stopped!")
 }
 
-func (pool *HashPool) GetTestDatabase(ctx context.Context, timeout time.Duration) (db db.TestDatabase, err error) {
-	var index int
+// EnsureReady synchronously extends the pool up to InitialPoolSize test DBs, instead of leaving the warm-up to the background workers.
+// It is meant to be called before Start, which then only extends the pool by the missing test DBs.
+// The ctx is checked between each new test DB: If it is done (or the pool is stopped meanwhile), the warm-up is aborted and the context error returned.
+// Test DBs created until then stay registered within the pool.
+func (pool *HashPool) EnsureReady(ctx context.Context) error {
+
+	log := pool.getPoolLogger(ctx, "EnsureReady")
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pool.Lock()
+	pool.cancelWarmUp = cancel
+	pool.Unlock()
+
+	defer func() {
+		pool.Lock()
+		pool.cancelWarmUp = nil
+		pool.Unlock()
+	}()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			pool.RLock()
+			log.Warn().Err(err).Int("dbs", len(pool.dbs)).Msg("warm-up aborted")
+			pool.RUnlock()
+			return err
+		}
+
+		pool.RLock()
+		done := len(pool.dbs) >= pool.InitialPoolSize
+		pool.RUnlock()
+
+		if done {
+			return nil
+		}
+
+		if err := pool.extend(ctx); err != nil {
+			if errors.Is(err, ErrPoolFull) {
+				return nil
+			}
+
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+
+			return err
+		}
+	}
+}
+
+// GetTestDatabase picks up a ready to use test DB. It waits the given timeout until a DB is available.
+// If PingDB is configured, dead test DBs are flagged for recreation and the next ready one is tried instead.
+func (pool *HashPool) GetTestDatabase(ctx context.Context, timeout time.Duration) (testDB db.TestDatabase, err error) {
+	return pool.GetTestDatabaseWithOptions(ctx, timeout, GetOptions{})
+}
+
+// GetTestDatabaseWithOptions picks up a ready to use test DB, applying the given options (see GetTestDatabase).
+// With a StickyKey, the test DB last handed out for the key is handed out again as is, unless it was recreated meanwhile.
+func (pool *HashPool) GetTestDatabaseWithOptions(ctx context.Context, timeout time.Duration, opts GetOptions) (testDB db.TestDatabase, err error) {
+	testDB, _, err = pool.acquireTestDatabase(ctx, timeout, opts)
+	return testDB, err
+}
+
+// GetTestDatabaseWithInfo picks up a ready to use test DB like GetTestDatabaseWithOptions, additionally describing the acquisition (see AcquireInfo).
+func (pool *HashPool) GetTestDatabaseWithInfo(ctx context.Context, timeout time.Duration, opts GetOptions) (testDB db.TestDatabase, info AcquireInfo, err error) {
+	start := time.Now()
+
+	testDB, dirty, err := pool.acquireTestDatabase(ctx, timeout, opts)
+	if err != nil {
+		return testDB, info, err
+	}
+
+	return testDB, pool.acquireInfo(start, dirty), nil
+}
+
+// acquireTestDatabase implements GetTestDatabaseWithOptions, dirty reports a sticky test DB handed out again as is.
+func (pool *HashPool) acquireTestDatabase(ctx context.Context, timeout time.Duration, opts GetOptions) (testDB db.TestDatabase, dirty bool, err error) {
+
+	atomic.AddUint64(&pool.getRequestsTotal, 1)
+
+	if len(opts.StickyKey) == 0 {
+		testDB, err = pool.getAliveTestDatabase(ctx, timeout, opts)
+		return testDB, false, err
+	}
+
+	if testDB, dirty, found := pool.getStickyTestDatabase(ctx, opts); found {
+		pool.rememberSticky(opts.StickyKey, testDB.ID)
+		return testDB, dirty, nil
+	}
+
+	testDB, err = pool.getAliveTestDatabase(ctx, timeout, opts)
+	if err != nil {
+		return testDB, false, err
+	}
+
+	pool.rememberSticky(opts.StickyKey, testDB.ID)
+
+	return testDB, false, nil
+}
+
+// getAliveTestDatabase picks up a ready to use test DB, checking it via PingDB (if configured).
+func (pool *HashPool) getAliveTestDatabase(ctx context.Context, timeout time.Duration, opts GetOptions) (testDB db.TestDatabase, err error) {
+	if pool.PingDB == nil {
+		return pool.getTestDatabase(ctx, timeout, opts)
+	}
 
 	log := pool.getPoolLogger(ctx, "GetTestDatabase")
+	deadline := time.Now().Add(timeout)
+
+	for try := 0; try <= pool.PingDBMaxRetries; try++ {
+		testDB, err = pool.getTestDatabase(ctx, time.Until(deadline), opts)
+		if err != nil {
+			return testDB, err
+		}
+
+		pingErr := pool.callPingDB(ctx, testDB)
+		if pingErr == nil {
+			return testDB, nil
+		}
+
+		if !errors.Is(pingErr, ErrTestDBDead) {
+			// e.g. the server is unreachable, recreating the test DB would not heal it, thus it is returned as is
+			log.Error().Err(pingErr).Int("id", testDB.ID).Int("try", try).Msg("bailout liveness check failed")
+
+			if err := pool.ReturnTestDatabaseWithLease(ctx, testDB.ID, testDB.Lease); err != nil {
+				log.Warn().Err(err).Int("id", testDB.ID).Msg("failed to return testdatabase")
+			}
+
+			return db.TestDatabase{}, pingErr
+		}
+
+		log.Warn().Err(pingErr).Int("id", testDB.ID).Int("try", try).Msg("testdatabase is dead, flagging for recreation...")
+
+		// never inline, the caller is still waiting for an alive test DB. Poisoned, as there is nothing left to reset.
+		if err := pool.returnTestDatabasePoisoned(ctx, testDB.ID, testDB.Lease, false); err != nil {
+			return db.TestDatabase{}, err
+		}
+	}
+
+	err = ErrNoAliveDB
+	log.Error().Err(err).Int("maxRetries", pool.PingDBMaxRetries).Msg("bailout no alive testdatabase")
+
+	return db.TestDatabase{}, err
+}
+
+func (pool *HashPool) getTestDatabase(ctx context.Context, timeout time.Duration, opts GetOptions) (db db.TestDatabase, err error) {
+	var index int
+
+	log := pool.getPoolLogger(ctx, "getTestDatabase")
+
+	pool.RLock()
+	draining := pool.draining
+	pool.RUnlock()
+
+	// don't wait for a ready ID that won't be handed out anyways
+	if draining {
+		err = ErrPoolDraining
+		log.Warn().Err(err).Msg("bailout draining")
+		return
+	}
+
 	log.Trace().Msg("waiting for ready ID...")
 
+	// a get is starving if no ready ID is immediately available
+	starved := false
+
 	select {
-	case <-time.After(timeout):
-		err = ErrTimeout
-		log.Error().Err(err).Dur("timeout", timeout).Msg("timeout")
-		return
-	case <-ctx.Done():
-		err = ctx.Err()
-		log.Warn().Err(err).Msg("ctx done")
-		return
 	case index = <-pool.ready:
+	default:
+		starved = true
+
+		// no test DB will ever get ready again
+		if pool.isTemplateMissing() {
+			err = ErrTemplateMissing
+			log.Error().Err(err).Msg("bailout template missing")
+			return
+		}
+
+		select {
+		case <-time.After(timeout):
+			err = ErrTimeout
+			log.Error().Err(err).Dur("timeout", timeout).Msg("timeout")
+			pool.Lock()
+			pool.unsafeTrackStarvation(ctx, log, starved)
+			pool.Unlock()
+			return
+		case <-ctx.Done():
+			err = ctx.Err()
+			log.Warn().Err(err).Msg("ctx done")
+			return
+		case index = <-pool.ready:
+		}
 	}
 
 	log = log.With().Int("id", index).Logger()
@@ -167,10 +450,24 @@ func (pool *HashPool) GetTestDatabase(ctx context.Context, timeout time.Duration
 	defer pool.Unlock()
 	reg.End()
 
+	if pool.draining {
+		// started draining while we were waiting, the test DB stays ready
+		pool.ready <- index
+		err = ErrPoolDraining
+		log.Warn().Err(err).Msg("bailout draining")
+		return
+	}
+
+	if pool.selectionPolicy != SelectionPolicyFIFO {
+		index = pool.unsafeSelectReady(index)
+		log = log.With().Int("id", index).Logger()
+	}
+
 	// sanity check, should never happen
 	if index < 0 || index >= len(pool.dbs) {
 		err = ErrInvalidIndex
 		log.Error().Err(err).Int("dbs", len(pool.dbs)).Msg("index out of bounds!")
+		pool.unsafeRestoreReady(log, index)
 		return
 	}
 
@@ -179,53 +476,325 @@ func (pool *HashPool) GetTestDatabase(ctx context.Context, timeout time.Duration
 	if testDB.state != dbStateReady {
 		err = ErrInvalidState
 		log.Error().Err(err).Msgf("testdatabase is not in ready state=%v!", testDB.state)
+		pool.unsafeRestoreReady(log, index)
 		return
 	}
 
 	// flag as dirty and block auto clean until
 	testDB.state = dbStateDirty
+	testDB.dirtyAt = time.Now()
 	testDB.blockAutoCleanDirtyUntil = time.Now().Add(pool.TestDatabaseMinimalLifetime)
+	testDB.Labels = copyLabels(opts.Labels)
+	testDB.Lease = uuid.NewString()
+	testDB.acquiredAt = time.Now()
+	testDB.leakWarned = false
+	testDB.unsafeReserve(opts.ReservationTTL)
 
 	pool.dbs[index] = testDB
 	pool.dirty <- index
 
 	if len(pool.dbs) < pool.PoolConfig.MaxPoolSize {
 		log.Trace().Msg("push workerTaskExtend")
-		pool.tasksChan <- workerTaskExtend
+		pool.tasksChan <- newQueuedTask(ctx, workerTaskExtend)
+
+		pool.unsafeRefill(ctx, log)
 	}
 
-	// we try to ensure that InitialPoolSize count is staying ready
+	// we try to ensure that InitialPoolSize count (or the bumped readyTarget) is staying ready
 	// thus, we try to move the oldest dirty dbs into recreating with the workerTaskAutoCleanDirty
-	if len(pool.dbs) >= pool.PoolConfig.MaxPoolSize && (len(pool.ready)+len(pool.recreating)) < pool.InitialPoolSize {
+	if len(pool.dbs) >= pool.PoolConfig.MaxPoolSize && (len(pool.ready)+len(pool.recreating)) < pool.readyTarget {
 		log.Trace().Msg("push workerTaskAutoCleanDirty")
-		pool.tasksChan <- workerTaskAutoCleanDirty
+		pool.tasksChan <- newQueuedTask(ctx, workerTaskAutoCleanDirty)
 	}
 
+	pool.unsafeEnsureMinReady(ctx, log)
+
+	pool.getCleanTotal++
+	pool.lastUsed = time.Now()
+	pool.unsafeTrackStarvation(ctx, log, starved)
 	pool.unsafeTraceLogStats(log)
 
 	return testDB.TestDatabase, nil
 }
 
-func (pool *HashPool) workerTaskLoop(ctx context.Context, taskChan <-chan workerTask, MaxParallelTasks int) {
+// unsafeRestoreReady puts a ready ID back after it failed a sanity check, thus the test DB is not orphaned if the inconsistency
+// turns out to be temporary (the pool would silently shrink otherwise). Never blocks if the ready channel is full.
+// The pool must be locked by the caller.
+func (pool *HashPool) unsafeRestoreReady(log zerolog.Logger, index int) {
+	select {
+	case pool.ready <- index:
+		log.Warn().Msg("restored ready ID after failed sanity check")
+	default:
+		log.Error().Msg("failed to restore ready ID after failed sanity check, ready channel is full")
+	}
+}
+
+// unsafeRefill tops up the pool ahead of demand if the ready (or currently recreating) test DBs dropped below the RefillWatermark
+// percentage of the ready target: Additional extend tasks are scheduled up to the ready target (and MaxPoolSize), on top of the one each get triggers anyways.
+// Never blocks if the task queue is full. The pool must be locked by the caller.
+func (pool *HashPool) unsafeRefill(ctx context.Context, log zerolog.Logger) {
+	if pool.PoolConfig.RefillWatermark <= 0 {
+		return
+	}
+
+	available := len(pool.ready) + len(pool.recreating)
+	if available*100 >= pool.readyTarget*pool.PoolConfig.RefillWatermark {
+		return
+	}
+
+	// one extend task was already pushed by the get
+	missing := pool.readyTarget - available - 1
+	if free := pool.PoolConfig.MaxPoolSize - len(pool.dbs) - 1; missing > free {
+		missing = free
+	}
+
+	pushed := 0
+	for ; pushed < missing; pushed++ {
+		select {
+		case pool.tasksChan <- newQueuedTask(ctx, workerTaskExtend):
+		default:
+			log.Debug().Int("pushed", pushed).Int("missing", missing).Msg("task queue full, bailout refilling")
+			return
+		}
+	}
+
+	if pushed > 0 {
+		log.Debug().Int("available", available).Int("readyTarget", pool.readyTarget).Int("pushed", pushed).Msg("below refill watermark, extending ahead of demand")
+	}
+}
+
+// unsafeEnsureMinReady keeps the warm standby of MinReady test DBs after a get: If fewer test DBs are ready (or recreating), the missing ones
+// are scheduled at once, extending the pool up to MaxPoolSize and eagerly cleaning dirty test DBs beyond. Tasks already pushed by the get
+// count towards the missing ones. Never blocks if the task queue is full. The pool must be locked by the caller.
+func (pool *HashPool) unsafeEnsureMinReady(ctx context.Context, log zerolog.Logger) {
+	if pool.PoolConfig.MinReady <= 0 {
+		return
+	}
+
+	available := len(pool.ready) + len(pool.recreating)
+	missing := pool.PoolConfig.MinReady - available - len(pool.tasksChan)
+	if missing <= 0 {
+		return
+	}
+
+	free := pool.PoolConfig.MaxPoolSize - len(pool.dbs) - len(pool.tasksChan)
+
+	pushed := 0
+	for ; pushed < missing; pushed++ {
+		var task workerTask = workerTaskAutoCleanDirty
+		if pushed < free {
+			task = workerTaskExtend
+		}
+
+		select {
+		case pool.tasksChan <- newQueuedTask(ctx, task):
+		default:
+			log.Debug().Int("pushed", pushed).Int("missing", missing).Msg("task queue full, bailout keeping min ready")
+			return
+		}
+	}
+
+	log.Debug().Int("available", available).Int("minReady", pool.PoolConfig.MinReady).Int("pushed", pushed).Msg("below min ready, scheduled warm standby")
+}
+
+// unsafeIsIdle reports whether the pool was not used by a client since the given time and no test DB is currently being recreated.
+// The pool must be (read) locked by the caller.
+func (pool *HashPool) unsafeIsIdle(since time.Time) bool {
+	if pool.lastUsed.After(since) {
+		return false
+	}
+
+	for _, testDB := range pool.dbs {
+		if testDB.state == dbStateRecreating {
+			return false
+		}
+	}
+
+	return true
+}
+
+// GetTestDatabaseByID picks up the test DB with the given ID, e.g. to reproduce a failure on a known test DB.
+// A ready test DB is handed out like via GetTestDatabase. A dirty test DB is handed out as is (without being recreated, thus dirty is true),
+// the caller is responsible for making sure it is no longer in use. Test DBs currently being recreated result in ErrInvalidState.
+func (pool *HashPool) GetTestDatabaseByID(ctx context.Context, id int) (testDB db.TestDatabase, dirty bool, err error) {
+	return pool.GetTestDatabaseByIDWithOptions(ctx, id, GetOptions{})
+}
+
+// GetTestDatabaseByIDWithOptions picks up the test DB with the given ID like GetTestDatabaseByID. If dirty test DBs are rejected
+// (either by the PoolConfig or the given options), a dirty test DB fails with ErrWouldReuseDirty instead of being handed out as is.
+// Labels are not applied.
+func (pool *HashPool) GetTestDatabaseByIDWithOptions(ctx context.Context, id int, opts GetOptions) (testDB db.TestDatabase, dirty bool, err error) {
+
+	log := pool.getPoolLogger(ctx, "GetTestDatabaseByID").With().Int("id", id).Logger()
+	log.Debug().Msg("getting by id...")
+
+	reg := trace.StartRegion(ctx, "wait_for_lock_hash_pool")
+	pool.Lock()
+	defer pool.Unlock()
+	reg.End()
+
+	if err := ctx.Err(); err != nil {
+		// client vanished
+		log.Warn().Err(err).Msg("bailout client vanished!")
+		return testDB, false, err
+	}
+
+	if pool.draining {
+		log.Warn().Err(ErrPoolDraining).Msg("bailout draining")
+		return testDB, false, ErrPoolDraining
+	}
+
+	if id < 0 || id >= len(pool.dbs) {
+		log.Warn().Int("dbs", len(pool.dbs)).Msg("bailout invalid index!")
+		return testDB, false, ErrInvalidIndex
+	}
+
+	existing := pool.dbs[id]
+
+	switch existing.state {
+	case dbStateReady:
+		// move from ready to dirty like the normal path
+		pool.excludeIDFromChannel(pool.ready, id)
+		existing.state = dbStateDirty
+		existing.dirtyAt = time.Now()
+		existing.Labels = nil
+
+		if len(pool.dbs) < pool.PoolConfig.MaxPoolSize {
+			log.Trace().Msg("push workerTaskExtend")
+			pool.tasksChan <- newQueuedTask(ctx, workerTaskExtend)
+		}
+	case dbStateDirty:
+		if pool.RejectDirty || opts.RejectDirty {
+			log.Warn().Err(ErrWouldReuseDirty).Msg("bailout dirty")
+			return testDB, false, ErrWouldReuseDirty
+		}
+
+		if cooldown := time.Until(existing.cooldownUntil(pool.ReturnCooldown)); cooldown > 0 {
+			// the previous holder might still be connected
+			log.Warn().Dur("cooldown", cooldown).Msg("bailout returned test DB is still cooling down")
+			return testDB, false, ErrTestDBInUse
+		}
+
+		// requeue at the end of the dirty channel, so it will be auto-cleaned last
+		pool.excludeIDFromChannel(pool.dirty, id)
+		dirty = true
+	case dbStateRecreating:
+		if cooldown := time.Until(existing.cooldownUntil(pool.ReturnCooldown)); cooldown > 0 {
+			// waiting for the previous holder to disconnect before recreating it
+			log.Warn().Dur("cooldown", cooldown).Msg("bailout returned test DB is still cooling down")
+			return testDB, false, ErrTestDBInUse
+		}
+
+		log.Warn().Msgf("bailout invalid state=%v.", existing.state)
+		return testDB, false, ErrInvalidState
+	default:
+		log.Warn().Msgf("bailout invalid state=%v.", existing.state)
+		return testDB, false, ErrInvalidState
+	}
+
+	// handed out to a new holder, invalidating the lease of the previous one
+	existing.Lease = uuid.NewString()
+	existing.acquiredAt = time.Now()
+	existing.leakWarned = false
+	existing.blockAutoCleanDirtyUntil = time.Now().Add(pool.TestDatabaseMinimalLifetime)
+	existing.unsafeReserve(opts.ReservationTTL)
+	pool.dbs[id] = existing
+	pool.dirty <- id
+
+	if dirty {
+		pool.getDirtyTotal++
+	} else {
+		pool.getCleanTotal++
+	}
+	pool.lastUsed = time.Now()
+
+	pool.unsafeTraceLogStats(log)
+
+	return existing.TestDatabase, dirty, nil
+}
+
+// unsafeTrackStarvation tracks whether a get had to wait for a ready test DB (AutoScale only).
+// If the starvation rate within the AutoScaleWindow exceeds the AutoScaleStarvationThreshold,
+// the ready target is doubled (up to MaxPoolSize) and the additional test DBs are prepared in background.
+// The pool must be locked by the caller.
+func (pool *HashPool) unsafeTrackStarvation(ctx context.Context, log zerolog.Logger, starved bool) {
+	if !pool.AutoScale {
+		return
+	}
+
+	pool.autoScaleGets++
+	if starved {
+		pool.autoScaleStarved++
+	}
+
+	if pool.autoScaleGets < pool.AutoScaleWindow {
+		return
+	}
+
+	// window completed, evaluate and start over
+	rate := pool.autoScaleStarved * 100 / pool.autoScaleGets
+	pool.autoScaleGets = 0
+	pool.autoScaleStarved = 0
+
+	if rate <= pool.AutoScaleStarvationThreshold || pool.readyTarget >= pool.MaxPoolSize {
+		return
+	}
+
+	target := pool.readyTarget * 2
+	if target == 0 {
+		target = 1
+	}
+	if target > pool.MaxPoolSize {
+		target = pool.MaxPoolSize
+	}
+
+	log.Info().Int("starvationRate", rate).Int("oldReadyTarget", pool.readyTarget).Int("readyTarget", target).Msg("pool is starving, bumping ready target")
+
+	// never block while holding the lock, the missing test DBs are prepared by the next gets otherwise
+schedule:
+	for i := pool.readyTarget; i < target; i++ {
+		var task workerTask = workerTaskAutoCleanDirty
+		if len(pool.dbs)+i-pool.readyTarget < pool.MaxPoolSize {
+			task = workerTaskExtend
+		}
+
+		select {
+		case pool.tasksChan <- newQueuedTask(ctx, task):
+		default:
+			log.Debug().Int("scheduled", i-pool.readyTarget).Int("missing", target-pool.readyTarget).Msg("task queue full, bailout scheduling")
+			break schedule
+		}
+	}
+
+	pool.readyTarget = target
+}
+
+func (pool *HashPool) workerTaskLoop(ctx context.Context, taskChan <-chan queuedTask, MaxParallelTasks int) {
 
 	log := pool.getPoolLogger(ctx, "workerTaskLoop")
 	log.Debug().Msg("starting...")
 
 	handlers := map[workerTask]func(ctx context.Context) error{
-		workerTaskExtend:         ignoreErrs(pool.extend, ErrPoolFull, context.Canceled),
+		workerTaskExtend:         ignoreErrs(pool.extend, ErrPoolFull, ErrInsufficientStorage, ErrTooManyConnections, ErrTemplateMissing, context.Canceled),
 		workerTaskAutoCleanDirty: ignoreErrs(pool.autoCleanDirty, context.Canceled),
 	}
 
 	// to limit the number of running goroutines.
 	var semaphore = make(chan struct{}, MaxParallelTasks)
 
-	for task := range taskChan {
+	for queued := range taskChan {
+		task := queued.task
 		handler, ok := handlers[task]
 		if !ok {
 			log.Error().Msgf("invalid task: %s", task)
 			continue
 		}
 
+		taskCtx := ctx
+		if len(queued.requestID) > 0 {
+			taskCtx = util.ContextWithRequestID(ctx, queued.requestID)
+		}
+
 		select {
 		case <-ctx.Done():
 			log.Warn().Err(ctx.Err()).Msg("ctx done!")
@@ -234,9 +803,12 @@ func (pool *HashPool) workerTaskLoop(ctx context.Context, taskChan <-chan worker
 		}
 
 		pool.wg.Add(1)
-		go func(task workerTask) {
+		go func(ctx context.Context, task workerTask) {
+
+			atomic.AddInt32(&pool.workersBusy, 1)
 
 			defer func() {
+				atomic.AddInt32(&pool.workersBusy, -1)
 				pool.wg.Done()
 				<-semaphore
 			}()
@@ -244,9 +816,10 @@ func (pool *HashPool) workerTaskLoop(ctx context.Context, taskChan <-chan worker
 			log.Debug().Msgf("task=%v", task)
 
 			if err := handler(ctx); err != nil {
+				log := pool.getPoolLogger(ctx, "workerTaskLoop")
 				log.Error().Err(err).Msgf("task=%v FAILED!", task)
 			}
-		}(task)
+		}(taskCtx, task)
 
 	}
 }
@@ -258,7 +831,7 @@ func (pool *HashPool) controlLoop(ctx context.Context, cancel context.CancelFunc
 
 	defer cancel()
 
-	workerTasksChan := make(chan workerTask, len(pool.tasksChan))
+	workerTasksChan := make(chan queuedTask, len(pool.tasksChan))
 	pool.wg.Add(1)
 	go func() {
 		defer pool.wg.Done()
@@ -266,7 +839,7 @@ func (pool *HashPool) controlLoop(ctx context.Context, cancel context.CancelFunc
 	}()
 
 	for task := range pool.tasksChan {
-		if task == workerTaskStop {
+		if task.task == workerTaskStop {
 			log.Debug().Msg("stopping...")
 			close(workerTasksChan)
 			cancel()
@@ -282,28 +855,191 @@ func (pool *HashPool) controlLoop(ctx context.Context, cancel context.CancelFunc
 	}
 }
 
-// ReturnTestDatabase returns the given test DB directly to the pool, without cleaning (recreating it).
-func (pool *HashPool) ReturnTestDatabase(ctx context.Context, id int) error {
+// unsafeCheckCapacity returns ErrExceedsCapacity if the given size exceeds the MaxPoolSize the pool was created with.
+// The pool must be locked by the caller.
+func (pool *HashPool) unsafeCheckCapacity(size int) error {
+	if capacity := cap(pool.dbs); size > capacity {
+		return fmt.Errorf("%w: %d > %d", ErrExceedsCapacity, size, capacity)
+	}
 
-	log := pool.getPoolLogger(ctx, "ReturnTestDatabase").With().Int("id", id).Logger()
-	log.Debug().Msg("returning...")
+	return nil
+}
+
+// SetMaxPoolSize changes the maximal number of test DBs of this pool at runtime and returns the effective size.
+// Lowering it below the current number of test DBs does not remove any, but prevents extending the pool until it drops below the new limit.
+// Raising it is only possible up to the MaxPoolSize the pool was created with, as its channels have a fixed capacity (ErrExceedsCapacity otherwise).
+func (pool *HashPool) SetMaxPoolSize(ctx context.Context, size int) (int, error) {
+
+	log := pool.getPoolLogger(ctx, "SetMaxPoolSize")
+
+	if size < 1 {
+		return 0, ErrInvalidSize
+	}
 
 	pool.Lock()
 	defer pool.Unlock()
 
-	if err := ctx.Err(); err != nil {
-		// client vanished
-		log.Warn().Err(err).Msg("bailout client vanished!")
-		return err
+	if err := pool.unsafeCheckCapacity(size); err != nil {
+		log.Warn().Err(err).Msg("bailout size exceeds pool capacity")
+		return 0, err
 	}
 
-	if id < 0 || id >= len(pool.dbs) {
-		log.Warn().Int("dbs", len(pool.dbs)).Msg("bailout invalid index!")
-		return ErrInvalidIndex
-	}
+	pool.PoolConfig.MaxPoolSize = size
+
+	// keep the ready target within the new bounds, restoring InitialPoolSize (or MinReady) if raised again
+	target := pool.readyTarget
+	if initial := pool.warmReadyTarget(pool.InitialPoolSize); target < initial {
+		target = initial
+	}
+	if target > size {
+		target = size
+	}
+	pool.readyTarget = target
+
+	log.Info().Int("maxPoolSize", size).Int("readyTarget", target).Int("dbs", len(pool.dbs)).Msg("max pool size changed")
+
+	return size, nil
+}
+
+// SetReadyTarget changes the number of test DBs the pool tries to keep ready (initially InitialPoolSize) at runtime and returns the effective target,
+// e.g. to pre-scale the pool ahead of a big test run. Raising it schedules the missing test DBs right away (if the workers are running),
+// extending the pool up to its MaxPoolSize and cleaning dirty test DBs beyond. Lowering it does not remove any test DBs, the excess ones
+// are just no longer replenished once handed out. The target is clamped to MaxPoolSize, AutoScale may still bump it afterwards.
+func (pool *HashPool) SetReadyTarget(ctx context.Context, target int) (int, error) {
+
+	log := pool.getPoolLogger(ctx, "SetReadyTarget")
+
+	if target < 1 {
+		return 0, ErrInvalidSize
+	}
+
+	pool.Lock()
+	defer pool.Unlock()
+
+	if target > pool.MaxPoolSize {
+		log.Warn().Int("target", target).Int("maxPoolSize", pool.MaxPoolSize).Msg("target exceeds max pool size, clamping")
+		target = pool.MaxPoolSize
+	}
+	if warm := pool.warmReadyTarget(target); warm > target {
+		log.Warn().Int("target", target).Int("minReady", pool.MinReady).Msg("target below min ready, raising")
+		target = warm
+	}
+
+	// tasks for up to the previous target were already scheduled before
+	missing := target - pool.readyTarget
+	if remaining := target - len(pool.ready) - len(pool.recreating); remaining < missing {
+		missing = remaining
+	}
+
+	// also applies if SetMaxPoolSize restores the ready target later on
+	pool.PoolConfig.InitialPoolSize = target
+	pool.readyTarget = target
+
+	scheduled := 0
+	if pool.running {
+		free := pool.MaxPoolSize - len(pool.dbs)
+
+	schedule:
+		for ; scheduled < missing; scheduled++ {
+			var task workerTask = workerTaskAutoCleanDirty
+			if scheduled < free {
+				task = workerTaskExtend
+			}
+
+			select {
+			case pool.tasksChan <- newQueuedTask(ctx, task):
+			default:
+				log.Debug().Int("scheduled", scheduled).Int("missing", missing).Msg("task queue full, bailout scheduling")
+				break schedule
+			}
+		}
+	}
+
+	log.Info().Int("readyTarget", target).Int("dbs", len(pool.dbs)).Int("scheduled", scheduled).Msg("ready target changed")
+
+	return target, nil
+}
+
+// CleanDirty schedules all currently dirty test DBs for immediate recreation by the background workers, instead of waiting
+// until the pool runs out of ready test DBs. TestDatabaseMinimalLifetime still applies, test DBs still in use are recreated as soon as their clients disconnect.
+// Returns the number of scheduled test DBs (0 if the workers are not running), fewer than the dirty ones if the task queue is full.
+func (pool *HashPool) CleanDirty(ctx context.Context) int {
+
+	log := pool.getPoolLogger(ctx, "CleanDirty")
+
+	pool.Lock()
+	defer pool.Unlock()
+
+	if !pool.running {
+		log.Warn().Msg("bailout workers not running")
+		return 0
+	}
+
+	// never block while holding the lock, the remaining dirty test DBs are auto-cleaned later on
+	scheduled := 0
+	for dirty := len(pool.dirty); scheduled < dirty; scheduled++ {
+		select {
+		case pool.tasksChan <- newQueuedTask(ctx, workerTaskAutoCleanDirty):
+		default:
+			log.Debug().Int("scheduled", scheduled).Int("dirty", dirty).Msg("task queue full, bailout scheduling")
+			return scheduled
+		}
+	}
+
+	log.Debug().Int("scheduled", scheduled).Msg("scheduled dirty test DBs for cleaning")
+	pool.unsafeTraceLogStats(log)
+
+	return scheduled
+}
+
+// ReturnTestDatabase returns the given test DB directly to the pool, without cleaning (recreating it).
+func (pool *HashPool) ReturnTestDatabase(ctx context.Context, id int) error {
+	return pool.ReturnTestDatabaseWithLease(ctx, id, "")
+}
+
+// ReturnTestDatabaseWithLease returns the given test DB like ReturnTestDatabase, but only if the given lease (if any)
+// is still the one of its current holder. Otherwise ErrInvalidLease is returned.
+// Returning a test DB that is still ready (not handed out, e.g. returned twice) fails with ErrUnknownID, unless LenientReturns is configured.
+// If VerifyClean is configured and reports the test DB dirty, it is flagged for recreation instead (see RecreateTestDatabase).
+func (pool *HashPool) ReturnTestDatabaseWithLease(ctx context.Context, id int, lease string) error {
+
+	log := pool.getPoolLogger(ctx, "ReturnTestDatabase").With().Int("id", id).Logger()
+	log.Debug().Msg("returning...")
+
+	if pool.VerifyClean != nil && !pool.verifyClean(ctx, log, id, lease) {
+		pool.Lock()
+		pool.verifyCleanFailedTotal++
+		pool.Unlock()
+
+		return pool.RecreateTestDatabaseWithLease(ctx, id, lease)
+	}
+
+	pool.Lock()
+	defer pool.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		// client vanished
+		log.Warn().Err(err).Msg("bailout client vanished!")
+		return err
+	}
+
+	if id < 0 || id >= len(pool.dbs) {
+		log.Warn().Int("dbs", len(pool.dbs)).Msg("bailout invalid index!")
+		return ErrInvalidIndex
+	}
+
+	if err := pool.unsafeCheckLease(id, lease); err != nil {
+		log.Warn().Err(err).Msg("bailout invalid lease!")
+		return err
+	}
 
 	// check if db is in the correct state
 	testDB := pool.dbs[id]
+	if testDB.state == dbStateReady && !pool.LenientReturns {
+		log.Warn().Msg("bailout testdatabase was not handed out!")
+		return ErrUnknownID
+	}
+
 	if testDB.state != dbStateDirty {
 		log.Warn().Int("dbs", len(pool.dbs)).Msgf("bailout invalid state=%v.", testDB.state)
 		return nil
@@ -311,7 +1047,13 @@ func (pool *HashPool) ReturnTestDatabase(ctx context.Context, id int) error {
 
 	// directly change the state to 'ready'
 	testDB.state = dbStateReady
+	testDB.readyAt = time.Now()
+	testDB.Labels = nil
+	testDB.Lease = ""
+	testDB.acquiredAt = time.Time{}
+	testDB.unsafeReserve(0)
 	pool.dbs[id] = testDB
+	pool.lastUsed = time.Now()
 
 	// remove id from dirty and add it to ready channel
 	pool.excludeIDFromChannel(pool.dirty, id)
@@ -322,13 +1064,50 @@ func (pool *HashPool) ReturnTestDatabase(ctx context.Context, id int) error {
 	return nil
 }
 
+// verifyClean checks the dirty test DB with the given ID via VerifyClean, without holding the pool lock while it runs.
+// Invalid IDs, states or leases are reported clean, they are rejected by the caller.
+func (pool *HashPool) verifyClean(ctx context.Context, log zerolog.Logger, id int, lease string) bool {
+	pool.RLock()
+	if id < 0 || id >= len(pool.dbs) || pool.dbs[id].state != dbStateDirty || pool.unsafeCheckLease(id, lease) != nil {
+		pool.RUnlock()
+		return true
+	}
+	testDB := pool.dbs[id].TestDatabase
+	pool.RUnlock()
+
+	clean, err := pool.callVerifyClean(ctx, testDB)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to verify testdatabase is clean, flagging for recreation...")
+		return false
+	}
+
+	if !clean {
+		log.Warn().Msg("testdatabase returned as clean is dirty, flagging for recreation...")
+	}
+
+	return clean
+}
+
+// unsafeCheckLease checks that the given lease (if any) is held by the dirty test DB with the given ID. The pool must be locked.
+func (pool *HashPool) unsafeCheckLease(id int, lease string) error {
+	if len(lease) == 0 {
+		return nil
+	}
+
+	if pool.dbs[id].state != dbStateDirty || pool.dbs[id].Lease != lease {
+		return ErrInvalidLease
+	}
+
+	return nil
+}
+
 func (pool *HashPool) excludeIDFromChannel(ch chan int, excludeID int) {
 
 	// The testDB identified by overgiven id may still in a specific channel (typically dirty). We want to exclude it.
 	// We need to explicitly remove it from there by filtering the current channel to a tmp channel.
 	// We finally close the tmp channel and flush it onto the specific channel again.
 	// The id is now no longer in the channel.
-	filtered := make(chan int, pool.MaxPoolSize)
+	filtered := make(chan int, cap(ch))
 
 	var id int
 	for loop := true; loop; {
@@ -352,21 +1131,87 @@ func (pool *HashPool) excludeIDFromChannel(ch chan int, excludeID int) {
 	}
 }
 
+// ReturnTestDatabasePoisoned returns a test DB corrupted beyond what cleaning can fix (e.g. altered roles, broken extensions).
+// Like RecreateTestDatabase it is prioritized to be recreated next, but always dropped and copied from the template again,
+// even if the pool would otherwise only clean it via ResetDB.
+func (pool *HashPool) ReturnTestDatabasePoisoned(ctx context.Context, id int) error {
+	return pool.ReturnTestDatabasePoisonedWithLease(ctx, id, "")
+}
+
+// ReturnTestDatabasePoisonedWithLease returns the given test DB as poisoned like ReturnTestDatabasePoisoned, but only if the given lease (if any)
+// is still the one of its current holder. Otherwise ErrInvalidLease is returned.
+func (pool *HashPool) ReturnTestDatabasePoisonedWithLease(ctx context.Context, id int, lease string) error {
+	return pool.returnTestDatabasePoisoned(ctx, id, lease, pool.RecreateInline)
+}
+
+// returnTestDatabasePoisoned implements ReturnTestDatabasePoisonedWithLease, recreating inline only if allowed (see recreateTestDatabase).
+func (pool *HashPool) returnTestDatabasePoisoned(ctx context.Context, id int, lease string, inline bool) error {
+
+	log := pool.getPoolLogger(ctx, "ReturnTestDatabasePoisoned").With().Int("id", id).Logger()
+
+	pool.Lock()
+
+	if id < 0 || id >= len(pool.dbs) {
+		log.Warn().Int("dbs", len(pool.dbs)).Msg("bailout invalid index!")
+		pool.Unlock()
+		return ErrInvalidIndex
+	}
+
+	if err := pool.unsafeCheckLease(id, lease); err != nil {
+		log.Warn().Err(err).Msg("bailout invalid lease!")
+		pool.Unlock()
+		return err
+	}
+
+	// only test DBs handed out may be poisoned, ready ones are untouched
+	if pool.dbs[id].state == dbStateDirty {
+		log.Warn().Msg("flagged as poisoned")
+		pool.dbs[id].poisoned = true
+	}
+
+	pool.Unlock()
+
+	return pool.recreateTestDatabase(ctx, id, lease, inline)
+}
+
 // RecreateTestDatabase prioritizes the test DB to be recreated next via the dirty worker.
 func (pool *HashPool) RecreateTestDatabase(ctx context.Context, id int) error {
+	return pool.RecreateTestDatabaseWithLease(ctx, id, "")
+}
+
+// RecreateTestDatabaseWithLease recreates the given test DB like RecreateTestDatabase, but only if the given lease (if any)
+// is still the one of its current holder. Otherwise ErrInvalidLease is returned.
+func (pool *HashPool) RecreateTestDatabaseWithLease(ctx context.Context, id int, lease string) error {
+	return pool.recreateTestDatabase(ctx, id, lease, pool.RecreateInline)
+}
+
+// recreateTestDatabase implements RecreateTestDatabaseWithLease, the test DB is only recreated inline if allowed
+// (RecreateInline, unless the caller must not be blocked by it) and no ReturnCooldown is configured.
+func (pool *HashPool) recreateTestDatabase(ctx context.Context, id int, lease string, inline bool) error {
 
 	log := pool.getPoolLogger(ctx, "RecreateTestDatabase").With().Int("id", id).Logger()
 	log.Debug().Msg("flag testdatabase for recreation...")
 
-	pool.RLock()
+	pool.Lock()
 
 	if id < 0 || id >= len(pool.dbs) {
 		log.Warn().Int("dbs", len(pool.dbs)).Msg("bailout invalid index!")
-		pool.RUnlock()
+		pool.Unlock()
 		return ErrInvalidIndex
 	}
 
-	pool.RUnlock()
+	if err := pool.unsafeCheckLease(id, lease); err != nil {
+		log.Warn().Err(err).Msg("bailout invalid lease!")
+		pool.Unlock()
+		return err
+	}
+
+	// released by its holder, no longer in use
+	pool.dbs[id].acquiredAt = time.Time{}
+	pool.dbs[id].unsafeReserve(0)
+	pool.dbs[id].unsafeStartCooldown(pool.ReturnCooldown)
+	pool.lastUsed = time.Now()
+	pool.Unlock()
 
 	if err := ctx.Err(); err != nil {
 		// client vanished
@@ -374,13 +1219,45 @@ func (pool *HashPool) RecreateTestDatabase(ctx context.Context, id int) error {
 		return err
 	}
 
+	pool.RLock()
+	workerContext := pool.workerContext
+	pool.RUnlock()
+
+	if workerContext == nil {
+		// not yet started (or stopped), stays in the dirty channel thus it is cleaned once the workers are running
+		log.Warn().Msg("bailout workers not running, keeping dirty!")
+		return nil
+	}
+
 	// exclude from the normal dirty channel, force recreation in a background worker...
 	pool.excludeIDFromChannel(pool.dirty, id)
 
+	if inline && pool.ReturnCooldown <= 0 {
+		// tiny templates: try to recreate synchronously, bounded by the request (a single attempt, no retries).
+		// the testdatabase is reserved via the recreating state, the actual work happens outside the lock.
+		log.Trace().Msg("recreating inline...")
+		err := pool.recreateOnce(ctx, workerContext, id)
+		if err == nil {
+			return nil
+		}
+
+		if workerContext.Err() != nil || !(errors.Is(err, ErrTestDBInUse) || errors.Is(err, ErrTestDBTimeout) || errors.Is(err, ErrTooManyConnections) || ctx.Err() != nil) {
+			// not worth retrying right away (or the pool was closed): queue as dirty again and schedule cleaning it like any other dirty test DB.
+			// the return itself succeeded, as with the background worker the client is not bothered with the failed recreation.
+			log.Error().Err(err).Msg("recreating inline failed, queued as dirty again")
+			pool.requeueDirty(id)
+			pool.scheduleAutoCleanDirty(ctx, log)
+			return nil
+		}
+
+		// still in use, timed out or the client vanished: fall back to the retrying background worker below
+		log.Warn().Err(err).Msg("recreating inline failed, falling back to background worker...")
+	}
+
 	// directly spawn a new worker in the bg (with the same ctx as the typical workers)
 	// note that this runs unchained, meaning we do not care about errors that may happen via this bg task
 	//nolint:errcheck
-	go pool.recreateDatabaseGracefully(pool.workerContext, id)
+	go pool.recreateDatabaseGracefully(workerContext, id)
 
 	pool.unsafeTraceLogStats(log)
 	return nil
@@ -398,22 +1275,31 @@ func (pool *HashPool) recreateDatabaseGracefully(ctx context.Context, id int) er
 		return err
 	}
 
-	pool.Lock()
-
-	if state := pool.dbs[id].state; state != dbStateDirty {
-		// nothing to do
-		log.Error().Msgf("bailout not dbStateDirty state=%v", state)
-		pool.Unlock()
+	testDB, reserved := pool.reserveRecreating(log, id)
+	if !reserved {
 		return nil
 	}
 
-	testDB := pool.dbs[id]
+	return pool.recreateReservedGracefully(ctx, log, testDB)
+}
 
-	// set state recreating...
-	pool.dbs[id].state = dbStateRecreating
-	pool.dbs[id] = testDB
+// recreateReservedGracefully is recreateDatabaseGracefully for a test DB already moved into the recreating state by the caller (see reserveRecreating).
+func (pool *HashPool) recreateReservedGracefully(ctx context.Context, log zerolog.Logger, testDB existingDB) error {
+	id := testDB.ID
 
-	pool.Unlock()
+	// reserved via the recreating state meanwhile, thus no one else returns, cleans or hands it out.
+	// If we bail out, it is released as dirty again, thus it may be cleaned later on.
+	recreated := false
+	defer func() {
+		if !recreated {
+			pool.releaseRecreating(id)
+		}
+	}()
+
+	if err := pool.waitCooldown(ctx, id); err != nil {
+		log.Error().Err(err).Msg("bailout ctx err while cooling down")
+		return err
+	}
 
 	pool.recreating <- struct{}{}
 
@@ -431,20 +1317,30 @@ func (pool *HashPool) recreateDatabaseGracefully(ctx context.Context, id int) er
 			try++
 
 			log.Trace().Int("try", try).Msg("trying to recreate...")
-			err := pool.recreateDB(ctx, &testDB)
+			err := pool.recreateDBAttempt(ctx, &testDB)
 			if err != nil {
-				// only still connected errors are worthy a retry
-				if errors.Is(err, ErrTestDBInUse) {
+				// only still connected or timed out (e.g. waiting for a lock) errors are worthy a retry
+				if errors.Is(err, ErrTestDBInUse) || errors.Is(err, ErrTestDBTimeout) {
 
 					backoff := time.Duration(try) * pool.PoolConfig.TestDatabaseRetryRecreateSleepMin
 					if backoff > pool.PoolConfig.TestDatabaseRetryRecreateSleepMax {
 						backoff = pool.PoolConfig.TestDatabaseRetryRecreateSleepMax
 					}
 
-					log.Warn().Int("try", try).Dur("backoff", backoff).Msg("DB is still in use, will retry...")
+					log.Warn().Int("try", try).Dur("backoff", backoff).Err(err).Msg("DB is still in use or timed out, will retry...")
+					time.Sleep(backoff)
+				} else if errors.Is(err, ErrTooManyConnections) {
+
+					backoff := pool.backOffTooManyConnections()
+
+					log.Warn().Int("try", try).Dur("backoff", backoff).Err(err).Msg("max_connections exceeded, will retry...")
 					time.Sleep(backoff)
 				} else {
 
+					if errors.Is(err, ErrTemplateMissing) {
+						pool.markTemplateMissing(log)
+					}
+
 					log.Error().Int("try", try).Err(err).Msg("bailout worker task DB error while cleanup!")
 					return err
 				}
@@ -455,6 +1351,81 @@ func (pool *HashPool) recreateDatabaseGracefully(ctx context.Context, id int) er
 	}
 
 MoveToReady:
+	err := pool.moveRecreatedToReady(ctx, log, id)
+	recreated = err == nil
+
+	return err
+}
+
+// recreateOnce makes a single attempt of recreating the given dirty test DB (e.g. inline within a request, bounded by its ctx).
+// Retries (e.g. as the test DB is still in use) are left to the caller, the test DB is dirty again if it failed (but not queued in the dirty channel).
+func (pool *HashPool) recreateOnce(ctx context.Context, workerContext context.Context, id int) error {
+
+	log := pool.getPoolLogger(ctx, "recreateOnce").With().Int("id", id).Logger()
+
+	testDB, reserved := pool.reserveRecreating(log, id)
+	if !reserved {
+		return nil
+	}
+
+	pool.recreating <- struct{}{}
+	err := pool.recreateDBAttempt(ctx, &testDB)
+	<-pool.recreating
+
+	if err == nil {
+		// the workerContext tells whether the pool was closed in the meantime
+		err = pool.moveRecreatedToReady(workerContext, log, id)
+	} else if errors.Is(err, ErrTemplateMissing) {
+		pool.markTemplateMissing(log)
+	}
+
+	if err != nil {
+		pool.releaseRecreating(id)
+		return err
+	}
+
+	return nil
+}
+
+// reserveRecreating moves the given dirty test DB into the recreating state, thus no one else returns, cleans or hands it out meanwhile.
+// Returns a copy of the test DB to recreate, reports false if it is not dirty (e.g. already being recreated by someone else).
+func (pool *HashPool) reserveRecreating(log zerolog.Logger, id int) (existingDB, bool) {
+	pool.Lock()
+	defer pool.Unlock()
+
+	if state := pool.dbs[id].state; state != dbStateDirty {
+		// nothing to do
+		log.Error().Msgf("bailout not dbStateDirty state=%v", state)
+		return existingDB{}, false
+	}
+
+	pool.dbs[id].state = dbStateRecreating
+
+	return pool.dbs[id], true
+}
+
+// releaseRecreating moves the given test DB back into the dirty state after recreating it failed, unless it was moved on meanwhile.
+func (pool *HashPool) releaseRecreating(id int) {
+	pool.Lock()
+	defer pool.Unlock()
+
+	if pool.dbs[id].state == dbStateRecreating {
+		pool.dbs[id].state = dbStateDirty
+	}
+}
+
+// moveRecreatedToReady moves the just recreated test DB into the ready state, the ctx tells whether the pool was closed in the meantime.
+func (pool *HashPool) moveRecreatedToReady(ctx context.Context, log zerolog.Logger, id int) error {
+	// deferred before unlocking, thus called without holding the lock (the callback may use the pool)
+	var onReadyHash string
+	defer func() {
+		if len(onReadyHash) > 0 {
+			if err := pool.PoolConfig.callOnReady(onReadyHash); err != nil {
+				log.Error().Err(err).Msg("on ready callback failed")
+			}
+		}
+	}()
+
 	pool.Lock()
 	defer pool.Unlock()
 
@@ -473,18 +1444,147 @@ MoveToReady:
 	// increase the generation of the testdb (as we just recreated it) and move into ready!
 	pool.dbs[id].generation++
 	pool.dbs[id].state = dbStateReady
+	pool.dbs[id].readyAt = time.Now()
+	pool.dbs[id].Labels = nil
+	pool.dbs[id].Lease = ""
+	pool.dbs[id].acquiredAt = time.Time{}
+	pool.dbs[id].unsafeReserve(0)
+	pool.dbs[id].poisoned = false
 
 	pool.ready <- pool.dbs[id].ID
 
+	if pool.unsafeCheckOnReady() {
+		onReadyHash = pool.templateDB.TemplateHash
+	}
+
 	log.Debug().Uint("generation", pool.dbs[id].generation).Msg("ready")
 	pool.unsafeTraceLogStats(log)
 	return nil
 }
 
+// unsafeCheckOnReady reports whether the OnReady callback is due, as the ready test DBs reached the InitialPoolSize (at least 1) for the first time.
+// The pool must be locked by the caller, which must call the callback only after unlocking the pool.
+func (pool *HashPool) unsafeCheckOnReady() bool {
+	if pool.PoolConfig.OnReady == nil || pool.onReadyCalled {
+		return false
+	}
+
+	target := pool.PoolConfig.InitialPoolSize
+	if target < 1 {
+		target = 1
+	}
+
+	if len(pool.ready) < target {
+		return false
+	}
+
+	pool.onReadyCalled = true
+	return true
+}
+
+// recreateDBAttempt runs a single attempt of recreating the given test DB, bounded by the configured RecreateAttemptTimeout.
+func (pool *HashPool) recreateDBAttempt(ctx context.Context, testDB *existingDB) error {
+	timeout := pool.PoolConfig.RecreateAttemptTimeout
+	if timeout <= 0 {
+		return pool.resetOrRecreateDB(ctx, testDB)
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := pool.resetOrRecreateDB(attemptCtx, testDB)
+	if err == nil || ctx.Err() != nil || !errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+
+	// the attempt itself ran out of time, make sure the deadline surfaces even if the driver reported a different error
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("recreate attempt exceeded %s: %w", timeout, err)
+	}
+
+	return fmt.Errorf("recreate attempt exceeded %s: %w: %w", timeout, context.DeadlineExceeded, err)
+}
+
+// resetOrRecreateDB cleans the given test DB via the configured ResetDB if it has been ready before (thus exists) and was not returned as poisoned,
+// falling back to recreating it from the template if resetting fails for another reason than the test DB still being in use.
+func (pool *HashPool) resetOrRecreateDB(ctx context.Context, testDB *existingDB) error {
+	if pool.PoolConfig.ResetDB == nil || testDB.generation == 0 || testDB.poisoned {
+		return pool.timedRecreateDB(ctx, testDB)
+	}
+
+	err := pool.PoolConfig.callResetDB(ctx, testDB.TestDatabase)
+	if err == nil || ctx.Err() != nil || errors.Is(err, ErrTestDBInUse) || errors.Is(err, ErrTestDBTimeout) {
+		return err
+	}
+
+	log := pool.getPoolLogger(ctx, "resetOrRecreateDB").With().Int("id", testDB.ID).Logger()
+	log.Warn().Err(err).Msg("resetting failed, falling back to recreate...")
+
+	return pool.timedRecreateDB(ctx, testDB)
+}
+
+// timedRecreateDB copies the template into the given test DB, recording the duration of successful copies.
+// The copy itself runs without holding the pool lock, it is only acquired to record the duration.
+// If MaxConcurrentCopies is configured, a free copy slot is awaited first (counting towards the RecreateAttemptTimeout).
+func (pool *HashPool) timedRecreateDB(ctx context.Context, testDB *existingDB) error {
+	if pool.copySlots != nil {
+		waitStart := time.Now()
+
+		reg := trace.StartRegion(ctx, "wait_for_copy_slot")
+		select {
+		case pool.copySlots <- struct{}{}:
+			reg.End()
+		case <-ctx.Done():
+			reg.End()
+			return ctx.Err()
+		}
+		defer func() { <-pool.copySlots }()
+
+		waited := time.Since(waitStart)
+
+		pool.Lock()
+		pool.copyWaitDurations.observe(waited)
+		pool.Unlock()
+	}
+
+	start := time.Now()
+
+	if err := pool.recreateDB(ctx, testDB); err != nil {
+		return err
+	}
+
+	elapsed := time.Since(start)
+
+	pool.Lock()
+	pool.copyDurations.observe(elapsed)
+	pool.Unlock()
+
+	return nil
+}
+
+// newCopySlots returns the semaphore limiting the concurrent copies to the given number, nil if unlimited (<= 0).
+func newCopySlots(maxConcurrentCopies int) chan struct{} {
+	if maxConcurrentCopies <= 0 {
+		return nil
+	}
+
+	return make(chan struct{}, maxConcurrentCopies)
+}
+
 // autoCleanDirty reads 'dirty' channel and cleans up a test DB with the received index.
 // When the DB is recreated according to a template, its index goes to the 'ready' channel.
 // Note that we generally gurantee FIFO when it comes to auto-cleaning as long as no manual unlock/recreates happen.
+// If CleanBatchSize is configured, multiple dirty test DBs are cleaned within a single call (see autoCleanDirtyBatch).
 func (pool *HashPool) autoCleanDirty(ctx context.Context) error {
+	if pool.PoolConfig.CleanBatchSize > 1 {
+		return pool.autoCleanDirtyBatch(ctx)
+	}
+
+	return pool.autoCleanDirtyOne(ctx)
+}
+
+// autoCleanDirtyOne recreates the oldest dirty test DB, waiting until TestDatabaseMinimalLifetime has passed if needed.
+func (pool *HashPool) autoCleanDirtyOne(ctx context.Context) error {
 
 	log := pool.getPoolLogger(ctx, "autoCleanDirty")
 	log.Trace().Msg("autocleaning...")
@@ -552,6 +1652,107 @@ func (pool *HashPool) autoCleanDirty(ctx context.Context) error {
 	return pool.recreateDatabaseGracefully(ctx, id)
 }
 
+// autoCleanDirtyBatch recreates up to CleanBatchSize dirty test DBs back to back within a single task. Each one still takes its own
+// DROP/CREATE DATABASE round-trips (they can't be combined into a single statement or transaction), the batch merely saves queuing
+// and dispatching a task per test DB, thus a single task (e.g. scheduled while the task queue was full) drains several of them.
+// Only test DBs no longer blocked by TestDatabaseMinimalLifetime (or their ReturnCooldown) are picked. Each one gets a single attempt,
+// thus one still in use does not hold up the others: if recreating it fails, only that one is queued as dirty again for a later task.
+func (pool *HashPool) autoCleanDirtyBatch(ctx context.Context) error {
+
+	log := pool.getPoolLogger(ctx, "autoCleanDirtyBatch")
+	log.Trace().Msg("autocleaning...")
+
+	ctx, task := trace.NewTask(ctx, "worker_clean_dirty_batch")
+	defer task.End()
+
+	regLock := trace.StartRegion(ctx, "worker_wait_for_lock_hash_pool")
+	pool.Lock()
+	regLock.End()
+
+	now := time.Now()
+	batch := make([]int, 0, pool.PoolConfig.CleanBatchSize)
+	var blocked []int
+
+	for loop := true; loop && len(batch) < pool.PoolConfig.CleanBatchSize; {
+		select {
+		case id := <-pool.dirty:
+			if id < 0 || id >= len(pool.dbs) {
+				// sanity check, should never happen
+				log.Warn().Int("id", id).Int("dbs", len(pool.dbs)).Msg("skipping invalid index!")
+				continue
+			}
+
+			if pool.dbs[id].blockAutoCleanDirtyUntil.After(now) {
+				blocked = append(blocked, id)
+				continue
+			}
+
+			batch = append(batch, id)
+		default:
+			loop = false
+		}
+	}
+
+	// blocked ones stay queued
+	for _, id := range blocked {
+		pool.dirty <- id
+	}
+
+	pool.Unlock()
+
+	if len(batch) == 0 {
+		if len(blocked) > 0 {
+			// wait until the oldest blocked one may be cleaned, like without batching
+			return pool.autoCleanDirtyOne(ctx)
+		}
+
+		log.Trace().Msg("noop")
+		return nil
+	}
+
+	log.Trace().Ints("ids", batch).Msg("cleaning batch...")
+
+	var errs []error
+	for _, id := range batch {
+		if err := pool.recreateOnce(ctx, ctx, id); err != nil {
+			log.Warn().Err(err).Int("id", id).Msg("recreating failed, queued as dirty again")
+			pool.requeueDirty(id)
+			errs = append(errs, fmt.Errorf("id %d: %w", id, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// requeueDirty queues the given test DB at the end of the dirty channel again, if it is still dirty.
+func (pool *HashPool) requeueDirty(id int) {
+	pool.Lock()
+	defer pool.Unlock()
+
+	if pool.dbs[id].state != dbStateDirty {
+		return
+	}
+
+	pool.excludeIDFromChannel(pool.dirty, id)
+	pool.dirty <- id
+}
+
+// scheduleAutoCleanDirty pushes a single workerTaskAutoCleanDirty (if the workers are running), never blocking on a full task queue.
+func (pool *HashPool) scheduleAutoCleanDirty(ctx context.Context, log zerolog.Logger) {
+	pool.Lock()
+	defer pool.Unlock()
+
+	if !pool.running {
+		return
+	}
+
+	select {
+	case pool.tasksChan <- newQueuedTask(ctx, workerTaskAutoCleanDirty):
+	default:
+		log.Debug().Msg("task queue full, bailout scheduling")
+	}
+}
+
 func ignoreErrs(f func(ctx context.Context) error, errs ...error) func(context.Context) error {
 	return func(ctx context.Context) error {
 		err := f(ctx)
@@ -572,18 +1773,61 @@ func (pool *HashPool) extend(ctx context.Context) error {
 	ctx, task := trace.NewTask(ctx, "worker_extend")
 	defer task.End()
 
+	pool.RLock()
+	backoffUntil := pool.extendBackoffUntil
+	templateMissing := pool.templateMissing
+	pool.RUnlock()
+
+	// copying the template would fail anyways, don't add another test DB stuck recreating
+	if templateMissing {
+		log.Debug().Msg("bailout template missing")
+		return ErrTemplateMissing
+	}
+
+	// the server recently refused connections, don't add to the pressure by yet another test DB
+	if time.Now().Before(backoffUntil) {
+		log.Warn().Time("backoffUntil", backoffUntil).Msg("bailout backing off after max_connections was exceeded")
+		return ErrTooManyConnections
+	}
+
+	if pool.PoolConfig.CheckStorage != nil {
+		pool.RLock()
+		full := len(pool.dbs) >= pool.PoolConfig.MaxPoolSize || len(pool.dbs) == cap(pool.dbs)
+		templateDB := pool.templateDB
+		pool.RUnlock()
+
+		// no need to check the storage if we can't extend anyways
+		if full {
+			pool.notifyPoolFull(ctx, ErrPoolFull)
+			return ErrPoolFull
+		}
+
+		if err := pool.PoolConfig.callCheckStorage(ctx, templateDB); err != nil {
+			log.Error().Err(err).Msg("bailout storage check failed")
+			return err
+		}
+	}
+
 	reg := trace.StartRegion(ctx, "worker_wait_for_lock_hash_pool")
 	pool.Lock()
 	reg.End()
 
 	// get index of a next test DB - its ID
 	index := len(pool.dbs)
-	if index == cap(pool.dbs) {
+	if index >= pool.PoolConfig.MaxPoolSize || index == cap(pool.dbs) {
 		log.Error().Int("dbs", len(pool.dbs)).Int("cap", cap(pool.dbs)).Err(ErrPoolFull).Msg("pool is full")
 		pool.Unlock()
+		pool.notifyPoolFull(ctx, ErrPoolFull)
 		return ErrPoolFull
 	}
 
+	if !pool.budget.reserve() {
+		log.Debug().Int("dbs", len(pool.dbs)).Err(ErrMaxTotalDBs).Msg("bailout max total dbs reached")
+		pool.Unlock()
+		pool.notifyPoolFull(ctx, ErrMaxTotalDBs)
+		return ErrMaxTotalDBs
+	}
+
 	// initalization of a new DB using template config, it must start in state dirty!
 	newTestDB := existingDB{
 		state: dbStateDirty,
@@ -596,7 +1840,7 @@ func (pool *HashPool) extend(ctx context.Context) error {
 		},
 	}
 	// set DB name
-	newTestDB.Database.Config.Database = makeDBName(pool.TestDBNamePrefix, pool.templateDB.TemplateHash, index)
+	newTestDB.Database.Config.Database = pool.PoolConfig.buildDBName(pool.templateDB.TemplateHash, index)
 
 	// add new test DB to the pool (currently it's dirty!)
 	pool.dbs = append(pool.dbs, newTestDB)
@@ -609,50 +1853,239 @@ func (pool *HashPool) extend(ctx context.Context) error {
 	return pool.recreateDatabaseGracefully(ctx, index)
 }
 
+// backOffTooManyConnections records a recreate attempt rejected due to max_connections being exceeded and
+// refuses extending the pool for the TooManyConnectionsBackoff. Returns the duration to wait before retrying.
+func (pool *HashPool) backOffTooManyConnections() time.Duration {
+	backoff := pool.PoolConfig.TooManyConnectionsBackoff
+	if backoff < pool.PoolConfig.TestDatabaseRetryRecreateSleepMin {
+		backoff = pool.PoolConfig.TestDatabaseRetryRecreateSleepMin
+	}
+
+	pool.Lock()
+	defer pool.Unlock()
+
+	pool.tooManyConnectionsTotal++
+	pool.extendBackoffUntil = time.Now().Add(backoff)
+
+	return backoff
+}
+
+// drainPollInterval is the interval of checking whether all test DBs have been returned while draining.
+const drainPollInterval = 50 * time.Millisecond
+
+// drain stops handing out test DBs (ErrPoolDraining) and waits until none is held by a client anymore (see InUse).
+// If the ctx is done before, handing out test DBs is resumed and ErrStillInUse is returned, naming the IDs of the test DBs still held.
+func (pool *HashPool) drain(ctx context.Context) error {
+
+	log := pool.getPoolLogger(ctx, "drain")
+
+	pool.Lock()
+	pool.draining = true
+	pool.Unlock()
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for {
+		inUse := pool.InUse()
+		if len(inUse) == 0 {
+			log.Debug().Msg("drained")
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			pool.Lock()
+			pool.draining = false
+			pool.Unlock()
+
+			ids := make([]int, 0, len(inUse))
+			for _, info := range inUse {
+				ids = append(ids, info.ID)
+			}
+
+			log.Warn().Ints("ids", ids).Err(ctx.Err()).Msg("bailout test databases still in use")
+			return fmt.Errorf("%w: ids %v: %w", ErrStillInUse, ids, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// RemoveAll removes all test DBs of the pool via the given removeFunc, dropping up to MaxParallelRemoves of them concurrently.
+// If removing a test DB fails, the pool is left in a consistent state: It holds only the not yet removed test DBs
+// (ID below and including the highest failed one) and its background workers are restarted (if they were running before), thus the operation can be repeated.
 func (pool *HashPool) RemoveAll(ctx context.Context, removeFunc RemoveDBFunc) error {
+	return pool.removeAll(ctx, removeFunc, newRemoveSlots(pool.MaxParallelRemoves))
+}
+
+// removeAll implements RemoveAll, the given semaphore limits the concurrent removals (shared by all pools within PoolCollection.RemoveAll).
+func (pool *HashPool) removeAll(ctx context.Context, removeFunc RemoveDBFunc, slots chan struct{}) error {
 
 	log := pool.getPoolLogger(ctx, "RemoveAll")
 
+	pool.RLock()
+	wasRunning := pool.running
+	pool.RUnlock()
+
 	// stop all workers
 	pool.Stop()
 
 	// wait until all current "recreating" tasks are finished...
 
 	pool.Lock()
-	defer pool.Unlock()
 
 	if len(pool.dbs) == 0 {
 		log.Error().Msg("bailout no dbs.")
+		pool.Unlock()
 		return nil
 	}
 
-	// remove from back to be able to repeat operation in case of error
-	for id := len(pool.dbs) - 1; id >= 0; id-- {
-		testDB := pool.dbs[id].TestDatabase
+	// collect the test DBs under lock, they are removed without holding it (no test DBs are handed out meanwhile)
+	wasDraining := pool.draining
+	pool.draining = true
 
-		if err := removeFunc(ctx, testDB); err != nil {
-			log.Error().Int("id", id).Err(err).Msg("removeFunc testdatabase err")
-			return err
-		}
+	testDBs := make([]db.TestDatabase, len(pool.dbs))
+	for id := range pool.dbs {
+		testDBs[id] = pool.dbs[id].TestDatabase
+	}
 
-		if len(pool.dbs) > 1 {
-			pool.dbs = pool.dbs[:len(pool.dbs)-1]
-		}
+	pool.Unlock()
+
+	removed, errs := removeTestDatabases(ctx, testDBs, removeFunc, slots)
 
+	pool.Lock()
+	pool.draining = wasDraining
+
+	// remove from back to be able to repeat operation in case of error
+	kept := len(testDBs)
+	for kept > 0 && removed[kept-1] {
+		kept--
+	}
+
+	for id := kept; id < len(testDBs); id++ {
 		pool.excludeIDFromChannel(pool.dirty, id)
 		pool.excludeIDFromChannel(pool.ready, id)
 		log.Debug().Int("id", id).Msg("testdatabase removed!")
 	}
+	pool.budget.release(len(pool.dbs) - kept)
+	pool.dbs = pool.dbs[:kept]
+
+	if kept > 0 {
+		// test DBs removed below a failed one are kept, but recreated from the template by the restarted workers
+		for id := 0; id < kept; id++ {
+			if !removed[id] {
+				continue
+			}
+
+			pool.excludeIDFromChannel(pool.dirty, id)
+			pool.excludeIDFromChannel(pool.ready, id)
+			pool.dbs[id].state = dbStateDirty
+			pool.dbs[id].poisoned = true
+			pool.dbs[id].dirtyAt = time.Now()
+			pool.dbs[id].acquiredAt = time.Time{}
+			pool.dbs[id].blockAutoCleanDirtyUntil = time.Time{}
+			pool.dbs[id].unsafeReserve(0)
+			pool.dirty <- id
+		}
+
+		pool.unsafeTraceLogStats(log)
+		pool.Unlock()
+
+		// the pool stays in use, restart the workers
+		if wasRunning {
+			pool.Start()
+		}
+
+		return errors.Join(errs...)
+	}
 
 	// close all only if removal of all succeeded
 	pool.dbs = nil
 	close(pool.tasksChan)
 
 	pool.unsafeTraceLogStats(log)
+	pool.Unlock()
 
 	return nil
 }
 
+// removeTestDatabases removes the given test DBs (indexed by ID) via the removeFunc, from the highest ID to the lowest one.
+// Up to cap(slots) removals run concurrently. After the first failure (or once the ctx is done), no further removals are started.
+// Returns which test DBs have been removed and the errors of the failed ones.
+func removeTestDatabases(ctx context.Context, testDBs []db.TestDatabase, removeFunc RemoveDBFunc, slots chan struct{}) ([]bool, []error) {
+	removed := make([]bool, len(testDBs))
+
+	var (
+		wg     sync.WaitGroup
+		mutex  sync.Mutex
+		errs   []error
+		failed int32
+	)
+
+	for id := len(testDBs) - 1; id >= 0 && atomic.LoadInt32(&failed) == 0; id-- {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			mutex.Lock()
+			errs = append(errs, fmt.Errorf("remove db %s: %w", testDBs[id].TemplateHash, ctx.Err()))
+			mutex.Unlock()
+			atomic.StoreInt32(&failed, 1)
+			continue
+		}
+
+		// the slot may have been freed by a failed removal
+		if atomic.LoadInt32(&failed) != 0 {
+			<-slots
+			break
+		}
+
+		wg.Add(1)
+		go func(id int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			testDB := testDBs[id]
+			if err := callRemoveDB(ctx, removeFunc, testDB); err != nil {
+				atomic.StoreInt32(&failed, 1)
+
+				mutex.Lock()
+				errs = append(errs, fmt.Errorf("remove db %s id %d: %w", testDB.TemplateHash, id, err))
+				mutex.Unlock()
+				return
+			}
+
+			removed[id] = true
+		}(id)
+	}
+
+	wg.Wait()
+
+	return removed, errs
+}
+
+// newRemoveSlots returns the semaphore limiting the concurrent removals to the given number (values <= 1 remove one at a time).
+func newRemoveSlots(maxParallelRemoves int) chan struct{} {
+	if maxParallelRemoves < 1 {
+		maxParallelRemoves = 1
+	}
+
+	return make(chan struct{}, maxParallelRemoves)
+}
+
+// rehash makes the pool serve the given template hash (see PoolCollection.SwapHash).
+// Existing test DBs keep their names, new ones are named according to the new hash.
+func (pool *HashPool) rehash(hash string) {
+	pool.Lock()
+	defer pool.Unlock()
+
+	pool.templateDB.TemplateHash = hash
+	for i := range pool.dbs {
+		pool.dbs[i].TemplateHash = hash
+	}
+}
+
 func (pool *HashPool) getPoolLogger(ctx context.Context, poolFunction string) zerolog.Logger {
 	return util.LogFromContext(ctx).With().Str("poolHash", pool.templateDB.TemplateHash).Str("poolFn", poolFunction).Logger()
 }
@@ -0,0 +1,25 @@
+package pool
+
+import "time"
+
+// AcquireInfo describes how a test DB was handed out (see GetTestDatabaseWithInfo), thus clients may adapt to the pressure on the pool
+// (e.g. reduce their parallelism while it is thin).
+type AcquireInfo struct {
+	Dirty       bool          // handed out as is (again for a sticky key) instead of freshly cleaned
+	Waited      time.Duration // time spent within the pool, mostly waiting for a ready test DB
+	Ready       int           // test DBs left ready right after the acquisition
+	ReadyTarget int           // test DBs the pool tries to keep ready
+}
+
+// acquireInfo describes the acquisition started at the given time by the current counts of the pool.
+func (pool *HashPool) acquireInfo(start time.Time, dirty bool) AcquireInfo {
+	pool.RLock()
+	defer pool.RUnlock()
+
+	return AcquireInfo{
+		Dirty:       dirty,
+		Waited:      time.Since(start),
+		Ready:       len(pool.ready),
+		ReadyTarget: pool.readyTarget,
+	}
+}
@@ -0,0 +1,50 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolGetTestDatabaseWithInfo(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	cfg := PoolConfig{
+		InitialPoolSize:        3,
+		MaxPoolSize:            3,
+		MaxParallelTasks:       1,
+		disableWorkerAutostart: true,
+	}
+	p := newTestPoolCollection(t, cfg, db.Database{TemplateHash: "h1"}, 3, noopRecreateDB)
+
+	sticky := GetOptions{StickyKey: "TestFlaky"}
+
+	first, info, err := p.GetTestDatabaseWithInfo(ctx, "h1", time.Second, sticky)
+	require.NoError(t, err)
+	assert.False(t, info.Dirty)
+	assert.Equal(t, 2, info.Ready)
+	assert.Equal(t, 3, info.ReadyTarget)
+	assert.GreaterOrEqual(t, info.Waited, time.Duration(0))
+
+	// handed out again as is for the sticky key, thus not taken from the ready ones
+	retry, info, err := p.GetTestDatabaseWithInfo(ctx, "h1", time.Second, sticky)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, retry.ID)
+	assert.True(t, info.Dirty)
+	assert.Equal(t, 2, info.Ready)
+
+	_, info, err = p.GetTestDatabaseWithInfo(ctx, "h1", time.Second, GetOptions{})
+	require.NoError(t, err)
+	assert.False(t, info.Dirty)
+	assert.Equal(t, 1, info.Ready)
+
+	// failed gets describe nothing
+	_, info, err = p.GetTestDatabaseWithInfo(ctx, "unknown", time.Second, GetOptions{})
+	assert.ErrorIs(t, err, ErrUnknownHash)
+	assert.Equal(t, AcquireInfo{}, info)
+}
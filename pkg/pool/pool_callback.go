@@ -0,0 +1,70 @@
+package pool
+
+import (
+	"context"
+	"runtime/debug"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+)
+
+// The user-supplied callbacks are invoked via the following wrappers, which convert a panic into a CallbackPanicError.
+// Thus a buggy callback fails like any other error of it (e.g. the test DB is recreated later on) instead of crashing the server.
+
+// recoverCallback converts a panic of the named callback into a CallbackPanicError assigned to err, to be deferred by the invoker.
+func recoverCallback(callback string, err *error) {
+	if r := recover(); r != nil {
+		*err = &CallbackPanicError{Callback: callback, Value: r, Stack: debug.Stack()}
+	}
+}
+
+func callRecreateDB(ctx context.Context, recreateFunc RecreateDBFunc, testDB db.TestDatabase, templateName string) (err error) {
+	defer recoverCallback("RecreateDBFunc", &err)
+	return recreateFunc(ctx, testDB, templateName)
+}
+
+func callRemoveDB(ctx context.Context, removeFunc RemoveDBFunc, testDB db.TestDatabase) (err error) {
+	defer recoverCallback("RemoveDBFunc", &err)
+	return removeFunc(ctx, testDB)
+}
+
+func callSelectTemplate(selectTemplate SelectTemplateFunc, testDB db.TestDatabase, templateName string) (name string, err error) {
+	defer recoverCallback("SelectTemplateFunc", &err)
+	return selectTemplate(testDB, templateName), nil
+}
+
+func (cfg PoolConfig) callResetDB(ctx context.Context, testDB db.TestDatabase) (err error) {
+	defer recoverCallback("ResetDBFunc", &err)
+	return cfg.ResetDB(ctx, testDB)
+}
+
+func (cfg PoolConfig) callPingDB(ctx context.Context, testDB db.TestDatabase) (err error) {
+	defer recoverCallback("PingDBFunc", &err)
+	return cfg.PingDB(ctx, testDB)
+}
+
+func (cfg PoolConfig) callVerifyClean(ctx context.Context, testDB db.TestDatabase) (clean bool, err error) {
+	defer recoverCallback("VerifyCleanFunc", &err)
+	return cfg.VerifyClean(ctx, testDB)
+}
+
+func (cfg PoolConfig) callCheckStorage(ctx context.Context, templateDB db.Database) (err error) {
+	defer recoverCallback("CheckStorageFunc", &err)
+	return cfg.CheckStorage(ctx, templateDB)
+}
+
+func (cfg PoolConfig) callOnReady(hash string) (err error) {
+	defer recoverCallback("OnReadyFunc", &err)
+	cfg.OnReady(hash)
+	return nil
+}
+
+func (cfg PoolConfig) callOnPoolFull(hash string, snapshot PoolSnapshot, poolErr error) (err error) {
+	defer recoverCallback("OnPoolFullFunc", &err)
+	cfg.OnPoolFull(hash, snapshot, poolErr)
+	return nil
+}
+
+func (cfg PoolConfig) callDBName(hash string, id int) (name string, err error) {
+	defer recoverCallback("DBNameFunc", &err)
+	return cfg.DBName(cfg.TestDBNamePrefix, hash, cfg.InstanceID, id), nil
+}
@@ -0,0 +1,74 @@
+package pool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolCallbackPanic(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+	}
+
+	var panicking atomic.Bool
+	panicking.Store(true)
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		if panicking.CompareAndSwap(true, false) {
+			panic("init failed")
+		}
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:      2,
+		MaxParallelTasks: 1,
+		RecreateInline:   true, // recreate synchronously
+		DBName: func(testDBPrefix string, hash string, instanceID string, id int) string {
+			panic("name failed")
+		},
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 0, initFunc)
+
+	// the panic is returned as error, including the stack
+	err := p.extend(ctx, templateDB1)
+	require.ErrorIs(t, err, ErrCallbackPanic)
+
+	var panicErr *CallbackPanicError
+	require.ErrorAs(t, err, &panicErr)
+	assert.Equal(t, "RecreateDBFunc", panicErr.Callback)
+	assert.Equal(t, "init failed", panicErr.Value)
+	assert.NotEmpty(t, panicErr.Stack)
+
+	// the pool remains usable, a panicking name builder falls back to the default name
+	require.NoError(t, p.extend(ctx, templateDB1))
+
+	testDB, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, makeDBName("", hash1, "", testDB.ID), testDB.Config.Database)
+
+	// a panicking inline recreation keeps the test DB dirty, it is cleaned in background once the callback recovered
+	panicking.Store(true)
+	require.NoError(t, p.RecreateTestDatabase(ctx, hash1, testDB.ID))
+	requireSnapshotEventually(t, p, hash1, func(snapshot PoolSnapshot) bool { return snapshot.Ready == 1 })
+	assert.False(t, panicking.Load())
+
+	// a panicking removal keeps the pool, it may be removed again
+	err = p.RemoveAllWithHash(ctx, hash1, func(ctx context.Context, testDB db.TestDatabase) error {
+		panic("remove failed")
+	})
+	assert.ErrorIs(t, err, ErrCallbackPanic)
+
+	require.NoError(t, p.RemoveAllWithHash(ctx, hash1, func(ctx context.Context, testDB db.TestDatabase) error {
+		return nil
+	}))
+}
@@ -5,23 +5,71 @@ import (
 	"errors"
 	"fmt"
 	"runtime/trace"
+	"sort"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/allaboutapps/integresql/pkg/db"
 )
 
-var ErrUnknownHash = errors.New("no database pool exists for this hash")
+// MaxDBNameLength is the maximal length of database names (in bytes) supported by Postgres (NAMEDATALEN - 1).
+const MaxDBNameLength = 63
+
+var (
+	ErrUnknownHash = errors.New("no database pool exists for this hash")
+	ErrPoolInUse   = errors.New("database pool has test databases in use, return them first")
+
+	// ErrHashRemoved is returned instead of ErrUnknownHash if the pool of the hash was removed recently (see PoolConfig.RemovedHashHistory).
+	ErrHashRemoved = fmt.Errorf("%w, it was removed", ErrUnknownHash)
+
+	// ErrMaxTotalDBs is returned instead of ErrPoolFull if the pool can't be extended as the cap across all pools is reached (see PoolConfig.MaxTotalDBs).
+	ErrMaxTotalDBs = fmt.Errorf("%w, the maximal number of test databases across all pools is reached", ErrPoolFull)
+)
 
 // we explicitly want to access this struct via pool.PoolConfig, thus we disable revive for the next line
 type PoolConfig struct { //nolint:revive
-	InitialPoolSize                   int           // Initial number of ready DBs prepared in background
-	MaxPoolSize                       int           // Maximal pool size that won't be exceeded
-	TestDBNamePrefix                  string        // Test-Database prefix: DatabasePrefix_TestDBNamePrefix_HASH_ID
-	MaxParallelTasks                  int           // Maximal number of pool tasks running in parallel. Must be a number greater or equal 1.
-	TestDatabaseRetryRecreateSleepMin time.Duration // Minimal time to wait after a test db recreate has failed (e.g. as client is still connected). Subsequent retries multiply this values until...
-	TestDatabaseRetryRecreateSleepMax time.Duration // ... the maximum possible sleep time between retries (e.g. 3 seconds) is reached.
-	TestDatabaseMinimalLifetime       time.Duration // After a testdatabase transitions from ready to dirty, always block auto-recreation for this duration (except manual recreate).
+	InitialPoolSize                   int                // Initial number of ready DBs prepared in background
+	MaxPoolSize                       int                // Maximal pool size that won't be exceeded
+	TestDBNamePrefix                  string             // Test-Database prefix: DatabasePrefix_TestDBNamePrefix_HASH_ID
+	InstanceID                        string             // Optional short ID of the server instance (e.g. its start timestamp or a CI run ID) embedded into the test DB names: TestDBNamePrefix_HASH_INSTANCEID_ID, tracing leftover test DBs back to the run that created them.
+	MaxParallelTasks                  int                // Maximal number of pool tasks running in parallel. Must be a number greater or equal 1.
+	MaxParallelRemoves                int                // Maximal number of test DBs dropped concurrently by RemoveAll (values <= 1 drop one at a time), speeding up the shutdown of large pools.
+	RemovedHashHistory                int                // Number of recently removed hashes remembered, requesting their pools fails with ErrHashRemoved instead of ErrUnknownHash (0 disables). The least recently removed hashes fall out first.
+	TestDatabaseRetryRecreateSleepMin time.Duration      // Minimal time to wait after a test db recreate has failed (e.g. as client is still connected). Subsequent retries multiply this values until...
+	TestDatabaseRetryRecreateSleepMax time.Duration      // ... the maximum possible sleep time between retries (e.g. 3 seconds) is reached.
+	TestDatabaseMinimalLifetime       time.Duration      // After a testdatabase transitions from ready to dirty, always block auto-recreation for this duration (except manual recreate).
+	DirtyMaxAge                       time.Duration      // Dirty test DBs not cleaned within this duration after they turned dirty are force-recreated by RecreateStaleDirty (0 disables), bounding how stale a reused test DB can be.
+	ReturnCooldown                    time.Duration      // After a test DB was returned for recreation, neither clean it nor hand it out as is via GetTestDatabaseByID for this duration (0 disables), as connections of the previous holder may still be closing.
+	RecreateInline                    bool               // Recreate test DBs synchronously within RecreateTestDatabase instead of dispatching to a background worker (keeps tiny pools always-hot). A single attempt bounded by the request, falls back to a background worker if it failed (e.g. still in use), or queues the test DB as dirty again on other errors.
+	LenientReturns                    bool               // Ignore returns of test DBs that are still ready (not handed out, e.g. defensive double returns) instead of failing with ErrUnknownID.
+	RejectDirty                       bool               // Fail GetTestDatabaseByID with ErrWouldReuseDirty instead of handing out a dirty test DB as is (e.g. in CI, surfacing under-provisioning instead of flaky tests).
+	StickyKeyTTL                      time.Duration      // Time the test DB handed out for a sticky key (see GetOptions.StickyKey) is remembered after the last get with the key (defaults to 5 minutes).
+	PingDB                            PingDBFunc         `json:"-"` // Optional liveness check of a ready test DB before handing it out. Dead test DBs are flagged for recreation and the next ready one is tried...
+	PingDBMaxRetries                  int                // ... up to this number of times (to avoid spinning through an empty pool).
+	DBName                            DBNameFunc         `json:"-"` // Optional builder of test DB names, defaults to TestDBNamePrefix_HASH_ID (or TestDBNamePrefix_HASH_INSTANCEID_ID).
+	AutoScale                         bool               // Track the rate of gets that had to wait for a ready test DB and double the ready target (initially InitialPoolSize, up to MaxPoolSize) if...
+	AutoScaleStarvationThreshold      int                // ... more than this percentage of gets had to wait...
+	AutoScaleWindow                   int                // ... within this number of consecutive gets.
+	RecreateAttemptTimeout            time.Duration      // Deadline of a single attempt to (re)create a test DB via the RecreateDBFunc (0 disables). Cancellation of the parent context still applies.
+	CleanBatchSize                    int                // Maximal number of dirty test DBs recreated back to back by a single auto-clean task, a single attempt each (values <= 1 clean one at a time, retrying).
+	RefillWatermark                   int                // Percentage of the ready target: If fewer test DBs are ready (or recreating) after a get, the pool is extended up to the ready target at once instead of by one test DB per get (0 disables).
+	MinReady                          int                // Warm-standby: Minimal number of ready (or recreating) test DBs kept under churn, each get eagerly schedules the missing ones (extending up to MaxPoolSize, cleaning dirty test DBs beyond) and the ready target never drops below (0 disables).
+	MaxConcurrentCopies               int                // Maximal number of test DBs copied from their template at the same time across all pools of the collection (0 disables), smoothing the load of Postgres during bursts.
+	ConnectionLimit                   int                // Maximal number of connections to each test DB (<= 0 unlimited), enforced by the RecreateDBFunc, only used to estimate the connection usage (see EstimatedMaxConnections).
+	MaxTotalDBs                       int                // Maximal number of test DBs across all pools of the collection (0 disables), pools are no longer extended once reached (ErrMaxTotalDBs). See PoolCollection.Rebalance to redistribute them by demand.
+	TooManyConnectionsBackoff         time.Duration      // Pause of extending the pool and retrying a recreate after the server refused a connection as max_connections was exceeded.
+	LeakWarnTimeout                   time.Duration      // Test DBs held by a client for longer are logged as suspected leaks by ReclaimLeaked (0 disables)...
+	LeakReclaimTimeout                time.Duration      // ... and force-returned for recreation once held for longer than this (0 disables), typically a multiple of the LeakWarnTimeout.
+	SelectionSeed                     int64              // Seed of the RNG of SelectionPolicyRandom (0 seeds it by time), implied if no SelectionPolicy is set. Reruns pick the same IDs in the same order, given a deterministic workload.
+	SelectionPolicy                   SelectionPolicy    // Which of the ready test DBs is handed out next, defaults to SelectionPolicyFIFO (unknown policies fall back to it).
+	CheckStorage                      CheckStorageFunc   `json:"-"` // Optional pre-flight check before the pool is extended by a new test DB, returning ErrInsufficientStorage refuses the extension.
+	OnReady                           OnReadyFunc        `json:"-"` // Optional callback invoked once per pool, as soon as the ready test DBs first reach the InitialPoolSize (e.g. to proceed with a multi-stage test setup).
+	OnPoolFull                        OnPoolFullFunc     `json:"-"` // Optional callback invoked if a pool could not be extended as it is full (ErrPoolFull or ErrMaxTotalDBs), e.g. to alert on capacity incidents...
+	OnPoolFullInterval                time.Duration      // ... at most once per this interval per pool (defaults to a minute).
+	ResetDB                           ResetDBFunc        `json:"-"` // Optional cheaper clean of a dirty test DB (e.g. TRUNCATE) used instead of the RecreateDBFunc. New test DBs are always created via the RecreateDBFunc, which is also the fallback if resetting fails.
+	SelectTemplate                    SelectTemplateFunc `json:"-"` // Optional selection of the template DB a test DB is copied from (e.g. WeightedRoundRobin among identical copies of the template), defaults to the template DB of the hash.
+	VerifyClean                       VerifyCleanFunc    `json:"-"` // Optional check of a test DB returned as clean (ReturnTestDatabase) before it is ready again, test DBs reported dirty are recreated instead. Costs a query per return.
 
 	disableWorkerAutostart bool // test only private flag for starting without background worker task system
 }
@@ -30,8 +78,11 @@ type PoolConfig struct { //nolint:revive
 type PoolCollection struct { //nolint:revive
 	PoolConfig
 
-	pools map[string]*HashPool // map[hash]
-	mutex sync.RWMutex
+	pools     map[string]*HashPool // map[hash]
+	removed   *removedHashes       // recently removed hashes (see RemovedHashHistory)
+	copySlots chan struct{}        // shared by all pools to limit the concurrent copies (nil if unlimited, see MaxConcurrentCopies)
+	budget    *dbBudget            // shared by all pools to cap the test DBs (nil if unlimited, see MaxTotalDBs)
+	mutex     sync.RWMutex
 }
 
 // enableDBRecreate set to false will allow reusing test databases that are marked as 'dirty'.
@@ -39,6 +90,9 @@ type PoolCollection struct { //nolint:revive
 func NewPoolCollection(cfg PoolConfig) *PoolCollection {
 	return &PoolCollection{
 		pools:      make(map[string]*HashPool),
+		removed:    newRemovedHashes(cfg.RemovedHashHistory),
+		copySlots:  newCopySlots(cfg.MaxConcurrentCopies),
+		budget:     newDBBudget(cfg.MaxTotalDBs),
 		PoolConfig: cfg,
 	}
 }
@@ -49,30 +103,108 @@ type RecreateDBFunc func(ctx context.Context, testDB db.TestDatabase, templateNa
 // RemoveDBFunc callback executed to remove a database
 type RemoveDBFunc func(ctx context.Context, testDB db.TestDatabase) error
 
-func makeActualRecreateTestDBFunc(templateName string, userRecreateFunc RecreateDBFunc) recreateTestDBFunc {
+// GetOptions hold optional parameters for acquiring a test DB.
+type GetOptions struct {
+	Labels      map[string]string // Custom labels stored with the in-use test DB (e.g. the CI job ID), cleared on return.
+	RejectDirty bool              // Fail with ErrWouldReuseDirty instead of handing out a dirty test DB as is (GetTestDatabaseByID), regardless of PoolConfig.RejectDirty.
+
+	ReservationTTL time.Duration // Soft reservation: The test DB is reclaimed by ReclaimExpired unless returned or heartbeated (ExtendReservation) within the TTL (0 disables).
+
+	StickyKey string // Optional key (e.g. the name of a retried test): Gets with the same key hand out the test DB last handed out for it again (as is, like GetTestDatabaseByID) as long as it was not recreated, within the StickyKeyTTL.
+}
+
+// DBNameFunc builds the name of a test DB from the configured prefix, the template hash, the ID of the server instance (empty if not configured) and the ID of the DB.
+// The name is always quoted when used as identifier, thus it may contain any characters (e.g. hyphens or uppercase letters, which are not folded).
+// Postgres silently truncates names beyond MaxDBNameLength bytes, the builder must keep them within.
+type DBNameFunc func(testDBPrefix string, hash string, instanceID string, id int) string
+
+// ResetDBFunc callback executed to clean an existing dirty test DB in place instead of recreating it from the template.
+type ResetDBFunc func(ctx context.Context, testDB db.TestDatabase) error
+
+// CheckStorageFunc callback executed before a new test DB is created from the given template, refusing it if the PostgreSQL server is low on storage (ErrInsufficientStorage).
+type CheckStorageFunc func(ctx context.Context, templateDB db.Database) error
+
+// PingDBFunc callback executed to check that a test DB is still alive before it is handed out.
+// Dead test DBs (e.g. dropped out-of-band) must be reported by an error wrapping ErrTestDBDead, thus they are recreated.
+// Other errors (e.g. the server is unreachable) are returned to the client, the test DB is kept as is.
+type PingDBFunc func(ctx context.Context, testDB db.TestDatabase) error
+
+// SelectTemplateFunc callback executed before a test DB is (re)created via the RecreateDBFunc, returning the name of the template DB to copy from.
+// templateName is the template DB of the hash, which must be returned if no other source is suitable.
+type SelectTemplateFunc func(testDB db.TestDatabase, templateName string) string
+
+// VerifyCleanFunc callback executed to check that a test DB returned without cleaning is actually still in the state of its template.
+// Failing checks are treated as dirty.
+type VerifyCleanFunc func(ctx context.Context, testDB db.TestDatabase) (bool, error)
+
+// OnReadyFunc callback executed once the pool of the given hash has warmed up (see PoolConfig.OnReady).
+// It is called without holding any lock, thus it may use the PoolCollection.
+type OnReadyFunc func(hash string)
+
+// OnPoolFullFunc callback executed if the pool of the given hash could not be extended as it is full, err is either ErrPoolFull
+// or ErrMaxTotalDBs (the cap across all pools is reached). The snapshot describes the pool right after (see PoolConfig.OnPoolFull).
+// It is called without holding any lock, but by the task extending the pool, thus it should return quickly (e.g. enqueue an alert).
+type OnPoolFullFunc func(hash string, snapshot PoolSnapshot, err error)
+
+func makeActualRecreateTestDBFunc(templateName string, selectTemplate SelectTemplateFunc, userRecreateFunc RecreateDBFunc) recreateTestDBFunc {
+	if selectTemplate == nil {
+		return func(ctx context.Context, testDBWrapper *existingDB) error {
+			return callRecreateDB(ctx, userRecreateFunc, testDBWrapper.TestDatabase, templateName)
+		}
+	}
+
 	return func(ctx context.Context, testDBWrapper *existingDB) error {
-		return userRecreateFunc(ctx, testDBWrapper.TestDatabase, templateName)
+		selected, err := callSelectTemplate(selectTemplate, testDBWrapper.TestDatabase, templateName)
+		if err != nil {
+			return err
+		}
+
+		return callRecreateDB(ctx, userRecreateFunc, testDBWrapper.TestDatabase, selected)
 	}
 }
 
 type recreateTestDBFunc func(context.Context, *existingDB) error
 
 // InitHashPool creates a new pool with a given template hash and starts the cleanup workers.
-func (p *PoolCollection) InitHashPool(_ context.Context, templateDB db.Database, initDBFunc RecreateDBFunc) {
+func (p *PoolCollection) InitHashPool(ctx context.Context, templateDB db.Database, initDBFunc RecreateDBFunc) {
+	p.InitHashPoolWithConfig(ctx, p.DefaultConfig(), templateDB, initDBFunc)
+}
+
+// DefaultConfig returns the config applied to new pools (which may differ from the initial one, see SetMaxPoolSize).
+func (p *PoolCollection) DefaultConfig() PoolConfig {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.PoolConfig
+}
+
+// InitHashPoolWithConfig creates a new pool with a given template hash and starts the cleanup workers.
+// The provided config is used for this hash only, allowing to override the collection defaults per hash.
+func (p *PoolCollection) InitHashPoolWithConfig(_ context.Context, cfg PoolConfig, templateDB db.Database, initDBFunc RecreateDBFunc) {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
-	cfg := p.PoolConfig
-
 	// Create a new HashPool
 	pool := NewHashPool(cfg, templateDB, initDBFunc)
 
+	// the limit of concurrent copies and the cap of test DBs apply to the whole collection, not per hash
+	pool.copySlots = p.copySlots
+	pool.budget = p.budget
+
+	// a replaced pool is forgotten, thus its test DBs no longer count
+	if replaced, ok := p.pools[templateDB.TemplateHash]; ok {
+		replaced.RLock()
+		p.budget.release(len(replaced.dbs))
+		replaced.RUnlock()
+	}
+
 	if !cfg.disableWorkerAutostart {
 		pool.Start()
 	}
 
 	// pool is ready
 	p.pools[pool.templateDB.TemplateHash] = pool
+	p.removed.forget(pool.templateDB.TemplateHash)
 }
 
 // Start is used to start all background workers
@@ -95,37 +227,335 @@ func (p *PoolCollection) Stop() {
 	}
 }
 
+// EnsureReadyWithHash synchronously warms up the pool of the given hash (see HashPool.EnsureReady).
+// Stop aborts a running warm-up.
+func (p *PoolCollection) EnsureReadyWithHash(ctx context.Context, hash string) error {
+	pool, err := p.getPool(ctx, hash)
+	if err != nil {
+		return wrapPoolError("EnsureReady", hash, -1, err)
+	}
+
+	return wrapPoolError("EnsureReady", hash, -1, pool.EnsureReady(ctx))
+}
+
 // GetTestDatabase picks up a ready to use test DB. It waits the given timeout until a DB is available.
 // If there is no DB ready and time elapses, ErrTimeout is returned.
 // Otherwise, the obtained test DB is marked as 'dirty' and can be reused only if returned to the pool.
 func (p *PoolCollection) GetTestDatabase(ctx context.Context, hash string, timeout time.Duration) (db db.TestDatabase, err error) {
+	return p.GetTestDatabaseWithOptions(ctx, hash, timeout, GetOptions{})
+}
+
+// GetTestDatabaseWithOptions picks up a ready to use test DB, applying the given options (see GetTestDatabase).
+func (p *PoolCollection) GetTestDatabaseWithOptions(ctx context.Context, hash string, timeout time.Duration, opts GetOptions) (db db.TestDatabase, err error) {
+
+	pool, err := p.getPool(ctx, hash)
+	if err != nil {
+		return db, wrapPoolError("GetTestDatabase", hash, -1, err)
+	}
+
+	db, err = pool.GetTestDatabaseWithOptions(ctx, timeout, opts)
+	return db, wrapPoolError("GetTestDatabase", hash, -1, err)
+}
+
+// GetTestDatabaseWithInfo picks up a ready to use test DB, applying the given options and describing the acquisition (see HashPool.GetTestDatabaseWithInfo).
+func (p *PoolCollection) GetTestDatabaseWithInfo(ctx context.Context, hash string, timeout time.Duration, opts GetOptions) (db db.TestDatabase, info AcquireInfo, err error) {
+
+	pool, err := p.getPool(ctx, hash)
+	if err != nil {
+		return db, info, wrapPoolError("GetTestDatabase", hash, -1, err)
+	}
+
+	db, info, err = pool.GetTestDatabaseWithInfo(ctx, timeout, opts)
+	return db, info, wrapPoolError("GetTestDatabase", hash, -1, err)
+}
+
+// GetTestDatabaseByID picks up the test DB with the given ID (see HashPool.GetTestDatabaseByID).
+func (p *PoolCollection) GetTestDatabaseByID(ctx context.Context, hash string, id int) (db db.TestDatabase, dirty bool, err error) {
+	pool, err := p.getPool(ctx, hash)
+	if err != nil {
+		return db, false, wrapPoolError("GetTestDatabaseByID", hash, id, err)
+	}
+
+	db, dirty, err = pool.GetTestDatabaseByID(ctx, id)
+	return db, dirty, wrapPoolError("GetTestDatabaseByID", hash, id, err)
+}
 
+// GetTestDatabaseByIDWithOptions picks up the test DB with the given ID, applying the given options (see HashPool.GetTestDatabaseByIDWithOptions).
+func (p *PoolCollection) GetTestDatabaseByIDWithOptions(ctx context.Context, hash string, id int, opts GetOptions) (db db.TestDatabase, dirty bool, err error) {
 	pool, err := p.getPool(ctx, hash)
 	if err != nil {
-		return db, err
+		return db, false, wrapPoolError("GetTestDatabaseByID", hash, id, err)
 	}
 
-	return pool.GetTestDatabase(ctx, timeout)
+	db, dirty, err = pool.GetTestDatabaseByIDWithOptions(ctx, id, opts)
+	return db, dirty, wrapPoolError("GetTestDatabaseByID", hash, id, err)
 }
 
 // ReturnTestDatabase returns the given test DB directly to the pool, without cleaning (recreating it).
 func (p *PoolCollection) ReturnTestDatabase(ctx context.Context, hash string, id int) error {
+	return p.ReturnTestDatabaseWithLease(ctx, hash, id, "")
+}
+
+// ReturnTestDatabaseWithLease returns the given test DB if the given lease (if any) is still valid (see HashPool.ReturnTestDatabaseWithLease).
+func (p *PoolCollection) ReturnTestDatabaseWithLease(ctx context.Context, hash string, id int, lease string) error {
 	pool, err := p.getPool(ctx, hash)
 	if err != nil {
-		return err
+		return wrapPoolError("ReturnTestDatabase", hash, id, err)
+	}
+
+	return wrapPoolError("ReturnTestDatabase", hash, id, pool.ReturnTestDatabaseWithLease(ctx, id, lease))
+}
+
+// ReturnTestDatabasePoisoned returns the given test DB as corrupted beyond what cleaning can fix, it is fully recreated from the template.
+func (p *PoolCollection) ReturnTestDatabasePoisoned(ctx context.Context, hash string, id int) error {
+	return p.ReturnTestDatabasePoisonedWithLease(ctx, hash, id, "")
+}
+
+// ReturnTestDatabasePoisonedWithLease returns the given test DB as poisoned, checking the given lease (if any) like ReturnTestDatabaseWithLease.
+func (p *PoolCollection) ReturnTestDatabasePoisonedWithLease(ctx context.Context, hash string, id int, lease string) error {
+	pool, err := p.getPool(ctx, hash)
+	if err != nil {
+		return wrapPoolError("ReturnTestDatabasePoisoned", hash, id, err)
 	}
 
-	return pool.ReturnTestDatabase(ctx, id)
+	return wrapPoolError("ReturnTestDatabasePoisoned", hash, id, pool.ReturnTestDatabasePoisonedWithLease(ctx, id, lease))
 }
 
 // RecreateTestDatabase recreates the test DB according to the template and returns it back to the pool.
 func (p *PoolCollection) RecreateTestDatabase(ctx context.Context, hash string, id int) error {
+	return p.RecreateTestDatabaseWithLease(ctx, hash, id, "")
+}
+
+// RecreateTestDatabaseWithLease recreates the given test DB if the given lease (if any) is still valid (see HashPool.RecreateTestDatabaseWithLease).
+func (p *PoolCollection) RecreateTestDatabaseWithLease(ctx context.Context, hash string, id int, lease string) error {
 	pool, err := p.getPool(ctx, hash)
 	if err != nil {
-		return err
+		return wrapPoolError("RecreateTestDatabase", hash, id, err)
+	}
+
+	return wrapPoolError("RecreateTestDatabase", hash, id, pool.RecreateTestDatabaseWithLease(ctx, id, lease))
+}
+
+// Snapshot returns the current state of the pool with the given template hash.
+func (p *PoolCollection) Snapshot(ctx context.Context, hash string) (PoolSnapshot, error) {
+	pool, err := p.getPool(ctx, hash)
+	if err != nil {
+		return PoolSnapshot{}, err
+	}
+
+	return pool.Snapshot(), nil
+}
+
+// InUse returns the test DBs of the given hash currently held by clients, sorted by acquisition time (see HashPool.InUse).
+func (p *PoolCollection) InUse(ctx context.Context, hash string) ([]InUseInfo, error) {
+	pool, err := p.getPool(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return pool.InUse(), nil
+}
+
+// PeekReady returns the IDs of the currently ready test DBs of the pool with the given template hash, without reserving any of them.
+// Contrary to GetTestDatabase, the pool is not mutated, the returned slice is a copy.
+func (p *PoolCollection) PeekReady(ctx context.Context, hash string) ([]int, error) {
+	pool, err := p.getPool(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return pool.PeekReady(), nil
+}
+
+// PeekTestDatabase returns the test DB with the given ID of the pool with the given template hash, without handing it out.
+func (p *PoolCollection) PeekTestDatabase(ctx context.Context, hash string, id int) (db.TestDatabase, error) {
+	pool, err := p.getPool(ctx, hash)
+	if err != nil {
+		return db.TestDatabase{}, err
+	}
+
+	testDB, err := pool.PeekTestDatabase(id)
+	return testDB, wrapPoolError("PeekTestDatabase", hash, id, err)
+}
+
+// SnapshotAll returns the current state of all tracked pools, sorted by template hash.
+func (p *PoolCollection) SnapshotAll(_ context.Context) []PoolSnapshot {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	snapshots := make([]PoolSnapshot, 0, len(p.pools))
+	for _, pool := range p.pools {
+		snapshots = append(snapshots, pool.Snapshot())
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].TemplateHash < snapshots[j].TemplateHash
+	})
+
+	return snapshots
+}
+
+// PendingCleanup returns the IDs of the dirty test DBs waiting to be cleaned per template hash (see HashPool.PendingCleanup),
+// pools without any are omitted. The collection is locked meanwhile, thus no pools are added or removed while the snapshot is taken.
+// Persistently growing lists hint that the workers don't keep up (e.g. raise MaxParallelTasks).
+func (p *PoolCollection) PendingCleanup(_ context.Context) map[string][]int {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	pending := make(map[string][]int)
+	for hash, pool := range p.pools {
+		if ids := pool.PendingCleanup(); len(ids) > 0 {
+			pending[hash] = ids
+		}
+	}
+
+	return pending
+}
+
+// ForEachPool calls fn with the current snapshot of every tracked pool, sorted by template hash.
+// The list of hashes is taken up front and the collection is not locked while fn runs, thus fn may safely call
+// other PoolCollection methods (e.g. RemoveAllWithHash). Pools may change between taking the list and the call of fn:
+// Pools removed in the meantime are skipped, pools added in the meantime are not visited.
+// Iteration stops at the first error returned by fn, which is then returned.
+func (p *PoolCollection) ForEachPool(ctx context.Context, fn func(hash string, snapshot PoolSnapshot) error) error {
+	p.mutex.RLock()
+	hashes := make([]string, 0, len(p.pools))
+	for hash := range p.pools {
+		hashes = append(hashes, hash)
+	}
+	p.mutex.RUnlock()
+
+	sort.Strings(hashes)
+
+	for _, hash := range hashes {
+		pool, err := p.getPool(ctx, hash)
+		if err != nil {
+			// pool has been removed in the meantime
+			continue
+		}
+
+		if err := fn(hash, pool.Snapshot()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SnapshotPage returns the snapshots of the pools at the given offset of all pools sorted by template hash, at most limit of them
+// (all remaining if limit <= 0), and the total number of pools. Only the pools of the page are snapshotted, thus paging through
+// hundreds of pools doesn't lock all of them per call. Pools removed in the meantime are skipped, thus the page may be short.
+func (p *PoolCollection) SnapshotPage(ctx context.Context, offset int, limit int) ([]PoolSnapshot, int) {
+	p.mutex.RLock()
+	hashes := make([]string, 0, len(p.pools))
+	for hash := range p.pools {
+		hashes = append(hashes, hash)
+	}
+	p.mutex.RUnlock()
+
+	sort.Strings(hashes)
+
+	total := len(hashes)
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > total {
+		offset = total
+	}
+
+	hashes = hashes[offset:]
+	if limit > 0 && limit < len(hashes) {
+		hashes = hashes[:limit]
+	}
+
+	snapshots := make([]PoolSnapshot, 0, len(hashes))
+	for _, hash := range hashes {
+		pool, err := p.getPool(ctx, hash)
+		if err != nil {
+			// pool has been removed in the meantime
+			continue
+		}
+
+		snapshots = append(snapshots, pool.Snapshot())
+	}
+
+	return snapshots, total
+}
+
+// SetMaxPoolSize changes the maximal size of all tracked pools at runtime (see HashPool.SetMaxPoolSize) and of all pools created from now on.
+// Nothing is changed if the size exceeds the capacity of any tracked pool (ErrExceedsCapacity).
+func (p *PoolCollection) SetMaxPoolSize(ctx context.Context, size int) error {
+	if size < 1 {
+		return ErrInvalidSize
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	// all or nothing, the capacity of a pool is fixed once created
+	for hash, pool := range p.pools {
+		pool.RLock()
+		err := pool.unsafeCheckCapacity(size)
+		pool.RUnlock()
+
+		if err != nil {
+			return fmt.Errorf("pool %s: %w", hash, err)
+		}
+	}
+
+	p.PoolConfig.MaxPoolSize = size
+
+	for _, pool := range p.pools {
+		if _, err := pool.SetMaxPoolSize(ctx, size); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetReadyTargetWithHash changes the number of test DBs the pool with the given template hash tries to keep ready at runtime
+// and returns the effective target (see HashPool.SetReadyTarget).
+func (p *PoolCollection) SetReadyTargetWithHash(ctx context.Context, hash string, target int) (int, error) {
+	pool, err := p.getPool(ctx, hash)
+	if err != nil {
+		return 0, err
+	}
+
+	return pool.SetReadyTarget(ctx, target)
+}
+
+// SetMaxPoolSizeWithHash changes the maximal size of the pool with the given template hash at runtime and returns the effective size (see HashPool.SetMaxPoolSize).
+func (p *PoolCollection) SetMaxPoolSizeWithHash(ctx context.Context, hash string, size int) (int, error) {
+	pool, err := p.getPool(ctx, hash)
+	if err != nil {
+		return 0, err
+	}
+
+	return pool.SetMaxPoolSize(ctx, size)
+}
+
+// CleanDirtyWithHash schedules all dirty test DBs of the pool with the given template hash for immediate recreation (see HashPool.CleanDirty).
+func (p *PoolCollection) CleanDirtyWithHash(ctx context.Context, hash string) (int, error) {
+	pool, err := p.getPool(ctx, hash)
+	if err != nil {
+		return 0, err
+	}
+
+	return pool.CleanDirty(ctx), nil
+}
+
+// CleanAllDirty schedules all dirty test DBs of all tracked pools for immediate recreation and returns their total number.
+func (p *PoolCollection) CleanAllDirty(ctx context.Context) int {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	var scheduled int
+	for _, pool := range p.pools {
+		scheduled += pool.CleanDirty(ctx)
 	}
 
-	return pool.RecreateTestDatabase(ctx, id)
+	return scheduled
 }
 
 // RemoveAllWithHash removes a pool with a given template hash.
@@ -144,24 +574,165 @@ func (p *PoolCollection) RemoveAllWithHash(ctx context.Context, hash string, rem
 
 	// all DBs have been removed, now remove the pool itself
 	delete(p.pools, hash)
+	p.removed.add(hash, time.Now())
+
+	return nil
+}
+
+// DrainAndRemove stops handing out test DBs of the given hash (ErrPoolDraining), waits until all test DBs held by clients
+// have been returned and removes the pool like RemoveAllWithHash. If the ctx is done before, ErrStillInUse (naming the IDs of the
+// test DBs still held) is returned and the pool keeps handing out test DBs. If removing fails, the pool stays draining and the call may be repeated.
+func (p *PoolCollection) DrainAndRemove(ctx context.Context, hash string, removeFunc RemoveDBFunc) error {
+	pool, err := p.getPool(ctx, hash)
+	if err != nil {
+		return wrapPoolError("DrainAndRemove", hash, -1, err)
+	}
+
+	if err := pool.drain(ctx); err != nil {
+		return wrapPoolError("DrainAndRemove", hash, -1, err)
+	}
+
+	return p.RemoveAllWithHash(ctx, hash, removeFunc)
+}
+
+// RemoveIdleWithHash removes the pool with the given template hash like RemoveAllWithHash, but only if it is idle:
+// It was not used by any client since the given time and none of its test DBs is currently being recreated.
+// Returns whether the pool has been removed.
+func (p *PoolCollection) RemoveIdleWithHash(ctx context.Context, hash string, idleSince time.Time, removeFunc RemoveDBFunc) (bool, error) {
+	pool, collUnlock, err := p.getPoolLockCollection(ctx, hash)
+	defer collUnlock()
+
+	if err != nil {
+		return false, err
+	}
+
+	// no new test DBs can be requested from this pool while the collection is locked
+	pool.RLock()
+	idle := pool.unsafeIsIdle(idleSince)
+	pool.RUnlock()
+
+	if !idle {
+		return false, nil
+	}
+
+	if err := pool.RemoveAll(ctx, removeFunc); err != nil {
+		return false, err
+	}
+
+	delete(p.pools, hash)
+	p.removed.add(hash, time.Now())
+
+	return true, nil
+}
+
+// SwapHash replaces the pool of toHash by the pool of fromHash, e.g. to switch to a freshly built fixture version at once.
+// The test DBs of the previous toHash pool are removed via the given removeFunc and its workers are stopped,
+// afterwards the fromHash pool (including its test DBs and workers) is served under toHash, fromHash is no longer known.
+// The swap is rejected with ErrPoolInUse if any test DB of either pool is currently held by a client.
+// Test DBs keep their names, the template DB of fromHash must thus be kept until the pool is removed.
+func (p *PoolCollection) SwapHash(ctx context.Context, fromHash string, toHash string, removeFunc RemoveDBFunc) error {
+	pool, collUnlock, err := p.getPoolLockCollection(ctx, fromHash)
+	defer collUnlock()
+
+	if err != nil {
+		return wrapPoolError("SwapHash", fromHash, -1, err)
+	}
+
+	if fromHash == toHash {
+		return nil
+	}
+
+	// no new test DBs can be requested from both pools while the collection is locked
+	if len(pool.InUse()) > 0 {
+		return wrapPoolError("SwapHash", fromHash, -1, ErrPoolInUse)
+	}
+
+	if oldPool, ok := p.pools[toHash]; ok {
+		if len(oldPool.InUse()) > 0 {
+			return wrapPoolError("SwapHash", toHash, -1, ErrPoolInUse)
+		}
+
+		if err := oldPool.RemoveAll(ctx, removeFunc); err != nil {
+			// the old pool stays in place, the swap may be repeated
+			return wrapPoolError("SwapHash", toHash, -1, err)
+		}
+	}
+
+	pool.rehash(toHash)
+
+	p.pools[toHash] = pool
+	delete(p.pools, fromHash)
+	p.removed.forget(toHash)
+	p.removed.add(fromHash, time.Now())
 
 	return nil
 }
 
 // RemoveAll removes all tracked pools.
+// The pools are removed concurrently, dropping up to MaxParallelRemoves test DBs at the same time across all of them.
+// Pools that fail to be removed stay tracked (see HashPool.RemoveAll), the removal of the other pools continues and all errors are joined.
 func (p *PoolCollection) RemoveAll(ctx context.Context, removeFunc RemoveDBFunc) error {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
+	slots := newRemoveSlots(p.MaxParallelRemoves)
+
+	var wg sync.WaitGroup
+	errs := make(map[string]error, len(p.pools))
+	var errsMutex sync.Mutex
+
 	for hash, pool := range p.pools {
-		if err := pool.RemoveAll(ctx, removeFunc); err != nil {
-			return err
+		wg.Add(1)
+		go func(hash string, pool *HashPool) {
+			defer wg.Done()
+
+			if err := pool.removeAll(ctx, removeFunc, slots); err != nil {
+				errsMutex.Lock()
+				errs[hash] = err
+				errsMutex.Unlock()
+			}
+		}(hash, pool)
+	}
+
+	wg.Wait()
+
+	hashes := make([]string, 0, len(p.pools))
+	for hash := range p.pools {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+
+	now := time.Now()
+
+	var joined []error
+	for _, hash := range hashes {
+		if err, ok := errs[hash]; ok {
+			// the error already names the hash and ID of the failed test DB
+			joined = append(joined, err)
+			continue
 		}
 
 		delete(p.pools, hash)
+		p.removed.add(hash, now)
 	}
 
-	return nil
+	return errors.Join(joined...)
+}
+
+// Reset stops all background workers and forgets all tracked pools (including their counters), keeping the PoolConfig.
+// Contrary to RemoveAll, no test DBs are removed. It must only be called while no other operations are in flight,
+// e.g. to get a pristine collection between test cases.
+func (p *PoolCollection) Reset() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for _, pool := range p.pools {
+		pool.Stop()
+	}
+
+	p.pools = make(map[string]*HashPool)
+	p.removed.reset()
+	p.budget.reset()
 }
 
 // MakeDBName makes a test DB name with the configured prefix, template hash and ID of the DB.
@@ -169,12 +740,60 @@ func (p *PoolCollection) MakeDBName(hash string, id int) string {
 	p.mutex.RLock()
 	defer p.mutex.RUnlock()
 
-	return makeDBName(p.PoolConfig.TestDBNamePrefix, hash, id)
+	return p.PoolConfig.buildDBName(hash, id)
+}
+
+// buildDBName builds a test DB name using the configured DBName builder (if any), falling back to the default name if the builder panics.
+func (cfg PoolConfig) buildDBName(hash string, id int) string {
+	if cfg.DBName != nil {
+		if name, err := cfg.callDBName(hash, id); err == nil {
+			return name
+		}
+	}
+
+	return makeDBName(cfg.TestDBNamePrefix, hash, cfg.InstanceID, id)
 }
 
-func makeDBName(testDBPrefix string, hash string, id int) string {
+// warmReadyTarget raises the given ready target to MinReady (clamped to MaxPoolSize), thus the warm standby is never undercut.
+func (cfg PoolConfig) warmReadyTarget(target int) int {
+	minReady := cfg.MinReady
+	if minReady > cfg.MaxPoolSize {
+		minReady = cfg.MaxPoolSize
+	}
+
+	if target < minReady {
+		return minReady
+	}
+
+	return target
+}
+
+// makeDBName makes the default test DB name. The prefix is truncated if the name would exceed MaxDBNameLength,
+// as Postgres would otherwise silently cut off the ID suffix (thus test DBs of the same hash would collide).
+func makeDBName(testDBPrefix string, hash string, instanceID string, id int) string {
 	// db name has an ID in suffix
-	return fmt.Sprintf("%s%s_%03d", testDBPrefix, hash, id)
+	suffix := fmt.Sprintf("%s_%03d", hash, id)
+	if len(instanceID) > 0 {
+		suffix = fmt.Sprintf("%s_%s_%03d", hash, instanceID, id)
+	}
+
+	return truncateDBNamePrefix(testDBPrefix, MaxDBNameLength-len(suffix)) + suffix
+}
+
+// truncateDBNamePrefix cuts the prefix to at most maxLen bytes, without splitting a multi-byte character.
+func truncateDBNamePrefix(prefix string, maxLen int) string {
+	if len(prefix) <= maxLen {
+		return prefix
+	}
+	if maxLen <= 0 {
+		return ""
+	}
+
+	for maxLen > 0 && !utf8.RuneStart(prefix[maxLen]) {
+		maxLen--
+	}
+
+	return prefix[:maxLen]
 }
 
 func (p *PoolCollection) getPool(ctx context.Context, hash string) (pool *HashPool, err error) {
@@ -186,7 +805,7 @@ func (p *PoolCollection) getPool(ctx context.Context, hash string) (pool *HashPo
 	pool, ok := p.pools[hash]
 	if !ok {
 		// no such pool
-		return nil, ErrUnknownHash
+		return nil, p.removed.unknownHashError(hash)
 	}
 
 	return pool, nil
@@ -201,7 +820,7 @@ func (p *PoolCollection) getPoolLockCollection(ctx context.Context, hash string)
 	pool, ok := p.pools[hash]
 	if !ok {
 		// no such pool
-		err = ErrUnknownHash
+		err = p.removed.unknownHashError(hash)
 	}
 
 	return pool, unlock, err
@@ -216,8 +835,8 @@ func (p *PoolCollection) extend(ctx context.Context, templateDB db.Database) err
 
 	pool, err := p.getPool(ctx, hash)
 	if err != nil {
-		return err
+		return wrapPoolError("extend", hash, -1, err)
 	}
 
-	return pool.extend(ctx)
+	return wrapPoolError("extend", hash, -1, pool.extend(ctx))
 }
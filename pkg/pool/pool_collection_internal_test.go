@@ -2,15 +2,53 @@ package pool
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/allaboutapps/integresql/pkg/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+// noopRecreateDB (re)creates test DBs of the pools under test successfully right away.
+func noopRecreateDB(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+	return nil
+}
+
+// newTestPoolCollection is the shared fixture of the pool tests: It creates a pool collection of the given config (stopped on cleanup)
+// and initializes the pool of the template with the given number of ready test DBs, (re)created by recreateDB.
+func newTestPoolCollection(t *testing.T, cfg PoolConfig, templateDB db.Database, ready int, recreateDB RecreateDBFunc) *PoolCollection {
+	t.Helper()
+
+	ctx := context.Background()
+	p := NewPoolCollection(cfg)
+	t.Cleanup(func() { p.Stop() })
+
+	p.InitHashPool(ctx, templateDB, recreateDB)
+	for i := 0; i < ready; i++ {
+		require.NoError(t, p.extend(ctx, templateDB))
+	}
+
+	return p
+}
+
+// requireSnapshotEventually waits until the snapshot of the pool of the given hash satisfies the condition,
+// e.g. until the background workers caught up.
+func requireSnapshotEventually(t *testing.T, p *PoolCollection, hash string, condition func(snapshot PoolSnapshot) bool) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		snapshot, err := p.Snapshot(context.Background(), hash)
+		return err == nil && condition(snapshot)
+	}, time.Second, 5*time.Millisecond)
+}
+
 func TestPoolAddGet(t *testing.T) {
 	t.Parallel()
 
@@ -74,6 +112,46 @@ func TestPoolAddGet(t *testing.T) {
 	assert.NotEqual(t, testDB1.ID, testDB2.ID)
 }
 
+func TestPoolLease(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cfg := PoolConfig{
+		MaxPoolSize:            1,
+		MaxParallelTasks:       1,
+		disableWorkerAutostart: true,
+	}
+	p := NewPoolCollection(cfg)
+	t.Cleanup(func() { p.Stop() })
+
+	hash1 := "h1"
+	templateDB := db.Database{
+		TemplateHash: hash1,
+	}
+	p.InitHashPool(ctx, templateDB, noopRecreateDB)
+	require.NoError(t, p.extend(ctx, templateDB))
+
+	testDB1, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	assert.NotEmpty(t, testDB1.Lease)
+
+	assert.ErrorIs(t, p.ReturnTestDatabaseWithLease(ctx, hash1, testDB1.ID, "wrong"), ErrInvalidLease)
+	require.NoError(t, p.ReturnTestDatabaseWithLease(ctx, hash1, testDB1.ID, testDB1.Lease))
+
+	// the same ID is handed out to the next holder with a new lease, the old one is stale
+	testDB2, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, testDB1.ID, testDB2.ID)
+	assert.NotEqual(t, testDB1.Lease, testDB2.Lease)
+
+	assert.ErrorIs(t, p.ReturnTestDatabaseWithLease(ctx, hash1, testDB1.ID, testDB1.Lease), ErrInvalidLease)
+	assert.ErrorIs(t, p.RecreateTestDatabaseWithLease(ctx, hash1, testDB1.ID, testDB1.Lease), ErrInvalidLease)
+
+	// returning without a lease is still possible
+	require.NoError(t, p.ReturnTestDatabase(ctx, hash1, testDB2.ID))
+	assert.ErrorIs(t, p.ReturnTestDatabaseWithLease(ctx, hash1, testDB2.ID, testDB2.Lease), ErrInvalidLease)
+}
+
 func TestPoolAddGetConcurrent(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -243,105 +321,2173 @@ func TestPoolRemoveAll(t *testing.T) {
 	assert.Equal(t, 0, testDB.ID)
 }
 
-func TestPoolReuseDirty(t *testing.T) {
+func TestPoolRemoveAllPartialFailure(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
 
 	hash1 := "h1"
+	hash2 := "h2"
 	templateDB1 := db.Database{
 		TemplateHash: hash1,
-		Config: db.DatabaseConfig{
-			Database: "h1_template",
-		},
 	}
-
-	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
-		t.Log("(re)create ", testDB.Database.Config.Database)
+	templateDB2 := db.Database{
+		TemplateHash: hash2,
+	}
+	// removing the test DB with ID 1 of the first pool fails
+	errRemove := errors.New("database is stuck")
+	failRemove := true
+	removeFunc := func(ctx context.Context, testDB db.TestDatabase) error {
+		if failRemove && testDB.TemplateHash == hash1 && testDB.ID == 1 {
+			return errRemove
+		}
 		return nil
 	}
 
-	maxPoolSize := 40
 	cfg := PoolConfig{
-		MaxPoolSize:      maxPoolSize,
-		InitialPoolSize:  maxPoolSize,
+		MaxPoolSize:      3,
 		MaxParallelTasks: 1,
-		TestDBNamePrefix: "test_",
 	}
-	p := NewPoolCollection(cfg)
+	p := newTestPoolCollection(t, cfg, templateDB1, 0, noopRecreateDB)
+	p.InitHashPool(ctx, templateDB2, noopRecreateDB)
 
-	p.InitHashPool(ctx, templateDB1, initFunc)
-	t.Cleanup(func() { p.Stop() })
+	for i := 0; i < cfg.MaxPoolSize; i++ {
+		require.NoError(t, p.extend(ctx, templateDB1))
+		require.NoError(t, p.extend(ctx, templateDB2))
+	}
 
-	getDirty := func(seenIDMap *sync.Map) {
-		newTestDB1, err := p.GetTestDatabase(ctx, templateDB1.TemplateHash, 3*time.Second)
-		assert.NoError(t, err)
-		seenIDMap.Store(newTestDB1.ID, true)
+	err := p.RemoveAll(ctx, removeFunc)
+	assert.ErrorIs(t, err, errRemove)
+	assert.EqualError(t, err, "remove db h1 id 1: database is stuck")
+
+	// the other pool is removed nevertheless
+	_, err = p.Snapshot(ctx, hash2)
+	assert.ErrorIs(t, err, ErrUnknownHash)
+
+	// the failed pool is still usable, holding the not yet removed test DBs
+	snapshot, err := p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Len(t, snapshot.TestDatabases, 2)
+	assert.Equal(t, 2, snapshot.Ready)
+
+	testDB, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	assert.Less(t, testDB.ID, 2)
+
+	// repeating the operation succeeds
+	failRemove = false
+	require.NoError(t, p.RemoveAll(ctx, removeFunc))
+
+	_, err = p.Snapshot(ctx, hash1)
+	assert.ErrorIs(t, err, ErrUnknownHash)
+}
+
+func TestPoolRemoveAllConcurrently(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	hash2 := "h2"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+	}
+	templateDB2 := db.Database{
+		TemplateHash: hash2,
 	}
+	var (
+		mutex    sync.Mutex
+		removed  = make(map[string]bool)
+		running  int32
+		parallel int32
+	)
+	errRemove := errors.New("database is stuck")
+	failRemove := true
+	removeFunc := func(ctx context.Context, testDB db.TestDatabase) error {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
 
-	// allow for recycling inUse test DBs
-	var wg sync.WaitGroup
-	seenIDMap := sync.Map{}
-	for i := 0; i < 3*cfg.MaxPoolSize; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			getDirty(&seenIDMap)
-		}()
+		mutex.Lock()
+		if current > parallel {
+			parallel = current
+		}
+		fail := failRemove && testDB.ID == 4
+		mutex.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		if fail {
+			return errRemove
+		}
+
+		mutex.Lock()
+		removed[testDB.Database.Config.Database] = true
+		mutex.Unlock()
+
+		return nil
 	}
 
-	wg.Wait()
+	cfg := PoolConfig{
+		MaxPoolSize:            6,
+		MaxParallelTasks:       1,
+		MaxParallelRemoves:     3,
+		disableWorkerAutostart: true, // no extend / cleanDirty tasks should run automatically!
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 0, noopRecreateDB)
+	p.InitHashPool(ctx, templateDB2, noopRecreateDB)
 
-	for id := 0; id < cfg.MaxPoolSize; id++ {
-		_, ok := seenIDMap.Load(id)
-		// every index should show up at least once
-		assert.True(t, ok, id)
+	for i := 0; i < cfg.MaxPoolSize; i++ {
+		require.NoError(t, p.extend(ctx, templateDB1))
+		require.NoError(t, p.extend(ctx, templateDB2))
+	}
+
+	// the failures of both pools are joined
+	err := p.RemoveAll(ctx, removeFunc)
+	assert.ErrorIs(t, err, errRemove)
+	assert.Contains(t, err.Error(), "remove db h1 id 4: database is stuck")
+	assert.Contains(t, err.Error(), "remove db h2 id 4: database is stuck")
+
+	// removals ran concurrently, bounded across all pools
+	assert.Greater(t, parallel, int32(1))
+	assert.LessOrEqual(t, parallel, int32(cfg.MaxParallelRemoves))
+
+	// both pools keep the not yet removed test DBs, the ones removed below the failed one are recreated
+	for _, hash := range []string{hash1, hash2} {
+		snapshot, err := p.Snapshot(ctx, hash)
+		require.NoError(t, err)
+		assert.Len(t, snapshot.TestDatabases, 5)
+		assert.False(t, removed[p.MakeDBName(hash, 4)])
+		assert.True(t, removed[p.MakeDBName(hash, 5)])
+		assert.Equal(t, snapshot.Ready+snapshot.Dirty, 5)
+	}
+
+	// repeating the operation drops all test DBs
+	failRemove = false
+	require.NoError(t, p.RemoveAll(ctx, removeFunc))
+
+	for _, hash := range []string{hash1, hash2} {
+		_, err = p.Snapshot(ctx, hash)
+		assert.ErrorIs(t, err, ErrUnknownHash)
+
+		for id := 0; id < cfg.MaxPoolSize; id++ {
+			assert.True(t, removed[p.MakeDBName(hash, id)], "test DB %d of %s not removed", id, hash)
+		}
 	}
 }
 
-func TestPoolReturnTestDatabase(t *testing.T) {
+func TestPoolResetDB(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
 
 	hash1 := "h1"
 	templateDB1 := db.Database{
 		TemplateHash: hash1,
-		Config: db.DatabaseConfig{
-			Database: "h1_template",
+	}
+
+	var recreated, reset int32
+	failReset := false
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		atomic.AddInt32(&recreated, 1)
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:      1,
+		MaxParallelTasks: 1,
+		RecreateInline:   true, // recreate synchronously
+		ResetDB: func(ctx context.Context, testDB db.TestDatabase) error {
+			atomic.AddInt32(&reset, 1)
+			if failReset {
+				return errors.New("relation does not exist")
+			}
+			return nil
 		},
 	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 0, initFunc)
 
-	recreateTimesMap := sync.Map{}
+	// new test DBs are created via the RecreateDBFunc
+	require.NoError(t, p.extend(ctx, templateDB1))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&recreated))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&reset))
+
+	// dirty ones are reset
+	testDB, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	require.NoError(t, p.RecreateTestDatabase(ctx, hash1, testDB.ID))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&recreated))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&reset))
+
+	// ... falling back to recreate if resetting fails
+	failReset = true
+	testDB, err = p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	require.NoError(t, p.RecreateTestDatabase(ctx, hash1, testDB.ID))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&recreated))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&reset))
+
+	// the registered clean hook is exposed per hash
+	snapshot, err := p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.True(t, snapshot.CleanHook)
+
+	cfg2 := cfg
+	cfg2.ResetDB = nil
+	p.InitHashPoolWithConfig(ctx, cfg2, db.Database{TemplateHash: "h2"}, initFunc)
+	snapshot, err = p.Snapshot(ctx, "h2")
+	require.NoError(t, err)
+	assert.False(t, snapshot.CleanHook)
+}
+
+func TestPoolReturnTestDatabasePoisoned(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+	}
+
+	var recreated, reset int32
 	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
-		times, existing := recreateTimesMap.LoadOrStore(testDB.ID, 1)
-		if existing {
-			recreateTimesMap.Store(testDB.ID, times.(int)+1)
-		}
+		atomic.AddInt32(&recreated, 1)
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:      1,
+		MaxParallelTasks: 1,
+		RecreateInline:   true, // recreate synchronously
+		ResetDB: func(ctx context.Context, testDB db.TestDatabase) error {
+			atomic.AddInt32(&reset, 1)
+			return nil
+		},
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 1, initFunc)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&recreated))
+
+	// the lease is checked like on return
+	testDB, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	assert.ErrorIs(t, p.ReturnTestDatabasePoisonedWithLease(ctx, hash1, testDB.ID, "invalid"), ErrInvalidLease)
+
+	snapshot, err := p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, 0, snapshot.Poisoned)
+
+	// poisoned ones are fully recreated instead of reset
+	require.NoError(t, p.ReturnTestDatabasePoisonedWithLease(ctx, hash1, testDB.ID, testDB.Lease))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&recreated))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&reset))
+
+	// ... and no longer flagged afterwards
+	snapshot, err = p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, 0, snapshot.Poisoned)
+	assert.Equal(t, 1, snapshot.Ready)
+	assert.False(t, snapshot.TestDatabases[0].Poisoned)
+
+	// thus the next dirty one is reset again
+	testDB, err = p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	require.NoError(t, p.RecreateTestDatabase(ctx, hash1, testDB.ID))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&recreated))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&reset))
+
+	assert.ErrorIs(t, p.ReturnTestDatabasePoisoned(ctx, hash1, 5), ErrInvalidIndex)
+}
+
+func TestPoolSetMaxPoolSize(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	hash2 := "h2"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+	}
+	templateDB2 := db.Database{
+		TemplateHash: hash2,
+	}
+	cfg := PoolConfig{
+		MaxPoolSize:            3,
+		MaxParallelTasks:       1,
+		disableWorkerAutostart: true,
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 2, noopRecreateDB)
+
+	_, err := p.SetMaxPoolSizeWithHash(ctx, hash1, 0)
+	assert.ErrorIs(t, err, ErrInvalidSize)
+
+	// lowering below the current number of test DBs keeps them, but prevents extending
+	size, err := p.SetMaxPoolSizeWithHash(ctx, hash1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, size)
+	assert.ErrorIs(t, p.extend(ctx, templateDB1), ErrPoolFull)
+
+	snapshot, err := p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, snapshot.MaxPoolSize)
+	assert.Len(t, snapshot.TestDatabases, 2)
+
+	testDB, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	testDB2, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	require.NoError(t, p.ReturnTestDatabase(ctx, hash1, testDB.ID))
+	require.NoError(t, p.ReturnTestDatabase(ctx, hash1, testDB2.ID))
+
+	// raising is limited to the initial capacity of the pool
+	_, err = p.SetMaxPoolSizeWithHash(ctx, hash1, 10)
+	assert.ErrorIs(t, err, ErrExceedsCapacity)
+	assert.ErrorIs(t, p.SetMaxPoolSize(ctx, 10), ErrExceedsCapacity)
+	assert.Equal(t, cfg.MaxPoolSize, p.DefaultConfig().MaxPoolSize)
+
+	size, err = p.SetMaxPoolSizeWithHash(ctx, hash1, cfg.MaxPoolSize)
+	require.NoError(t, err)
+	assert.Equal(t, cfg.MaxPoolSize, size)
+	require.NoError(t, p.extend(ctx, templateDB1))
+	assert.ErrorIs(t, p.extend(ctx, templateDB1), ErrPoolFull)
+
+	// the collection wide size applies to existing and new pools
+	require.NoError(t, p.SetMaxPoolSize(ctx, 2))
+	assert.Equal(t, 2, p.DefaultConfig().MaxPoolSize)
+
+	p.InitHashPool(ctx, templateDB2, noopRecreateDB)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, p.extend(ctx, templateDB2))
+	}
+	assert.ErrorIs(t, p.extend(ctx, templateDB2), ErrPoolFull)
+
+	for _, snapshot := range p.SnapshotAll(ctx) {
+		assert.Equal(t, 2, snapshot.MaxPoolSize)
+	}
+
+	assert.ErrorIs(t, p.SetMaxPoolSize(ctx, -1), ErrInvalidSize)
+}
+
+func TestPoolCleanDirty(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+	}
+	// no ready target, thus dirty test DBs are never cleaned automatically
+	cfg := PoolConfig{
+		InitialPoolSize:  0,
+		MaxPoolSize:      2,
+		MaxParallelTasks: 2,
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 0, noopRecreateDB)
+
+	for i := 0; i < cfg.MaxPoolSize; i++ {
+		require.NoError(t, p.extend(ctx, templateDB1))
+		_, err := p.GetTestDatabase(ctx, hash1, time.Second)
+		require.NoError(t, err)
+	}
+
+	snapshot, err := p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, snapshot.Dirty)
+
+	scheduled, err := p.CleanDirtyWithHash(ctx, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, scheduled)
+
+	assert.Eventually(t, func() bool {
+		snapshot, err := p.Snapshot(ctx, hash1)
+		return err == nil && snapshot.Ready == 2
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, 0, p.CleanAllDirty(ctx))
+
+	_, err = p.CleanDirtyWithHash(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrUnknownHash)
+}
+
+func TestPoolCleanDirtyQueueFull(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
 
+	pool := NewHashPool(PoolConfig{MaxPoolSize: 3, MaxParallelTasks: 1}, db.Database{TemplateHash: "h1"}, func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
 		return nil
+	})
+
+	// no workers consuming the queue, only a single slot left
+	pool.running = true
+	for id := 0; id < 3; id++ {
+		pool.dirty <- id
+		pool.tasksChan <- newQueuedTask(ctx, workerTaskExtend)
 	}
 
+	// never blocks, only the actually scheduled test DBs are reported
+	assert.Equal(t, 1, pool.CleanDirty(ctx))
+	assert.Len(t, pool.tasksChan, cap(pool.tasksChan))
+}
+
+func TestPoolReset(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+	}
 	cfg := PoolConfig{
-		MaxPoolSize:            10,
-		MaxParallelTasks:       3,
-		disableWorkerAutostart: true, // no extend / cleanDirty tasks should run automatically!
+		MaxPoolSize:      2,
+		MaxParallelTasks: 1,
 	}
-	p := NewPoolCollection(cfg)
+	p := newTestPoolCollection(t, cfg, templateDB1, 1, noopRecreateDB)
 
-	p.InitHashPool(ctx, templateDB1, initFunc)
-	// add just one test DB
+	_, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+
+	p.Reset()
+
+	assert.Empty(t, p.SnapshotAll(ctx))
+	_, err = p.GetTestDatabase(ctx, hash1, time.Millisecond)
+	assert.ErrorIs(t, err, ErrUnknownHash)
+	assert.Equal(t, cfg.MaxPoolSize, p.MaxPoolSize)
+
+	// pools can be initialized again, starting with fresh counters
+	p.InitHashPool(ctx, templateDB1, noopRecreateDB)
 	require.NoError(t, p.extend(ctx, templateDB1))
 
-	testDB1, err := p.GetTestDatabase(ctx, templateDB1.TemplateHash, time.Millisecond)
-	assert.NoError(t, err)
+	snapshot, err := p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), snapshot.GetCleanTotal)
+	assert.Len(t, snapshot.TestDatabases, 1)
+}
 
-	// assert that workers are stopped and no new DB showed up
-	_, err = p.GetTestDatabase(ctx, templateDB1.TemplateHash, time.Millisecond)
-	assert.ErrorIs(t, err, ErrTimeout)
+func TestPoolRecreateAttemptTimeout(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
 
-	// return and get the same one
-	assert.NoError(t, p.ReturnTestDatabase(ctx, hash1, testDB1.ID))
-	testDB2, err := p.GetTestDatabase(ctx, templateDB1.TemplateHash, time.Millisecond)
-	assert.NoError(t, err)
-	assert.Equal(t, testDB1.ID, testDB2.ID)
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+	}
+
+	// slow CREATE DATABASE, only aborted by the context
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return nil
+		}
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:            2,
+		MaxParallelTasks:       1,
+		RecreateAttemptTimeout: 50 * time.Millisecond,
+		disableWorkerAutostart: true,
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 0, initFunc)
+
+	start := time.Now()
+	err := p.extend(ctx, templateDB1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// cancellation of the parent context still propagates
+	cancelCtx, cancel := context.WithCancel(ctx)
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	cfg.RecreateAttemptTimeout = time.Second
+	p.InitHashPoolWithConfig(ctx, cfg, templateDB1, initFunc)
+
+	err = p.extend(cancelCtx, templateDB1)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NotErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestPoolForEachPool(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	removeFunc := func(ctx context.Context, testDB db.TestDatabase) error {
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:            2,
+		MaxParallelTasks:       1,
+		disableWorkerAutostart: true,
+	}
+	p := NewPoolCollection(cfg)
+	t.Cleanup(func() { p.Stop() })
+
+	for _, hash := range []string{"h3", "h1", "h2"} {
+		templateDB := db.Database{TemplateHash: hash}
+		p.InitHashPool(ctx, templateDB, noopRecreateDB)
+		require.NoError(t, p.extend(ctx, templateDB))
+	}
+
+	// the collection is not locked during the callback, thus pools may be removed (or added) within it
+	var visited []string
+	err := p.ForEachPool(ctx, func(hash string, snapshot PoolSnapshot) error {
+		visited = append(visited, hash)
+		assert.Equal(t, hash, snapshot.TemplateHash)
+		assert.Len(t, snapshot.TestDatabases, 1)
+
+		if hash == "h1" {
+			require.NoError(t, p.RemoveAllWithHash(ctx, "h2", removeFunc))
+			p.InitHashPool(ctx, db.Database{TemplateHash: "h4"}, noopRecreateDB)
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"h1", "h3"}, visited)
+
+	// iteration stops at the first error
+	errStop := errors.New("stop")
+	visited = nil
+	err = p.ForEachPool(ctx, func(hash string, snapshot PoolSnapshot) error {
+		visited = append(visited, hash)
+		return errStop
+	})
+	assert.ErrorIs(t, err, errStop)
+	assert.Equal(t, []string{"h1"}, visited)
+}
+
+func TestPoolRemoveIdleWithHash(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+	}
+	var removed int
+	var removedMutex sync.Mutex
+	removeFunc := func(ctx context.Context, testDB db.TestDatabase) error {
+		removedMutex.Lock()
+		defer removedMutex.Unlock()
+		removed++
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:      2,
+		MaxParallelTasks: 1,
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 1, noopRecreateDB)
+
+	idleSince := time.Now()
+
+	// used after idleSince, thus not idle
+	testDB, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+
+	snapshot, err := p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.True(t, snapshot.LastUsed.After(idleSince))
+
+	ok, err := p.RemoveIdleWithHash(ctx, hash1, idleSince, removeFunc)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, p.ReturnTestDatabase(ctx, hash1, testDB.ID))
+
+	// idle since now
+	ok, err = p.RemoveIdleWithHash(ctx, hash1, time.Now(), removeFunc)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	removedMutex.Lock()
+	assert.GreaterOrEqual(t, removed, 1)
+	removedMutex.Unlock()
+
+	_, err = p.RemoveIdleWithHash(ctx, hash1, time.Now(), removeFunc)
+	assert.ErrorIs(t, err, ErrUnknownHash)
+}
+
+func TestPoolSwapHash(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+	}
+	hash2 := "h2"
+	templateDB2 := db.Database{
+		TemplateHash: hash2,
+	}
+	var removed []string
+	var removedMutex sync.Mutex
+	removeFunc := func(ctx context.Context, testDB db.TestDatabase) error {
+		removedMutex.Lock()
+		defer removedMutex.Unlock()
+		removed = append(removed, testDB.Config.Database)
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:      2,
+		MaxParallelTasks: 1,
+		TestDBNamePrefix: "test_",
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 1, noopRecreateDB)
+	p.InitHashPool(ctx, templateDB2, noopRecreateDB)
+	require.NoError(t, p.extend(ctx, templateDB2))
+
+	// in use test DBs of the replaced pool reject the swap
+	testDB, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	assert.ErrorIs(t, p.SwapHash(ctx, hash2, hash1, removeFunc), ErrPoolInUse)
+	require.NoError(t, p.ReturnTestDatabase(ctx, hash1, testDB.ID))
+
+	require.NoError(t, p.SwapHash(ctx, hash2, hash1, removeFunc))
+
+	removedMutex.Lock()
+	assert.Contains(t, removed, "test_h1_000")
+	assert.NotContains(t, removed, "test_h2_000")
+	removedMutex.Unlock()
+
+	_, err = p.Snapshot(ctx, hash2)
+	assert.ErrorIs(t, err, ErrUnknownHash)
+
+	// the test DBs built for hash2 are now served for hash1
+	testDB, err = p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, hash1, testDB.TemplateHash)
+	assert.Equal(t, "test_h2_000", testDB.Config.Database)
+
+	assert.ErrorIs(t, p.SwapHash(ctx, hash2, hash1, removeFunc), ErrUnknownHash)
+}
+
+func TestPoolDrainAndRemove(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+	}
+	var removed int32
+	removeFunc := func(ctx context.Context, testDB db.TestDatabase) error {
+		atomic.AddInt32(&removed, 1)
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:            3,
+		MaxParallelTasks:       1,
+		disableWorkerAutostart: true, // no extend / cleanDirty tasks should run automatically!
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 2, noopRecreateDB)
+
+	testDB, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+
+	// the test DB is never returned, thus draining times out
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	err = p.DrainAndRemove(timeoutCtx, hash1, removeFunc)
+	assert.ErrorIs(t, err, ErrStillInUse)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), fmt.Sprintf("ids [%d]", testDB.ID))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&removed))
+
+	// the pool is still handing out test DBs
+	otherDB, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	require.NoError(t, p.ReturnTestDatabase(ctx, hash1, otherDB.ID))
+
+	drained := make(chan error, 1)
+	go func() {
+		drained <- p.DrainAndRemove(ctx, hash1, removeFunc)
+	}()
+
+	// no more test DBs are handed out while draining
+	require.Eventually(t, func() bool {
+		_, err := p.GetTestDatabase(ctx, hash1, time.Millisecond)
+		return errors.Is(err, ErrPoolDraining)
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, p.ReturnTestDatabase(ctx, hash1, testDB.ID))
+	require.NoError(t, <-drained)
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&removed))
+
+	_, err = p.Snapshot(ctx, hash1)
+	assert.ErrorIs(t, err, ErrUnknownHash)
+}
+
+func TestPoolReuseDirty(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+		Config: db.DatabaseConfig{
+			Database: "h1_template",
+		},
+	}
+
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		t.Log("(re)create ", testDB.Database.Config.Database)
+		return nil
+	}
+
+	maxPoolSize := 40
+	cfg := PoolConfig{
+		MaxPoolSize:      maxPoolSize,
+		InitialPoolSize:  maxPoolSize,
+		MaxParallelTasks: 1,
+		TestDBNamePrefix: "test_",
+	}
+	p := NewPoolCollection(cfg)
+
+	p.InitHashPool(ctx, templateDB1, initFunc)
+	t.Cleanup(func() { p.Stop() })
+
+	getDirty := func(seenIDMap *sync.Map) {
+		newTestDB1, err := p.GetTestDatabase(ctx, templateDB1.TemplateHash, 3*time.Second)
+		assert.NoError(t, err)
+		seenIDMap.Store(newTestDB1.ID, true)
+	}
+
+	// allow for recycling inUse test DBs
+	var wg sync.WaitGroup
+	seenIDMap := sync.Map{}
+	for i := 0; i < 3*cfg.MaxPoolSize; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			getDirty(&seenIDMap)
+		}()
+	}
+
+	wg.Wait()
+
+	for id := 0; id < cfg.MaxPoolSize; id++ {
+		_, ok := seenIDMap.Load(id)
+		// every index should show up at least once
+		assert.True(t, ok, id)
+	}
+}
+
+func TestPoolReturnTestDatabase(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+		Config: db.DatabaseConfig{
+			Database: "h1_template",
+		},
+	}
+
+	recreateTimesMap := sync.Map{}
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		times, existing := recreateTimesMap.LoadOrStore(testDB.ID, 1)
+		if existing {
+			recreateTimesMap.Store(testDB.ID, times.(int)+1)
+		}
+
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:            10,
+		MaxParallelTasks:       3,
+		disableWorkerAutostart: true, // no extend / cleanDirty tasks should run automatically!
+	}
+	p := NewPoolCollection(cfg)
+
+	p.InitHashPool(ctx, templateDB1, initFunc)
+	// add just one test DB
+	require.NoError(t, p.extend(ctx, templateDB1))
+
+	testDB1, err := p.GetTestDatabase(ctx, templateDB1.TemplateHash, time.Millisecond)
+	assert.NoError(t, err)
+
+	// assert that workers are stopped and no new DB showed up
+	_, err = p.GetTestDatabase(ctx, templateDB1.TemplateHash, time.Millisecond)
+	assert.ErrorIs(t, err, ErrTimeout)
+
+	// return and get the same one
+	assert.NoError(t, p.ReturnTestDatabase(ctx, hash1, testDB1.ID))
+	testDB2, err := p.GetTestDatabase(ctx, templateDB1.TemplateHash, time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, testDB1.ID, testDB2.ID)
+
+}
+
+func TestPoolRecreateInline(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+		Config: db.DatabaseConfig{
+			Database: "h1_template",
+		},
+	}
+
+	var recreateTimes int
+	var recreateMutex sync.Mutex
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		recreateMutex.Lock()
+		defer recreateMutex.Unlock()
+		recreateTimes++
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:      1,
+		MaxParallelTasks: 1,
+		RecreateInline:   true,
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 1, initFunc)
+
+	testDB1, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+
+	// recreation must have already happened once RecreateTestDatabase returns
+	require.NoError(t, p.RecreateTestDatabase(ctx, hash1, testDB1.ID))
+	recreateMutex.Lock()
+	assert.Equal(t, 2, recreateTimes)
+	recreateMutex.Unlock()
+
+	testDB2, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, testDB1.ID, testDB2.ID)
+}
+
+func TestPoolRecreateInlineFallback(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+		Config: db.DatabaseConfig{
+			Database: "h1_template",
+		},
+	}
+
+	var inUse atomic.Bool
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		if inUse.Load() {
+			inUse.Store(false)
+			return ErrTestDBInUse
+		}
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:                       1,
+		MaxParallelTasks:                  1,
+		RecreateInline:                    true,
+		TestDatabaseRetryRecreateSleepMin: time.Millisecond,
+		TestDatabaseRetryRecreateSleepMax: time.Millisecond,
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 1, initFunc)
+
+	testDB1, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+
+	// still in use: the inline attempt fails, the background worker retries
+	inUse.Store(true)
+	require.NoError(t, p.RecreateTestDatabase(ctx, hash1, testDB1.ID))
+
+	testDB2, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, testDB1.ID, testDB2.ID)
+}
+
+func TestPoolRecreateInlineFailed(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+		Config: db.DatabaseConfig{
+			Database: "h1_template",
+		},
+	}
+
+	var failing atomic.Bool
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		if failing.CompareAndSwap(true, false) {
+			return errors.New("connection reset by peer")
+		}
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:      1,
+		MaxParallelTasks: 1,
+		RecreateInline:   true,
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 1, initFunc)
+
+	testDB1, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+
+	// the inline attempt fails for good: the return still succeeds, the test DB is queued as dirty again and cleaned in background
+	failing.Store(true)
+	require.NoError(t, p.RecreateTestDatabase(ctx, hash1, testDB1.ID))
+	requireSnapshotEventually(t, p, hash1, func(snapshot PoolSnapshot) bool { return snapshot.Ready == 1 })
+	assert.False(t, failing.Load())
+
+	testDB2, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, testDB1.ID, testDB2.ID)
+}
+
+func TestPoolRecreateInlineNotRunning(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	pool := NewHashPool(PoolConfig{MaxPoolSize: 1, MaxParallelTasks: 1, RecreateInline: true}, db.Database{TemplateHash: "h1"}, func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		return nil
+	})
+
+	pool.dbs = append(pool.dbs, existingDB{state: dbStateDirty, TestDatabase: db.TestDatabase{ID: 0}})
+	pool.dirty <- 0
+
+	// no workers (thus no workerContext) yet: the test DB stays dirty instead of panicking
+	require.NoError(t, pool.RecreateTestDatabase(ctx, 0))
+	assert.Equal(t, dbStateDirty, pool.dbs[0].state)
+	assert.Len(t, pool.dirty, 1)
+}
+
+func TestPoolRecreateRetryTimeout(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+		Config: db.DatabaseConfig{
+			Database: "h1_template",
+		},
+	}
+
+	// the first recreate times out (e.g. waiting for a lock), the retry succeeds
+	var recreateTimes int
+	var recreateMutex sync.Mutex
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		recreateMutex.Lock()
+		defer recreateMutex.Unlock()
+		recreateTimes++
+		if recreateTimes == 1 {
+			return fmt.Errorf("%w: pq: canceling statement due to lock timeout", ErrTestDBTimeout)
+		}
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:                       1,
+		MaxParallelTasks:                  1,
+		TestDatabaseRetryRecreateSleepMin: time.Millisecond,
+		TestDatabaseRetryRecreateSleepMax: time.Millisecond,
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 1, initFunc)
+	recreateMutex.Lock()
+	assert.Equal(t, 2, recreateTimes)
+	recreateMutex.Unlock()
+
+	_, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+}
+
+func TestPoolAutoScaleReadyTarget(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	templateDB1 := db.Database{
+		TemplateHash: "h1",
+		Config: db.DatabaseConfig{
+			Database: "h1_template",
+		},
+	}
+
+	cfg := PoolConfig{
+		InitialPoolSize:              1,
+		MaxPoolSize:                  3,
+		MaxParallelTasks:             1,
+		AutoScale:                    true,
+		AutoScaleStarvationThreshold: 50,
+		AutoScaleWindow:              2,
+	}
+
+	// workers are not started, we only inspect the pushed tasks
+	pool := NewHashPool(cfg, templateDB1, noopRecreateDB)
+	log := pool.getPoolLogger(ctx, "test")
+
+	pool.Lock()
+
+	// 50% starvation does not exceed the threshold
+	pool.unsafeTrackStarvation(ctx, log, true)
+	pool.unsafeTrackStarvation(ctx, log, false)
+	assert.Equal(t, 1, pool.readyTarget)
+	assert.Equal(t, 0, len(pool.tasksChan))
+
+	// 100% starvation doubles the target
+	pool.unsafeTrackStarvation(ctx, log, true)
+	assert.Equal(t, 1, pool.readyTarget, "window not yet completed")
+	pool.unsafeTrackStarvation(ctx, log, true)
+	assert.Equal(t, 2, pool.readyTarget)
+	assert.Equal(t, 1, len(pool.tasksChan))
+
+	// capped by MaxPoolSize
+	pool.unsafeTrackStarvation(ctx, log, true)
+	pool.unsafeTrackStarvation(ctx, log, true)
+	assert.Equal(t, 3, pool.readyTarget)
+	assert.Equal(t, 2, len(pool.tasksChan))
+
+	pool.unsafeTrackStarvation(ctx, log, true)
+	pool.unsafeTrackStarvation(ctx, log, true)
+	assert.Equal(t, 3, pool.readyTarget)
+	assert.Equal(t, 2, len(pool.tasksChan))
+
+	pool.Unlock()
+
+	assert.Equal(t, 3, pool.Snapshot().ReadyTarget)
+}
+
+func TestPoolAutoScaleQueueFull(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	cfg := PoolConfig{
+		InitialPoolSize:              1,
+		MaxPoolSize:                  4,
+		MaxParallelTasks:             1,
+		AutoScale:                    true,
+		AutoScaleStarvationThreshold: 50,
+		AutoScaleWindow:              1,
+	}
+
+	// workers are not started, only a single slot left in the task queue
+	pool := NewHashPool(cfg, db.Database{TemplateHash: "h1"}, func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		return nil
+	})
+	log := pool.getPoolLogger(ctx, "test")
+
+	for i := 0; i < cap(pool.tasksChan)-1; i++ {
+		pool.tasksChan <- newQueuedTask(ctx, workerTaskExtend)
+	}
+
+	pool.Lock()
+	defer pool.Unlock()
+
+	// never blocks while holding the lock, the target is bumped regardless
+	pool.unsafeTrackStarvation(ctx, log, true)
+	pool.unsafeTrackStarvation(ctx, log, true)
+	assert.Equal(t, 4, pool.readyTarget)
+	assert.Len(t, pool.tasksChan, cap(pool.tasksChan))
+}
+
+func TestPoolReturnTestDatabaseNotHandedOut(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	templateDB1 := db.Database{TemplateHash: "h1"}
+
+	for _, lenient := range []bool{false, true} {
+		cfg := PoolConfig{
+			MaxPoolSize:            2,
+			MaxParallelTasks:       1,
+			LenientReturns:         lenient,
+			disableWorkerAutostart: true,
+		}
+		p := NewPoolCollection(cfg)
+		t.Cleanup(func() { p.Stop() })
+
+		p.InitHashPool(ctx, templateDB1, noopRecreateDB)
+		require.NoError(t, p.extend(ctx, templateDB1))
+		require.NoError(t, p.extend(ctx, templateDB1))
+
+		testDB, err := p.GetTestDatabase(ctx, "h1", time.Second)
+		require.NoError(t, err)
+		require.NoError(t, p.ReturnTestDatabase(ctx, "h1", testDB.ID))
+
+		// returned twice, respectively never handed out
+		otherID := 1 - testDB.ID
+		if lenient {
+			assert.NoError(t, p.ReturnTestDatabase(ctx, "h1", testDB.ID))
+			assert.NoError(t, p.ReturnTestDatabase(ctx, "h1", otherID))
+		} else {
+			assert.ErrorIs(t, p.ReturnTestDatabase(ctx, "h1", testDB.ID), ErrUnknownID)
+			assert.ErrorIs(t, p.ReturnTestDatabase(ctx, "h1", otherID), ErrUnknownID)
+		}
+
+		// the ready test DBs are not affected either way
+		ready, err := p.PeekReady(ctx, "h1")
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []int{0, 1}, ready)
+	}
+}
+
+func TestPoolReturnTestDatabaseVerifyClean(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+	}
+
+	var recreated int32
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		atomic.AddInt32(&recreated, 1)
+		return nil
+	}
+
+	var clean atomic.Bool
+	var verifyErr error
+	cfg := PoolConfig{
+		MaxPoolSize:      1,
+		MaxParallelTasks: 1,
+		RecreateInline:   true, // recreate synchronously
+		VerifyClean: func(ctx context.Context, testDB db.TestDatabase) (bool, error) {
+			return clean.Load(), verifyErr
+		},
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 1, initFunc)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&recreated))
+
+	// verified clean ones are ready again as is
+	clean.Store(true)
+	testDB, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	require.NoError(t, p.ReturnTestDatabase(ctx, hash1, testDB.ID))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&recreated))
+
+	// dirty ones are recreated
+	clean.Store(false)
+	testDB, err = p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	require.NoError(t, p.ReturnTestDatabase(ctx, hash1, testDB.ID))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&recreated))
+
+	snapshot, err := p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), snapshot.VerifyCleanFailedTotal)
+	assert.Equal(t, 1, snapshot.Ready)
+
+	// the lease is still checked
+	testDB, err = p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	assert.ErrorIs(t, p.ReturnTestDatabaseWithLease(ctx, hash1, testDB.ID, "invalid"), ErrInvalidLease)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&recreated))
+
+	snapshot, err = p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), snapshot.VerifyCleanFailedTotal)
+
+	// failing checks are treated as dirty
+	clean.Store(true)
+	verifyErr = errors.New("verify failed")
+	require.NoError(t, p.ReturnTestDatabaseWithLease(ctx, hash1, testDB.ID, testDB.Lease))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&recreated))
+
+	assert.ErrorIs(t, p.ReturnTestDatabase(ctx, hash1, 5), ErrInvalidIndex)
+}
+
+func TestPoolSetReadyTarget(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	templateDB1 := db.Database{
+		TemplateHash: "h1",
+		Config: db.DatabaseConfig{
+			Database: "h1_template",
+		},
+	}
+
+	cfg := PoolConfig{
+		InitialPoolSize:  1,
+		MaxPoolSize:      4,
+		MaxParallelTasks: 1,
+	}
+
+	// workers are not started, we only inspect the pushed tasks
+	pool := NewHashPool(cfg, templateDB1, noopRecreateDB)
+
+	_, err := pool.SetReadyTarget(ctx, 0)
+	assert.ErrorIs(t, err, ErrInvalidSize)
+
+	// a stopped pool only changes its target
+	target, err := pool.SetReadyTarget(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, target)
+	assert.Equal(t, 0, len(pool.tasksChan))
+
+	pool.Lock()
+	pool.running = true
+	pool.Unlock()
+
+	// raising it schedules the missing test DBs, capped by MaxPoolSize
+	target, err = pool.SetReadyTarget(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 4, target)
+	assert.Equal(t, 2, len(pool.tasksChan), "Start schedules the previous target itself")
+	assert.Equal(t, 4, pool.Snapshot().ReadyTarget)
+	assert.Equal(t, 4, pool.InitialPoolSize)
+
+	// lowering it schedules nothing
+	target, err = pool.SetReadyTarget(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, target)
+	assert.Equal(t, 2, len(pool.tasksChan))
+	assert.Equal(t, 1, pool.Snapshot().ReadyTarget)
+}
+
+func TestPoolGetTestDatabaseByID(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+		Config: db.DatabaseConfig{
+			Database: "h1_template",
+		},
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:      2,
+		MaxParallelTasks: 1,
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 2, noopRecreateDB)
+
+	// ready test DB is handed out like the normal path
+	testDB, dirty, err := p.GetTestDatabaseByID(ctx, hash1, 1)
+	require.NoError(t, err)
+	assert.False(t, dirty)
+	assert.Equal(t, 1, testDB.ID)
+
+	snapshot, err := p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, snapshot.Ready)
+	assert.Equal(t, 1, snapshot.Dirty)
+	assert.Equal(t, "dirty", snapshot.TestDatabases[1].State)
+
+	// dirty test DB is handed out as is
+	testDB, dirty, err = p.GetTestDatabaseByID(ctx, hash1, 1)
+	require.NoError(t, err)
+	assert.True(t, dirty)
+	assert.Equal(t, 1, testDB.ID)
+
+	snapshot, err = p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, snapshot.Dirty)
+	assert.Equal(t, uint64(1), snapshot.GetCleanTotal)
+	assert.Equal(t, uint64(1), snapshot.GetDirtyTotal)
+	assert.InDelta(t, 0.5, snapshot.DirtyRatio, 0.001)
+
+	// the normal path skips the test DB reserved by ID
+	testDB, err = p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 0, testDB.ID)
+
+	_, _, err = p.GetTestDatabaseByID(ctx, hash1, 2)
+	assert.ErrorIs(t, err, ErrInvalidIndex)
+
+	_, _, err = p.GetTestDatabaseByID(ctx, "unknown", 0)
+	assert.ErrorIs(t, err, ErrUnknownHash)
+}
+
+func TestPoolGetTestDatabaseByIDRejectDirty(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+		Config: db.DatabaseConfig{
+			Database: "h1_template",
+		},
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:      2,
+		MaxParallelTasks: 1,
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 1, noopRecreateDB)
+
+	// ready test DBs are handed out regardless
+	_, dirty, err := p.GetTestDatabaseByIDWithOptions(ctx, hash1, 0, GetOptions{RejectDirty: true})
+	require.NoError(t, err)
+	assert.False(t, dirty)
+
+	// a dirty test DB is refused per request, without being handed out
+	_, _, err = p.GetTestDatabaseByIDWithOptions(ctx, hash1, 0, GetOptions{RejectDirty: true})
+	assert.ErrorIs(t, err, ErrWouldReuseDirty)
+
+	snapshot, err := p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), snapshot.GetDirtyTotal)
+
+	// ... or by the pool config
+	pool, err := p.getPool(ctx, hash1)
+	require.NoError(t, err)
+	pool.Lock()
+	pool.RejectDirty = true
+	pool.Unlock()
+
+	_, _, err = p.GetTestDatabaseByID(ctx, hash1, 0)
+	assert.ErrorIs(t, err, ErrWouldReuseDirty)
+}
+
+func TestPoolDBName(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	// hyphens and uppercase letters must be kept as is (postgres only folds unquoted identifiers)
+	p := NewPoolCollection(PoolConfig{TestDBNamePrefix: "Team-A_test_"})
+	assert.Equal(t, "Team-A_test_Hash-ABC_001", p.MakeDBName("Hash-ABC", 1))
+
+	hash1 := "Hash-ABC"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+		Config: db.DatabaseConfig{
+			Database: "Team-A_template_Hash-ABC",
+		},
+	}
+
+	var names []string
+	var namesMutex sync.Mutex
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		namesMutex.Lock()
+		defer namesMutex.Unlock()
+		names = append(names, testDB.Config.Database)
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:      1,
+		MaxParallelTasks: 1,
+		TestDBNamePrefix: "Team-A_",
+		DBName: func(testDBPrefix string, hash string, instanceID string, id int) string {
+			return fmt.Sprintf("%s%d-%s", testDBPrefix, id, hash)
+		},
+	}
+	p = NewPoolCollection(cfg)
+	assert.Equal(t, "Team-A_1-Hash-ABC", p.MakeDBName(hash1, 1))
+
+	p.InitHashPool(ctx, templateDB1, initFunc)
+	t.Cleanup(func() { p.Stop() })
+
+	require.NoError(t, p.extend(ctx, templateDB1))
+
+	testDB, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "Team-A_0-Hash-ABC", testDB.Config.Database)
+
+	namesMutex.Lock()
+	assert.Equal(t, []string{"Team-A_0-Hash-ABC"}, names)
+	namesMutex.Unlock()
+}
+
+func TestPoolDBNameInstanceID(t *testing.T) {
+	t.Parallel()
+
+	p := NewPoolCollection(PoolConfig{TestDBNamePrefix: "integresql_test_", InstanceID: "261016042648"})
+	assert.Equal(t, "integresql_test_Hash-ABC_261016042648_001", p.MakeDBName("Hash-ABC", 1))
+
+	// custom builders receive the instance ID as well
+	p = NewPoolCollection(PoolConfig{
+		TestDBNamePrefix: "test_",
+		InstanceID:       "run-42",
+		DBName: func(testDBPrefix string, hash string, instanceID string, id int) string {
+			return fmt.Sprintf("%s%s-%s-%d", testDBPrefix, instanceID, hash, id)
+		},
+	})
+	assert.Equal(t, "test_run-42-Hash-ABC-1", p.MakeDBName("Hash-ABC", 1))
+
+	// the prefix is truncated to keep the whole name (including the ID suffix) within the postgres limit
+	hash := strings.Repeat("a", 32)
+	p = NewPoolCollection(PoolConfig{TestDBNamePrefix: "integresql_very_long_prefix_", InstanceID: "261016042648"})
+	name := p.MakeDBName(hash, 1)
+	assert.Len(t, name, MaxDBNameLength)
+	assert.Equal(t, "integresql_ver"+hash+"_261016042648_001", name)
+
+	// multi-byte characters are never split
+	assert.Equal(t, "ü", truncateDBNamePrefix("üü", 3))
+	assert.Equal(t, "", truncateDBNamePrefix("ü", 1))
+	assert.Equal(t, "", truncateDBNamePrefix("test_", -2))
+}
+
+func TestPoolGetTestDatabasePingDB(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+		Config: db.DatabaseConfig{
+			Database: "h1_template",
+		},
+	}
+
+	recreateTimesMap := sync.Map{}
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		times, existing := recreateTimesMap.LoadOrStore(testDB.ID, 1)
+		if existing {
+			recreateTimesMap.Store(testDB.ID, times.(int)+1)
+		}
+
+		return nil
+	}
+
+	// the first handed out test DB (id 0) is dead, all others are alive
+	deadID := 0
+	var deadOnce sync.Once
+	pingFunc := func(ctx context.Context, testDB db.TestDatabase) error {
+		dead := false
+		if testDB.ID == deadID {
+			deadOnce.Do(func() { dead = true })
+		}
+		if dead {
+			return fmt.Errorf("%w: database does not exist", ErrTestDBDead)
+		}
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:      2,
+		MaxParallelTasks: 1,
+		PingDB:           pingFunc,
+		PingDBMaxRetries: 1,
+		RecreateInline:   true, // not inline while another test DB is awaited
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 2, initFunc)
+
+	// dead test DB is skipped
+	testDB, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	assert.NotEqual(t, deadID, testDB.ID)
+
+	// and recreated in background
+	require.Eventually(t, func() bool {
+		times, ok := recreateTimesMap.Load(deadID)
+		return ok && times.(int) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	testDB, err = p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, deadID, testDB.ID)
+}
+
+func TestPoolGetTestDatabasePingDBMaxRetries(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+	}
+	pingFunc := func(ctx context.Context, testDB db.TestDatabase) error {
+		return fmt.Errorf("%w: database does not exist", ErrTestDBDead)
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:      1,
+		MaxParallelTasks: 1,
+		PingDB:           pingFunc,
+		PingDBMaxRetries: 2,
+		RecreateInline:   true,
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 1, noopRecreateDB)
+
+	_, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	assert.ErrorIs(t, err, ErrNoAliveDB)
+}
+
+func TestPoolGetTestDatabasePingDBError(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+	}
+
+	var recreated atomic.Int32
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		recreated.Add(1)
+		return nil
+	}
+
+	errUnreachable := errors.New("connection refused")
+	var unreachable atomic.Bool
+	unreachable.Store(true)
+	pingFunc := func(ctx context.Context, testDB db.TestDatabase) error {
+		if unreachable.Load() {
+			return errUnreachable
+		}
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:      1,
+		MaxParallelTasks: 1,
+		PingDB:           pingFunc,
+		PingDBMaxRetries: 2,
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 1, initFunc)
+
+	// not dead, thus neither recreated nor retried, but returned as is
+	_, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	assert.ErrorIs(t, err, errUnreachable)
+	assert.Equal(t, int32(1), recreated.Load())
+
+	unreachable.Store(false)
+	_, dirty, err := p.GetTestDatabaseByID(ctx, hash1, 0)
+	require.NoError(t, err)
+	assert.False(t, dirty)
+}
+
+func TestPoolGetTestDatabaseRestoresReadyID(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+	}
+	cfg := PoolConfig{
+		MaxPoolSize:            2,
+		MaxParallelTasks:       1,
+		disableWorkerAutostart: true, // no extend / cleanDirty tasks should run automatically!
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 1, noopRecreateDB)
+
+	pool, err := p.getPool(ctx, hash1)
+	require.NoError(t, err)
+
+	// inject an inconsistent state: the ready test DB is flagged as recreating
+	pool.Lock()
+	pool.dbs[0].state = dbStateRecreating
+	pool.Unlock()
+
+	_, err = p.GetTestDatabase(ctx, hash1, time.Millisecond)
+	assert.ErrorIs(t, err, ErrInvalidState)
+	assert.Equal(t, 1, len(pool.ready))
+
+	// the ID is recovered once the state is consistent again
+	pool.Lock()
+	pool.dbs[0].state = dbStateReady
+	pool.Unlock()
+
+	testDB, err := p.GetTestDatabase(ctx, hash1, time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, 0, testDB.ID)
+
+	// inject an out of range ID
+	pool.ready <- 5
+
+	_, err = p.GetTestDatabase(ctx, hash1, time.Millisecond)
+	assert.ErrorIs(t, err, ErrInvalidIndex)
+	require.Equal(t, 1, len(pool.ready))
+	assert.Equal(t, 5, <-pool.ready)
+}
+
+func TestPoolGetTestDatabaseLabels(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+	}
+	cfg := PoolConfig{
+		MaxPoolSize:            2,
+		MaxParallelTasks:       1,
+		TestDBNamePrefix:       "test_",
+		disableWorkerAutostart: true, // no extend / cleanDirty tasks should run automatically!
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 1, noopRecreateDB)
+
+	labels := map[string]string{"ciJob": "1234"}
+	testDB, err := p.GetTestDatabaseWithOptions(ctx, hash1, time.Millisecond, GetOptions{Labels: labels})
+	require.NoError(t, err)
+	assert.Equal(t, labels, testDB.Labels)
+
+	snapshot, err := p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	require.Len(t, snapshot.TestDatabases, 1)
+	assert.Equal(t, "dirty", snapshot.TestDatabases[0].State)
+	assert.Equal(t, "test_h1_000", snapshot.TestDatabases[0].Database)
+	assert.Equal(t, labels, snapshot.TestDatabases[0].Labels)
+
+	// labels are cleared on return
+	require.NoError(t, p.ReturnTestDatabase(ctx, hash1, testDB.ID))
+
+	snapshot, err = p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, "ready", snapshot.TestDatabases[0].State)
+	assert.Nil(t, snapshot.TestDatabases[0].Labels)
+	assert.Equal(t, 1, snapshot.Ready)
+
+	_, err = p.Snapshot(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrUnknownHash)
+}
+
+func TestPoolMaxConcurrentCopies(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	templateDB1 := db.Database{
+		TemplateHash: "h1",
+	}
+	templateDB2 := db.Database{
+		TemplateHash: "h2",
+	}
+
+	var copying, maxCopying int32
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		current := atomic.AddInt32(&copying, 1)
+		defer atomic.AddInt32(&copying, -1)
+
+		for {
+			previous := atomic.LoadInt32(&maxCopying)
+			if current <= previous || atomic.CompareAndSwapInt32(&maxCopying, previous, current) {
+				break
+			}
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:            4,
+		MaxParallelTasks:       4,
+		MaxConcurrentCopies:    1,
+		disableWorkerAutostart: true, // no extend / cleanDirty tasks should run automatically!
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 0, initFunc)
+	p.InitHashPool(ctx, templateDB2, initFunc)
+
+	// the limit applies across the pools of the collection
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		for _, templateDB := range []db.Database{templateDB1, templateDB2} {
+			wg.Add(1)
+			go func(templateDB db.Database) {
+				defer wg.Done()
+				assert.NoError(t, p.extend(ctx, templateDB))
+			}(templateDB)
+		}
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&maxCopying))
+
+	for _, hash := range []string{"h1", "h2"} {
+		snapshot, err := p.Snapshot(ctx, hash)
+		require.NoError(t, err)
+		assert.Equal(t, 2, snapshot.Ready)
+		assert.Equal(t, uint64(2), snapshot.CopyWaitDurations.Count)
+	}
+}
+
+func TestPoolOnReady(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+	}
+	var p *PoolCollection
+	var ready []int
+	onReady := func(hash string) {
+		// called outside the lock, thus the pool may be used
+		snapshot, err := p.Snapshot(ctx, hash)
+		assert.NoError(t, err)
+		ready = append(ready, snapshot.Ready)
+	}
+
+	cfg := PoolConfig{
+		InitialPoolSize:        2,
+		MaxPoolSize:            4,
+		MaxParallelTasks:       1,
+		OnReady:                onReady,
+		disableWorkerAutostart: true, // no extend / cleanDirty tasks should run automatically!
+	}
+	p = NewPoolCollection(cfg)
+
+	p.InitHashPool(ctx, templateDB1, noopRecreateDB)
+
+	require.NoError(t, p.extend(ctx, templateDB1))
+	assert.Empty(t, ready)
+
+	require.NoError(t, p.extend(ctx, templateDB1))
+	assert.Equal(t, []int{2}, ready)
+
+	// only called once
+	require.NoError(t, p.extend(ctx, templateDB1))
+	testDB, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	require.NoError(t, p.ReturnTestDatabase(ctx, hash1, testDB.ID))
+	require.NoError(t, p.extend(ctx, templateDB1))
+	assert.Equal(t, []int{2}, ready)
+}
+
+func TestPoolEnsureReady(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+	}
+
+	warmUpCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var created int32
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		// cancel mid warm-up, after the second test DB was created
+		if atomic.AddInt32(&created, 1) == 2 {
+			cancel()
+		}
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:            6,
+		InitialPoolSize:        5,
+		MaxParallelTasks:       1,
+		disableWorkerAutostart: true,
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 0, initFunc)
+
+	err := p.EnsureReadyWithHash(warmUpCtx, hash1)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// already created test DBs stay registered, the one being recreated when cancelled stays dirty
+	snapshot, err := p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Len(t, snapshot.TestDatabases, 2)
+	assert.Equal(t, 1, snapshot.Ready)
+	assert.Equal(t, "dirty", snapshot.TestDatabases[1].State)
+
+	// continuing the warm-up only creates the missing ones
+	require.NoError(t, p.EnsureReadyWithHash(ctx, hash1))
+	snapshot, err = p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Len(t, snapshot.TestDatabases, 5)
+	assert.Equal(t, 4, snapshot.Ready)
+}
+
+func TestPoolEnsureReadyStop(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+	}
+
+	started := make(chan struct{}, 10)
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		started <- struct{}{}
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:            10,
+		InitialPoolSize:        10,
+		MaxParallelTasks:       1,
+		disableWorkerAutostart: true,
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 0, initFunc)
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- p.EnsureReadyWithHash(ctx, hash1)
+	}()
+
+	<-started
+	p.Stop()
+
+	select {
+	case err := <-errChan:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("warm-up was not aborted by Stop")
+	}
+
+	snapshot, err := p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Less(t, len(snapshot.TestDatabases), 10)
+}
+
+func TestPoolCheckStorage(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+		Config: db.DatabaseConfig{
+			Database: "template_h1",
+		},
+	}
+	var checked int32
+	cfg := PoolConfig{
+		MaxPoolSize:            3,
+		MaxParallelTasks:       1,
+		disableWorkerAutostart: true,
+		CheckStorage: func(ctx context.Context, templateDB db.Database) error {
+			assert.Equal(t, "template_h1", templateDB.Config.Database)
+			if atomic.AddInt32(&checked, 1) > 1 {
+				return fmt.Errorf("%w: 100 bytes used", ErrInsufficientStorage)
+			}
+			return nil
+		},
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 1, noopRecreateDB)
+
+	// refused without creating a test DB
+	err := p.extend(ctx, templateDB1)
+	assert.ErrorIs(t, err, ErrInsufficientStorage)
+
+	snapshot, err := p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Len(t, snapshot.TestDatabases, 1)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&checked))
+}
+
+func TestPoolTooManyConnections(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+	}
+
+	var attempts int32
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			return fmt.Errorf("%w: pq: sorry, too many clients already", ErrTooManyConnections)
+		}
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:               3,
+		MaxParallelTasks:          1,
+		TooManyConnectionsBackoff: 100 * time.Millisecond,
+		disableWorkerAutostart:    true,
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 0, initFunc)
+
+	// the rejected attempt is retried after the backoff
+	start := time.Now()
+	require.NoError(t, p.extend(ctx, templateDB1))
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+
+	snapshot, err := p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), snapshot.TooManyConnectionsTotal)
+	assert.Equal(t, 1, snapshot.Ready)
+
+	// further extensions are refused until the backoff passed
+	pool, err := p.getPool(ctx, hash1)
+	require.NoError(t, err)
+	pool.Lock()
+	pool.extendBackoffUntil = time.Now().Add(time.Hour)
+	pool.Unlock()
+
+	assert.ErrorIs(t, p.extend(ctx, templateDB1), ErrTooManyConnections)
+
+	snapshot, err = p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Len(t, snapshot.TestDatabases, 1)
+
+	pool.Lock()
+	pool.extendBackoffUntil = time.Time{}
+	pool.Unlock()
+
+	require.NoError(t, p.extend(ctx, templateDB1))
+}
+
+func TestPoolWorkersBusy(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+	}
+
+	release := make(chan struct{})
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		<-release
+		return nil
+	}
+
+	cfg := PoolConfig{
+		InitialPoolSize:  3,
+		MaxPoolSize:      3,
+		MaxParallelTasks: 2,
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 0, initFunc)
+
+	// the third extension has to wait for a worker
+	assert.Eventually(t, func() bool {
+		snapshot, err := p.Snapshot(ctx, hash1)
+		require.NoError(t, err)
+		return snapshot.WorkersBusy == 2
+	}, time.Second, 5*time.Millisecond)
+
+	snapshot, err := p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, snapshot.Workers)
+
+	close(release)
+
+	assert.Eventually(t, func() bool {
+		snapshot, err := p.Snapshot(ctx, hash1)
+		require.NoError(t, err)
+		return snapshot.WorkersBusy == 0 && snapshot.Ready == 3
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestPoolRefillWatermark(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+	}
+	cfg := PoolConfig{
+		InitialPoolSize:        5,
+		MaxPoolSize:            8,
+		MaxParallelTasks:       1,
+		RefillWatermark:        50,
+		disableWorkerAutostart: true,
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 5, noopRecreateDB)
+
+	pool, err := p.getPool(ctx, hash1)
+	require.NoError(t, err)
+
+	// above the watermark, each get schedules a single extension
+	for i := 1; i <= 2; i++ {
+		_, err := p.GetTestDatabase(ctx, hash1, 0)
+		require.NoError(t, err)
+		assert.Equal(t, i, len(pool.tasksChan))
+	}
+
+	// 2 of 5 ready (40%), top up to the ready target at once
+	_, err = p.GetTestDatabase(ctx, hash1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2+3, len(pool.tasksChan))
+
+	// 1 of 5 ready, but only 3 more test DBs fit within MaxPoolSize (the already scheduled ones are not yet created)
+	_, err = p.GetTestDatabase(ctx, hash1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 5+3, len(pool.tasksChan))
+}
+
+func TestPoolMinReady(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+	}
+	cfg := PoolConfig{
+		InitialPoolSize:        1,
+		MaxPoolSize:            6,
+		MaxParallelTasks:       1,
+		MinReady:               4,
+		disableWorkerAutostart: true,
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 4, noopRecreateDB)
+
+	pool, err := p.getPool(ctx, hash1)
+	require.NoError(t, err)
+
+	// the ready target never drops below MinReady
+	snapshot := pool.Snapshot()
+	assert.Equal(t, 4, snapshot.MinReady)
+	assert.Equal(t, 4, snapshot.ReadyTarget)
+
+	target, err := pool.SetReadyTarget(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 4, target)
+
+	// drains the task queue as if the workers picked up all tasks
+	drain := func() []workerTask {
+		tasks := []workerTask{}
+		for len(pool.tasksChan) > 0 {
+			tasks = append(tasks, (<-pool.tasksChan).task)
+		}
+		return tasks
+	}
+
+	// 3 ready, the extension scheduled by the get covers the missing one
+	_, err = p.GetTestDatabase(ctx, hash1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []workerTask{workerTaskExtend}, drain())
+
+	// 2 ready, one more test DB is scheduled eagerly
+	_, err = p.GetTestDatabase(ctx, hash1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []workerTask{workerTaskExtend, workerTaskExtend}, drain())
+
+	// 1 ready, only one more test DB fits within MaxPoolSize, thus a dirty one is cleaned eagerly
+	_, err = p.GetTestDatabase(ctx, hash1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []workerTask{workerTaskExtend, workerTaskExtend, workerTaskAutoCleanDirty}, drain())
+}
+
+func TestPoolTasksRequestID(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+	}
+	cfg := PoolConfig{
+		MaxPoolSize:            2,
+		MaxParallelTasks:       1,
+		disableWorkerAutostart: true,
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 1, noopRecreateDB)
+
+	pool, err := p.getPool(ctx, hash1)
+	require.NoError(t, err)
+
+	// the extension triggered by the get carries the ID of the request
+	_, err = p.GetTestDatabase(util.ContextWithRequestID(ctx, "req-1"), hash1, 0)
+	require.NoError(t, err)
+
+	require.Equal(t, 1, len(pool.tasksChan))
+	task := <-pool.tasksChan
+	assert.Equal(t, queuedTask{task: workerTaskExtend, requestID: "req-1"}, task)
+}
+
+func TestPoolCleanBatch(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+	}
+
+	var failID int32 = -1
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		if int32(testDB.ID) == atomic.LoadInt32(&failID) {
+			return errors.New("disk full")
+		}
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:            4,
+		MaxParallelTasks:       1,
+		CleanBatchSize:         3,
+		disableWorkerAutostart: true,
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, cfg.MaxPoolSize, initFunc)
+
+	for i := 0; i < cfg.MaxPoolSize; i++ {
+		_, err := p.GetTestDatabase(ctx, hash1, time.Second)
+		require.NoError(t, err)
+	}
+
+	pool, err := p.getPool(ctx, hash1)
+	require.NoError(t, err)
+
+	// a single task cleans up to CleanBatchSize test DBs, only the failed one stays dirty
+	atomic.StoreInt32(&failID, 1)
+	err = pool.autoCleanDirty(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "id 1: ")
+
+	snapshot, err := p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, snapshot.Ready)
+	assert.Equal(t, 2, snapshot.Dirty)
+	assert.Equal(t, "ready", snapshot.TestDatabases[0].State)
+	assert.Equal(t, "dirty", snapshot.TestDatabases[1].State)
+	assert.Equal(t, "ready", snapshot.TestDatabases[2].State)
+	assert.Equal(t, "dirty", snapshot.TestDatabases[3].State)
+
+	// the next one cleans the remaining and the requeued failed one
+	atomic.StoreInt32(&failID, -1)
+	require.NoError(t, pool.autoCleanDirty(ctx))
+
+	snapshot, err = p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, 4, snapshot.Ready)
+	assert.Equal(t, 0, snapshot.Dirty)
+}
+
+func TestPoolCleanBatchInUse(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+	}
+
+	var inUseID int32 = -1
+	var attempts int32
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		if int32(testDB.ID) == atomic.LoadInt32(&inUseID) {
+			atomic.AddInt32(&attempts, 1)
+			return ErrTestDBInUse
+		}
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:                       3,
+		MaxParallelTasks:                  1,
+		CleanBatchSize:                    3,
+		TestDatabaseRetryRecreateSleepMin: time.Hour, // a retry would hold up the batch
+		TestDatabaseRetryRecreateSleepMax: time.Hour,
+		disableWorkerAutostart:            true,
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, cfg.MaxPoolSize, initFunc)
+
+	for i := 0; i < cfg.MaxPoolSize; i++ {
+		_, err := p.GetTestDatabase(ctx, hash1, time.Second)
+		require.NoError(t, err)
+	}
+
+	pool, err := p.getPool(ctx, hash1)
+	require.NoError(t, err)
+
+	// the test DB still in use gets a single attempt, the others are recreated and only it is queued as dirty again
+	atomic.StoreInt32(&inUseID, 0)
+	err = pool.autoCleanDirty(ctx)
+	require.ErrorIs(t, err, ErrTestDBInUse)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
 
+	snapshot, err := p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, snapshot.Ready)
+	assert.Equal(t, 1, snapshot.Dirty)
+	assert.Equal(t, "dirty", snapshot.TestDatabases[0].State)
 }
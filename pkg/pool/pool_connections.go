@@ -0,0 +1,47 @@
+package pool
+
+import (
+	"context"
+	"sort"
+)
+
+// ConnectionEstimate is the upper bound of the connections the clients of the test DBs of a PoolCollection could demand.
+type ConnectionEstimate struct {
+	Connections     int      `json:"connections"`               // maximal pool size times the connection limit, summed over all pools with a limit (capped by MaxTotalDBs)
+	UnlimitedHashes []string `json:"unlimitedHashes,omitempty"` // pools without a connection limit, their demand is unbounded, thus not included in Connections
+}
+
+// EstimatedMaxConnections sums up the connections the test DBs of all pools could demand at most, each pool its MaxPoolSize times
+// its ConnectionLimit. If MaxTotalDBs is configured, the sum is capped by that number of test DBs at the highest connection limit.
+// Pools without a connection limit are reported separately, as they can't be bounded.
+func (p *PoolCollection) EstimatedMaxConnections(_ context.Context) ConnectionEstimate {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	var estimate ConnectionEstimate
+	maxLimit := 0
+
+	for hash, pool := range p.pools {
+		pool.RLock()
+		maxPoolSize, connectionLimit := pool.PoolConfig.MaxPoolSize, pool.PoolConfig.ConnectionLimit
+		pool.RUnlock()
+
+		if connectionLimit <= 0 {
+			estimate.UnlimitedHashes = append(estimate.UnlimitedHashes, hash)
+			continue
+		}
+
+		estimate.Connections += maxPoolSize * connectionLimit
+		if connectionLimit > maxLimit {
+			maxLimit = connectionLimit
+		}
+	}
+
+	if capped := p.MaxTotalDBs * maxLimit; p.MaxTotalDBs > 0 && capped < estimate.Connections {
+		estimate.Connections = capped
+	}
+
+	sort.Strings(estimate.UnlimitedHashes)
+
+	return estimate
+}
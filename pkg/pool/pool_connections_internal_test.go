@@ -0,0 +1,51 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolEstimatedMaxConnections(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	cfg := PoolConfig{
+		MaxPoolSize:            10,
+		MaxParallelTasks:       1,
+		disableWorkerAutostart: true,
+	}
+	p := NewPoolCollection(cfg)
+	t.Cleanup(func() { p.Stop() })
+
+	assert.Equal(t, ConnectionEstimate{}, p.EstimatedMaxConnections(ctx))
+
+	limited := cfg
+	limited.ConnectionLimit = 5
+	p.InitHashPoolWithConfig(ctx, limited, db.Database{TemplateHash: "h1"}, noopRecreateDB)
+
+	limited.MaxPoolSize = 4
+	limited.ConnectionLimit = 20
+	p.InitHashPoolWithConfig(ctx, limited, db.Database{TemplateHash: "h2"}, noopRecreateDB)
+
+	p.InitHashPoolWithConfig(ctx, cfg, db.Database{TemplateHash: "h3"}, noopRecreateDB)
+
+	// 10 * 5 + 4 * 20, the pool without a limit is unbounded
+	assert.Equal(t, ConnectionEstimate{Connections: 130, UnlimitedHashes: []string{"h3"}}, p.EstimatedMaxConnections(ctx))
+
+	// lowering the maximal pool size at runtime lowers the estimate
+	_, err := p.SetMaxPoolSizeWithHash(ctx, "h1", 2)
+	require.NoError(t, err)
+	assert.Equal(t, 90, p.EstimatedMaxConnections(ctx).Connections)
+
+	// capped by the maximal number of test DBs across all pools at the highest connection limit
+	capped := NewPoolCollection(PoolConfig{MaxTotalDBs: 3, MaxParallelTasks: 1, disableWorkerAutostart: true})
+	t.Cleanup(func() { capped.Stop() })
+
+	capped.InitHashPoolWithConfig(ctx, limited, db.Database{TemplateHash: "h1"}, noopRecreateDB)
+	capped.InitHashPoolWithConfig(ctx, limited, db.Database{TemplateHash: "h2"}, noopRecreateDB)
+	assert.Equal(t, 60, capped.EstimatedMaxConnections(ctx).Connections)
+}
@@ -0,0 +1,53 @@
+package pool
+
+import (
+	"context"
+	"time"
+)
+
+// unsafeStartCooldown tracks the return of the test DB for recreation by its holder. Connections of the holder may still be
+// closing, thus the test DB is neither cleaned (auto-cleans are blocked as well) nor handed out as is until the cooldown passed.
+// The pool must be locked by the caller.
+func (testDB *existingDB) unsafeStartCooldown(cooldown time.Duration) {
+	if cooldown <= 0 {
+		return
+	}
+
+	testDB.returnedAt = time.Now()
+
+	if until := testDB.cooldownUntil(cooldown); testDB.blockAutoCleanDirtyUntil.Before(until) {
+		testDB.blockAutoCleanDirtyUntil = until
+	}
+}
+
+// cooldownUntil returns the time the test DB leaves its cooldown after the last return, zero if it was never returned.
+func (testDB existingDB) cooldownUntil(cooldown time.Duration) time.Time {
+	if cooldown <= 0 || testDB.returnedAt.IsZero() {
+		return time.Time{}
+	}
+
+	return testDB.returnedAt.Add(cooldown)
+}
+
+// waitCooldown blocks until the given test DB left its cooldown or the ctx is done. The cooldown is re-read after waiting,
+// as the test DB may be returned again meanwhile (restarting its cooldown), thus it is reserved by the caller (e.g. recreating state).
+func (pool *HashPool) waitCooldown(ctx context.Context, id int) error {
+	for {
+		pool.RLock()
+		wait := time.Until(pool.dbs[id].cooldownUntil(pool.ReturnCooldown))
+		pool.RUnlock()
+
+		if wait <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
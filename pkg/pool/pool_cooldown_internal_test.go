@@ -0,0 +1,111 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolReturnCooldown(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+
+	var recreatedAt []time.Time
+	var recreateMutex sync.Mutex
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		recreateMutex.Lock()
+		defer recreateMutex.Unlock()
+		recreatedAt = append(recreatedAt, time.Now())
+		return nil
+	}
+
+	cooldown := 200 * time.Millisecond
+	cfg := PoolConfig{
+		MaxPoolSize:      1,
+		MaxParallelTasks: 1,
+		RecreateInline:   true, // not inline while cooling down
+		ReturnCooldown:   cooldown,
+	}
+	p := newTestPoolCollection(t, cfg, db.Database{TemplateHash: hash1}, 1, initFunc)
+
+	testDB, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+
+	returnedAt := time.Now()
+	require.NoError(t, p.RecreateTestDatabase(ctx, hash1, testDB.ID))
+
+	// neither handed out as is, nor recreated while cooling down
+	_, _, err = p.GetTestDatabaseByID(ctx, hash1, testDB.ID)
+	assert.ErrorIs(t, err, ErrTestDBInUse)
+
+	recreateMutex.Lock()
+	assert.Len(t, recreatedAt, 1)
+	recreateMutex.Unlock()
+
+	testDB, err = p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+
+	recreateMutex.Lock()
+	require.Len(t, recreatedAt, 2)
+	assert.GreaterOrEqual(t, recreatedAt[1].Sub(returnedAt), cooldown)
+	recreateMutex.Unlock()
+
+	// an unlocked test DB is ready again right away
+	require.NoError(t, p.ReturnTestDatabase(ctx, hash1, testDB.ID))
+	_, dirty, err := p.GetTestDatabaseByID(ctx, hash1, testDB.ID)
+	require.NoError(t, err)
+	assert.False(t, dirty)
+}
+
+func TestPoolReturnCooldownConcurrentReturn(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+
+	var recreatedAt []time.Time
+	var recreateMutex sync.Mutex
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		recreateMutex.Lock()
+		defer recreateMutex.Unlock()
+		recreatedAt = append(recreatedAt, time.Now())
+		return nil
+	}
+
+	cooldown := 200 * time.Millisecond
+	cfg := PoolConfig{
+		MaxPoolSize:      1,
+		MaxParallelTasks: 1,
+		ReturnCooldown:   cooldown,
+	}
+	p := newTestPoolCollection(t, cfg, db.Database{TemplateHash: hash1}, 1, initFunc)
+
+	testDB, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+
+	require.NoError(t, p.RecreateTestDatabase(ctx, hash1, testDB.ID))
+
+	// returned again halfway through the cooldown (e.g. by a retrying client), restarting it
+	time.Sleep(cooldown / 2)
+	returnedAt := time.Now()
+	require.NoError(t, p.RecreateTestDatabase(ctx, hash1, testDB.ID))
+
+	_, _, err = p.GetTestDatabaseByID(ctx, hash1, testDB.ID)
+	assert.ErrorIs(t, err, ErrTestDBInUse)
+
+	_, err = p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+
+	// recreated once after the restarted cooldown
+	recreateMutex.Lock()
+	require.Len(t, recreatedAt, 2)
+	assert.GreaterOrEqual(t, recreatedAt[1].Sub(returnedAt), cooldown)
+	recreateMutex.Unlock()
+}
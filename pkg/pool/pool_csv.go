@@ -0,0 +1,61 @@
+package pool
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// snapshotCSVHeader names the columns of the records written by SnapshotCSVWriter.
+var snapshotCSVHeader = []string{"hash", "ready", "dirty", "inUse", "total", "maxSize", "createdAt", "lastUsed"}
+
+// SnapshotCSVWriter renders pool snapshots as CSV (RFC 4180, quoting as needed and CRLF line breaks), one record per pool.
+// Each record is flushed right away, thus large outputs are streamed instead of buffered.
+type SnapshotCSVWriter struct {
+	w *csv.Writer
+}
+
+// NewSnapshotCSVWriter returns a writer rendering to w, the header is written by WriteHeader.
+func NewSnapshotCSVWriter(w io.Writer) *SnapshotCSVWriter {
+	cw := csv.NewWriter(w)
+	cw.UseCRLF = true
+
+	return &SnapshotCSVWriter{w: cw}
+}
+
+// WriteHeader writes the header record naming the columns.
+func (sw *SnapshotCSVWriter) WriteHeader() error {
+	return sw.write(snapshotCSVHeader)
+}
+
+// Write writes the record of the given pool snapshot, timestamps are formatted as RFC 3339 (UTC).
+func (sw *SnapshotCSVWriter) Write(s PoolSnapshot) error {
+	return sw.write([]string{
+		s.TemplateHash,
+		strconv.Itoa(s.Ready),
+		strconv.Itoa(s.Dirty),
+		strconv.Itoa(s.InUse),
+		strconv.Itoa(len(s.TestDatabases)),
+		strconv.Itoa(s.MaxPoolSize),
+		formatCSVTime(s.CreatedAt),
+		formatCSVTime(s.LastUsed),
+	})
+}
+
+func (sw *SnapshotCSVWriter) write(record []string) error {
+	if err := sw.w.Write(record); err != nil {
+		return err
+	}
+
+	sw.w.Flush()
+	return sw.w.Error()
+}
+
+func formatCSVTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
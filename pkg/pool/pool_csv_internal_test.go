@@ -0,0 +1,49 @@
+package pool
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolSnapshotCSVWriter(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	cfg := PoolConfig{
+		MaxPoolSize:            3,
+		MaxParallelTasks:       1,
+		disableWorkerAutostart: true, // no extend / cleanDirty tasks should run automatically!
+	}
+	p := newTestPoolCollection(t, cfg, db.Database{TemplateHash: "h1"}, 2, noopRecreateDB)
+	p.InitHashPool(ctx, db.Database{TemplateHash: "h,\"2\""}, noopRecreateDB)
+
+	_, err := p.GetTestDatabase(ctx, "h1", time.Second)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	w := NewSnapshotCSVWriter(&buf)
+	require.NoError(t, w.WriteHeader())
+	require.NoError(t, p.ForEachPool(ctx, func(hash string, snapshot PoolSnapshot) error {
+		return w.Write(snapshot)
+	}))
+
+	lines := strings.Split(buf.String(), "\r\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, "hash,ready,dirty,inUse,total,maxSize,createdAt,lastUsed", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "\"h,\"\"2\"\"\",0,0,0,0,3,"), lines[1])
+	assert.True(t, strings.HasPrefix(lines[2], "h1,1,1,1,2,3,"), lines[2])
+	assert.Empty(t, lines[3])
+
+	fields := strings.Split(lines[2], ",")
+	_, err = time.Parse(time.RFC3339, fields[6])
+	assert.NoError(t, err)
+	_, err = time.Parse(time.RFC3339, fields[7])
+	assert.NoError(t, err)
+}
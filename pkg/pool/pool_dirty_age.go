@@ -0,0 +1,88 @@
+package pool
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// RecreateStaleDirty force-recreates the dirty test DBs that turned dirty longer than DirtyMaxAge ago, as the cleaning workers did
+// not catch up with them (e.g. backed up or stuck), thus they are never reused in an unknown state. Like auto-cleaning, test DBs still
+// blocked from it (TestDatabaseMinimalLifetime, ReturnCooldown or an active reservation) or still held by a client are skipped. Unlike auto-cleaning, the stale
+// test DBs bypass the queue of the workers: Each is recreated by a dedicated goroutine. Returns the IDs of the force-recreated test DBs.
+func (pool *HashPool) RecreateStaleDirty(ctx context.Context) []int {
+	if pool.DirtyMaxAge <= 0 {
+		return nil
+	}
+
+	log := pool.getPoolLogger(ctx, "RecreateStaleDirty")
+
+	pool.Lock()
+
+	// not yet started (or stopped), cleaned like any other dirty test DB once the workers are running
+	workerContext := pool.workerContext
+	if !pool.running || workerContext == nil {
+		pool.Unlock()
+		return nil
+	}
+
+	now := time.Now()
+
+	var stale []existingDB
+	for id := range pool.dbs {
+		testDB := &pool.dbs[id]
+		if testDB.state != dbStateDirty || testDB.dirtyAt.IsZero() || now.Sub(testDB.dirtyAt) <= pool.DirtyMaxAge || testDB.blockAutoCleanDirtyUntil.After(now) {
+			continue
+		}
+
+		// dirtyAt is set on acquisition, still held test DBs are left to the leak sweeper
+		if !testDB.acquiredAt.IsZero() || (testDB.ReservedUntil != nil && testDB.ReservedUntil.After(now)) {
+			continue
+		}
+
+		log.Warn().Int("id", id).Dur("dirty", now.Sub(testDB.dirtyAt)).Interface("labels", testDB.Labels).Msg("force-recreating stale dirty test database")
+
+		// reserved before unlocking, thus neither selected again by the next sweep nor cleaned by the workers meanwhile
+		testDB.state = dbStateRecreating
+		pool.excludeIDFromChannel(pool.dirty, id)
+		stale = append(stale, *testDB)
+	}
+
+	pool.staleDirtyTotal += uint64(len(stale))
+	pool.Unlock()
+
+	ids := make([]int, 0, len(stale))
+	for _, testDB := range stale {
+		ids = append(ids, testDB.ID)
+
+		// unchained like RecreateTestDatabase, failed recreations leave the test DB dirty for the next sweep
+		//nolint:errcheck
+		go pool.recreateReservedGracefully(workerContext, log.With().Int("id", testDB.ID).Logger(), testDB)
+	}
+
+	return ids
+}
+
+// RecreateStaleDirty force-recreates the stale dirty test DBs of all pools (see HashPool.RecreateStaleDirty).
+// Returns the IDs of the force-recreated test DBs by template hash, pools without any are omitted.
+func (p *PoolCollection) RecreateStaleDirty(ctx context.Context) map[string][]int {
+	p.mutex.RLock()
+	hashes := make([]string, 0, len(p.pools))
+	pools := make(map[string]*HashPool, len(p.pools))
+	for hash, pool := range p.pools {
+		hashes = append(hashes, hash)
+		pools[hash] = pool
+	}
+	p.mutex.RUnlock()
+
+	sort.Strings(hashes)
+
+	recreated := make(map[string][]int)
+	for _, hash := range hashes {
+		if ids := pools[hash].RecreateStaleDirty(ctx); len(ids) > 0 {
+			recreated[hash] = ids
+		}
+	}
+
+	return recreated
+}
@@ -0,0 +1,84 @@
+package pool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolRecreateStaleDirty(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	var recreated atomic.Int32
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		recreated.Add(1)
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:            3,
+		MaxParallelTasks:       1,
+		DirtyMaxAge:            time.Minute,
+		disableWorkerAutostart: true, // no cleanDirty tasks should run automatically!
+	}
+	p := newTestPoolCollection(t, cfg, db.Database{TemplateHash: "h1"}, 3, initFunc)
+
+	stale, err := p.GetTestDatabase(ctx, "h1", time.Second)
+	require.NoError(t, err)
+	fresh, err := p.GetTestDatabase(ctx, "h1", time.Second)
+	require.NoError(t, err)
+
+	pool, err := p.getPool(ctx, "h1")
+	require.NoError(t, err)
+
+	// workers are not started, nothing to recreate with yet
+	pool.Lock()
+	pool.dbs[stale.ID].dirtyAt = time.Now().Add(-2 * time.Minute)
+	pool.Unlock()
+	assert.Empty(t, p.RecreateStaleDirty(ctx))
+
+	workerCtx, cancel := context.WithCancel(ctx)
+	t.Cleanup(cancel)
+	pool.Lock()
+	pool.running = true
+	pool.workerContext = workerCtx
+	pool.Unlock()
+
+	// still held by its client (dirtyAt is set on acquisition), left to the leak sweeper
+	assert.Empty(t, p.RecreateStaleDirty(ctx))
+
+	// released, but not recreated (e.g. a failed recreation)
+	pool.Lock()
+	pool.dbs[stale.ID].acquiredAt = time.Time{}
+	pool.Unlock()
+
+	// only the test DB dirty for longer than DirtyMaxAge is force-recreated, reserved right away thus not selected again
+	assert.Equal(t, map[string][]int{"h1": {stale.ID}}, p.RecreateStaleDirty(ctx))
+	assert.Empty(t, p.RecreateStaleDirty(ctx))
+
+	requireSnapshotEventually(t, p, "h1", func(snapshot PoolSnapshot) bool { return snapshot.Ready == 2 })
+	assert.Equal(t, int32(4), recreated.Load())
+
+	snapshot, err := p.Snapshot(ctx, "h1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, snapshot.Dirty)
+	assert.Equal(t, uint64(1), snapshot.StaleDirtyTotal)
+	assert.Equal(t, dbStateDirty, pool.dbs[fresh.ID].state)
+
+	// disabled by default
+	pool.Lock()
+	pool.DirtyMaxAge = 0
+	pool.dbs[fresh.ID].dirtyAt = time.Now().Add(-time.Hour)
+	pool.Unlock()
+	assert.Empty(t, p.RecreateStaleDirty(ctx))
+
+	pool.Lock()
+	pool.running = false
+	pool.Unlock()
+}
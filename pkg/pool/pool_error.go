@@ -0,0 +1,49 @@
+package pool
+
+import "fmt"
+
+// PoolError wraps errors returned by PoolCollection operations on a test DB with the context they occurred in.
+// Use errors.Is to check for the sentinel errors (e.g. ErrPoolFull) and errors.As to inspect the context.
+type PoolError struct { //nolint:revive
+	Op   string // operation that failed, e.g. "GetTestDatabase"
+	Hash string // template hash of the pool
+	ID   int    // ID of the test DB, -1 if the operation does not target a specific one
+	Err  error
+}
+
+func (e *PoolError) Error() string {
+	if e.ID < 0 {
+		return fmt.Sprintf("%s %s: %v", e.Op, e.Hash, e.Err)
+	}
+
+	return fmt.Sprintf("%s %s id %d: %v", e.Op, e.Hash, e.ID, e.Err)
+}
+
+func (e *PoolError) Unwrap() error {
+	return e.Err
+}
+
+// wrapPoolError wraps the given error (if any) into a PoolError.
+func wrapPoolError(op string, hash string, id int, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &PoolError{Op: op, Hash: hash, ID: id, Err: err}
+}
+
+// CallbackPanicError is returned if a user-supplied callback (e.g. the RecreateDBFunc or RemoveDBFunc) panicked,
+// the panic is recovered thus it neither crashes the server nor leaves any locks held. Matches ErrCallbackPanic via errors.Is.
+type CallbackPanicError struct {
+	Callback string // name of the callback that panicked, e.g. "RecreateDBFunc"
+	Value    any    // value passed to panic
+	Stack    []byte // stack trace of the panicking goroutine
+}
+
+func (e *CallbackPanicError) Error() string {
+	return fmt.Sprintf("%v: %s: %v", ErrCallbackPanic, e.Callback, e.Value)
+}
+
+func (e *CallbackPanicError) Is(target error) bool {
+	return target == ErrCallbackPanic
+}
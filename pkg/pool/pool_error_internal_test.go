@@ -0,0 +1,53 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolError(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cfg := PoolConfig{
+		MaxPoolSize:            1,
+		MaxParallelTasks:       1,
+		disableWorkerAutostart: true,
+	}
+	p := NewPoolCollection(cfg)
+	t.Cleanup(func() { p.Stop() })
+
+	hash1 := "h1"
+	templateDB := db.Database{
+		TemplateHash: hash1,
+	}
+
+	_, err := p.GetTestDatabase(ctx, hash1, 0)
+	assert.ErrorIs(t, err, ErrUnknownHash)
+
+	var poolErr *PoolError
+	require.ErrorAs(t, err, &poolErr)
+	assert.Equal(t, "GetTestDatabase", poolErr.Op)
+	assert.Equal(t, hash1, poolErr.Hash)
+	assert.Equal(t, -1, poolErr.ID)
+	assert.EqualError(t, err, "GetTestDatabase h1: no database pool exists for this hash")
+
+	p.InitHashPool(ctx, templateDB, noopRecreateDB)
+	require.NoError(t, p.extend(ctx, templateDB))
+
+	err = p.extend(ctx, templateDB)
+	assert.ErrorIs(t, err, ErrPoolFull)
+	require.ErrorAs(t, err, &poolErr)
+	assert.Equal(t, "extend", poolErr.Op)
+
+	err = p.ReturnTestDatabase(ctx, hash1, 3)
+	assert.ErrorIs(t, err, ErrInvalidIndex)
+	require.ErrorAs(t, err, &poolErr)
+	assert.Equal(t, "ReturnTestDatabase", poolErr.Op)
+	assert.Equal(t, 3, poolErr.ID)
+	assert.EqualError(t, err, "ReturnTestDatabase h1 id 3: invalid database index (id)")
+}
@@ -0,0 +1,40 @@
+package pool
+
+import (
+	"context"
+	"time"
+)
+
+// defaultOnPoolFullInterval is the minimal interval between OnPoolFull calls per pool if none is configured.
+const defaultOnPoolFullInterval = time.Minute
+
+// notifyPoolFull invokes the OnPoolFull callback after extending the pool failed with the given ErrPoolFull or ErrMaxTotalDBs,
+// at most once per OnPoolFullInterval. The pool must not be locked by the caller.
+func (pool *HashPool) notifyPoolFull(ctx context.Context, err error) {
+	if pool.PoolConfig.OnPoolFull == nil {
+		return
+	}
+
+	interval := pool.PoolConfig.OnPoolFullInterval
+	if interval <= 0 {
+		interval = defaultOnPoolFullInterval
+	}
+
+	now := time.Now()
+
+	pool.Lock()
+	due := pool.poolFullNotifiedAt.IsZero() || now.Sub(pool.poolFullNotifiedAt) >= interval
+	if due {
+		pool.poolFullNotifiedAt = now
+	}
+	pool.Unlock()
+
+	if !due {
+		return
+	}
+
+	if cbErr := pool.PoolConfig.callOnPoolFull(pool.templateDB.TemplateHash, pool.Snapshot(), err); cbErr != nil {
+		log := pool.getPoolLogger(ctx, "notifyPoolFull")
+		log.Error().Err(cbErr).Msg("on pool full callback failed")
+	}
+}
@@ -0,0 +1,60 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolOnPoolFull(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	type poolFullEvent struct {
+		hash     string
+		snapshot PoolSnapshot
+		err      error
+	}
+	var events []poolFullEvent
+
+	cfg := PoolConfig{
+		MaxPoolSize:            2,
+		MaxParallelTasks:       1,
+		MaxTotalDBs:            2,
+		disableWorkerAutostart: true,
+		OnPoolFull: func(hash string, snapshot PoolSnapshot, err error) {
+			events = append(events, poolFullEvent{hash: hash, snapshot: snapshot, err: err})
+		},
+		OnPoolFullInterval: time.Hour,
+	}
+	templateDB1 := db.Database{TemplateHash: "h1"}
+	p := newTestPoolCollection(t, cfg, templateDB1, 0, noopRecreateDB)
+	_, err := p.SetMaxPoolSizeWithHash(ctx, "h1", 1)
+	require.NoError(t, err)
+	require.NoError(t, p.extend(ctx, templateDB1))
+
+	// the callback is invoked with the state of the full pool...
+	assert.ErrorIs(t, p.extend(ctx, templateDB1), ErrPoolFull)
+	require.Len(t, events, 1)
+	assert.Equal(t, "h1", events[0].hash)
+	assert.Equal(t, ErrPoolFull, events[0].err)
+	assert.Len(t, events[0].snapshot.TestDatabases, 1)
+
+	// ... but rate-limited per pool
+	assert.ErrorIs(t, p.extend(ctx, templateDB1), ErrPoolFull)
+	assert.Len(t, events, 1)
+
+	// reaching the cap across all pools is reported as well
+	require.NoError(t, p.SetMaxPoolSize(ctx, 2))
+	templateDB2 := db.Database{TemplateHash: "h2"}
+	p.InitHashPool(ctx, templateDB2, noopRecreateDB)
+	require.NoError(t, p.extend(ctx, templateDB2))
+	assert.ErrorIs(t, p.extend(ctx, templateDB2), ErrMaxTotalDBs)
+	require.Len(t, events, 2)
+	assert.Equal(t, "h2", events[1].hash)
+	assert.ErrorIs(t, events[1].err, ErrMaxTotalDBs)
+}
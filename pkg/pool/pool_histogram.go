@@ -0,0 +1,119 @@
+package pool
+
+import (
+	"sync"
+	"time"
+)
+
+// copyDurationBuckets are the upper bounds of the buckets of the template copy duration histogram.
+var copyDurationBuckets = []time.Duration{
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// DurationHistogram describes the distribution of observed durations (in milliseconds).
+// Buckets are cumulative (like Prometheus histograms): Each holds the number of observations less or equal its upper bound,
+// observations above the last bound are only part of Count.
+type DurationHistogram struct {
+	Count   uint64           `json:"count"`
+	SumMs   float64          `json:"sumMs"`
+	MinMs   float64          `json:"minMs"`
+	MaxMs   float64          `json:"maxMs"`
+	Buckets []DurationBucket `json:"buckets"`
+}
+
+type DurationBucket struct {
+	LeMs  float64 `json:"leMs"`
+	Count uint64  `json:"count"`
+}
+
+// durationHistogram records durations into fixed buckets. It is not safe for concurrent use, the HashPool lock guards it.
+type durationHistogram struct {
+	bounds []time.Duration
+	counts []uint64 // non-cumulative, per bucket
+	count  uint64
+	sum    time.Duration
+	min    time.Duration
+	max    time.Duration
+}
+
+func newDurationHistogram(bounds []time.Duration) durationHistogram {
+	return durationHistogram{
+		bounds: bounds,
+		counts: make([]uint64, len(bounds)),
+	}
+}
+
+func (h *durationHistogram) observe(d time.Duration) {
+	for i, bound := range h.bounds {
+		if d <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+
+	h.count++
+	h.sum += d
+}
+
+func (h *durationHistogram) snapshot() DurationHistogram {
+	s := DurationHistogram{
+		Count:   h.count,
+		SumMs:   toMs(h.sum),
+		MinMs:   toMs(h.min),
+		MaxMs:   toMs(h.max),
+		Buckets: make([]DurationBucket, len(h.bounds)),
+	}
+
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i]
+		s.Buckets[i] = DurationBucket{LeMs: toMs(bound), Count: cumulative}
+	}
+
+	return s
+}
+
+// DurationRecorder records durations into fixed buckets like the copy durations of the pools, safe for concurrent use.
+type DurationRecorder struct {
+	histogram durationHistogram
+	mutex     sync.Mutex
+}
+
+// NewDurationRecorder returns a recorder with the given upper bounds of its buckets (ascending).
+func NewDurationRecorder(bounds []time.Duration) *DurationRecorder {
+	return &DurationRecorder{histogram: newDurationHistogram(bounds)}
+}
+
+func (r *DurationRecorder) Observe(d time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.histogram.observe(d)
+}
+
+func (r *DurationRecorder) Snapshot() DurationHistogram {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.histogram.snapshot()
+}
+
+func toMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
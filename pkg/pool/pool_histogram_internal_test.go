@@ -0,0 +1,57 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolCopyDurations(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+	}
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		time.Sleep(30 * time.Millisecond) // simulated duration of the copy
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:            2,
+		MaxParallelTasks:       1,
+		disableWorkerAutostart: true,
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 0, initFunc)
+
+	snapshot, err := p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), snapshot.CopyDurations.Count)
+	require.Len(t, snapshot.CopyDurations.Buckets, len(copyDurationBuckets))
+
+	require.NoError(t, p.extend(ctx, templateDB1))
+	require.NoError(t, p.extend(ctx, templateDB1))
+
+	snapshot, err = p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+
+	durations := snapshot.CopyDurations
+	assert.Equal(t, uint64(2), durations.Count)
+	assert.GreaterOrEqual(t, durations.MinMs, 30.0)
+	assert.GreaterOrEqual(t, durations.MaxMs, durations.MinMs)
+	assert.GreaterOrEqual(t, durations.SumMs, 60.0)
+
+	// buckets are cumulative, the ones below the copy duration are empty
+	assert.Equal(t, uint64(0), durations.Buckets[0].Count) // <= 10ms
+	assert.Equal(t, uint64(0), durations.Buckets[1].Count) // <= 25ms
+	for i := 1; i < len(durations.Buckets); i++ {
+		assert.GreaterOrEqual(t, durations.Buckets[i].Count, durations.Buckets[i-1].Count)
+	}
+	assert.Equal(t, uint64(2), durations.Buckets[len(durations.Buckets)-1].Count)
+}
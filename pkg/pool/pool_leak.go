@@ -0,0 +1,170 @@
+package pool
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReclaimLeaked checks how long the test DBs of the pool have been held by their clients (see InUse):
+// Test DBs held longer than the LeakWarnTimeout are logged as suspected leaks (once per acquisition), legitimately long tests keep them.
+// Test DBs held longer than the LeakReclaimTimeout are force-returned: The lease of their holder is invalidated
+// (returning it afterwards fails with ErrInvalidLease) and they are scheduled to be recreated. Returns the IDs of the reclaimed test DBs.
+func (pool *HashPool) ReclaimLeaked(ctx context.Context) []int {
+	log := pool.getPoolLogger(ctx, "ReclaimLeaked")
+
+	if pool.LeakWarnTimeout <= 0 && pool.LeakReclaimTimeout <= 0 {
+		return nil
+	}
+
+	pool.Lock()
+	defer pool.Unlock()
+
+	now := time.Now()
+
+	var reclaimed []int
+	for id := range pool.dbs {
+		testDB := &pool.dbs[id]
+		if testDB.state != dbStateDirty || testDB.acquiredAt.IsZero() {
+			continue
+		}
+
+		held := now.Sub(testDB.acquiredAt)
+
+		if pool.LeakReclaimTimeout > 0 && held > pool.LeakReclaimTimeout {
+			log.Warn().Int("id", id).Dur("held", held).Interface("labels", testDB.Labels).Msg("reclaiming leaked test database")
+
+			// a new lease invalidates the one of the holder, the test DB stays dirty and is cleaned like any returned one
+			testDB.Lease = uuid.NewString()
+			testDB.Labels = nil
+			testDB.acquiredAt = time.Time{}
+			testDB.blockAutoCleanDirtyUntil = time.Time{}
+			testDB.leakWarned = false
+			testDB.unsafeReserve(0)
+
+			reclaimed = append(reclaimed, id)
+			continue
+		}
+
+		if pool.LeakWarnTimeout > 0 && held > pool.LeakWarnTimeout && !testDB.leakWarned {
+			log.Warn().Int("id", id).Dur("held", held).Interface("labels", testDB.Labels).Msg("suspected leaked test database")
+			testDB.leakWarned = true
+		}
+	}
+
+	if len(reclaimed) == 0 {
+		return nil
+	}
+
+	pool.lastUsed = now
+
+	if pool.running {
+		for range reclaimed {
+			select {
+			case pool.tasksChan <- newQueuedTask(ctx, workerTaskAutoCleanDirty):
+			default:
+				// the queue is full, the test DBs are cleaned by the next auto-clean task
+			}
+		}
+	}
+
+	return reclaimed
+}
+
+// ForceRemove is the manual counterpart of ReclaimLeaked for a single test DB held by a client (e.g. flagged as suspected leak):
+// The lease of its holder is invalidated (returning it afterwards fails with ErrInvalidLease), the test DB is removed via the removeFunc
+// (typically terminating all its connections and dropping it) and finally recreated from the template in the background.
+// Returns ErrUnknownID if the test DB is not held by a client. The test DB is recreated even if removing it failed.
+func (pool *HashPool) ForceRemove(ctx context.Context, id int, removeFunc RemoveDBFunc) error {
+	log := pool.getPoolLogger(ctx, "ForceRemove").With().Int("id", id).Logger()
+
+	pool.Lock()
+
+	if id < 0 || id >= len(pool.dbs) {
+		log.Warn().Int("dbs", len(pool.dbs)).Msg("bailout invalid index!")
+		pool.Unlock()
+		return ErrInvalidIndex
+	}
+
+	testDB := &pool.dbs[id]
+	if testDB.state != dbStateDirty || testDB.acquiredAt.IsZero() {
+		pool.Unlock()
+		return ErrUnknownID
+	}
+
+	log.Warn().Dur("held", time.Since(testDB.acquiredAt)).Interface("labels", testDB.Labels).Msg("force-removing test database")
+
+	testDB.Lease = uuid.NewString()
+	testDB.Labels = nil
+	testDB.acquiredAt = time.Time{}
+	testDB.blockAutoCleanDirtyUntil = time.Time{}
+	testDB.leakWarned = false
+	testDB.unsafeReserve(0)
+
+	// reserved while removing, thus no worker cleans it meanwhile
+	testDB.state = dbStateRecreating
+	removed := testDB.TestDatabase
+
+	pool.lastUsed = time.Now()
+	workerContext := pool.workerContext
+	pool.Unlock()
+
+	err := callRemoveDB(ctx, removeFunc, removed)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to remove test database, recreating anyway")
+	}
+
+	pool.Lock()
+	pool.dbs[id].state = dbStateDirty
+	pool.Unlock()
+
+	if workerContext == nil {
+		// not yet started, cleaned like any returned test DB once the workers are running
+		return err
+	}
+
+	// exclude from the normal dirty channel, force recreation in a background worker...
+	pool.excludeIDFromChannel(pool.dirty, id)
+
+	// directly spawn a new worker in the bg (with the same ctx as the typical workers), like RecreateTestDatabase
+	//nolint:errcheck
+	go pool.recreateDatabaseGracefully(workerContext, id)
+
+	return err
+}
+
+// ForceRemove force-removes the given test DB held by a client (see HashPool.ForceRemove).
+func (p *PoolCollection) ForceRemove(ctx context.Context, hash string, id int, removeFunc RemoveDBFunc) error {
+	pool, err := p.getPool(ctx, hash)
+	if err != nil {
+		return wrapPoolError("ForceRemove", hash, id, err)
+	}
+
+	return wrapPoolError("ForceRemove", hash, id, pool.ForceRemove(ctx, id, removeFunc))
+}
+
+// ReclaimLeaked reclaims the leaked test DBs of all pools (see HashPool.ReclaimLeaked).
+// Returns the IDs of the reclaimed test DBs by template hash, pools without any are omitted.
+func (p *PoolCollection) ReclaimLeaked(ctx context.Context) map[string][]int {
+	p.mutex.RLock()
+	hashes := make([]string, 0, len(p.pools))
+	pools := make(map[string]*HashPool, len(p.pools))
+	for hash, pool := range p.pools {
+		hashes = append(hashes, hash)
+		pools[hash] = pool
+	}
+	p.mutex.RUnlock()
+
+	sort.Strings(hashes)
+
+	reclaimed := make(map[string][]int)
+	for _, hash := range hashes {
+		if ids := pools[hash].ReclaimLeaked(ctx); len(ids) > 0 {
+			reclaimed[hash] = ids
+		}
+	}
+
+	return reclaimed
+}
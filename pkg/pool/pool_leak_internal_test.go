@@ -0,0 +1,128 @@
+package pool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolReclaimLeaked(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	cfg := PoolConfig{
+		MaxPoolSize:            2,
+		MaxParallelTasks:       1,
+		LeakWarnTimeout:        time.Minute,
+		LeakReclaimTimeout:     time.Hour,
+		disableWorkerAutostart: true, // no extend / cleanDirty tasks should run automatically!
+	}
+	p := newTestPoolCollection(t, cfg, db.Database{TemplateHash: hash1}, 2, noopRecreateDB)
+
+	leaked, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	returned, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+
+	pool, err := p.getPool(ctx, hash1)
+	require.NoError(t, err)
+	holdFor := func(id int, held time.Duration) {
+		pool.Lock()
+		pool.dbs[id].acquiredAt = time.Now().Add(-held)
+		pool.Unlock()
+	}
+
+	// suspected leaks are only logged, legitimately long tests keep their test DBs
+	holdFor(leaked.ID, 2*time.Minute)
+	holdFor(returned.ID, 2*time.Minute)
+	assert.Empty(t, p.ReclaimLeaked(ctx))
+
+	inUse, err := p.InUse(ctx, hash1)
+	require.NoError(t, err)
+	assert.Len(t, inUse, 2)
+
+	require.NoError(t, p.ReturnTestDatabaseWithLease(ctx, hash1, returned.ID, returned.Lease))
+
+	// force-returned after the second threshold
+	holdFor(leaked.ID, 2*time.Hour)
+	assert.Equal(t, map[string][]int{hash1: {leaked.ID}}, p.ReclaimLeaked(ctx))
+
+	inUse, err = p.InUse(ctx, hash1)
+	require.NoError(t, err)
+	assert.Empty(t, inUse)
+
+	snapshot, err := p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, snapshot.Dirty)
+	assert.Equal(t, 1, snapshot.Ready)
+
+	// the holder no longer owns the test DB
+	err = p.ReturnTestDatabaseWithLease(ctx, hash1, leaked.ID, leaked.Lease)
+	assert.ErrorIs(t, err, ErrInvalidLease)
+
+	// reclaimed only once
+	assert.Empty(t, p.ReclaimLeaked(ctx))
+}
+
+func TestPoolForceRemove(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	var recreated atomic.Int32
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		recreated.Add(1)
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:            2,
+		MaxParallelTasks:       1,
+		disableWorkerAutostart: true, // no extend / cleanDirty tasks should run automatically!
+	}
+	p := newTestPoolCollection(t, cfg, db.Database{TemplateHash: hash1}, 2, initFunc)
+
+	var removed []string
+	removeFunc := func(ctx context.Context, testDB db.TestDatabase) error {
+		removed = append(removed, testDB.Config.Database)
+		return nil
+	}
+
+	// only test DBs held by a client may be force-removed
+	err := p.ForceRemove(ctx, hash1, 0, removeFunc)
+	assert.ErrorIs(t, err, ErrUnknownID)
+	assert.ErrorIs(t, p.ForceRemove(ctx, hash1, 5, removeFunc), ErrInvalidIndex)
+	assert.ErrorIs(t, p.ForceRemove(ctx, "unknown", 0, removeFunc), ErrUnknownHash)
+
+	leaked, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+
+	pool, err := p.getPool(ctx, hash1)
+	require.NoError(t, err)
+	pool.Start()
+
+	require.NoError(t, p.ForceRemove(ctx, hash1, leaked.ID, removeFunc))
+	assert.Equal(t, []string{leaked.Config.Database}, removed)
+
+	// the holder no longer owns the test DB
+	err = p.ReturnTestDatabaseWithLease(ctx, hash1, leaked.ID, leaked.Lease)
+	assert.ErrorIs(t, err, ErrInvalidLease)
+
+	inUse, err := p.InUse(ctx, hash1)
+	require.NoError(t, err)
+	assert.Empty(t, inUse)
+
+	// recreated in the background
+	requireSnapshotEventually(t, p, hash1, func(snapshot PoolSnapshot) bool { return snapshot.Ready == 2 })
+	assert.Equal(t, int32(3), recreated.Load())
+
+	snapshot, err := p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, 0, snapshot.Dirty)
+}
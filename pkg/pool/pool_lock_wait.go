@@ -0,0 +1,65 @@
+package pool
+
+import (
+	"time"
+)
+
+// lockWaitBuckets are the upper bounds of the buckets of the lock wait histogram, uncontended acquisitions are observed as 0.
+var lockWaitBuckets = []time.Duration{
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	1 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+}
+
+// Lock locks the pool for writing, observing how long it waited for the lock (see LockWaitDurations of the PoolSnapshot).
+// Only contended acquisitions are timed, thus the uncontended fast path stays cheap.
+func (pool *HashPool) Lock() {
+	if pool.RWMutex.TryLock() {
+		pool.lockWaits.Observe(0)
+		return
+	}
+
+	start := time.Now()
+	pool.RWMutex.Lock()
+	pool.lockWaits.Observe(time.Since(start))
+}
+
+// RLock locks the pool for reading, observing how long it waited for the lock like Lock.
+func (pool *HashPool) RLock() {
+	if pool.RWMutex.TryRLock() {
+		pool.lockWaits.Observe(0)
+		return
+	}
+
+	start := time.Now()
+	pool.RWMutex.RLock()
+	pool.lockWaits.Observe(time.Since(start))
+}
+
+// QuantileMs estimates the q-quantile (0 < q <= 1, e.g. 0.95) of the observed durations as the upper bound of the bucket it falls into.
+// Quantiles above the last bound are estimated as MaxMs, 0 is returned if nothing was observed.
+func (h DurationHistogram) QuantileMs(q float64) float64 {
+	if h.Count == 0 {
+		return 0
+	}
+
+	rank := q * float64(h.Count)
+	for _, bucket := range h.Buckets {
+		if float64(bucket.Count) >= rank {
+			// the bound may exceed the maximum if all observations of the bucket were lower
+			if bucket.LeMs > h.MaxMs {
+				return h.MaxMs
+			}
+
+			return bucket.LeMs
+		}
+	}
+
+	return h.MaxMs
+}
@@ -0,0 +1,54 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolLockWaitDurations(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	cfg := PoolConfig{
+		MaxPoolSize:            1,
+		MaxParallelTasks:       1,
+		disableWorkerAutostart: true,
+	}
+	p := newTestPoolCollection(t, cfg, db.Database{TemplateHash: "h1"}, 0, noopRecreateDB)
+
+	pool, err := p.getPool(ctx, "h1")
+	require.NoError(t, err)
+
+	// the second acquisition waits for the first to be released
+	pool.Lock()
+	time.AfterFunc(50*time.Millisecond, pool.Unlock)
+	pool.Lock()
+	pool.Unlock()
+
+	snapshot, err := p.Snapshot(ctx, "h1")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, snapshot.LockWaitDurations.Count, uint64(2))
+	assert.GreaterOrEqual(t, snapshot.LockWaitDurations.MaxMs, float64(50))
+	assert.Equal(t, float64(0), snapshot.LockWaitDurations.MinMs)
+	assert.GreaterOrEqual(t, snapshot.LockWaitP95Ms, float64(50))
+
+	// the percentile is estimated as the upper bound of its bucket, capped by the maximum
+	h := DurationHistogram{
+		Count: 20,
+		MaxMs: 80,
+		Buckets: []DurationBucket{
+			{LeMs: 1, Count: 18},
+			{LeMs: 10, Count: 19},
+			{LeMs: 100, Count: 20},
+		},
+	}
+	assert.Equal(t, float64(10), h.QuantileMs(0.95))
+	assert.Equal(t, float64(1), h.QuantileMs(0.5))
+	assert.Equal(t, float64(80), h.QuantileMs(1))
+	assert.Equal(t, float64(0), DurationHistogram{}.QuantileMs(0.95))
+}
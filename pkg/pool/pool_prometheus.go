@@ -0,0 +1,104 @@
+package pool
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// prometheusMetric describes a single metric of the pool snapshots rendered by WritePrometheus.
+type prometheusMetric struct {
+	name  string
+	kind  string // gauge or counter
+	help  string
+	value func(s PoolSnapshot) float64
+}
+
+var prometheusMetrics = []prometheusMetric{
+	{"integresql_pool_ready", "gauge", "Test databases ready to be handed out.", func(s PoolSnapshot) float64 { return float64(s.Ready) }},
+	{"integresql_pool_dirty", "gauge", "Test databases waiting to be cleaned.", func(s PoolSnapshot) float64 { return float64(s.Dirty) }},
+	{"integresql_pool_recreating", "gauge", "Test databases currently being recreated.", func(s PoolSnapshot) float64 { return float64(s.Recreating) }},
+	{"integresql_pool_poisoned", "gauge", "Test databases returned as poisoned, waiting to be fully recreated.", func(s PoolSnapshot) float64 { return float64(s.Poisoned) }},
+	{"integresql_pool_max_size", "gauge", "Maximal number of test databases of the pool.", func(s PoolSnapshot) float64 { return float64(s.MaxPoolSize) }},
+	{"integresql_pool_ready_target", "gauge", "Number of test databases the pool tries to keep ready.", func(s PoolSnapshot) float64 { return float64(s.ReadyTarget) }},
+	{"integresql_pool_min_ready", "gauge", "Minimal number of ready test databases the pool keeps as warm standby.", func(s PoolSnapshot) float64 { return float64(s.MinReady) }},
+	{"integresql_pool_template_missing", "gauge", "1 if the template database no longer exists, thus no test databases can be created.", func(s PoolSnapshot) float64 { return prometheusBool(s.TemplateMissing) }},
+	{"integresql_pool_workers", "gauge", "Maximal number of pool tasks running in parallel.", func(s PoolSnapshot) float64 { return float64(s.Workers) }},
+	{"integresql_pool_workers_busy", "gauge", "Currently running pool tasks.", func(s PoolSnapshot) float64 { return float64(s.WorkersBusy) }},
+	{"integresql_pool_get_clean_total", "counter", "Test databases handed out in a clean state.", func(s PoolSnapshot) float64 { return float64(s.GetCleanTotal) }},
+	{"integresql_pool_get_dirty_total", "counter", "Test databases handed out as is, without being recreated.", func(s PoolSnapshot) float64 { return float64(s.GetDirtyTotal) }},
+	{"integresql_pool_too_many_connections_total", "counter", "Recreate attempts rejected as max_connections was exceeded.", func(s PoolSnapshot) float64 { return float64(s.TooManyConnectionsTotal) }},
+	{"integresql_pool_stale_dirty_total", "counter", "Dirty test databases force-recreated as they were not cleaned within the maximal dirty age.", func(s PoolSnapshot) float64 { return float64(s.StaleDirtyTotal) }},
+	{"integresql_pool_verify_clean_failed_total", "counter", "Test databases returned as clean, but verified to be dirty.", func(s PoolSnapshot) float64 { return float64(s.VerifyCleanFailedTotal) }},
+	{"integresql_pool_lock_wait_p95_seconds", "gauge", "Estimated 95th percentile of waiting for the lock of the pool.", func(s PoolSnapshot) float64 { return s.LockWaitP95Ms / 1000 }},
+	{"integresql_pool_last_used_timestamp_seconds", "gauge", "Last time a test database was requested, returned or recreated by a client.", func(s PoolSnapshot) float64 { return float64(s.LastUsed.UnixNano()) / 1e9 }},
+}
+
+// WritePrometheus renders the given pool snapshots in the Prometheus text exposition format, labeled by template_hash (and variant if any).
+// The copy and lock wait durations are rendered as histograms (in seconds).
+func WritePrometheus(w io.Writer, snapshots []PoolSnapshot) error {
+	bw := bufio.NewWriter(w)
+
+	for _, metric := range prometheusMetrics {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind)
+		for _, s := range snapshots {
+			fmt.Fprintf(bw, "%s{%s} %s\n", metric.name, prometheusPoolLabels(s), formatPrometheusValue(metric.value(s)))
+		}
+	}
+
+	writePrometheusHistogram(bw, "integresql_pool_copy_duration_seconds", "Durations of copying the template into test databases.", snapshots, func(s PoolSnapshot) DurationHistogram { return s.CopyDurations })
+	writePrometheusHistogram(bw, "integresql_pool_copy_wait_duration_seconds", "Durations of waiting for a free copy slot.", snapshots, func(s PoolSnapshot) DurationHistogram { return s.CopyWaitDurations })
+	writePrometheusHistogram(bw, "integresql_pool_lock_wait_duration_seconds", "Durations of waiting for the lock of the pool.", snapshots, func(s PoolSnapshot) DurationHistogram { return s.LockWaitDurations })
+
+	return bw.Flush()
+}
+
+func writePrometheusHistogram(w io.Writer, name string, help string, snapshots []PoolSnapshot, histogram func(s PoolSnapshot) DurationHistogram) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+
+	for _, s := range snapshots {
+		WritePrometheusHistogramSeries(w, name, prometheusPoolLabels(s), histogram(s))
+	}
+}
+
+// WritePrometheusHistogramSeries renders the bucket, sum and count series (in seconds) of a single histogram
+// with the given labels (formatted and escaped already, e.g. outcome="success"), the HELP and TYPE lines are left to the caller.
+func WritePrometheusHistogramSeries(w io.Writer, name string, labels string, h DurationHistogram) {
+	for _, bucket := range h.Buckets {
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, formatPrometheusValue(bucket.LeMs/1000), bucket.Count)
+	}
+	fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.Count)
+	fmt.Fprintf(w, "%s_sum{%s} %s\n", name, labels, formatPrometheusValue(h.SumMs/1000))
+	fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels, h.Count)
+}
+
+// prometheusPoolLabels returns the labels of the series of the given pool snapshot, variant pools are broken down by their variant.
+func prometheusPoolLabels(s PoolSnapshot) string {
+	hash, variant := SplitVariantHash(s.TemplateHash)
+	if len(variant) == 0 {
+		return fmt.Sprintf("template_hash=\"%s\"", escapePrometheusLabel(hash))
+	}
+
+	return fmt.Sprintf("template_hash=\"%s\",variant=\"%s\"", escapePrometheusLabel(hash), escapePrometheusLabel(variant))
+}
+
+func formatPrometheusValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// prometheusBool renders flags as gauges (1 if set, 0 otherwise).
+func prometheusBool(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+var prometheusLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapePrometheusLabel(v string) string {
+	return prometheusLabelEscaper.Replace(v)
+}
@@ -0,0 +1,42 @@
+package pool
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolWritePrometheus(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	cfg := PoolConfig{
+		MaxPoolSize:            2,
+		MaxParallelTasks:       1,
+		disableWorkerAutostart: true, // no extend / cleanDirty tasks should run automatically!
+	}
+	p := newTestPoolCollection(t, cfg, db.Database{TemplateHash: hash1}, 2, noopRecreateDB)
+
+	_, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WritePrometheus(&buf, p.SnapshotAll(ctx)))
+
+	out := buf.String()
+	assert.Contains(t, out, "# TYPE integresql_pool_ready gauge\n")
+	assert.Contains(t, out, "integresql_pool_ready{template_hash=\"h1\"} 1\n")
+	assert.Contains(t, out, "integresql_pool_dirty{template_hash=\"h1\"} 1\n")
+	assert.Contains(t, out, "integresql_pool_max_size{template_hash=\"h1\"} 2\n")
+	assert.Contains(t, out, "integresql_pool_get_clean_total{template_hash=\"h1\"} 1\n")
+	assert.Contains(t, out, "# TYPE integresql_pool_copy_duration_seconds histogram\n")
+	assert.Contains(t, out, "integresql_pool_copy_duration_seconds_bucket{template_hash=\"h1\",le=\"0.01\"} 2\n")
+	assert.Contains(t, out, "integresql_pool_copy_duration_seconds_bucket{template_hash=\"h1\",le=\"+Inf\"} 2\n")
+	assert.Contains(t, out, "integresql_pool_copy_duration_seconds_count{template_hash=\"h1\"} 2\n")
+}
@@ -0,0 +1,225 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync/atomic"
+)
+
+// dbBudget caps the number of test DBs across all pools of a PoolCollection (see MaxTotalDBs). A nil budget is unlimited.
+type dbBudget struct {
+	max   int64
+	total atomic.Int64
+}
+
+// newDBBudget returns the budget of the given number of test DBs, nil if unlimited (<= 0).
+func newDBBudget(maxTotalDBs int) *dbBudget {
+	if maxTotalDBs <= 0 {
+		return nil
+	}
+
+	return &dbBudget{max: int64(maxTotalDBs)}
+}
+
+// reserve accounts for another test DB, false if the cap is reached.
+func (b *dbBudget) reserve() bool {
+	if b == nil {
+		return true
+	}
+
+	for {
+		total := b.total.Load()
+		if total >= b.max {
+			return false
+		}
+
+		if b.total.CompareAndSwap(total, total+1) {
+			return true
+		}
+	}
+}
+
+// release gives back the given number of removed test DBs.
+func (b *dbBudget) release(n int) {
+	if b == nil || n <= 0 {
+		return
+	}
+
+	b.total.Add(-int64(n))
+}
+
+// free returns the number of test DBs that may still be created.
+func (b *dbBudget) free() int {
+	if free := b.max - b.total.Load(); free > 0 {
+		return int(free)
+	}
+
+	return 0
+}
+
+// reset forgets all accounted test DBs.
+func (b *dbBudget) reset() {
+	if b == nil {
+		return
+	}
+
+	b.total.Store(0)
+}
+
+// rebalanceState describes a single pool while rebalancing.
+type rebalanceState struct {
+	pool    *HashPool
+	demand  uint64 // gets requested since the previous rebalance
+	dbs     int    // current number of test DBs
+	missing int    // test DBs missing to reach the ready target (0 if not starving)
+	share   int    // test DBs the pool is entitled to
+}
+
+// Rebalance redistributes the MaxTotalDBs across all pools proportionally to their recent demand (gets requested since the previous rebalance),
+// e.g. periodically while a few hashes are in heavy use: Starving pools (fewer ready test DBs than their ready target) are entitled
+// to grow up to their share, pools holding more test DBs than their share drop idle ready ones via the removeFunc to free the capacity.
+// As IDs are positions within a pool, only the ready test DBs at the end of a pool are dropped, test DBs in use are never touched.
+// The starving pools are extended right away by their background workers. No-op without a cap (MaxTotalDBs) or without any demand.
+func (p *PoolCollection) Rebalance(ctx context.Context, removeFunc RemoveDBFunc) error {
+	if p.budget == nil {
+		return nil
+	}
+
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	states := make([]rebalanceState, 0, len(p.pools))
+	var totalDemand uint64
+	for _, pool := range p.pools {
+		state := pool.rebalanceState()
+		totalDemand += state.demand
+		states = append(states, state)
+	}
+
+	if totalDemand == 0 {
+		return nil
+	}
+
+	// the number of test DBs the starving pools need to reach their share
+	needed := 0
+	for i := range states {
+		states[i].share = int(uint64(p.budget.max) * states[i].demand / totalDemand) //nolint:gosec
+
+		if grow := states[i].share - states[i].dbs; states[i].missing > grow {
+			states[i].missing = grow
+		}
+		if states[i].missing > 0 {
+			needed += states[i].missing
+		}
+	}
+
+	if needed == 0 {
+		return nil
+	}
+
+	// free capacity from the most over-provisioned pools first
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].dbs-states[i].share > states[j].dbs-states[j].share
+	})
+
+	var errs []error
+	for i := range states {
+		toFree := needed - p.budget.free()
+		excess := states[i].dbs - states[i].share
+		if toFree <= 0 || excess <= 0 {
+			break
+		}
+
+		if excess > toFree {
+			excess = toFree
+		}
+
+		if _, err := states[i].pool.shrinkReady(ctx, excess, removeFunc); err != nil {
+			errs = append(errs, wrapPoolError("Rebalance", states[i].pool.templateDB.TemplateHash, -1, err))
+		}
+	}
+
+	for i := range states {
+		if states[i].missing > 0 {
+			states[i].pool.scheduleExtend(ctx, states[i].missing)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// rebalanceState returns the demand since the previous rebalance and the current size of the pool, starting the next demand period.
+func (pool *HashPool) rebalanceState() rebalanceState {
+	pool.Lock()
+	defer pool.Unlock()
+
+	requests := atomic.LoadUint64(&pool.getRequestsTotal)
+	state := rebalanceState{
+		pool:   pool,
+		demand: requests - pool.rebalanceRequests,
+		dbs:    len(pool.dbs),
+	}
+	pool.rebalanceRequests = requests
+
+	if missing := pool.readyTarget - len(pool.ready) - len(pool.recreating); missing > 0 {
+		state.missing = missing
+		if room := pool.MaxPoolSize - len(pool.dbs); state.missing > room {
+			state.missing = room
+		}
+	}
+
+	return state
+}
+
+// shrinkReady removes up to n test DBs from the end of the pool via the removeFunc, as long as they are ready.
+// The pool stays locked meanwhile, thus no test DB is handed out or added. Returns the number of removed test DBs.
+func (pool *HashPool) shrinkReady(ctx context.Context, n int, removeFunc RemoveDBFunc) (int, error) {
+
+	log := pool.getPoolLogger(ctx, "shrinkReady")
+
+	pool.Lock()
+	defer pool.Unlock()
+
+	removed := 0
+	for removed < n && len(pool.dbs) > 0 {
+		id := len(pool.dbs) - 1
+		if pool.dbs[id].state != dbStateReady {
+			break
+		}
+
+		if err := callRemoveDB(ctx, removeFunc, pool.dbs[id].TestDatabase); err != nil {
+			log.Error().Err(err).Int("id", id).Msg("failed to remove test database")
+			return removed, err
+		}
+
+		pool.excludeIDFromChannel(pool.ready, id)
+		pool.dbs = pool.dbs[:id]
+		pool.budget.release(1)
+		removed++
+	}
+
+	if removed > 0 {
+		log.Info().Int("removed", removed).Int("dbs", len(pool.dbs)).Msg("dropped idle ready test databases")
+	}
+
+	return removed, nil
+}
+
+// scheduleExtend pushes up to n extend tasks to the background workers (if running), never blocks if the task queue is full.
+func (pool *HashPool) scheduleExtend(ctx context.Context, n int) {
+	pool.Lock()
+	defer pool.Unlock()
+
+	if !pool.running {
+		return
+	}
+
+	for i := 0; i < n; i++ {
+		select {
+		case pool.tasksChan <- newQueuedTask(ctx, workerTaskExtend):
+		default:
+			return
+		}
+	}
+}
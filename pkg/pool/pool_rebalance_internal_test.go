@@ -0,0 +1,79 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolRebalance(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	cfg := PoolConfig{
+		InitialPoolSize:        1,
+		MaxPoolSize:            4,
+		MaxParallelTasks:       1,
+		MaxTotalDBs:            4,
+		disableWorkerAutostart: true,
+	}
+
+	// h1 takes all of the budget
+	p := newTestPoolCollection(t, cfg, db.Database{TemplateHash: "h1"}, 4, noopRecreateDB)
+
+	templateDB2 := db.Database{TemplateHash: "h2"}
+	p.InitHashPool(ctx, templateDB2, noopRecreateDB)
+	err := p.extend(ctx, templateDB2)
+	assert.ErrorIs(t, err, ErrMaxTotalDBs)
+	assert.ErrorIs(t, err, ErrPoolFull)
+
+	var removed []string
+	removeFunc := func(ctx context.Context, testDB db.TestDatabase) error {
+		removed = append(removed, testDB.Config.Database)
+		return nil
+	}
+
+	// no demand, nothing to rebalance
+	require.NoError(t, p.Rebalance(ctx, removeFunc))
+	assert.Empty(t, removed)
+
+	// h1 holds a test DB in use, h2 is starving
+	_, err = p.GetTestDatabase(ctx, "h1", time.Second)
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = p.GetTestDatabase(ctx, "h2", time.Millisecond)
+		require.ErrorIs(t, err, ErrTimeout)
+	}
+
+	pool1, err := p.getPool(ctx, "h1")
+	require.NoError(t, err)
+	pool2, err := p.getPool(ctx, "h2")
+	require.NoError(t, err)
+	for len(pool1.tasksChan) > 0 {
+		<-pool1.tasksChan
+	}
+	pool2.Lock()
+	pool2.running = true
+	pool2.Unlock()
+
+	// h2 is entitled to 3 of 4 test DBs, but only needs 1 to reach its ready target, freed from the end of h1
+	require.NoError(t, p.Rebalance(ctx, removeFunc))
+	assert.Equal(t, []string{makeDBName("", "h1", "", 3)}, removed)
+	assert.Equal(t, 1, len(pool2.tasksChan))
+
+	snapshot, err := p.Snapshot(ctx, "h1")
+	require.NoError(t, err)
+	assert.Len(t, snapshot.TestDatabases, 3)
+	assert.Equal(t, 2, snapshot.Ready)
+
+	require.NoError(t, p.extend(ctx, templateDB2))
+	assert.ErrorIs(t, p.extend(ctx, templateDB2), ErrMaxTotalDBs)
+
+	// removing a pool gives back its test DBs
+	require.NoError(t, p.RemoveAllWithHash(ctx, "h1", removeFunc))
+	require.NoError(t, p.extend(ctx, templateDB2))
+}
@@ -0,0 +1,75 @@
+package pool
+
+import (
+	"container/list"
+	"fmt"
+	"time"
+)
+
+// removedHashes remembers the most recently removed hashes and the time of their removal, so requesting one of their pools
+// fails with ErrHashRemoved instead of the plain ErrUnknownHash. The history is bounded by its capacity: Once full, the least
+// recently removed hash falls out (and is reported as unknown again), thus branch churn on a long-running server does not grow it.
+// It is protected by the mutex of the PoolCollection.
+type removedHashes struct {
+	capacity int
+	order    *list.List               // removedHash values, most recently removed first
+	entries  map[string]*list.Element // map[hash]
+}
+
+type removedHash struct {
+	hash      string
+	removedAt time.Time
+}
+
+func newRemovedHashes(capacity int) *removedHashes {
+	return &removedHashes{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// add remembers the hash as removed at the given time, evicting the least recently removed hash if the capacity is exceeded.
+func (r *removedHashes) add(hash string, removedAt time.Time) {
+	if r.capacity <= 0 {
+		return
+	}
+
+	if elem, ok := r.entries[hash]; ok {
+		elem.Value = removedHash{hash: hash, removedAt: removedAt}
+		r.order.MoveToFront(elem)
+		return
+	}
+
+	r.entries[hash] = r.order.PushFront(removedHash{hash: hash, removedAt: removedAt})
+
+	for r.order.Len() > r.capacity {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.entries, oldest.Value.(removedHash).hash)
+	}
+}
+
+// forget drops the hash from the history, e.g. as a new pool has been initialized for it.
+func (r *removedHashes) forget(hash string) {
+	if elem, ok := r.entries[hash]; ok {
+		r.order.Remove(elem)
+		delete(r.entries, hash)
+	}
+}
+
+// reset drops all hashes from the history.
+func (r *removedHashes) reset() {
+	r.order.Init()
+	r.entries = make(map[string]*list.Element)
+}
+
+// unknownHashError returns ErrHashRemoved (naming the time of the removal) if the hash is remembered, ErrUnknownHash otherwise.
+func (r *removedHashes) unknownHashError(hash string) error {
+	elem, ok := r.entries[hash]
+	if !ok {
+		return ErrUnknownHash
+	}
+
+	return fmt.Errorf("%w at %s", ErrHashRemoved, elem.Value.(removedHash).removedAt.UTC().Format(time.RFC3339))
+}
@@ -0,0 +1,64 @@
+package pool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolRemovedHashHistory(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	removeFunc := func(ctx context.Context, testDB db.TestDatabase) error {
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:            2,
+		MaxParallelTasks:       1,
+		RemovedHashHistory:     2,
+		disableWorkerAutostart: true, // no extend / cleanDirty tasks should run automatically!
+	}
+	p := newTestPoolCollection(t, cfg, db.Database{TemplateHash: "h1"}, 0, noopRecreateDB)
+
+	hashes := []string{"h1", "h2", "h3"}
+	for _, hash := range hashes[1:] {
+		p.InitHashPool(ctx, db.Database{TemplateHash: hash}, noopRecreateDB)
+	}
+
+	_, err := p.Snapshot(ctx, "never")
+	assert.ErrorIs(t, err, ErrUnknownHash)
+	assert.NotErrorIs(t, err, ErrHashRemoved)
+
+	for _, hash := range hashes {
+		require.NoError(t, p.RemoveAllWithHash(ctx, hash, removeFunc))
+	}
+
+	// the least recently removed hash fell out of the history
+	_, err = p.Snapshot(ctx, "h1")
+	assert.ErrorIs(t, err, ErrUnknownHash)
+	assert.NotErrorIs(t, err, ErrHashRemoved)
+
+	for _, hash := range []string{"h2", "h3"} {
+		_, err = p.Snapshot(ctx, hash)
+		assert.ErrorIs(t, err, ErrHashRemoved)
+		assert.ErrorIs(t, err, ErrUnknownHash, "callers checking for unknown hashes must keep working")
+	}
+
+	// reinitialized hashes are no longer reported as removed
+	p.InitHashPool(ctx, db.Database{TemplateHash: "h3"}, noopRecreateDB)
+	_, err = p.Snapshot(ctx, "h3")
+	require.NoError(t, err)
+	require.NoError(t, p.RemoveAllWithHash(ctx, "h3", removeFunc))
+
+	err = p.RemoveAllWithHash(ctx, "h3", removeFunc)
+	assert.ErrorIs(t, err, ErrHashRemoved)
+
+	p.Reset()
+	_, err = p.Snapshot(ctx, "h2")
+	assert.NotErrorIs(t, err, ErrHashRemoved)
+}
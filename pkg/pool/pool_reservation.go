@@ -0,0 +1,153 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotReserved is returned by ExtendReservation if the test DB was acquired without a ReservationTTL.
+var ErrNotReserved = errors.New("test database was acquired without a reservation TTL")
+
+// unsafeReserve starts a soft reservation of the given TTL for the current holder (0 clears it). While reserved, the test DB is not
+// auto-cleaned, regardless of the TestDatabaseMinimalLifetime. The pool must be locked by the caller.
+func (testDB *existingDB) unsafeReserve(ttl time.Duration) {
+	testDB.reservationTTL = ttl
+
+	if ttl <= 0 {
+		testDB.ReservedUntil = nil
+		return
+	}
+
+	reservedUntil := time.Now().Add(ttl)
+	testDB.ReservedUntil = &reservedUntil
+
+	if testDB.blockAutoCleanDirtyUntil.Before(reservedUntil) {
+		testDB.blockAutoCleanDirtyUntil = reservedUntil
+	}
+}
+
+// ExtendReservation heartbeats the soft reservation of the given test DB, extending it by its ReservationTTL from now on.
+// Only the current holder may extend it, thus the lease (if any) must match (ErrInvalidLease). Fails with ErrUnknownID if the
+// test DB is not held by a client (e.g. returned or already reclaimed) and with ErrNotReserved if it was acquired without a TTL.
+// Returns the new deadline of the reservation.
+func (pool *HashPool) ExtendReservation(ctx context.Context, id int, lease string) (time.Time, error) {
+	log := pool.getPoolLogger(ctx, "ExtendReservation").With().Int("id", id).Logger()
+
+	pool.Lock()
+	defer pool.Unlock()
+
+	if id < 0 || id >= len(pool.dbs) {
+		log.Warn().Int("dbs", len(pool.dbs)).Msg("bailout invalid index!")
+		return time.Time{}, ErrInvalidIndex
+	}
+
+	if err := pool.unsafeCheckLease(id, lease); err != nil {
+		log.Warn().Err(err).Msg("bailout invalid lease!")
+		return time.Time{}, err
+	}
+
+	testDB := &pool.dbs[id]
+	if testDB.state != dbStateDirty || testDB.acquiredAt.IsZero() {
+		return time.Time{}, ErrUnknownID
+	}
+
+	if testDB.reservationTTL <= 0 {
+		return time.Time{}, ErrNotReserved
+	}
+
+	testDB.unsafeReserve(testDB.reservationTTL)
+	pool.lastUsed = time.Now()
+
+	log.Trace().Time("reservedUntil", *testDB.ReservedUntil).Msg("reservation extended")
+
+	return *testDB.ReservedUntil, nil
+}
+
+// ReclaimExpired reclaims the test DBs whose soft reservation expired, as their holders neither returned nor heartbeated them in time
+// (e.g. crashed clients): Like ReclaimLeaked, the lease of the holder is invalidated (returning it afterwards fails with ErrInvalidLease)
+// and the test DBs are scheduled to be cleaned. Returns the IDs of the reclaimed test DBs.
+func (pool *HashPool) ReclaimExpired(ctx context.Context) []int {
+	log := pool.getPoolLogger(ctx, "ReclaimExpired")
+
+	pool.Lock()
+	defer pool.Unlock()
+
+	now := time.Now()
+
+	var reclaimed []int
+	for id := range pool.dbs {
+		testDB := &pool.dbs[id]
+		if testDB.state != dbStateDirty || testDB.acquiredAt.IsZero() || testDB.ReservedUntil == nil || testDB.ReservedUntil.After(now) {
+			continue
+		}
+
+		log.Warn().Int("id", id).Time("reservedUntil", *testDB.ReservedUntil).Interface("labels", testDB.Labels).Msg("reclaiming expired reservation")
+
+		// a new lease invalidates the one of the holder, the test DB stays dirty and is cleaned like any returned one
+		testDB.Lease = uuid.NewString()
+		testDB.Labels = nil
+		testDB.acquiredAt = time.Time{}
+		testDB.blockAutoCleanDirtyUntil = time.Time{}
+		testDB.leakWarned = false
+		testDB.unsafeReserve(0)
+
+		reclaimed = append(reclaimed, id)
+	}
+
+	if len(reclaimed) == 0 {
+		return nil
+	}
+
+	pool.lastUsed = now
+
+	if pool.running {
+		for range reclaimed {
+			select {
+			case pool.tasksChan <- newQueuedTask(ctx, workerTaskAutoCleanDirty):
+			default:
+				// the queue is full, the test DBs are cleaned by the next auto-clean task
+			}
+		}
+	}
+
+	return reclaimed
+}
+
+// ExtendReservation heartbeats the soft reservation of the given test DB (see HashPool.ExtendReservation).
+func (p *PoolCollection) ExtendReservation(ctx context.Context, hash string, id int, lease string) (time.Time, error) {
+	pool, err := p.getPool(ctx, hash)
+	if err != nil {
+		return time.Time{}, wrapPoolError("ExtendReservation", hash, id, err)
+	}
+
+	reservedUntil, err := pool.ExtendReservation(ctx, id, lease)
+	return reservedUntil, wrapPoolError("ExtendReservation", hash, id, err)
+}
+
+// ReclaimExpired reclaims the test DBs with expired reservations of all pools (see HashPool.ReclaimExpired).
+// Returns the IDs of the reclaimed test DBs by template hash, pools without any are omitted.
+func (p *PoolCollection) ReclaimExpired(ctx context.Context) map[string][]int {
+	p.mutex.RLock()
+	hashes := make([]string, 0, len(p.pools))
+	pools := make(map[string]*HashPool, len(p.pools))
+	for hash, pool := range p.pools {
+		hashes = append(hashes, hash)
+		pools[hash] = pool
+	}
+	p.mutex.RUnlock()
+
+	sort.Strings(hashes)
+
+	reclaimed := make(map[string][]int)
+	for _, hash := range hashes {
+		if ids := pools[hash].ReclaimExpired(ctx); len(ids) > 0 {
+			reclaimed[hash] = ids
+		}
+	}
+
+	return reclaimed
+}
@@ -0,0 +1,97 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolReservationTTL(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	cfg := PoolConfig{
+		MaxPoolSize:            3,
+		MaxParallelTasks:       1,
+		disableWorkerAutostart: true, // no extend / cleanDirty tasks should run automatically!
+	}
+	p := newTestPoolCollection(t, cfg, db.Database{TemplateHash: hash1}, 3, noopRecreateDB)
+
+	pool, err := p.getPool(ctx, hash1)
+	require.NoError(t, err)
+
+	// moves the deadline of the reservation of a test DB (if reserved at all), instead of waiting for it
+	moveDeadline := func(id int, reservedUntil time.Time) {
+		pool.Lock()
+		defer pool.Unlock()
+		if pool.dbs[id].ReservedUntil != nil {
+			pool.dbs[id].ReservedUntil = &reservedUntil
+		}
+	}
+
+	ttl := time.Hour
+
+	reserved, err := p.GetTestDatabaseWithOptions(ctx, hash1, time.Second, GetOptions{ReservationTTL: ttl})
+	require.NoError(t, err)
+	require.NotNil(t, reserved.ReservedUntil)
+
+	heartbeated, err := p.GetTestDatabaseWithOptions(ctx, hash1, time.Second, GetOptions{ReservationTTL: ttl})
+	require.NoError(t, err)
+
+	unreserved, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	assert.Nil(t, unreserved.ReservedUntil)
+
+	// only the current holder of a reserved test DB may heartbeat
+	_, err = p.ExtendReservation(ctx, hash1, unreserved.ID, unreserved.Lease)
+	assert.ErrorIs(t, err, ErrNotReserved)
+	_, err = p.ExtendReservation(ctx, hash1, heartbeated.ID, "invalid")
+	assert.ErrorIs(t, err, ErrInvalidLease)
+	_, err = p.ExtendReservation(ctx, hash1, 5, "")
+	assert.ErrorIs(t, err, ErrInvalidIndex)
+
+	// nothing expired yet
+	assert.Empty(t, p.ReclaimExpired(ctx))
+
+	// heartbeats extend the reservation by its TTL from now on
+	moveDeadline(heartbeated.ID, time.Now().Add(time.Minute))
+	reservedUntil, err := p.ExtendReservation(ctx, hash1, heartbeated.ID, heartbeated.Lease)
+	require.NoError(t, err)
+	assert.True(t, reservedUntil.After(time.Now().Add(ttl/2)))
+
+	moveDeadline(reserved.ID, time.Now().Add(-time.Second))
+	assert.Equal(t, map[string][]int{hash1: {reserved.ID}}, p.ReclaimExpired(ctx))
+
+	// the holder of the expired reservation no longer owns the test DB, which stays dirty until cleaned
+	err = p.ReturnTestDatabaseWithLease(ctx, hash1, reserved.ID, reserved.Lease)
+	assert.ErrorIs(t, err, ErrInvalidLease)
+	_, err = p.ExtendReservation(ctx, hash1, reserved.ID, reserved.Lease)
+	assert.ErrorIs(t, err, ErrInvalidLease)
+
+	inUse, err := p.InUse(ctx, hash1)
+	require.NoError(t, err)
+	require.Len(t, inUse, 2)
+	for _, info := range inUse {
+		assert.NotEqual(t, reserved.ID, info.ID)
+		if info.ID == heartbeated.ID {
+			require.NotNil(t, info.ReservedUntil)
+			assert.Equal(t, reservedUntil, *info.ReservedUntil)
+		}
+	}
+
+	// returning ends the reservation
+	require.NoError(t, p.ReturnTestDatabaseWithLease(ctx, hash1, heartbeated.ID, heartbeated.Lease))
+	_, err = p.ExtendReservation(ctx, hash1, heartbeated.ID, "")
+	assert.ErrorIs(t, err, ErrUnknownID)
+
+	// nothing is left to reclaim, even once the TTL passed
+	for _, testDB := range []db.TestDatabase{reserved, heartbeated, unreserved} {
+		moveDeadline(testDB.ID, time.Now().Add(-time.Second))
+	}
+	assert.Empty(t, p.ReclaimExpired(ctx))
+}
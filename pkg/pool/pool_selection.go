@@ -0,0 +1,98 @@
+package pool
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// SelectionPolicy defines which of the ready test DBs is handed out next by GetTestDatabase.
+type SelectionPolicy string
+
+const (
+	SelectionPolicyFIFO     SelectionPolicy = "fifo"      // in the order they got ready (default)
+	SelectionPolicyLRU      SelectionPolicy = "lru"       // the one that is ready for the longest time, wearing all test DBs evenly
+	SelectionPolicyMRU      SelectionPolicy = "mru"       // the one that got ready most recently, favoring the cache locality of a few hot test DBs
+	SelectionPolicyRandom   SelectionPolicy = "random"    // uniformly random, reproducible if a SelectionSeed is configured
+	SelectionPolicyLowestID SelectionPolicy = "lowest-id" // the one with the lowest ID, keeping the handed out names predictable
+)
+
+// Valid reports whether the policy is known, the empty policy selects the default.
+func (p SelectionPolicy) Valid() bool {
+	switch p {
+	case "", SelectionPolicyFIFO, SelectionPolicyLRU, SelectionPolicyMRU, SelectionPolicyRandom, SelectionPolicyLowestID:
+		return true
+	default:
+		return false
+	}
+}
+
+// activeSelectionPolicy resolves the configured SelectionPolicy: If none is set, a SelectionSeed implies SelectionPolicyRandom
+// (as before the policy was configurable), otherwise SelectionPolicyFIFO is used. Unknown policies fall back to SelectionPolicyFIFO.
+func (cfg PoolConfig) activeSelectionPolicy() SelectionPolicy {
+	switch {
+	case len(cfg.SelectionPolicy) == 0 && cfg.SelectionSeed != 0:
+		return SelectionPolicyRandom
+	case len(cfg.SelectionPolicy) == 0 || !cfg.SelectionPolicy.Valid():
+		return SelectionPolicyFIFO
+	default:
+		return cfg.SelectionPolicy
+	}
+}
+
+// newSelectionRNG creates the RNG of SelectionPolicyRandom, seeded by the SelectionSeed if configured.
+func (cfg PoolConfig) newSelectionRNG() *rand.Rand {
+	seed := cfg.SelectionSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	return rand.New(rand.NewSource(seed)) //nolint:gosec // reproducibility, not security
+}
+
+// unsafeSelectReady picks one of the ready IDs (the given one received from the ready channel plus all still waiting within it)
+// according to the active selection policy. The other ones are put back into the ready channel.
+// The pool must be locked by the caller.
+func (pool *HashPool) unsafeSelectReady(index int) int {
+	candidates := []int{index}
+
+	for loop := true; loop; {
+		select {
+		case id := <-pool.ready:
+			candidates = append(candidates, id)
+		default:
+			loop = false
+		}
+	}
+
+	// the pick only depends on the policy (respectively the seed) and the set of ready IDs
+	sort.Ints(candidates)
+
+	picked := candidates[0]
+
+	switch pool.selectionPolicy {
+	case SelectionPolicyRandom:
+		picked = candidates[pool.rng.Intn(len(candidates))]
+	case SelectionPolicyLRU, SelectionPolicyMRU:
+		for _, id := range candidates[1:] {
+			// invalid IDs are sorted first, they fail the sanity checks of the caller
+			if picked < 0 || picked >= len(pool.dbs) || id >= len(pool.dbs) {
+				break
+			}
+
+			readyAt := pool.dbs[id].readyAt
+			if (pool.selectionPolicy == SelectionPolicyLRU && readyAt.Before(pool.dbs[picked].readyAt)) ||
+				(pool.selectionPolicy == SelectionPolicyMRU && readyAt.After(pool.dbs[picked].readyAt)) {
+				picked = id
+			}
+		}
+	}
+
+	for _, id := range candidates {
+		if id != picked {
+			pool.ready <- id
+		}
+	}
+
+	return picked
+}
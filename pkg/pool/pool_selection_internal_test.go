@@ -0,0 +1,128 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolSelectionSeed(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+
+	// hands out all test DBs of a fresh pool with the given seed, returning the IDs in order
+	sequence := func(seed int64) []int {
+		cfg := PoolConfig{
+			MaxPoolSize:            8,
+			MaxParallelTasks:       1,
+			SelectionSeed:          seed,
+			disableWorkerAutostart: true,
+		}
+		p := newTestPoolCollection(t, cfg, db.Database{TemplateHash: hash1}, cfg.MaxPoolSize, noopRecreateDB)
+
+		ids := make([]int, 0, cfg.MaxPoolSize)
+		for i := 0; i < cfg.MaxPoolSize; i++ {
+			testDB, err := p.GetTestDatabase(ctx, hash1, time.Second)
+			require.NoError(t, err)
+			ids = append(ids, testDB.ID)
+		}
+
+		// each test DB is handed out exactly once
+		_, err := p.GetTestDatabase(ctx, hash1, 10*time.Millisecond)
+		assert.ErrorIs(t, err, ErrTimeout)
+
+		return ids
+	}
+
+	// without a seed, test DBs are handed out in the order they got ready
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7}, sequence(0))
+
+	// reruns with the same seed pick the same IDs in the same order
+	seeded := sequence(42)
+	assert.Equal(t, seeded, sequence(42))
+	assert.ElementsMatch(t, []int{0, 1, 2, 3, 4, 5, 6, 7}, seeded)
+	assert.NotEqual(t, []int{0, 1, 2, 3, 4, 5, 6, 7}, seeded)
+	assert.NotEqual(t, seeded, sequence(7))
+}
+
+func TestPoolSelectionPolicy(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+
+	// readies the test DBs 0-3, gets two of them and returns them in reverse order, then hands out the next one with the given policy
+	next := func(policy SelectionPolicy) (int, PoolSnapshot) {
+		cfg := PoolConfig{
+			MaxPoolSize:            4,
+			MaxParallelTasks:       1,
+			SelectionPolicy:        policy,
+			disableWorkerAutostart: true,
+		}
+		p := newTestPoolCollection(t, cfg, db.Database{TemplateHash: hash1}, cfg.MaxPoolSize, noopRecreateDB)
+
+		pool, err := p.getPool(ctx, hash1)
+		require.NoError(t, err)
+
+		// distinct ready times in the order the test DBs got ready, independent of the resolution of the clock
+		readyAt := time.Now().Add(-time.Hour)
+		markReady := func(id int) {
+			readyAt = readyAt.Add(time.Second)
+			pool.Lock()
+			pool.dbs[id].readyAt = readyAt
+			pool.Unlock()
+		}
+		for id := 0; id < cfg.MaxPoolSize; id++ {
+			markReady(id)
+		}
+
+		first, err := p.GetTestDatabase(ctx, hash1, time.Second)
+		require.NoError(t, err)
+		second, err := p.GetTestDatabase(ctx, hash1, time.Second)
+		require.NoError(t, err)
+
+		require.NoError(t, p.ReturnTestDatabase(ctx, hash1, second.ID))
+		markReady(second.ID)
+		require.NoError(t, p.ReturnTestDatabase(ctx, hash1, first.ID))
+		markReady(first.ID)
+
+		testDB, err := p.GetTestDatabase(ctx, hash1, time.Second)
+		require.NoError(t, err)
+
+		snapshot, err := p.Snapshot(ctx, hash1)
+		require.NoError(t, err)
+
+		return testDB.ID, snapshot
+	}
+
+	tests := []struct {
+		policy SelectionPolicy
+		want   int
+		active SelectionPolicy
+	}{
+		{policy: "", want: 2, active: SelectionPolicyFIFO},
+		{policy: SelectionPolicyFIFO, want: 2, active: SelectionPolicyFIFO},
+		{policy: SelectionPolicyLRU, want: 2, active: SelectionPolicyLRU},
+		{policy: SelectionPolicyMRU, want: 3, active: SelectionPolicyMRU}, // 3 and 2 were handed out first, 3 got ready again last
+		{policy: SelectionPolicyLowestID, want: 0, active: SelectionPolicyLowestID},
+		{policy: "unknown", want: 2, active: SelectionPolicyFIFO},
+	}
+
+	for _, tt := range tests {
+		id, snapshot := next(tt.policy)
+		assert.Equal(t, tt.want, id, "policy %q", tt.policy)
+		assert.Equal(t, tt.active, snapshot.SelectionPolicy, "policy %q", tt.policy)
+	}
+
+	// random picks among all ready test DBs
+	id, snapshot := next(SelectionPolicyRandom)
+	assert.Contains(t, []int{0, 1, 2, 3}, id)
+	assert.Equal(t, SelectionPolicyRandom, snapshot.SelectionPolicy)
+	assert.Equal(t, 3, snapshot.Ready)
+}
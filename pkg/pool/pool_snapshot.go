@@ -0,0 +1,220 @@
+package pool
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+)
+
+// PoolSnapshot describes the current state of a single HashPool.
+type PoolSnapshot struct { //nolint:revive
+	TemplateHash            string                 `json:"templateHash"`
+	Variant                 string                 `json:"variant,omitempty"` // variant of the template served by the pool (see VariantHash), empty if none
+	Ready                   int                    `json:"ready"`
+	Dirty                   int                    `json:"dirty"` // depth of the dirty queue, test DBs waiting to be cleaned
+	Recreating              int                    `json:"recreating"`
+	Poisoned                int                    `json:"poisoned"` // test DBs returned as poisoned, waiting to be fully recreated
+	InUse                   int                    `json:"inUse"`    // test DBs currently held by clients (see InUseInfo)
+	MaxPoolSize             int                    `json:"maxPoolSize"`
+	Workers                 int                    `json:"workers"`                 // maximal number of tasks (extending or cleaning) running in parallel (MaxParallelTasks)
+	WorkersBusy             int                    `json:"workersBusy"`             // currently running tasks, persistently equal to Workers with a deep dirty queue hints to raise MaxParallelTasks
+	ReadyTarget             int                    `json:"readyTarget"`             // number of test DBs the pool tries to keep ready (InitialPoolSize unless bumped by AutoScale, at least MinReady)
+	MinReady                int                    `json:"minReady"`                // warm standby of ready (or recreating) test DBs kept under churn (0 disabled)
+	SelectionPolicy         SelectionPolicy        `json:"selectionPolicy"`         // active policy selecting among the ready test DBs
+	CleanHook               bool                   `json:"cleanHook"`               // dirty test DBs are cleaned in place by a hook registered for the hash (ResetDB, e.g. the reset SQL of the truncate clean strategy)
+	TemplateMissing         bool                   `json:"templateMissing"`         // the template DB no longer exists (e.g. dropped out-of-band), no test DBs can be created until the template is initialized and finalized again
+	GetCleanTotal           uint64                 `json:"getCleanTotal"`           // number of test DBs handed out in a clean state
+	GetDirtyTotal           uint64                 `json:"getDirtyTotal"`           // number of test DBs handed out as is, without being recreated
+	DirtyRatio              float64                `json:"dirtyRatio"`              // share of handed out test DBs that were dirty (0 if none)
+	CreatedAt               time.Time              `json:"createdAt"`               // time the pool was created
+	LastUsed                time.Time              `json:"lastUsed"`                // last time a test DB was requested, returned or recreated by a client
+	TooManyConnectionsTotal uint64                 `json:"tooManyConnectionsTotal"` // recreate attempts rejected as max_connections was exceeded
+	VerifyCleanFailedTotal  uint64                 `json:"verifyCleanFailedTotal"`  // test DBs returned as clean, but reported dirty by VerifyClean (thus recreated)
+	StaleDirtyTotal         uint64                 `json:"staleDirtyTotal"`         // dirty test DBs force-recreated as they were not cleaned within the DirtyMaxAge
+	CopyDurations           DurationHistogram      `json:"copyDurations"`           // durations of copying the template into test DBs
+	CopyWaitDurations       DurationHistogram      `json:"copyWaitDurations"`       // durations of waiting for a free copy slot (see MaxConcurrentCopies)
+	LockWaitDurations       DurationHistogram      `json:"lockWaitDurations"`       // durations of waiting for the lock of the pool, uncontended acquisitions as 0
+	LockWaitP95Ms           float64                `json:"lockWaitP95Ms"`           // estimated 95th percentile of LockWaitDurations (upper bound of its bucket)
+	TestDatabases           []TestDatabaseSnapshot `json:"testDatabases"`
+}
+
+// TestDatabaseSnapshot describes the current state of a single test DB within a HashPool.
+type TestDatabaseSnapshot struct {
+	ID       int               `json:"id"`
+	Database string            `json:"database"`
+	State    string            `json:"state"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Poisoned bool              `json:"poisoned,omitempty"`
+}
+
+// InUseInfo describes a test DB currently held by a client.
+type InUseInfo struct {
+	ID            int               `json:"id"`
+	Database      string            `json:"database"`
+	AcquiredAt    time.Time         `json:"acquiredAt"`
+	ReservedUntil *time.Time        `json:"reservedUntil,omitempty"` // deadline of the soft reservation (see GetOptions.ReservationTTL)
+	Labels        map[string]string `json:"labels,omitempty"`
+}
+
+func (s dbState) String() string {
+	switch s {
+	case dbStateReady:
+		return "ready"
+	case dbStateDirty:
+		return "dirty"
+	case dbStateRecreating:
+		return "recreating"
+	default:
+		return "unknown"
+	}
+}
+
+// Snapshot returns the current state of the pool and all its test DBs.
+func (pool *HashPool) Snapshot() PoolSnapshot {
+	pool.RLock()
+	defer pool.RUnlock()
+
+	_, variant := SplitVariantHash(pool.templateDB.TemplateHash)
+
+	snapshot := PoolSnapshot{
+		TemplateHash:            pool.templateDB.TemplateHash,
+		Variant:                 variant,
+		Ready:                   len(pool.ready),
+		Dirty:                   len(pool.dirty),
+		Recreating:              len(pool.recreating),
+		MaxPoolSize:             pool.MaxPoolSize,
+		Workers:                 pool.MaxParallelTasks,
+		WorkersBusy:             int(atomic.LoadInt32(&pool.workersBusy)),
+		ReadyTarget:             pool.readyTarget,
+		MinReady:                pool.MinReady,
+		SelectionPolicy:         pool.selectionPolicy,
+		CleanHook:               pool.ResetDB != nil,
+		TemplateMissing:         pool.templateMissing,
+		GetCleanTotal:           pool.getCleanTotal,
+		GetDirtyTotal:           pool.getDirtyTotal,
+		CreatedAt:               pool.createdAt,
+		LastUsed:                pool.lastUsed,
+		TooManyConnectionsTotal: pool.tooManyConnectionsTotal,
+		VerifyCleanFailedTotal:  pool.verifyCleanFailedTotal,
+		StaleDirtyTotal:         pool.staleDirtyTotal,
+		CopyDurations:           pool.copyDurations.snapshot(),
+		CopyWaitDurations:       pool.copyWaitDurations.snapshot(),
+		LockWaitDurations:       pool.lockWaits.Snapshot(),
+		TestDatabases:           make([]TestDatabaseSnapshot, 0, len(pool.dbs)),
+	}
+
+	snapshot.LockWaitP95Ms = snapshot.LockWaitDurations.QuantileMs(0.95)
+
+	if total := pool.getCleanTotal + pool.getDirtyTotal; total > 0 {
+		snapshot.DirtyRatio = float64(pool.getDirtyTotal) / float64(total)
+	}
+
+	for _, testDB := range pool.dbs {
+		snapshot.TestDatabases = append(snapshot.TestDatabases, TestDatabaseSnapshot{
+			ID:       testDB.ID,
+			Database: testDB.Config.Database,
+			State:    testDB.state.String(),
+			Labels:   copyLabels(testDB.Labels),
+			Poisoned: testDB.poisoned,
+		})
+
+		if testDB.poisoned {
+			snapshot.Poisoned++
+		}
+
+		if testDB.state == dbStateDirty && !testDB.acquiredAt.IsZero() {
+			snapshot.InUse++
+		}
+	}
+
+	return snapshot
+}
+
+// PeekReady returns the IDs of all currently ready test DBs (sorted by ID) without reserving any of them.
+func (pool *HashPool) PeekReady() []int {
+	pool.RLock()
+	defer pool.RUnlock()
+
+	ids := make([]int, 0, len(pool.ready))
+	for _, testDB := range pool.dbs {
+		if testDB.state == dbStateReady {
+			ids = append(ids, testDB.ID)
+		}
+	}
+
+	return ids
+}
+
+// PendingCleanup returns the IDs of the dirty test DBs (sorted ascending), waiting to be cleaned by the workers.
+// Contrary to InUse, this includes test DBs already returned or flagged for recreation by their holder.
+func (pool *HashPool) PendingCleanup() []int {
+	pool.RLock()
+	defer pool.RUnlock()
+
+	ids := make([]int, 0, len(pool.dirty))
+	for _, testDB := range pool.dbs {
+		if testDB.state == dbStateDirty {
+			ids = append(ids, testDB.ID)
+		}
+	}
+
+	return ids
+}
+
+// InUse returns all test DBs currently held by clients (handed out and neither returned nor flagged for recreation yet),
+// sorted by the time they were acquired (oldest first). Useful to spot leaked or wedged tests.
+func (pool *HashPool) InUse() []InUseInfo {
+	pool.RLock()
+	defer pool.RUnlock()
+
+	inUse := make([]InUseInfo, 0, len(pool.dirty))
+	for _, testDB := range pool.dbs {
+		if testDB.state != dbStateDirty || testDB.acquiredAt.IsZero() {
+			continue
+		}
+
+		inUse = append(inUse, InUseInfo{
+			ID:            testDB.ID,
+			Database:      testDB.Config.Database,
+			AcquiredAt:    testDB.acquiredAt,
+			ReservedUntil: testDB.ReservedUntil,
+			Labels:        copyLabels(testDB.Labels),
+		})
+	}
+
+	sort.SliceStable(inUse, func(i, j int) bool {
+		return inUse[i].AcquiredAt.Before(inUse[j].AcquiredAt)
+	})
+
+	return inUse
+}
+
+// PeekTestDatabase returns the test DB with the given ID regardless of its state, without handing it out.
+func (pool *HashPool) PeekTestDatabase(id int) (db.TestDatabase, error) {
+	pool.RLock()
+	defer pool.RUnlock()
+
+	if id < 0 || id >= len(pool.dbs) {
+		return db.TestDatabase{}, ErrInvalidIndex
+	}
+
+	testDB := pool.dbs[id].TestDatabase
+	testDB.Labels = copyLabels(testDB.Labels)
+
+	return testDB, nil
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+
+	c := make(map[string]string, len(labels))
+	for k, v := range labels {
+		c[k] = v
+	}
+
+	return c
+}
@@ -0,0 +1,221 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolSnapshotPage(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	cfg := PoolConfig{
+		MaxPoolSize:            2,
+		MaxParallelTasks:       1,
+		disableWorkerAutostart: true,
+	}
+	p := newTestPoolCollection(t, cfg, db.Database{TemplateHash: "h3"}, 0, noopRecreateDB)
+	for _, hash := range []string{"h1", "h5", "h2", "h4"} {
+		p.InitHashPool(ctx, db.Database{TemplateHash: hash}, noopRecreateDB)
+	}
+
+	pageHashes := func(snapshots []PoolSnapshot) []string {
+		hashes := make([]string, 0, len(snapshots))
+		for _, snapshot := range snapshots {
+			hashes = append(hashes, snapshot.TemplateHash)
+		}
+		return hashes
+	}
+
+	snapshots, total := p.SnapshotPage(ctx, 0, 0)
+	assert.Equal(t, 5, total)
+	assert.Equal(t, []string{"h1", "h2", "h3", "h4", "h5"}, pageHashes(snapshots))
+
+	snapshots, total = p.SnapshotPage(ctx, 1, 2)
+	assert.Equal(t, 5, total)
+	assert.Equal(t, []string{"h2", "h3"}, pageHashes(snapshots))
+
+	snapshots, total = p.SnapshotPage(ctx, 4, 2)
+	assert.Equal(t, 5, total)
+	assert.Equal(t, []string{"h5"}, pageHashes(snapshots))
+
+	// offsets beyond the last pool yield an empty page
+	snapshots, total = p.SnapshotPage(ctx, 10, 2)
+	assert.Equal(t, 5, total)
+	assert.Empty(t, snapshots)
+}
+
+func TestPoolPendingCleanup(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	cfg := PoolConfig{
+		MaxPoolSize:            3,
+		MaxParallelTasks:       1,
+		disableWorkerAutostart: true,
+	}
+	p := newTestPoolCollection(t, cfg, db.Database{TemplateHash: "h1"}, 3, noopRecreateDB)
+
+	templateDB2 := db.Database{TemplateHash: "h2"}
+	p.InitHashPool(ctx, templateDB2, noopRecreateDB)
+	require.NoError(t, p.extend(ctx, templateDB2))
+
+	assert.Empty(t, p.PendingCleanup(ctx))
+
+	testDB1, err := p.GetTestDatabase(ctx, "h1", time.Second)
+	require.NoError(t, err)
+	testDB2, err := p.GetTestDatabase(ctx, "h1", time.Second)
+	require.NoError(t, err)
+	_, err = p.GetTestDatabase(ctx, "h1", time.Second)
+	require.NoError(t, err)
+
+	// returned ones are no longer pending, held ones are
+	require.NoError(t, p.ReturnTestDatabase(ctx, "h1", testDB2.ID))
+
+	pending := p.PendingCleanup(ctx)
+	assert.Len(t, pending, 1, "pools without pending test DBs are omitted")
+	assert.Len(t, pending["h1"], 2)
+	assert.NotContains(t, pending["h1"], testDB2.ID)
+	assert.Contains(t, pending["h1"], testDB1.ID)
+	assert.IsIncreasing(t, pending["h1"])
+}
+
+func TestPoolPeekReady(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+		Config: db.DatabaseConfig{
+			Database: "h1_template",
+		},
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:            2,
+		MaxParallelTasks:       1,
+		disableWorkerAutostart: true,
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 2, noopRecreateDB)
+
+	ids, err := p.PeekReady(ctx, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1}, ids)
+
+	// peeking does not reserve, mutating the returned slice has no effect
+	ids[0] = 42
+	ids, err = p.PeekReady(ctx, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1}, ids)
+
+	testDB, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 0, testDB.ID)
+
+	ids, err = p.PeekReady(ctx, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, ids)
+
+	_, err = p.PeekReady(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrUnknownHash)
+}
+
+func TestPoolInUse(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	cfg := PoolConfig{
+		MaxPoolSize:            3,
+		MaxParallelTasks:       1,
+		disableWorkerAutostart: true,
+	}
+	p := newTestPoolCollection(t, cfg, db.Database{TemplateHash: hash1}, 3, noopRecreateDB)
+
+	inUse, err := p.InUse(ctx, hash1)
+	require.NoError(t, err)
+	assert.Empty(t, inUse)
+
+	first, err := p.GetTestDatabaseWithOptions(ctx, hash1, time.Second, GetOptions{Labels: map[string]string{"job": "a"}})
+	require.NoError(t, err)
+	second, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+	third, err := p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+
+	// distinct acquisition times independent of the resolution of the clock
+	pool, err := p.getPool(ctx, hash1)
+	require.NoError(t, err)
+	pool.Lock()
+	pool.dbs[first.ID].acquiredAt = time.Now().Add(-2 * time.Minute)
+	pool.dbs[third.ID].acquiredAt = time.Now().Add(-time.Minute)
+	pool.Unlock()
+
+	// returned test DBs are no longer in use
+	require.NoError(t, p.ReturnTestDatabase(ctx, hash1, second.ID))
+
+	inUse, err = p.InUse(ctx, hash1)
+	require.NoError(t, err)
+	require.Len(t, inUse, 2)
+
+	// sorted by acquisition time, oldest first
+	assert.Equal(t, first.ID, inUse[0].ID)
+	assert.Equal(t, first.Config.Database, inUse[0].Database)
+	assert.Equal(t, map[string]string{"job": "a"}, inUse[0].Labels)
+	assert.Equal(t, third.ID, inUse[1].ID)
+	assert.True(t, inUse[0].AcquiredAt.Before(inUse[1].AcquiredAt))
+
+	// a copy is returned
+	inUse[0].Labels["job"] = "b"
+	inUse, err = p.InUse(ctx, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, "a", inUse[0].Labels["job"])
+
+	_, err = p.InUse(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrUnknownHash)
+}
+
+func TestPoolPeekTestDatabase(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+		Config: db.DatabaseConfig{
+			Username: "ich",
+			Database: "template_h1",
+		},
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:            2,
+		MaxParallelTasks:       1,
+		TestDBNamePrefix:       "test_",
+		disableWorkerAutostart: true,
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 1, noopRecreateDB)
+
+	testDB, err := p.PeekTestDatabase(ctx, hash1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 0, testDB.ID)
+	assert.Equal(t, "ich", testDB.Config.Username)
+	assert.Equal(t, "test_h1_000", testDB.Config.Database)
+
+	// still ready, peeking does not hand it out
+	snapshot, err := p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, snapshot.Ready)
+
+	_, err = p.PeekTestDatabase(ctx, hash1, 1)
+	assert.ErrorIs(t, err, ErrInvalidIndex)
+
+	_, err = p.PeekTestDatabase(ctx, "unknown", 0)
+	assert.ErrorIs(t, err, ErrUnknownHash)
+}
@@ -0,0 +1,95 @@
+package pool
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// StatsDCounters renders metrics in the DogStatsD line format (tags are appended as |#key:value). As StatsD counters are
+// increments, it remembers the last value of each counter series (the ones of WritePrometheus are totals) to send the delta
+// since the previous flush. Not safe for concurrent use, a single exporter is expected to own it.
+type StatsDCounters struct {
+	last map[string]float64 // map[name|tags]
+	seen map[string]bool    // series written since the last Sweep
+}
+
+func NewStatsDCounters() *StatsDCounters {
+	return &StatsDCounters{
+		last: make(map[string]float64),
+		seen: make(map[string]bool),
+	}
+}
+
+// WriteMetric writes a single series of the given kind (gauge or counter) with the given tags (formatted and escaped already, e.g. outcome:success,
+// empty if none). Counters are written as delta to their last value (or as is if they were reset meanwhile), unchanged counters are skipped.
+func (c *StatsDCounters) WriteMetric(w io.Writer, name string, kind string, tags string, value float64) {
+	suffix := ""
+	if len(tags) > 0 {
+		suffix = "|#" + tags
+	}
+
+	if kind != "counter" {
+		fmt.Fprintf(w, "%s:%s|g%s\n", name, formatPrometheusValue(value), suffix)
+		return
+	}
+
+	key := name + "|" + tags
+	c.seen[key] = true
+
+	delta := value - c.last[key]
+	if delta < 0 {
+		// reset, e.g. the pool of the hash was recreated
+		delta = value
+	}
+	c.last[key] = value
+
+	if delta == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "%s:%s|c%s\n", name, formatPrometheusValue(delta), suffix)
+}
+
+// Sweep forgets the counter series not written since the previous Sweep (e.g. of removed pools), to be called after each flush.
+func (c *StatsDCounters) Sweep() {
+	for key := range c.last {
+		if !c.seen[key] {
+			delete(c.last, key)
+		}
+	}
+
+	c.seen = make(map[string]bool, len(c.last))
+}
+
+// WriteStatsD renders the gauges and counters of the given pool snapshots (the same ones as WritePrometheus, without the histograms)
+// in the DogStatsD line format, tagged by template_hash (and variant if any).
+func WriteStatsD(w io.Writer, snapshots []PoolSnapshot, counters *StatsDCounters) error {
+	bw := bufio.NewWriter(w)
+
+	for _, metric := range prometheusMetrics {
+		for _, s := range snapshots {
+			counters.WriteMetric(bw, metric.name, metric.kind, statsDPoolTags(s), metric.value(s))
+		}
+	}
+
+	return bw.Flush()
+}
+
+// statsDPoolTags returns the tags of the metrics of the given pool snapshot, variant pools are broken down by their variant.
+func statsDPoolTags(s PoolSnapshot) string {
+	hash, variant := SplitVariantHash(s.TemplateHash)
+	if len(variant) == 0 {
+		return "template_hash:" + EscapeStatsDTag(hash)
+	}
+
+	return "template_hash:" + EscapeStatsDTag(hash) + ",variant:" + EscapeStatsDTag(variant)
+}
+
+var statsDTagEscaper = strings.NewReplacer("|", "_", ",", "_", "#", "_", "\n", "_")
+
+// EscapeStatsDTag replaces the characters separating the parts of a DogStatsD line within the given tag value.
+func EscapeStatsDTag(v string) string {
+	return statsDTagEscaper.Replace(v)
+}
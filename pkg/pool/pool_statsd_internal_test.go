@@ -0,0 +1,41 @@
+package pool
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolWriteStatsD(t *testing.T) {
+	t.Parallel()
+
+	counters := NewStatsDCounters()
+	snapshots := []PoolSnapshot{{TemplateHash: "h|1", Ready: 2, GetCleanTotal: 3}}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteStatsD(&buf, snapshots, counters))
+	assert.Contains(t, buf.String(), "integresql_pool_ready:2|g|#template_hash:h_1\n")
+	assert.Contains(t, buf.String(), "integresql_pool_get_clean_total:3|c|#template_hash:h_1\n")
+	assert.NotContains(t, buf.String(), "integresql_pool_get_dirty_total", "unchanged counters are skipped")
+	counters.Sweep()
+
+	// counters are sent as delta to the previous flush
+	snapshots[0].GetCleanTotal = 5
+	buf.Reset()
+	require.NoError(t, WriteStatsD(&buf, snapshots, counters))
+	assert.Contains(t, buf.String(), "integresql_pool_get_clean_total:2|c|#template_hash:h_1\n")
+	counters.Sweep()
+
+	// removed pools are forgotten, thus recreated ones start over
+	buf.Reset()
+	require.NoError(t, WriteStatsD(&buf, nil, counters))
+	assert.Empty(t, buf.String())
+	counters.Sweep()
+
+	snapshots[0].GetCleanTotal = 1
+	buf.Reset()
+	require.NoError(t, WriteStatsD(&buf, snapshots, counters))
+	assert.Contains(t, buf.String(), "integresql_pool_get_clean_total:1|c|#template_hash:h_1\n")
+}
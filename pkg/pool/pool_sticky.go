@@ -0,0 +1,86 @@
+package pool
+
+import (
+	"context"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+)
+
+// defaultStickyKeyTTL is the time a sticky key is remembered after its last get if no StickyKeyTTL is configured.
+const defaultStickyKeyTTL = 5 * time.Minute
+
+// stickyEntry remembers the test DB last handed out for a sticky key (see GetOptions.StickyKey).
+type stickyEntry struct {
+	id         int
+	generation uint // generation of the test DB when handed out, the entry is stale once it was recreated
+	expiresAt  time.Time
+}
+
+// getStickyTestDatabase hands out the test DB last handed out for the sticky key of the given options again, as is (e.g. in its state
+// after a failed test), given it was not recreated meanwhile. Reports false if there is none, the caller falls back to a fresh test DB.
+// dirty reports whether it was handed out as is, as it was not returned meanwhile.
+func (pool *HashPool) getStickyTestDatabase(ctx context.Context, opts GetOptions) (testDB db.TestDatabase, dirty bool, found bool) {
+	log := pool.getPoolLogger(ctx, "getStickyTestDatabase")
+
+	id, found := pool.stickyID(opts.StickyKey)
+	if !found {
+		return db.TestDatabase{}, false, false
+	}
+
+	testDB, dirty, err := pool.GetTestDatabaseByIDWithOptions(ctx, id, opts)
+	if err != nil {
+		// e.g. currently being recreated or cooling down, isolation wins
+		log.Debug().Err(err).Int("id", id).Msg("sticky test database not available, falling back to a fresh one")
+		return db.TestDatabase{}, false, false
+	}
+
+	log.Debug().Int("id", id).Bool("dirty", dirty).Msg("handing out sticky test database again")
+
+	return testDB, dirty, true
+}
+
+// stickyID returns the ID of the test DB last handed out for the given sticky key, if it is neither expired nor was recreated meanwhile.
+func (pool *HashPool) stickyID(key string) (int, bool) {
+	pool.RLock()
+	defer pool.RUnlock()
+
+	entry, found := pool.stickyKeys[key]
+	if !found || time.Now().After(entry.expiresAt) {
+		return 0, false
+	}
+
+	if entry.id < 0 || entry.id >= len(pool.dbs) || pool.dbs[entry.id].generation != entry.generation {
+		return 0, false
+	}
+
+	return entry.id, true
+}
+
+// rememberSticky maps the given sticky key to the test DB just handed out for it, expired keys are forgotten meanwhile.
+func (pool *HashPool) rememberSticky(key string, id int) {
+	ttl := pool.PoolConfig.StickyKeyTTL
+	if ttl <= 0 {
+		ttl = defaultStickyKeyTTL
+	}
+
+	pool.Lock()
+	defer pool.Unlock()
+
+	if id < 0 || id >= len(pool.dbs) {
+		return
+	}
+
+	now := time.Now()
+	for k, entry := range pool.stickyKeys {
+		if now.After(entry.expiresAt) {
+			delete(pool.stickyKeys, k)
+		}
+	}
+
+	pool.stickyKeys[key] = stickyEntry{
+		id:         id,
+		generation: pool.dbs[id].generation,
+		expiresAt:  now.Add(ttl),
+	}
+}
@@ -0,0 +1,64 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolStickyKey(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	cfg := PoolConfig{
+		MaxPoolSize:            3,
+		MaxParallelTasks:       1,
+		StickyKeyTTL:           time.Hour,
+		disableWorkerAutostart: true,
+	}
+	p := newTestPoolCollection(t, cfg, db.Database{TemplateHash: "h1"}, 3, noopRecreateDB)
+
+	sticky := GetOptions{StickyKey: "TestFlaky"}
+
+	first, err := p.GetTestDatabaseWithOptions(ctx, "h1", time.Second, sticky)
+	require.NoError(t, err)
+
+	// the retry gets the same test DB as is, even though the failed attempt still holds it
+	retry, err := p.GetTestDatabaseWithOptions(ctx, "h1", time.Second, sticky)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, retry.ID)
+	assert.NotEqual(t, first.Lease, retry.Lease)
+
+	// other keys (or none) are isolated
+	other, err := p.GetTestDatabaseWithOptions(ctx, "h1", time.Second, GetOptions{StickyKey: "TestOther"})
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, other.ID)
+
+	// once recreated, the key gets a fresh test DB
+	pool, err := p.getPool(ctx, "h1")
+	require.NoError(t, err)
+	pool.Lock()
+	pool.dbs[first.ID].generation++
+	pool.Unlock()
+
+	fresh, err := p.GetTestDatabaseWithOptions(ctx, "h1", time.Second, sticky)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, fresh.ID)
+	assert.NotEqual(t, other.ID, fresh.ID)
+
+	// expired keys are forgotten
+	pool.Lock()
+	entry := pool.stickyKeys["TestOther"]
+	entry.expiresAt = time.Now().Add(-time.Second)
+	pool.stickyKeys["TestOther"] = entry
+	pool.Unlock()
+
+	_, found := pool.stickyID("TestOther")
+	assert.False(t, found)
+	_, found = pool.stickyID("TestFlaky")
+	assert.True(t, found)
+}
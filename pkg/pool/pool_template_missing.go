@@ -0,0 +1,33 @@
+package pool
+
+import (
+	"errors"
+
+	"github.com/rs/zerolog"
+)
+
+// ErrTemplateMissing is returned by the RecreateDBFunc if the template DB to copy from no longer exists (e.g. dropped out-of-band).
+// The pool is marked accordingly, as no test DB can be created anymore until the template is initialized and finalized again.
+var ErrTemplateMissing = errors.New("template database no longer exists (e.g. dropped out-of-band), discard the template and initialize and finalize it again")
+
+// markTemplateMissing flags the pool as broken after copying its template failed with ErrTemplateMissing: The pool is no longer
+// extended and gets fail right away with ErrTemplateMissing once no ready test DB is left, instead of waiting for the timeout.
+func (pool *HashPool) markTemplateMissing(log zerolog.Logger) {
+	pool.Lock()
+	defer pool.Unlock()
+
+	if pool.templateMissing {
+		return
+	}
+
+	pool.templateMissing = true
+	log.Error().Str("templateDB", pool.templateDB.Config.Database).Msg("template database no longer exists, marking pool as broken")
+}
+
+// isTemplateMissing reports whether the template DB of the pool turned out to be missing (see markTemplateMissing).
+func (pool *HashPool) isTemplateMissing() bool {
+	pool.RLock()
+	defer pool.RUnlock()
+
+	return pool.templateMissing
+}
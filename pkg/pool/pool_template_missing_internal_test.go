@@ -0,0 +1,59 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolTemplateMissing(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{TemplateHash: hash1}
+
+	var dropped atomic.Bool
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		if dropped.Load() {
+			return fmt.Errorf("%w: pq: template database %q does not exist", ErrTemplateMissing, templateName)
+		}
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:            3,
+		MaxParallelTasks:       1,
+		disableWorkerAutostart: true,
+	}
+	p := newTestPoolCollection(t, cfg, templateDB1, 1, initFunc)
+
+	// the template is dropped out-of-band, the pool is marked as broken
+	dropped.Store(true)
+	assert.ErrorIs(t, p.extend(ctx, templateDB1), ErrTemplateMissing)
+
+	snapshot, err := p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.True(t, snapshot.TemplateMissing)
+
+	// no further test DBs stuck recreating are added
+	assert.ErrorIs(t, p.extend(ctx, templateDB1), ErrTemplateMissing)
+	snapshot, err = p.Snapshot(ctx, hash1)
+	require.NoError(t, err)
+	assert.Len(t, snapshot.TestDatabases, 2)
+
+	// the remaining ready test DB is still handed out, afterwards gets fail right away instead of waiting for the timeout
+	_, err = p.GetTestDatabase(ctx, hash1, time.Second)
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = p.GetTestDatabase(ctx, hash1, 10*time.Second)
+	assert.ErrorIs(t, err, ErrTemplateMissing)
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,53 @@
+package pool
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+)
+
+// WeightedRoundRobin returns a SelectTemplateFunc distributing the copies among the given template DBs (name -> weight),
+// e.g. identical copies of the template, to spread the copy load. A template DB with weight 2 is picked twice as often as one with weight 1,
+// the picks are interleaved (smooth weighted round-robin) instead of being handed out in bursts. Template DBs with a weight <= 0 are ignored.
+// If no template DB remains, the template DB of the hash is used.
+func WeightedRoundRobin(weights map[string]int) SelectTemplateFunc {
+	names := make([]string, 0, len(weights))
+	for name, weight := range weights {
+		if weight > 0 {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	// deterministic order of the picks
+	sort.Strings(names)
+
+	total := 0
+	for _, name := range names {
+		total += weights[name]
+	}
+
+	var mutex sync.Mutex
+	current := make([]int, len(names))
+
+	return func(_ db.TestDatabase, _ string) string {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		best := 0
+		for i, name := range names {
+			current[i] += weights[name]
+			if current[i] > current[best] {
+				best = i
+			}
+		}
+
+		current[best] -= total
+
+		return names[best]
+	}
+}
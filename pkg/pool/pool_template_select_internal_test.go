@@ -0,0 +1,48 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoolSelectTemplate(t *testing.T) {
+	t.Parallel()
+
+	hash1 := "h1"
+	templateDB1 := db.Database{
+		TemplateHash: hash1,
+		Config: db.DatabaseConfig{
+			Database: "template_h1",
+		},
+	}
+
+	var mutex sync.Mutex
+	var copiedFrom []string
+	initFunc := func(ctx context.Context, testDB db.TestDatabase, templateName string) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		copiedFrom = append(copiedFrom, templateName)
+		return nil
+	}
+
+	cfg := PoolConfig{
+		MaxPoolSize:            6,
+		MaxParallelTasks:       1,
+		SelectTemplate:         WeightedRoundRobin(map[string]int{"template_h1": 1, "template_h1_copy": 2, "template_h1_disabled": 0}),
+		disableWorkerAutostart: true, // no extend / cleanDirty tasks should run automatically!
+	}
+	newTestPoolCollection(t, cfg, templateDB1, 6, initFunc)
+
+	// interleaved by weight
+	assert.Equal(t, []string{
+		"template_h1_copy", "template_h1", "template_h1_copy",
+		"template_h1_copy", "template_h1", "template_h1_copy",
+	}, copiedFrom)
+
+	// without any usable source the template DB of the hash is used
+	assert.Nil(t, WeightedRoundRobin(map[string]int{"template_h1_disabled": 0}))
+}
@@ -0,0 +1,37 @@
+package pool
+
+import (
+	"errors"
+	"strings"
+)
+
+// VariantSeparator separates the template hash from the variant within the key of a variant pool (e.g. "abc~schema-only").
+const VariantSeparator = "~"
+
+// ErrInvalidVariant is returned if a variant contains the VariantSeparator or a slash.
+var ErrInvalidVariant = errors.New("invalid variant, must not contain '" + VariantSeparator + "' or '/'")
+
+// VariantHash returns the key of the pool serving the given variant of a template (e.g. a schema-only flavor without seed data).
+// Each variant is initialized and finalized as a template on its own, thus it gets its own pool. The hash is returned as is without a variant.
+func VariantHash(hash string, variant string) string {
+	if len(variant) == 0 {
+		return hash
+	}
+
+	return hash + VariantSeparator + variant
+}
+
+// SplitVariantHash splits the key of a pool into the template hash and its variant (empty if none), the inverse of VariantHash.
+func SplitVariantHash(key string) (hash string, variant string) {
+	hash, variant, _ = strings.Cut(key, VariantSeparator)
+	return hash, variant
+}
+
+// ValidateVariant returns ErrInvalidVariant if the given variant can't be embedded into the key of a pool.
+func ValidateVariant(variant string) error {
+	if strings.Contains(variant, VariantSeparator) || strings.Contains(variant, "/") {
+		return ErrInvalidVariant
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package pool
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolVariants(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	assert.Equal(t, "h1", VariantHash("h1", ""))
+	assert.Equal(t, "h1~schema-only", VariantHash("h1", "schema-only"))
+	hash, variant := SplitVariantHash("h1~schema-only")
+	assert.Equal(t, "h1", hash)
+	assert.Equal(t, "schema-only", variant)
+	hash, variant = SplitVariantHash("h1")
+	assert.Equal(t, "h1", hash)
+	assert.Empty(t, variant)
+	assert.NoError(t, ValidateVariant("schema-only"))
+	assert.ErrorIs(t, ValidateVariant("a~b"), ErrInvalidVariant)
+	assert.ErrorIs(t, ValidateVariant("a/b"), ErrInvalidVariant)
+
+	cfg := PoolConfig{
+		MaxPoolSize:            2,
+		MaxParallelTasks:       1,
+		disableWorkerAutostart: true,
+	}
+
+	// the full template and its variant get pools of their own
+	templateFull := db.Database{TemplateHash: "h1"}
+	templateSchemaOnly := db.Database{TemplateHash: VariantHash("h1", "schema-only")}
+	p := newTestPoolCollection(t, cfg, templateFull, 1, noopRecreateDB)
+	p.InitHashPool(ctx, templateSchemaOnly, noopRecreateDB)
+	require.NoError(t, p.extend(ctx, templateSchemaOnly))
+	require.NoError(t, p.extend(ctx, templateSchemaOnly))
+
+	testDB, err := p.GetTestDatabase(ctx, templateSchemaOnly.TemplateHash, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "h1~schema-only", testDB.TemplateHash)
+
+	snapshot, err := p.Snapshot(ctx, templateSchemaOnly.TemplateHash)
+	require.NoError(t, err)
+	assert.Equal(t, "schema-only", snapshot.Variant)
+	snapshot, err = p.Snapshot(ctx, templateFull.TemplateHash)
+	require.NoError(t, err)
+	assert.Empty(t, snapshot.Variant)
+
+	var buf bytes.Buffer
+	require.NoError(t, WritePrometheus(&buf, p.SnapshotAll(ctx)))
+	out := buf.String()
+	assert.Contains(t, out, "integresql_pool_ready{template_hash=\"h1\"} 1\n")
+	assert.Contains(t, out, "integresql_pool_ready{template_hash=\"h1\",variant=\"schema-only\"} 1\n")
+	assert.Contains(t, out, "integresql_pool_copy_duration_seconds_count{template_hash=\"h1\",variant=\"schema-only\"} 2\n")
+
+	buf.Reset()
+	require.NoError(t, WriteStatsD(&buf, p.SnapshotAll(ctx), NewStatsDCounters()))
+	assert.Contains(t, buf.String(), "integresql_pool_ready:1|g|#template_hash:h1,variant:schema-only\n")
+}
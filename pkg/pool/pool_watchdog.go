@@ -0,0 +1,56 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrLockStuck is returned by CheckLocks if a mutex could not be acquired in time, hinting to a deadlock.
+var ErrLockStuck = errors.New("mutex could not be acquired in time, the process is possibly deadlocked")
+
+// lockProbeInterval is the interval of retrying to acquire a mutex while probing it.
+const lockProbeInterval = 5 * time.Millisecond
+
+// CheckLocks probes the mutex of the collection and the one of each pool via TryRLock, retrying up to the given timeout each.
+// Contrary to locking, it never blocks beyond the timeout, thus a watchdog may use it to detect a deadlocked process.
+// A single failure may also be caused by a long running operation holding the lock (e.g. removing all test DBs of a pool),
+// thus callers should only act on repeated failures.
+func (p *PoolCollection) CheckLocks(timeout time.Duration) error {
+	if !tryRLockWithin(&p.mutex, timeout) {
+		return fmt.Errorf("%w: pool collection", ErrLockStuck)
+	}
+
+	pools := make(map[string]*HashPool, len(p.pools))
+	for hash, pool := range p.pools {
+		pools[hash] = pool
+	}
+	p.mutex.RUnlock()
+
+	for hash, pool := range pools {
+		if !tryRLockWithin(&pool.RWMutex, timeout) {
+			return wrapPoolError("CheckLocks", hash, -1, ErrLockStuck)
+		}
+		pool.RUnlock()
+	}
+
+	return nil
+}
+
+// tryRLockWithin acquires a read lock of the given mutex if possible within the timeout, the caller must release it if true is returned.
+func tryRLockWithin(mu *sync.RWMutex, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		if mu.TryRLock() {
+			return true
+		}
+
+		if time.Now().After(deadline) {
+			return false
+		}
+
+		time.Sleep(lockProbeInterval)
+	}
+}
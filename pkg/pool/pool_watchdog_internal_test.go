@@ -0,0 +1,44 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolCheckLocks(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	cfg := PoolConfig{
+		MaxPoolSize:            1,
+		MaxParallelTasks:       1,
+		disableWorkerAutostart: true, // no extend / cleanDirty tasks should run automatically!
+	}
+	p := newTestPoolCollection(t, cfg, db.Database{TemplateHash: "h1"}, 0, noopRecreateDB)
+
+	require.NoError(t, p.CheckLocks(10*time.Millisecond))
+
+	pool, err := p.getPool(ctx, "h1")
+	require.NoError(t, err)
+
+	// a lock held beyond the timeout is reported as stuck
+	pool.Lock()
+	err = p.CheckLocks(10 * time.Millisecond)
+	assert.ErrorIs(t, err, ErrLockStuck)
+	var poolErr *PoolError
+	require.ErrorAs(t, err, &poolErr)
+	assert.Equal(t, "h1", poolErr.Hash)
+
+	// ... but not if released within
+	time.AfterFunc(10*time.Millisecond, pool.Unlock)
+	assert.NoError(t, p.CheckLocks(time.Second))
+
+	p.mutex.Lock()
+	assert.ErrorIs(t, p.CheckLocks(10*time.Millisecond), ErrLockStuck)
+	p.mutex.Unlock()
+}
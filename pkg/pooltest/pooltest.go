@@ -0,0 +1,114 @@
+// Package pooltest provides helpers for integration tests against the IntegreSQL pool internals, backed by a real PostgreSQL server.
+//
+// The server is configured like IntegreSQL itself (INTEGRESQL_PGHOST, INTEGRESQL_PGPORT, ... falling back to PGHOST, PGPORT, ...),
+// thus it may be a locally running or dockerized one (e.g. the postgres service of our docker-compose.yml).
+// Tests are skipped if no server is reachable.
+package pooltest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/manager"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ConnectTimeout is the time to wait for the PostgreSQL server before skipping the test.
+var ConnectTimeout = 5 * time.Second
+
+// NewTestManager returns an initialized manager (owning the pool of test DBs) connected to the configured PostgreSQL server
+// and a cleanup func, removing all template and test DBs created through it and disconnecting it.
+// Each call uses a unique database prefix, thus tests may run in parallel. The cleanup is also registered via t.Cleanup.
+// The test is skipped if the PostgreSQL server is not available.
+func NewTestManager(t testing.TB) (*manager.Manager, func()) {
+	t.Helper()
+
+	config := manager.DefaultManagerConfigFromEnv()
+	return NewTestManagerWithConfig(t, config)
+}
+
+// NewTestManagerWithConfig is like NewTestManager, but based on the given config. Its DatabasePrefix is overwritten.
+func NewTestManagerWithConfig(t testing.TB, config manager.ManagerConfig) (*manager.Manager, func()) {
+	t.Helper()
+
+	// unique per call, names must be valid PostgreSQL identifiers
+	config.DatabasePrefix = "pooltest" + strings.ReplaceAll(uuid.NewString(), "-", "")[:8]
+
+	m, config := manager.New(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), ConnectTimeout)
+	defer cancel()
+
+	if err := m.Initialize(ctx); err != nil {
+		t.Skipf("pooltest: PostgreSQL at %s:%d is not available, configure INTEGRESQL_PGHOST / PGHOST and friends: %v",
+			config.ManagerDatabaseConfig.Host, config.ManagerDatabaseConfig.Port, err)
+	}
+
+	var once sync.Once
+	cleanup := func() {
+		once.Do(func() {
+			t.Helper()
+
+			ctx := context.Background()
+
+			if err := m.ResetAllTracking(ctx); err != nil {
+				t.Errorf("pooltest: failed to remove test databases: %v", err)
+			}
+
+			if err := m.Disconnect(ctx, true); err != nil {
+				t.Errorf("pooltest: failed to disconnect manager: %v", err)
+			}
+
+			if err := dropTemplateDatabases(ctx, config); err != nil {
+				t.Errorf("pooltest: failed to remove template databases: %v", err)
+			}
+		})
+	}
+
+	t.Cleanup(cleanup)
+
+	return m, cleanup
+}
+
+// dropTemplateDatabases drops all remaining template databases with the prefix of the given config.
+func dropTemplateDatabases(ctx context.Context, config manager.ManagerConfig) error {
+	conn, err := sql.Open("postgres", config.ManagerDatabaseConfig.ConnectionString())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	rows, err := conn.QueryContext(ctx, "SELECT datname FROM pg_database WHERE left(datname, length($1)) = $1", fmt.Sprintf("%s_%s_", config.DatabasePrefix, config.TemplateDatabasePrefix))
+	if err != nil {
+		return err
+	}
+
+	var dbNames []string
+	for rows.Next() {
+		var dbName string
+		if err := rows.Scan(&dbName); err != nil {
+			rows.Close()
+			return err
+		}
+		dbNames = append(dbNames, dbName)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, dbName := range dbNames {
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS %s", pq.QuoteIdentifier(dbName))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
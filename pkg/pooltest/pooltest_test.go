@@ -0,0 +1,37 @@
+package pooltest_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/allaboutapps/integresql/pkg/pooltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTestManager(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	m, cleanup := pooltest.NewTestManager(t)
+	defer cleanup()
+
+	hash := "pooltesthash"
+
+	template, err := m.InitializeTemplateDatabase(ctx, hash)
+	require.NoError(t, err)
+
+	_, err = m.FinalizeTemplateDatabase(ctx, hash)
+	require.NoError(t, err)
+
+	test, err := m.GetTestDatabase(ctx, hash)
+	require.NoError(t, err)
+	assert.Equal(t, template.TemplateHash, test.TemplateHash)
+
+	conn, err := sql.Open("postgres", test.Config.ConnectionString())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.PingContext(ctx))
+}
@@ -17,30 +17,72 @@ const (
 	TemplateStateFinalized
 )
 
+func (s TemplateState) String() string {
+	switch s {
+	case TemplateStateInit:
+		return "init"
+	case TemplateStateDiscarded:
+		return "discarded"
+	case TemplateStateFinalized:
+		return "finalized"
+	default:
+		return "unknown"
+	}
+}
+
 type Template struct {
 	TemplateConfig
 	db.Database
 	state TemplateState
 
+	initializedAt time.Time // time the template was added to the collection (immutable)
+
 	cond  *sync.Cond
 	mutex sync.RWMutex
 }
 
 type TemplateConfig struct {
 	db.DatabaseConfig
+
+	InlineRecreateMaxSize int64          // Optional per hash override of the template size threshold (bytes) for inline recreation of test DBs
+	CleanStrategy         CleanStrategy  // How dirty test DBs are cleaned, defaults to CleanStrategyRecopy
+	ResetSQL              string         // SQL executed within the dirty test DB to reset it (required for CleanStrategyTruncate and CleanStrategySchema)
+	TestDatabaseOwner     string         // Optional per hash override of the role owning the test DBs
+	LeakWarnTimeout       time.Duration  // Optional per hash override of the duration a test DB may be held before it is logged as suspected leak
+	LeakReclaimTimeout    time.Duration  // Optional per hash override of the duration a test DB may be held before it is force-returned
+	Sources               map[string]int // Optional additional template DBs (name -> weight) with identical content the test DBs are copied from, balanced with the template DB
+	ConnectionLimit       int            // Optional per hash override of the maximal number of connections to each test DB (-1 unlimited)
+	MinReady              int            // Optional per hash override of the warm standby of ready test DBs kept under churn
+	Fingerprint           string         // Optional fingerprint of the template content supplied by the client, detecting a hash reused for changed content
+	External              bool           // The template DB is managed by another process (registered by name), thus it is never created or dropped
 }
 
+// CleanStrategy defines how dirty test DBs of a template are cleaned before being handed out again.
+type CleanStrategy string
+
+const (
+	CleanStrategyRecopy   CleanStrategy = "recopy"   // drop the test DB and create it again from the template (default)
+	CleanStrategyTruncate CleanStrategy = "truncate" // run the ResetSQL (e.g. TRUNCATE) within the test DB, recopy as fallback
+	CleanStrategySchema   CleanStrategy = "schema"   // drop all schemas within the test DB and recreate them by the ResetSQL (e.g. migrations), recopy as fallback
+)
+
 func NewTemplate(hash string, config TemplateConfig) *Template {
 	t := &Template{
 		TemplateConfig: config,
 		Database:       db.Database{TemplateHash: hash, Config: config.DatabaseConfig},
 		state:          TemplateStateInit,
+		initializedAt:  time.Now(),
 	}
 	t.cond = sync.NewCond(&t.mutex)
 
 	return t
 }
 
+// InitializedAt returns the time the template was initialized (added to the collection).
+func (t *Template) InitializedAt() time.Time {
+	return t.initializedAt
+}
+
 func (t *Template) GetConfig(_ context.Context) TemplateConfig {
 	t.mutex.RLock()
 	defer t.mutex.RUnlock()
@@ -122,6 +164,17 @@ func (l LockedTemplate) SetState(_ context.Context, newState TemplateState) {
 	l.t.cond.Broadcast()
 }
 
+// SetFingerprint stores the fingerprint of the template content of the locked template (without acquiring the lock again).
+func (l LockedTemplate) SetFingerprint(_ context.Context, fingerprint string) {
+	l.t.TemplateConfig.Fingerprint = fingerprint
+}
+
+// MatchesFingerprint checks whether the given fingerprint of the template content matches the stored one.
+// Fingerprints are optional, thus missing ones (on either side) always match.
+func (c TemplateConfig) MatchesFingerprint(fingerprint string) bool {
+	return len(c.Fingerprint) == 0 || len(fingerprint) == 0 || c.Fingerprint == fingerprint
+}
+
 func (c TemplateConfig) Equals(other TemplateConfig) bool {
 	return c.DatabaseConfig.ConnectionString() == other.ConnectionString()
 }
@@ -27,6 +27,13 @@ func TestTemplateGetSetState(t *testing.T) {
 	assert.Equal(t, templates.TemplateStateDiscarded, state)
 }
 
+func TestTemplateStateString(t *testing.T) {
+	assert.Equal(t, "init", templates.TemplateStateInit.String())
+	assert.Equal(t, "finalized", templates.TemplateStateFinalized.String())
+	assert.Equal(t, "discarded", templates.TemplateStateDiscarded.String())
+	assert.Equal(t, "unknown", templates.TemplateState(42).String())
+}
+
 func TestForReady(t *testing.T) {
 	ctx := context.Background()
 	goroutineNum := 10
@@ -79,3 +86,11 @@ func TestForReady(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestTemplateConfigMatchesFingerprint(t *testing.T) {
+	assert.True(t, templates.TemplateConfig{}.MatchesFingerprint(""))
+	assert.True(t, templates.TemplateConfig{}.MatchesFingerprint("v1"))
+	assert.True(t, templates.TemplateConfig{Fingerprint: "v1"}.MatchesFingerprint(""))
+	assert.True(t, templates.TemplateConfig{Fingerprint: "v1"}.MatchesFingerprint("v1"))
+	assert.False(t, templates.TemplateConfig{Fingerprint: "v1"}.MatchesFingerprint("v2"))
+}
@@ -0,0 +1,55 @@
+package util
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+var ErrNoMigrations = errors.New("no migration files found")
+
+// FingerprintMigrations computes a canonical hash over the migration files within the given directory of fsys (e.g. an embed.FS or os.DirFS),
+// to be used as template hash: The same migrations always map to the same pool, regardless of the machine computing the hash.
+// The files are hashed in the order of their names (the order migration tools typically apply them in), covering their names and contents.
+// Subdirectories and hidden files (e.g. .gitkeep or .DS_Store) are skipped, CRLF line endings (e.g. of Windows checkouts) are normalized to LF.
+// Returns ErrNoMigrations if the directory contains no migration files (typically a wrong dir).
+func FingerprintMigrations(fsys fs.FS, dir string) (string, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read migrations dir %q: %w", dir, err)
+	}
+
+	h := sha256.New()
+	files := 0
+
+	// entries are sorted by name
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return "", fmt.Errorf("failed to read migration file %q: %w", entry.Name(), err)
+		}
+
+		content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+
+		// length-prefixed, thus no concatenation of names and contents collides with another one
+		fmt.Fprintf(h, "%d:%s%d:", len(entry.Name()), entry.Name(), len(content))
+		h.Write(content)
+
+		files++
+	}
+
+	if files == 0 {
+		return "", fmt.Errorf("%w in %q", ErrNoMigrations, dir)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,63 @@
+package util_test
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/allaboutapps/integresql/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprintMigrations(t *testing.T) {
+	migrations := fstest.MapFS{
+		"migrations/001_create_users.sql":  {Data: []byte("CREATE TABLE users (id int);\n")},
+		"migrations/002_create_posts.sql":  {Data: []byte("CREATE TABLE posts (id int);\n")},
+		"migrations/.gitkeep":              {Data: []byte{}},
+		"migrations/archive/000_setup.sql": {Data: []byte("SELECT 1;\n")},
+	}
+
+	fingerprint, err := util.FingerprintMigrations(migrations, "migrations")
+	require.NoError(t, err)
+	assert.Len(t, fingerprint, 64)
+
+	// deterministic
+	again, err := util.FingerprintMigrations(migrations, "migrations")
+	require.NoError(t, err)
+	assert.Equal(t, fingerprint, again)
+
+	fingerprintOf := func(files fstest.MapFS) string {
+		t.Helper()
+		fingerprint, err := util.FingerprintMigrations(files, "migrations")
+		require.NoError(t, err)
+		return fingerprint
+	}
+
+	// hidden files and subdirectories are skipped, line endings are normalized
+	assert.Equal(t, fingerprint, fingerprintOf(fstest.MapFS{
+		"migrations/001_create_users.sql": {Data: []byte("CREATE TABLE users (id int);\r\n")},
+		"migrations/002_create_posts.sql": {Data: []byte("CREATE TABLE posts (id int);\n")},
+	}))
+
+	// changed contents, names or additional migrations change the fingerprint
+	assert.NotEqual(t, fingerprint, fingerprintOf(fstest.MapFS{
+		"migrations/001_create_users.sql": {Data: []byte("CREATE TABLE users (id bigint);\n")},
+		"migrations/002_create_posts.sql": {Data: []byte("CREATE TABLE posts (id int);\n")},
+	}))
+	assert.NotEqual(t, fingerprint, fingerprintOf(fstest.MapFS{
+		"migrations/001_create_users.sql": {Data: []byte("CREATE TABLE posts (id int);\n")},
+		"migrations/002_create_posts.sql": {Data: []byte("CREATE TABLE users (id int);\n")},
+	}))
+	assert.NotEqual(t, fingerprint, fingerprintOf(fstest.MapFS{
+		"migrations/001_create_users.sql": {Data: []byte("CREATE TABLE users (id int);\n")},
+		"migrations/002_create_posts.sql": {Data: []byte("CREATE TABLE posts (id int);\n")},
+		"migrations/003_create_tags.sql":  {Data: []byte("CREATE TABLE tags (id int);\n")},
+	}))
+
+	_, err = util.FingerprintMigrations(fstest.MapFS{"migrations/.gitkeep": {Data: []byte{}}}, "migrations")
+	assert.ErrorIs(t, err, util.ErrNoMigrations)
+
+	_, err = util.FingerprintMigrations(migrations, "unknown")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
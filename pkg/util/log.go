@@ -31,6 +31,13 @@ func LogFromEchoContext(c echo.Context) *zerolog.Logger {
 	return LogFromContext(c.Request().Context())
 }
 
+// ContextWithRequestID attaches the given request ID to the ctx (see RequestIDFromContext) and to its logger,
+// e.g. to correlate the logs of background work with the request that triggered it.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	l := LogFromContext(ctx).With().Str("requestID", id).Logger()
+	return l.WithContext(context.WithValue(ctx, CTXKeyRequestID, id))
+}
+
 func LogLevelFromString(s string) zerolog.Level {
 	l, err := zerolog.ParseLevel(s)
 	if err != nil {
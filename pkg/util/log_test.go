@@ -1,11 +1,14 @@
 package util_test
 
 import (
+	"bytes"
+	"context"
 	"testing"
 
 	"github.com/allaboutapps/integresql/pkg/util"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLogLevelFromString(t *testing.T) {
@@ -18,3 +21,17 @@ func TestLogLevelFromString(t *testing.T) {
 	res = util.LogLevelFromString("foo")
 	assert.Equal(t, zerolog.DebugLevel, res)
 }
+
+func TestContextWithRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	l := zerolog.New(&buf)
+
+	ctx := util.ContextWithRequestID(l.WithContext(context.Background()), "req-1")
+
+	id, err := util.RequestIDFromContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "req-1", id)
+
+	util.LogFromContext(ctx).Info().Msg("test")
+	assert.Contains(t, buf.String(), `"requestID":"req-1"`)
+}
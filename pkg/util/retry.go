@@ -1,6 +1,7 @@
 package util
 
 import (
+	"context"
 	"fmt"
 	"time"
 )
@@ -19,3 +20,32 @@ func Retry(attempts int, sleep time.Duration, f func() error) error {
 
 	return fmt.Errorf("failing after %d attempts, lat error: %w", attempts, err)
 }
+
+// RetryWithBackoff calls f until it succeeds, at most the given number of attempts (at least once).
+// The sleep between attempts starts at the given sleep and doubles after each failed attempt up to maxSleep.
+// f receives the number of the current attempt (starting at 1), retrying stops early with the ctx error once the ctx is done.
+func RetryWithBackoff(ctx context.Context, attempts int, sleep time.Duration, maxSleep time.Duration, f func(attempt int) error) error {
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		err = f(attempt)
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= attempts {
+			return fmt.Errorf("failing after %d attempts, last error: %w", attempt, err)
+		}
+
+		select {
+		case <-time.After(sleep):
+		case <-ctx.Done():
+			return fmt.Errorf("aborted after %d attempts: %w (last error: %v)", attempt, ctx.Err(), err)
+		}
+
+		sleep *= 2
+		if sleep > maxSleep {
+			sleep = maxSleep
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package util_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/allaboutapps/integresql/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryWithBackoff(t *testing.T) {
+	ctx := context.Background()
+	errFailed := errors.New("failed")
+
+	// succeeds on the third attempt
+	var attempts []int
+	err := util.RetryWithBackoff(ctx, 5, time.Millisecond, 2*time.Millisecond, func(attempt int) error {
+		attempts = append(attempts, attempt)
+		if attempt < 3 {
+			return errFailed
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, attempts)
+
+	// gives up after the given number of attempts
+	calls := 0
+	err = util.RetryWithBackoff(ctx, 3, time.Millisecond, time.Millisecond, func(attempt int) error {
+		calls++
+		return errFailed
+	})
+	assert.ErrorIs(t, err, errFailed)
+	assert.Equal(t, 3, calls)
+
+	// called at least once
+	calls = 0
+	err = util.RetryWithBackoff(ctx, 0, time.Millisecond, time.Millisecond, func(attempt int) error {
+		calls++
+		return errFailed
+	})
+	assert.ErrorIs(t, err, errFailed)
+	assert.Equal(t, 1, calls)
+
+	// the delay doubles up to the maximum
+	start := time.Now()
+	err = util.RetryWithBackoff(ctx, 4, 20*time.Millisecond, 40*time.Millisecond, func(attempt int) error {
+		return errFailed
+	})
+	assert.ErrorIs(t, err, errFailed)
+	elapsed := time.Since(start)
+	assert.GreaterOrEqual(t, elapsed, 100*time.Millisecond) // 20 + 40 + 40
+	assert.Less(t, elapsed, 140*time.Millisecond)           // 20 + 40 + 80 without the maximum
+
+	// aborted once the ctx is done
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	err = util.RetryWithBackoff(cancelCtx, 10, time.Second, time.Second, func(attempt int) error {
+		return errFailed
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -8,7 +8,7 @@ import (
 	"testing"
 	"time"
 
-	"github.com/allaboutapps/integresql/tests/testclient"
+	"github.com/allaboutapps/integresql/pkg/client"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -16,14 +16,14 @@ import (
 
 func BenchmarkGetDatabaseFromNewTemplate(b *testing.B) {
 	ctx := context.Background()
-	client, err := testclient.DefaultClientFromEnv()
+	c, err := client.DefaultClientFromEnv()
 	require.NoError(b, err)
 
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
 			newTemplateHash := uuid.NewString()
 
-			err := client.SetupTemplateWithDBClient(ctx, newTemplateHash, func(db *sql.DB) error {
+			err := c.SetupTemplateWithDBClient(ctx, newTemplateHash, func(db *sql.DB) error {
 				_, err := db.ExecContext(ctx, `CREATE TABLE users (
 			id int NOT NULL,
 			username varchar(255) NOT NULL,
@@ -44,7 +44,7 @@ func BenchmarkGetDatabaseFromNewTemplate(b *testing.B) {
 			})
 			require.NoError(b, err)
 
-			dbConfig, err := client.GetTestDatabase(ctx, newTemplateHash)
+			dbConfig, err := c.GetTestDatabase(ctx, newTemplateHash)
 			require.NoError(b, err)
 			db, err := sql.Open("postgres", dbConfig.Config.ConnectionString())
 			require.NoError(b, err)
@@ -58,7 +58,7 @@ func BenchmarkGetDatabaseFromNewTemplate(b *testing.B) {
 			assert.Equal(b, 2, userCnt)
 			db.Close()
 
-			require.NoError(b, client.DiscardTemplate(ctx, newTemplateHash))
+			require.NoError(b, c.DiscardTemplate(ctx, newTemplateHash))
 		}
 	})
 
@@ -66,11 +66,11 @@ func BenchmarkGetDatabaseFromNewTemplate(b *testing.B) {
 
 func BenchmarkGetDatabaseFromExistingTemplate(b *testing.B) {
 	ctx := context.Background()
-	client, err := testclient.DefaultClientFromEnv()
+	c, err := client.DefaultClientFromEnv()
 	require.NoError(b, err)
 
 	newTemplateHash := uuid.NewString()
-	err = client.SetupTemplateWithDBClient(ctx, newTemplateHash, func(db *sql.DB) error {
+	err = c.SetupTemplateWithDBClient(ctx, newTemplateHash, func(db *sql.DB) error {
 		_, err := db.ExecContext(ctx, `CREATE TABLE users (
 			id int NOT NULL,
 			username varchar(255) NOT NULL,
@@ -93,7 +93,7 @@ func BenchmarkGetDatabaseFromExistingTemplate(b *testing.B) {
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
 
-			dbConfig, err := client.GetTestDatabase(ctx, newTemplateHash)
+			dbConfig, err := c.GetTestDatabase(ctx, newTemplateHash)
 			require.NoError(b, err)
 			db, err := sql.Open("postgres", dbConfig.Config.ConnectionString())
 			require.NoError(b, err)
@@ -109,9 +109,49 @@ func BenchmarkGetDatabaseFromExistingTemplate(b *testing.B) {
 			time.Sleep(time.Second)
 			db.Close()
 
-			require.NoError(b, client.ReturnTestDatabase(ctx, newTemplateHash, dbConfig.ID))
+			require.NoError(b, c.ReturnTestDatabaseWithLease(ctx, newTemplateHash, dbConfig.ID, dbConfig.Lease))
 		}
 	})
 
-	b.Cleanup(func() { require.NoError(b, client.DiscardTemplate(ctx, newTemplateHash)) })
+	b.Cleanup(func() { require.NoError(b, c.DiscardTemplate(ctx, newTemplateHash)) })
+}
+
+func BenchmarkGetTestTransactionFromExistingTemplate(b *testing.B) {
+	ctx := context.Background()
+	c, err := client.DefaultClientFromEnv()
+	require.NoError(b, err)
+
+	newTemplateHash := uuid.NewString()
+	err = c.SetupTemplateWithDBClient(ctx, newTemplateHash, func(db *sql.DB) error {
+		_, err := db.ExecContext(ctx, `CREATE TABLE users (
+			id int NOT NULL,
+			username varchar(255) NOT NULL,
+			created_at timestamptz NOT NULL,
+			CONSTRAINT users_pkey PRIMARY KEY (id));`)
+		require.NoError(b, err)
+		return nil
+	})
+	require.NoError(b, err)
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			tx, rollback, err := c.GetTestTransaction(ctx, newTemplateHash)
+			require.NoError(b, err)
+
+			// rolled back afterwards, thus the next test can insert the same user again
+			_, err = tx.ExecContext(ctx, "INSERT INTO users (id, username, created_at) VALUES (1, 'user1', $1);", time.Now())
+			require.NoError(b, err)
+
+			var userCnt int
+			require.NoError(b, tx.QueryRowContext(ctx, "SELECT COUNT(id) FROM users;").Scan(&userCnt))
+			assert.Equal(b, 1, userCnt)
+
+			require.NoError(b, rollback())
+		}
+	})
+
+	b.Cleanup(func() {
+		require.NoError(b, c.CloseTestTransactions(ctx))
+		require.NoError(b, c.DiscardTemplate(ctx, newTemplateHash))
+	})
 }
@@ -1,56 +0,0 @@
-package testclient
-
-import (
-	"fmt"
-	"sort"
-	"strings"
-)
-
-type TestDatabase struct {
-	Database `json:"database"`
-
-	ID int `json:"id"`
-}
-
-type TemplateDatabase struct {
-	Database `json:"database"`
-}
-
-type Database struct {
-	TemplateHash string         `json:"templateHash"`
-	Config       DatabaseConfig `json:"config"`
-}
-
-type DatabaseConfig struct {
-	Host             string            `json:"host"`
-	Port             int               `json:"port"`
-	Username         string            `json:"username"`
-	Password         string            `json:"password"`
-	Database         string            `json:"database"`
-	AdditionalParams map[string]string `json:"additionalParams,omitempty"` // Optional additional connection parameters mapped into the connection string
-}
-
-// Generates a connection string to be passed to sql.Open or equivalents, assuming Postgres syntax
-func (c DatabaseConfig) ConnectionString() string {
-	var b strings.Builder
-	b.WriteString(fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s", c.Host, c.Port, c.Username, c.Password, c.Database))
-
-	if _, ok := c.AdditionalParams["sslmode"]; !ok {
-		b.WriteString(" sslmode=disable")
-	}
-
-	if len(c.AdditionalParams) > 0 {
-		params := make([]string, 0, len(c.AdditionalParams))
-		for param := range c.AdditionalParams {
-			params = append(params, param)
-		}
-
-		sort.Strings(params)
-
-		for _, param := range params {
-			fmt.Fprintf(&b, " %s=%s", param, c.AdditionalParams[param])
-		}
-	}
-
-	return b.String()
-}